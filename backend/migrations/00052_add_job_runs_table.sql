@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.job_runs (
+    id SERIAL NOT NULL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    job_id TEXT NOT NULL,
+    provider_platform_id INTEGER,
+    open_content_provider_id INTEGER,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    started_at timestamptz NOT NULL DEFAULT NOW(),
+    ended_at timestamptz,
+    error_message VARCHAR(1024),
+    courses_imported INTEGER NOT NULL DEFAULT 0,
+    milestones_upserted INTEGER NOT NULL DEFAULT 0,
+    videos_downloaded INTEGER NOT NULL DEFAULT 0,
+
+    FOREIGN KEY (task_id) REFERENCES public.runnable_tasks(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (job_id) REFERENCES public.cron_jobs(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (provider_platform_id) REFERENCES public.provider_platforms(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (open_content_provider_id) REFERENCES public.open_content_providers(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX idx_job_runs_task_id_status ON public.job_runs USING btree (task_id, status);
+CREATE INDEX idx_job_runs_provider_platform_id_started_at ON public.job_runs USING btree (provider_platform_id, started_at DESC);
+CREATE INDEX idx_job_runs_open_content_provider_id_started_at ON public.job_runs USING btree (open_content_provider_id, started_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.job_runs CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.runnable_tasks ADD COLUMN IF NOT EXISTS schedule VARCHAR(60);
+ALTER TABLE public.runnable_tasks ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.runnable_tasks DROP COLUMN IF EXISTS schedule;
+ALTER TABLE public.runnable_tasks DROP COLUMN IF EXISTS paused;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.provider_sync_cursors (
+    id SERIAL NOT NULL PRIMARY KEY,
+    provider_platform_id INTEGER NOT NULL,
+    entity VARCHAR(100) NOT NULL,
+    cursor TEXT NOT NULL DEFAULT '',
+    updated_at timestamptz NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (provider_platform_id) REFERENCES public.provider_platforms(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_sync_cursor ON public.provider_sync_cursors(provider_platform_id, entity);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.provider_sync_cursors CASCADE;
+-- +goose StatementEnd
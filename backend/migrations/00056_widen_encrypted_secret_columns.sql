@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.provider_platforms ALTER COLUMN access_key TYPE character varying(512);
+ALTER TABLE public.oidc_clients ALTER COLUMN client_secret TYPE character varying(512);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.provider_platforms ALTER COLUMN access_key TYPE character varying(255);
+ALTER TABLE public.oidc_clients ALTER COLUMN client_secret TYPE character varying(255);
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.program_class_enrollments ADD COLUMN waitlist_position INTEGER;
+CREATE INDEX IF NOT EXISTS idx_program_class_enrollments_waitlist ON public.program_class_enrollments USING btree (class_id, waitlist_position) WHERE waitlist_position IS NOT NULL;
+
+ALTER TABLE public.user_account_history ADD COLUMN program_class_id INTEGER;
+ALTER TABLE public.user_account_history ADD CONSTRAINT fk_user_account_history_program_class
+    FOREIGN KEY (program_class_id) REFERENCES public.program_classes(id) ON DELETE SET NULL ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.user_account_history DROP CONSTRAINT IF EXISTS fk_user_account_history_program_class;
+ALTER TABLE public.user_account_history DROP COLUMN IF EXISTS program_class_id;
+DROP INDEX IF EXISTS idx_program_class_enrollments_waitlist;
+ALTER TABLE public.program_class_enrollments DROP COLUMN IF EXISTS waitlist_position;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.program_prerequisites (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    program_id INTEGER NOT NULL,
+    prerequisite_type CHARACTER VARYING(255) NOT NULL,
+    required_program_id INTEGER,
+    credit_type CHARACTER VARYING(255),
+    create_user_id INTEGER,
+    FOREIGN KEY (program_id) REFERENCES public.programs(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (required_program_id) REFERENCES public.programs(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT program_prerequisites_rule_check CHECK (
+        (prerequisite_type = 'completed_program' AND required_program_id IS NOT NULL) OR
+        (prerequisite_type = 'credit_type' AND credit_type IS NOT NULL)
+    )
+);
+CREATE INDEX idx_program_prerequisites_program_id ON public.program_prerequisites USING btree (program_id);
+CREATE INDEX idx_program_prerequisites_deleted_at ON public.program_prerequisites USING btree (deleted_at);
+
+ALTER TABLE public.program_class_enrollments ADD COLUMN override_reason CHARACTER VARYING(255);
+ALTER TABLE public.program_class_enrollments ADD COLUMN override_user_id INTEGER;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.program_class_enrollments DROP COLUMN IF EXISTS override_user_id;
+ALTER TABLE public.program_class_enrollments DROP COLUMN IF EXISTS override_reason;
+DROP TABLE IF EXISTS public.program_prerequisites CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.calendar_feeds (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    token_hash CHARACTER VARYING(64) NOT NULL UNIQUE,
+    feed_type CHARACTER VARYING(255) NOT NULL,
+    facility_id INTEGER NOT NULL,
+    class_id INTEGER,
+    instructor_name CHARACTER VARYING(255),
+    create_user_id INTEGER,
+    FOREIGN KEY (facility_id) REFERENCES public.facilities(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (class_id) REFERENCES public.program_classes(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT calendar_feeds_type_check CHECK (
+        (feed_type = 'facility') OR
+        (feed_type = 'class' AND class_id IS NOT NULL) OR
+        (feed_type = 'instructor' AND instructor_name IS NOT NULL)
+    )
+);
+CREATE INDEX idx_calendar_feeds_facility_id ON public.calendar_feeds USING btree (facility_id);
+CREATE INDEX idx_calendar_feeds_deleted_at ON public.calendar_feeds USING btree (deleted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.calendar_feeds CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.facility_closures (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    facility_id INTEGER NOT NULL,
+    start_dt DATE NOT NULL,
+    end_dt DATE NOT NULL,
+    reason CHARACTER VARYING(255) NOT NULL,
+    rooms TEXT,
+    create_user_id INTEGER,
+    FOREIGN KEY (facility_id) REFERENCES public.facilities(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT facility_closures_dates_check CHECK (end_dt >= start_dt)
+);
+CREATE INDEX idx_facility_closures_facility_id ON public.facility_closures USING btree (facility_id);
+CREATE INDEX idx_facility_closures_deleted_at ON public.facility_closures USING btree (deleted_at);
+
+ALTER TABLE public.program_class_event_overrides ADD COLUMN closure_id INTEGER;
+ALTER TABLE public.program_class_event_overrides ADD CONSTRAINT fk_program_class_event_overrides_closure
+    FOREIGN KEY (closure_id) REFERENCES public.facility_closures(id) ON UPDATE CASCADE ON DELETE CASCADE;
+CREATE INDEX idx_program_class_event_overrides_closure_id ON public.program_class_event_overrides USING btree (closure_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_program_class_event_overrides_closure_id;
+ALTER TABLE public.program_class_event_overrides DROP CONSTRAINT IF EXISTS fk_program_class_event_overrides_closure;
+ALTER TABLE public.program_class_event_overrides DROP COLUMN IF EXISTS closure_id;
+DROP TABLE IF EXISTS public.facility_closures CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.program_classes ADD COLUMN flagged_reason CHARACTER VARYING(255);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.program_classes DROP COLUMN IF EXISTS flagged_reason;
+-- +goose StatementEnd
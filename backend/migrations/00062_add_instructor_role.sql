@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO public.user_roles (name) VALUES ('instructor');
+
+CREATE TABLE public.class_instructors (
+    class_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (class_id, user_id),
+    FOREIGN KEY (class_id) REFERENCES public.program_classes(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES public.users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX idx_class_instructors_user_id ON public.class_instructors USING btree (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.class_instructors CASCADE;
+UPDATE public.users SET role = 'student' WHERE role = 'instructor';
+DELETE FROM public.user_roles WHERE name = 'instructor';
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.certificate_templates (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    program_id INTEGER NOT NULL UNIQUE,
+    title CHARACTER VARYING(255) NOT NULL,
+    body TEXT NOT NULL,
+    signer_name CHARACTER VARYING(255),
+    signer_title CHARACTER VARYING(255),
+    FOREIGN KEY (program_id) REFERENCES public.programs(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX idx_certificate_templates_deleted_at ON public.certificate_templates USING btree (deleted_at);
+
+CREATE TABLE public.completion_certificates (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    program_completion_id INTEGER NOT NULL UNIQUE,
+    verification_code CHARACTER VARYING(32) NOT NULL UNIQUE,
+    FOREIGN KEY (program_completion_id) REFERENCES public.program_completions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX idx_completion_certificates_deleted_at ON public.completion_certificates USING btree (deleted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.completion_certificates CASCADE;
+DROP TABLE IF EXISTS public.certificate_templates CASCADE;
+-- +goose StatementEnd
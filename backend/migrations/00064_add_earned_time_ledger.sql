@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.earned_time_rules (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    program_id INTEGER NOT NULL UNIQUE,
+    hours_per_day NUMERIC(10,2) NOT NULL,
+    max_days NUMERIC(10,2),
+    create_user_id INTEGER,
+    FOREIGN KEY (program_id) REFERENCES public.programs(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT earned_time_rules_hours_per_day_check CHECK (hours_per_day > 0)
+);
+CREATE INDEX idx_earned_time_rules_deleted_at ON public.earned_time_rules USING btree (deleted_at);
+
+CREATE TABLE public.earned_time_entries (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    user_id INTEGER NOT NULL,
+    entry_type CHARACTER VARYING(32) NOT NULL,
+    days NUMERIC(10,2) NOT NULL,
+    credit_hours NUMERIC(10,2) NOT NULL DEFAULT 0,
+    program_completion_id INTEGER UNIQUE,
+    reversed_entry_id INTEGER UNIQUE,
+    reason CHARACTER VARYING(255),
+    create_user_id INTEGER,
+    FOREIGN KEY (user_id) REFERENCES public.users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (program_completion_id) REFERENCES public.program_completions(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    FOREIGN KEY (reversed_entry_id) REFERENCES public.earned_time_entries(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    FOREIGN KEY (create_user_id) REFERENCES public.users(id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+CREATE INDEX idx_earned_time_entries_user_id ON public.earned_time_entries USING btree (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.earned_time_entries CASCADE;
+DROP TABLE IF EXISTS public.earned_time_rules CASCADE;
+-- +goose StatementEnd
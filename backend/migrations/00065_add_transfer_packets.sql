@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.transfer_packets (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    user_id INTEGER NOT NULL,
+    from_facility_id INTEGER NOT NULL,
+    to_facility_id INTEGER NOT NULL,
+    create_user_id INTEGER,
+    contents TEXT,
+    received_at TIMESTAMP WITH TIME ZONE,
+    receive_user_id INTEGER,
+    FOREIGN KEY (user_id) REFERENCES public.users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (from_facility_id) REFERENCES public.facilities(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (to_facility_id) REFERENCES public.facilities(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX idx_transfer_packets_deleted_at ON public.transfer_packets USING btree (deleted_at);
+CREATE INDEX idx_transfer_packets_user_id ON public.transfer_packets USING btree (user_id);
+CREATE INDEX idx_transfer_packets_to_facility_id ON public.transfer_packets USING btree (to_facility_id);
+
+ALTER TABLE public.user_account_history ADD COLUMN transfer_packet_id INTEGER;
+ALTER TABLE public.user_account_history ADD CONSTRAINT fk_user_account_history_transfer_packet
+    FOREIGN KEY (transfer_packet_id) REFERENCES public.transfer_packets(id) ON UPDATE CASCADE ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.user_account_history DROP CONSTRAINT IF EXISTS fk_user_account_history_transfer_packet;
+ALTER TABLE public.user_account_history DROP COLUMN IF EXISTS transfer_packet_id;
+DROP TABLE IF EXISTS public.transfer_packets CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.users ADD COLUMN release_date DATE;
+ALTER TABLE public.users ADD COLUMN released_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX idx_users_release_date ON public.users USING btree (release_date) WHERE released_at IS NULL;
+
+ALTER TABLE public.provider_user_mappings ADD COLUMN deactivated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE public.resident_transcripts (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    user_id INTEGER NOT NULL,
+    reason CHARACTER VARYING(32) NOT NULL,
+    contents TEXT,
+    FOREIGN KEY (user_id) REFERENCES public.users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX idx_resident_transcripts_user_id ON public.resident_transcripts USING btree (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.resident_transcripts CASCADE;
+ALTER TABLE public.provider_user_mappings DROP COLUMN IF EXISTS deactivated_at;
+DROP INDEX IF EXISTS idx_users_release_date;
+ALTER TABLE public.users DROP COLUMN IF EXISTS released_at;
+ALTER TABLE public.users DROP COLUMN IF EXISTS release_date;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.users ADD COLUMN deactivated_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX idx_users_deactivated_at ON public.users USING btree (deactivated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_deactivated_at;
+ALTER TABLE public.users DROP COLUMN IF EXISTS deactivated_at;
+-- +goose StatementEnd
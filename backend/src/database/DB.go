@@ -108,6 +108,8 @@ func MigrateTesting(db *gorm.DB) {
 		&models.ProgramType{},
 		&models.ProgramClassEvent{},
 		&models.ProgramClassEnrollment{},
+		&models.ProgramCompletion{},
+		&models.FacilityClosure{},
 		&models.ProgramClassEventOverride{},
 		&models.ProgramClassEventAttendance{},
 		&models.Milestone{},
@@ -123,6 +125,7 @@ func MigrateTesting(db *gorm.DB) {
 		&models.OpenContentActivity{},
 		&models.CronJob{},
 		&models.RunnableTask{},
+		&models.JobRun{},
 		&models.Library{},
 		&models.FacilityVisibilityStatus{},
 		&models.Video{},
@@ -131,7 +134,17 @@ func MigrateTesting(db *gorm.DB) {
 		&models.UserEnrollment{},
 		&models.UserCourseActivityTotals{},
 		&models.ProgramClassesHistory{},
+		&models.TransferPacket{},
+		&models.ResidentTranscript{},
 		&models.UserAccountHistory{},
+		&models.ProviderSyncCursor{},
+		&models.ProgramPrerequisite{},
+		&models.CalendarFeed{},
+		&models.ClassInstructor{},
+		&models.CertificateTemplate{},
+		&models.CompletionCertificate{},
+		&models.EarnedTimeRule{},
+		&models.EarnedTimeEntry{},
 	}
 	logrus.Println("Running up migrations...")
 	for _, table := range TableList {
@@ -153,7 +166,7 @@ func (db *DB) SeedDefaultData(isTesting bool) {
 			logrus.Fatal("db transaction failed getting default facility")
 		}
 		if isTesting {
-			roles := []models.Role{{Name: "admin"}, {Name: "student"}, {Name: "system_admin"}}
+			roles := []models.Role{{Name: "admin"}, {Name: "student"}, {Name: "system_admin"}, {Name: "instructor"}}
 			for _, role := range roles {
 				if err := db.Create(&role).Error; err != nil {
 					logrus.Fatalf("Failed to create role: %v", err)
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CreateCalendarFeed saves the feed with a new token, which is set on the feed and only returned this once
+func (db *DB) CreateCalendarFeed(ctx context.Context, feed *models.CalendarFeed) error {
+	if err := Validate().Struct(feed); err != nil {
+		return NewDBError(err, "calendar feed validation error")
+	}
+	switch feed.FeedType {
+	case models.ClassCalendarFeed:
+		feed.InstructorName = nil
+		var count int64
+		if err := db.WithContext(ctx).Model(&models.ProgramClass{}).Where("id = ? AND facility_id = ?", feed.ClassID, feed.FacilityID).Count(&count).Error; err != nil {
+			return newGetRecordsDBError(err, "program_classes")
+		}
+		if count == 0 {
+			return newNotFoundDBError(gorm.ErrRecordNotFound, "program_classes")
+		}
+	case models.InstructorCalendarFeed:
+		feed.ClassID = nil
+		name := strings.TrimSpace(*feed.InstructorName)
+		feed.InstructorName = &name
+	default:
+		feed.ClassID, feed.InstructorName = nil, nil
+	}
+	token, hash, err := models.NewCalendarFeedToken()
+	if err != nil {
+		return NewDBError(err, "unable to generate calendar feed token")
+	}
+	feed.TokenHash = hash
+	if err := db.WithContext(ctx).Create(feed).Error; err != nil {
+		return newCreateDBError(err, "calendar_feeds")
+	}
+	feed.Token = token
+	return nil
+}
+
+func (db *DB) GetCalendarFeeds(args *models.QueryContext) ([]models.CalendarFeed, error) {
+	feeds := []models.CalendarFeed{}
+	tx := db.WithContext(args.Ctx).Model(&models.CalendarFeed{}).Preload("Class").Where("facility_id = ?", args.FacilityID)
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "calendar_feeds")
+	}
+	if err := tx.Order("created_at DESC").Limit(args.PerPage).Offset(args.CalcOffset()).Find(&feeds).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "calendar_feeds")
+	}
+	return feeds, nil
+}
+
+func (db *DB) DeleteCalendarFeed(ctx context.Context, id int, facilityID uint) error {
+	result := db.WithContext(ctx).Where("id = ? AND facility_id = ?", id, facilityID).Delete(&models.CalendarFeed{})
+	if result.Error != nil {
+		return newDeleteDBError(result.Error, "calendar_feeds")
+	}
+	if result.RowsAffected == 0 {
+		return newNotFoundDBError(gorm.ErrRecordNotFound, "calendar_feeds")
+	}
+	return nil
+}
+
+func (db *DB) GetCalendarFeedByToken(ctx context.Context, token string) (*models.CalendarFeed, error) {
+	feed := models.CalendarFeed{}
+	if err := db.WithContext(ctx).Preload("Facility").Where("token_hash = ?", models.HashCalendarFeedToken(token)).First(&feed).Error; err != nil {
+		return nil, newNotFoundDBError(err, "calendar_feeds")
+	}
+	return &feed, nil
+}
+
+// GetCalendarFeedEvents returns the events of the classes in the feed with their overrides, classes and programs.
+// Cancelled and archived classes are left out of feeds
+func (db *DB) GetCalendarFeedEvents(ctx context.Context, feed *models.CalendarFeed) ([]models.ProgramClassEvent, error) {
+	events := []models.ProgramClassEvent{}
+	tx := db.WithContext(ctx).Preload("Overrides").Preload("Class.Program").
+		Joins("JOIN program_classes c ON c.id = program_class_events.class_id AND c.deleted_at IS NULL AND c.archived_at IS NULL").
+		Where("c.facility_id = ? AND c.status <> ?", feed.FacilityID, models.Cancelled)
+	switch feed.FeedType {
+	case models.ClassCalendarFeed:
+		tx = tx.Where("c.id = ?", feed.ClassID)
+	case models.InstructorCalendarFeed:
+		tx = tx.Where("LOWER(TRIM(c.instructor_name)) = ?", strings.ToLower(*feed.InstructorName))
+	}
+	if err := tx.Order("program_class_events.id").Find(&events).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_events")
+	}
+	return events, nil
+}
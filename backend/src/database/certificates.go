@@ -0,0 +1,142 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetCertificateTemplate returns the certificate template of the program, or the default one when none was configured
+func (db *DB) GetCertificateTemplate(ctx context.Context, programID uint) (*models.CertificateTemplate, error) {
+	tmpl := models.CertificateTemplate{}
+	err := db.WithContext(ctx).Where("program_id = ?", programID).First(&tmpl).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultCertificateTemplate(programID), nil
+	}
+	if err != nil {
+		return nil, newGetRecordsDBError(err, "certificate_templates")
+	}
+	return &tmpl, nil
+}
+
+// SaveCertificateTemplate creates or replaces the certificate template of the program
+func (db *DB) SaveCertificateTemplate(ctx context.Context, tmpl *models.CertificateTemplate) error {
+	if err := Validate().Struct(tmpl); err != nil {
+		return NewDBError(err, "certificate template validation error")
+	}
+	if err := db.WithContext(ctx).First(&models.Program{}, tmpl.ProgramID).Error; err != nil {
+		return newNotFoundDBError(err, "programs")
+	}
+	existing := models.CertificateTemplate{}
+	err := db.WithContext(ctx).Where("program_id = ?", tmpl.ProgramID).First(&existing).Error
+	switch {
+	case err == nil:
+		tmpl.ID, tmpl.CreatedAt = existing.ID, existing.CreatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return newGetRecordsDBError(err, "certificate_templates")
+	}
+	if err := db.WithContext(ctx).Save(tmpl).Error; err != nil {
+		return newUpdateDBError(err, "certificate_templates")
+	}
+	return nil
+}
+
+/*
+IssueCertificate returns the certificate of the completion of the user along with the template of its program,
+issuing it with a new verification code the first time
+*/
+func (db *DB) IssueCertificate(ctx context.Context, userID, completionID int) (*models.CertificateData, *models.CertificateTemplate, error) {
+	completion := models.ProgramCompletion{}
+	if err := db.WithContext(ctx).Preload("User").Where("id = ? AND user_id = ?", completionID, userID).First(&completion).Error; err != nil {
+		return nil, nil, newNotFoundDBError(err, "program_completions")
+	}
+	code, err := models.NewCertificateVerificationCode()
+	if err != nil {
+		return nil, nil, NewDBError(err, "unable to generate certificate verification code")
+	}
+	// a completion only gets one certificate, when it was already issued (even by a concurrent request) that one is kept
+	certificate := models.CompletionCertificate{ProgramCompletionID: completion.ID, VerificationCode: code}
+	created := db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "program_completion_id"}}, DoNothing: true}).
+		Create(&certificate)
+	if created.Error != nil {
+		return nil, nil, newCreateDBError(created.Error, "completion_certificates")
+	}
+	if created.RowsAffected > 0 {
+		logrus.Infof("issued certificate %d for program completion %d", certificate.ID, completion.ID)
+	}
+	certificate = models.CompletionCertificate{}
+	if err := db.WithContext(ctx).Where("program_completion_id = ?", completion.ID).First(&certificate).Error; err != nil {
+		return nil, nil, newGetRecordsDBError(err, "completion_certificates")
+	}
+	tmpl, err := db.GetCertificateTemplate(ctx, completion.ProgramID)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := db.certificateData(ctx, &completion, certificate.VerificationCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, tmpl, nil
+}
+
+// VerifyCertificate returns what the certificate with the verification code shows, if it was issued
+func (db *DB) VerifyCertificate(ctx context.Context, code string) (*models.CertificateData, error) {
+	certificate := models.CompletionCertificate{}
+	if err := db.WithContext(ctx).Preload("ProgramCompletion.User").
+		Where("verification_code = ?", strings.ToUpper(strings.TrimSpace(code))).
+		First(&certificate).Error; err != nil {
+		return nil, newNotFoundDBError(err, "completion_certificates")
+	}
+	if certificate.ProgramCompletion == nil {
+		return nil, newNotFoundDBError(gorm.ErrRecordNotFound, "program_completions")
+	}
+	return db.certificateData(ctx, certificate.ProgramCompletion, certificate.VerificationCode)
+}
+
+func (db *DB) certificateData(ctx context.Context, completion *models.ProgramCompletion, code string) (*models.CertificateData, error) {
+	data := &models.CertificateData{
+		ProgramName:      completion.ProgramName,
+		ClassName:        completion.ProgramClassName,
+		CreditType:       completion.CreditType,
+		FacilityName:     completion.FacilityName,
+		CompletedAt:      completion.CreatedAt,
+		VerificationCode: code,
+	}
+	if completion.User != nil {
+		data.ResidentName = strings.TrimSpace(completion.User.NameFirst + " " + completion.User.NameLast)
+	}
+	hours, err := db.GetAttendedHours(ctx, completion.ProgramClassID, completion.UserID)
+	if err != nil {
+		return nil, err
+	}
+	data.Hours = hours
+	return data, nil
+}
+
+// GetAttendedHours returns the hours of the sessions of the class the user was marked present for
+func (db *DB) GetAttendedHours(ctx context.Context, classID, userID uint) (float64, error) {
+	durations := []string{}
+	if err := db.WithContext(ctx).Model(&models.ProgramClassEventAttendance{}).
+		Joins("JOIN program_class_events e ON e.id = program_class_event_attendance.event_id").
+		Where("e.class_id = ? AND program_class_event_attendance.user_id = ? AND program_class_event_attendance.attendance_status = ?", classID, userID, models.Present).
+		Pluck("e.duration", &durations).Error; err != nil {
+		return 0, newGetRecordsDBError(err, "program_class_event_attendance")
+	}
+	var attended time.Duration
+	for _, duration := range durations {
+		sessionDuration, err := time.ParseDuration(duration)
+		if err != nil {
+			logrus.Errorf("error parsing class event duration %q: %v", duration, err)
+			continue
+		}
+		attended += sessionDuration
+	}
+	return attended.Hours(), nil
+}
@@ -4,6 +4,9 @@ import (
 	"UnlockEdv2/src/models"
 	"context"
 	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
 )
 
 func (db *DB) GetProgramCompletionsForUser(args *models.QueryContext, userId int, classId *int) ([]models.ProgramCompletion, error) {
@@ -59,46 +62,64 @@ func (db *DB) GetProgramClassEnrollmentsForFacility(page, perPage int, facilityI
 	return total, content, nil
 }
 
-// Returns the number of enrollments that were skipped, and possible error
-func (db *DB) CreateProgramClassEnrollments(classID int, userIds []int) (int, error) {
-	var result struct {
-		Available int
-	}
-	err := db.
-		Table("program_classes").
-		Select("program_classes.capacity - COALESCE(COUNT(pce.id), 0) AS available").
-		Joins(`LEFT JOIN program_class_enrollments pce ON pce.class_id = program_classes.id
-			and pce.enrollment_status = 'Enrolled'`).
-		Where("program_classes.id = ?", classID).
-		Group("program_classes.id, program_classes.capacity").
-		Scan(&result).Error
+// PrerequisiteOverride records the admin that enrolled residents who don't meet the prerequisites
+// of the class's program, and why
+type PrerequisiteOverride struct {
+	AdminID uint
+	Reason  string
+	UserIDs []int
+}
+
+// Enrolls users until the class is at capacity and adds the rest to the end of the class waitlist.
+// Returns the number of users that were waitlisted, and possible error
+func (db *DB) CreateProgramClassEnrollments(classID int, userIds []int, override *PrerequisiteOverride) (int, error) {
+	trans := db.Begin()
+	if trans.Error != nil {
+		return 0, NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	available, err := availableSeats(trans, classID)
 	if err != nil {
-		return 0, newNotFoundDBError(err, "program_classes")
+		trans.Rollback()
+		return 0, err
 	}
-
-	if result.Available <= 0 {
-		return len(userIds), newNotFoundDBError(fmt.Errorf("class is full"), "program_classes")
+	position, err := nextWaitlistPosition(trans, classID)
+	if err != nil {
+		trans.Rollback()
+		return 0, err
 	}
-
-	enrollments := make([]models.ProgramClassEnrollment, 0, min(len(userIds), result.Available))
+	enrollments := make([]models.ProgramClassEnrollment, 0, len(userIds))
+	waitlisted := 0
 	for _, uid := range userIds {
-		if result.Available <= 0 {
-			break
-		}
-		enrollments = append(enrollments, models.ProgramClassEnrollment{
+		enrollment := models.ProgramClassEnrollment{
 			ClassID:          uint(classID),
 			UserID:           uint(uid),
 			EnrollmentStatus: models.Enrolled,
-		})
-		result.Available--
+		}
+		if override != nil && slices.Contains(override.UserIDs, uid) {
+			enrollment.OverrideReason = &override.Reason
+			enrollment.OverrideUserID = &override.AdminID
+		}
+		if available > 0 {
+			available--
+		} else {
+			enrollment.EnrollmentStatus = models.EnrollmentWaitlisted
+			waitlistPosition := position
+			enrollment.WaitlistPosition = &waitlistPosition
+			position++
+			waitlisted++
+		}
+		enrollments = append(enrollments, enrollment)
 	}
-
-	skipped := len(userIds) - len(enrollments)
-	if err := db.Create(&enrollments).Error; err != nil {
-		return 0, newCreateDBError(err, "class enrollment")
+	if len(enrollments) > 0 {
+		if err := trans.Create(&enrollments).Error; err != nil {
+			trans.Rollback()
+			return 0, newCreateDBError(err, "class enrollment")
+		}
 	}
-
-	return skipped, nil
+	if err := trans.Commit().Error; err != nil {
+		return 0, NewDBError(err, "unable to commit the database transaction")
+	}
+	return waitlisted, nil
 }
 
 func (db *DB) DeleteProgramClassEnrollments(id int) error {
@@ -109,9 +130,21 @@ func (db *DB) DeleteProgramClassEnrollments(id int) error {
 }
 
 func (db *DB) GraduateEnrollments(ctx context.Context, adminEmail string, userIds []int, classId int) error {
-	enrollment := models.ProgramClassEnrollment{}
-	// begin transaction
 	tx := db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return NewDBError(tx.Error, "unable to start DB transaction")
+	}
+	if err := graduateEnrollments(tx, adminEmail, userIds, classId); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// graduateEnrollments records the completions of the residents and marks their enrollments completed,
+// it is run in the caller's transaction
+func graduateEnrollments(tx *gorm.DB, adminEmail string, userIds []int, classId int) error {
+	enrollment := models.ProgramClassEnrollment{}
 
 	// preload necessary relationships
 	err := tx.Model(&models.ProgramClassEnrollment{}).
@@ -120,7 +153,6 @@ func (db *DB) GraduateEnrollments(ctx context.Context, adminEmail string, userId
 		Preload("Class.FacilityProg").
 		First(&enrollment, "class_id = ?", classId).Error
 	if err != nil {
-		tx.Rollback()
 		return newNotFoundDBError(err, "class enrollment")
 	}
 
@@ -151,35 +183,118 @@ func (db *DB) GraduateEnrollments(ctx context.Context, adminEmail string, userId
 	}
 
 	if err = tx.Create(&completions).Error; err != nil {
-		tx.Rollback()
 		return newCreateDBError(err, "enrollment completion")
 	}
 
+	if err = postEarnedTimeCredits(tx, enrollment.Class, completions); err != nil {
+		return err
+	}
+
 	// update enrollment status to "Completed"
 	if err = tx.Model(&models.ProgramClassEnrollment{}).
 		Where("user_id IN (?) AND class_id = ?", userIds, classId).
 		Update("enrollment_status", models.Completed).Error; err != nil {
-		tx.Rollback()
 		return newUpdateDBError(err, "enrollment status")
 	}
-
-	// commit the transaction
-	return tx.Commit().Error
+	return nil
 }
 
-func (db *DB) UpdateProgramClassEnrollments(classId int, userIds []int, status string, changeReason *string) error {
+// Residents moved to Enrolled must fit in the open seats of the class, and residents moved to the
+// waitlist are added to its end in the order of userIds. When the new status gives up the residents'
+// seats, the next residents on the class waitlist are enrolled in their place. Returns the number of
+// promoted residents
+func (db *DB) UpdateProgramClassEnrollments(ctx context.Context, classId int, userIds []int, status string, changeReason *string, adminID *uint) (int, error) {
 	updates := map[string]any{
 		"enrollment_status": status,
 	}
 	if changeReason != nil {
 		updates["change_reason"] = *changeReason
 	}
-	if err := db.Model(&models.ProgramClassEnrollment{}).
+	if status != string(models.EnrollmentWaitlisted) {
+		updates["waitlist_position"] = nil
+	}
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return 0, NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	enrollments := make([]models.ProgramClassEnrollment, 0, len(userIds))
+	if err := trans.Where("class_id = ? AND user_id IN (?)", classId, userIds).Find(&enrollments).Error; err != nil {
+		trans.Rollback()
+		return 0, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	positions := map[uint]int{}
+	switch models.ProgramEnrollmentStatus(status) {
+	case models.Enrolled:
+		joining := 0
+		for _, enrollment := range enrollments {
+			if enrollment.EnrollmentStatus != models.Enrolled {
+				joining++
+			}
+		}
+		if joining > 0 {
+			available, err := availableSeats(trans, classId)
+			if err != nil {
+				trans.Rollback()
+				return 0, err
+			}
+			if joining > available {
+				trans.Rollback()
+				return 0, NewDBError(gorm.ErrInvalidData, fmt.Sprintf("the class only has %d open seats for the %d residents being enrolled", max(available, 0), joining))
+			}
+		}
+	case models.EnrollmentWaitlisted:
+		if err := lockProgramClass(trans, classId); err != nil {
+			trans.Rollback()
+			return 0, err
+		}
+		position, err := nextWaitlistPosition(trans, classId)
+		if err != nil {
+			trans.Rollback()
+			return 0, err
+		}
+		for _, uid := range userIds {
+			idx := slices.IndexFunc(enrollments, func(enrollment models.ProgramClassEnrollment) bool {
+				return enrollment.UserID == uint(uid)
+			})
+			if idx == -1 || enrollments[idx].EnrollmentStatus == models.EnrollmentWaitlisted {
+				continue
+			}
+			if _, ok := positions[enrollments[idx].ID]; !ok {
+				positions[enrollments[idx].ID] = position
+				position++
+			}
+		}
+	}
+	if err := trans.Model(&models.ProgramClassEnrollment{}).
 		Where("class_id = ? AND user_id IN (?)", classId, userIds).
 		Updates(updates).Error; err != nil {
-		return newUpdateDBError(err, "class enrollment status")
+		trans.Rollback()
+		return 0, newUpdateDBError(err, "class enrollment status")
+	}
+	for id, position := range positions {
+		if err := trans.Model(&models.ProgramClassEnrollment{}).
+			Where("id = ?", id).
+			Update("waitlist_position", position).Error; err != nil {
+			trans.Rollback()
+			return 0, newUpdateDBError(err, "class enrollment status")
+		}
 	}
-	return nil
+	promoted := 0
+	if slices.Contains(models.SeatReleasingEnrollmentStatuses, models.ProgramEnrollmentStatus(status)) {
+		var err error
+		if promoted, err = promoteWaitlistedEnrollments(trans, classId, adminID); err != nil {
+			trans.Rollback()
+			return 0, err
+		}
+	}
+	if err := compactWaitlist(trans, classId); err != nil {
+		trans.Rollback()
+		return 0, err
+	}
+	if err := trans.Commit().Error; err != nil {
+		return 0, NewDBError(err, "unable to commit the database transaction")
+	}
+	return promoted, nil
 }
 
 func (db *DB) UpdateProgramClasses(ctx context.Context, classIDs []int, classMap map[string]any) error {
@@ -204,6 +319,15 @@ func (db *DB) UpdateProgramClasses(ctx context.Context, classIDs []int, classMap
 			tx.Rollback()
 			return newUpdateDBError(err, "class enrollment statuses")
 		}
+		// nobody can be promoted from the waitlist of a class that has ended
+		if err := tx.
+			Model(&models.ProgramClassEnrollment{}).
+			Where("class_id IN ? AND enrollment_status = ?", classIDs, models.EnrollmentWaitlisted).
+			Updates(map[string]any{"enrollment_status": models.EnrollmentCancelled, "waitlist_position": nil}).
+			Error; err != nil {
+			tx.Rollback()
+			return newUpdateDBError(err, "class enrollment statuses")
+		}
 	}
 	if err := tx.
 		Model(&models.ProgramClass{}).
@@ -250,8 +374,7 @@ type EnrollmentDetails struct {
 	CompletionDt string `json:"completion_dt"`
 }
 
-func (db *DB) GetProgramClassEnrollmentsForProgram(args *models.QueryContext, classId int, status string) ([]EnrollmentDetails, error) {
-	content := make([]EnrollmentDetails, 0, args.PerPage)
+func (db *DB) classEnrollmentsQuery(args *models.QueryContext, classId int, status string) *gorm.DB {
 	search := args.SearchQuery()
 	tx := db.WithContext(args.Ctx).Table("program_class_enrollments pse").Select("pse.*, u.name_last || ', ' || u.name_first as name_full, u.doc_id, c.name as class_name, c.start_dt, pc.created_at as completion_dt").
 		Joins("JOIN program_classes c ON pse.class_id = c.id AND c.deleted_at IS NULL").
@@ -264,7 +387,12 @@ func (db *DB) GetProgramClassEnrollmentsForProgram(args *models.QueryContext, cl
 	if search != "" {
 		tx = tx.Where("u.name_last ILIKE ? OR u.name_first ILIKE ? OR u.doc_id ILIKE ?", search, search, search)
 	}
+	return tx
+}
 
+func (db *DB) GetProgramClassEnrollmentsForProgram(args *models.QueryContext, classId int, status string) ([]EnrollmentDetails, error) {
+	content := make([]EnrollmentDetails, 0, args.PerPage)
+	tx := db.classEnrollmentsQuery(args, classId, status)
 	if err := tx.Count(&args.Total).Error; err != nil {
 		return nil, newNotFoundDBError(err, "program class enrollments")
 	}
@@ -449,6 +449,10 @@ func (db *DB) GetFacilityCalendar(args *models.QueryContext, dtRng *models.DateR
 	if classID > 0 {
 		tx.Where("c.id = ?", classID)
 	}
+	if !args.IsAdmin {
+		// instructors only see the classes they are assigned to
+		tx.Joins("JOIN class_instructors ci ON ci.class_id = c.id AND ci.user_id = ?", args.UserID)
+	}
 	tx.Group("pcev.id, c.instructor_name, c.name, p.name")
 	if err := tx.Scan(&events).Error; err != nil {
 		return nil, newGetRecordsDBError(err, "program_class_events")
@@ -503,6 +507,9 @@ func (db *DB) GetFacilityCalendar(args *models.QueryContext, dtRng *models.DateR
 				IsCancelled:       isCancelled,
 				IsOverride:        isCancelled,
 			}
+			if cancellation := findCancellation(occurrence, overrides); cancellation != nil && cancellation.ClosureID != nil {
+				facilityEvent.ClosureReason = &cancellation.Reason
+			}
 			facilityEvents = append(facilityEvents, facilityEvent)
 		}
 
@@ -536,6 +543,12 @@ func (db *DB) GetFacilityCalendar(args *models.QueryContext, dtRng *models.DateR
 				Frequency:         rRule.OrigOptions.Freq.String(),
 				IsOverride:        true,
 			}
+			if cancellation := findCancellation(overrideDate, overrides); cancellation != nil {
+				facilityEvent.IsCancelled = true
+				if cancellation.ClosureID != nil {
+					facilityEvent.ClosureReason = &cancellation.Reason
+				}
+			}
 			facilityEvents = append(facilityEvents, facilityEvent)
 		}
 	}
@@ -546,6 +559,23 @@ func (db *DB) GetFacilityCalendar(args *models.QueryContext, dtRng *models.DateR
 	return facilityEvents, nil
 }
 
+// findCancellation returns the override cancelling the session at occurrence, other than one moving the session to another time
+func findCancellation(occurrence time.Time, overrides []models.ProgramClassEventOverride) *models.ProgramClassEventOverride {
+	for idx := range overrides {
+		if !overrides[idx].IsCancelled || overrides[idx].Reason == "rescheduled" {
+			continue
+		}
+		options, err := rrule.StrToROption(overrides[idx].OverrideRrule)
+		if err != nil {
+			continue
+		}
+		if options.Dtstart.Equal(occurrence) {
+			return &overrides[idx]
+		}
+	}
+	return nil
+}
+
 func checkEventCancelledAndRescheduled(occurrence time.Time, overrides []models.ProgramClassEventOverride) (bool, bool) {
 	var (
 		isCancelled   = false
@@ -626,6 +656,7 @@ func applyOverrides(event models.ProgramClassEvent, start, end time.Time) []mode
 	mainSet.RRule(mainRule)
 
 	var rDates []models.EventInstance
+	cancelled := map[time.Time]bool{}
 
 	for _, override := range event.Overrides {
 		overrideOptions, err := rrule.StrToROption(override.OverrideRrule)
@@ -643,6 +674,9 @@ func applyOverrides(event models.ProgramClassEvent, start, end time.Time) []mode
 		if override.IsCancelled {
 			for _, occ := range overrideOccurrences {
 				mainSet.ExDate(occ.UTC())
+				if override.Reason != "rescheduled" {
+					cancelled[occ.UTC()] = true
+				}
 			}
 		} else {
 			for _, occ := range overrideOccurrences {
@@ -682,7 +716,12 @@ func applyOverrides(event models.ProgramClassEvent, start, end time.Time) []mode
 		instances = append(instances, instance)
 	}
 
-	instances = append(instances, rDates...)
+	for _, rDate := range rDates {
+		// rescheduled sessions can be cancelled too, e.g. by a facility closure
+		if !cancelled[rDate.StartTime] {
+			instances = append(instances, rDate)
+		}
+	}
 	sort.Slice(instances, func(i, j int) bool {
 		return instances[i].StartTime.Before(instances[j].StartTime)
 	})
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+func (db *DB) GetClassInstructors(ctx context.Context, classID int) ([]models.User, error) {
+	instructors := []models.User{}
+	if err := db.WithContext(ctx).Model(&models.User{}).
+		Joins("JOIN class_instructors ci ON ci.user_id = users.id").
+		Where("ci.class_id = ?", classID).
+		Order("users.name_last, users.name_first").
+		Find(&instructors).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "class_instructors")
+	}
+	return instructors, nil
+}
+
+/*
+SetClassInstructors replaces the instructors assigned to the class. Every user must have the instructor
+role and belong to the facility of the class
+*/
+func (db *DB) SetClassInstructors(ctx context.Context, classID int, userIDs []uint) error {
+	class := models.ProgramClass{}
+	if err := db.WithContext(ctx).First(&class, classID).Error; err != nil {
+		return newNotFoundDBError(err, "program_classes")
+	}
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+	if len(userIDs) > 0 {
+		var count int64
+		if err := db.WithContext(ctx).Model(&models.User{}).
+			Where("id IN ? AND role = ? AND facility_id = ?", userIDs, models.Instructor, class.FacilityID).
+			Count(&count).Error; err != nil {
+			return newGetRecordsDBError(err, "users")
+		}
+		if int(count) != len(userIDs) {
+			return newNotFoundDBError(gorm.ErrRecordNotFound, "instructors")
+		}
+	}
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	if err := trans.Where("class_id = ?", classID).Delete(&models.ClassInstructor{}).Error; err != nil {
+		trans.Rollback()
+		return newDeleteDBError(err, "class_instructors")
+	}
+	if len(userIDs) > 0 {
+		assignments := make([]models.ClassInstructor, 0, len(userIDs))
+		for _, userID := range userIDs {
+			assignments = append(assignments, models.ClassInstructor{ClassID: uint(classID), UserID: userID})
+		}
+		if err := trans.Create(&assignments).Error; err != nil {
+			trans.Rollback()
+			return newCreateDBError(err, "class_instructors")
+		}
+	}
+	if err := trans.Commit().Error; err != nil {
+		return NewDBError(err, "unable to commit the database transaction")
+	}
+	return nil
+}
+
+// GetInstructorClasses returns the classes the instructor is assigned to
+func (db *DB) GetInstructorClasses(args *models.QueryContext, userID int) ([]models.ProgramClass, error) {
+	classes := []models.ProgramClass{}
+	tx := db.WithContext(args.Ctx).Model(&models.ProgramClass{}).
+		Joins("JOIN class_instructors ci ON ci.class_id = program_classes.id").
+		Where("ci.user_id = ? AND program_classes.archived_at IS NULL", userID)
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_classes")
+	}
+	if err := tx.Preload("Program").Preload("Events").
+		Order("program_classes.start_dt DESC").
+		Limit(args.PerPage).Offset(args.CalcOffset()).
+		Find(&classes).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_classes")
+	}
+	return classes, nil
+}
+
+// IsClassInstructor reports whether the user is assigned to the class
+func (db *DB) IsClassInstructor(ctx context.Context, classID int, userID uint) bool {
+	var count int64
+	return db.WithContext(ctx).Model(&models.ClassInstructor{}).
+		Where("class_id = ? AND user_id = ?", classID, userID).
+		Count(&count).Error == nil && count > 0
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+)
+
+// the admin email recorded on the completions of residents graduated by the class status job
+const classStatusJobEmail = "system"
+
+const (
+	flagSessionsAfterEndDt  = "Sessions are still scheduled after the class end date"
+	flagSessionsBeforeEndDt = "The last session was held before the class end date"
+)
+
+/*
+TransitionClassStatuses moves classes through their statuses by their dates and sessions, in the timezone of their facility:
+scheduled classes become active on their start date, and active classes are completed after their last session once their
+end date (if any) has passed, graduating the residents still enrolled. Classes whose sessions and end date disagree are
+flagged for an admin instead. A class that fails to transition doesn't hold up the others, the errors are returned together
+*/
+func (db *DB) TransitionClassStatuses(ctx context.Context, now time.Time) error {
+	classes := []models.ProgramClass{}
+	if err := db.WithContext(ctx).
+		Preload("Facility").
+		Preload("Events.Overrides").
+		Where("archived_at IS NULL AND (status IN ? OR flagged_reason IS NOT NULL)", []models.ClassStatus{models.Scheduled, models.Active}).
+		Find(&classes).Error; err != nil {
+		return newGetRecordsDBError(err, "program_classes")
+	}
+	errs := make([]error, 0)
+	for idx := range classes {
+		if err := db.transitionClassStatus(ctx, &classes[idx], now); err != nil {
+			logrus.Errorf("unable to transition the status of class %d: %v", classes[idx].ID, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (db *DB) transitionClassStatus(ctx context.Context, class *models.ProgramClass, now time.Time) error {
+	loc := time.UTC
+	if class.Facility != nil {
+		if facilityLoc, err := time.LoadLocation(class.Facility.Timezone); err == nil {
+			loc = facilityLoc
+		}
+	}
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	status := class.Status
+	var flaggedReason *string
+	if status == models.Scheduled && !class.StartDt.After(today) {
+		status = models.Active
+	}
+	if status == models.Active {
+		sessionsEnd, ends := classSessionsEnd(class.Events)
+		sessionsOver := len(class.Events) > 0 && ends && !sessionsEnd.After(now)
+		endDtPassed := class.EndDt != nil && class.EndDt.Before(today)
+		switch {
+		case endDtPassed && (len(class.Events) == 0 || sessionsOver), class.EndDt == nil && sessionsOver:
+			status = models.Completed
+		case endDtPassed:
+			flaggedReason = models.StringPtr(flagSessionsAfterEndDt)
+		case sessionsOver:
+			flaggedReason = models.StringPtr(flagSessionsBeforeEndDt)
+		}
+	}
+	if status == class.Status && equalFlags(flaggedReason, class.FlaggedReason) {
+		return nil
+	}
+
+	before := *class
+	// the graduations are rolled back with the status update, so a failed transition is retried whole on the next run
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	if status == models.Completed {
+		if err := graduateCompletedClass(trans, class.ID); err != nil {
+			trans.Rollback()
+			return err
+		}
+	}
+	if err := trans.Model(&models.ProgramClass{}).Where("id = ?", class.ID).
+		Updates(map[string]any{"status": status, "flagged_reason": flaggedReason}).Error; err != nil {
+		trans.Rollback()
+		return newUpdateDBError(err, "program_classes")
+	}
+	class.Status, class.FlaggedReason = status, flaggedReason
+	// postgres records every update of program_classes with a trigger, other databases need the history written here
+	if db.Name() != "postgres" {
+		if err := trans.Create(newProgramClassesHistory(&before, class)).Error; err != nil {
+			trans.Rollback()
+			return newCreateDBError(err, "program_classes_history")
+		}
+	}
+	if err := trans.Commit().Error; err != nil {
+		return NewDBError(err, "unable to commit the database transaction")
+	}
+	logrus.Infof("class %d moved from %s to %s by the class status job", class.ID, before.Status, status)
+	return nil
+}
+
+// graduateCompletedClass graduates the residents still enrolled in the class and cancels its waitlist
+func graduateCompletedClass(tx *gorm.DB, classID uint) error {
+	userIDs := []int{}
+	if err := tx.Model(&models.ProgramClassEnrollment{}).
+		Where("class_id = ? AND enrollment_status = ?", classID, models.Enrolled).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	if len(userIDs) > 0 {
+		if err := graduateEnrollments(tx, classStatusJobEmail, userIDs, int(classID)); err != nil {
+			return err
+		}
+	}
+	// nobody can be promoted from the waitlist of a class that has ended
+	if err := tx.Model(&models.ProgramClassEnrollment{}).
+		Where("class_id = ? AND enrollment_status = ?", classID, models.EnrollmentWaitlisted).
+		Updates(map[string]any{"enrollment_status": models.EnrollmentCancelled, "waitlist_position": nil}).Error; err != nil {
+		return newUpdateDBError(err, "class enrollment statuses")
+	}
+	return nil
+}
+
+// classSessionsEnd returns when the last session of the class events ends, or false when a series never ends
+func classSessionsEnd(events []models.ProgramClassEvent) (time.Time, bool) {
+	var sessionsEnd time.Time
+	for _, event := range events {
+		rule, err := event.GetRRule()
+		if err != nil {
+			logrus.Errorf("error parsing the recurrence rule of event %d: %v", event.ID, err)
+			continue
+		}
+		if rule.OrigOptions.Count == 0 && rule.OrigOptions.Until.IsZero() {
+			return time.Time{}, false
+		}
+		first, last := rule.OrigOptions.Dtstart, rule.OrigOptions.Dtstart
+		if occurrences := rule.All(); len(occurrences) > 0 {
+			last = occurrences[len(occurrences)-1]
+		}
+		// sessions can be rescheduled outside of the series
+		for _, override := range event.Overrides {
+			options, err := rrule.StrToROption(override.OverrideRrule)
+			if err != nil {
+				continue
+			}
+			if options.Dtstart.Before(first) {
+				first = options.Dtstart
+			}
+			if options.Dtstart.After(last) {
+				last = options.Dtstart
+			}
+		}
+		for _, instance := range applyOverrides(event, first, last.Add(24*time.Hour)) {
+			if end := instance.StartTime.Add(instance.Duration); end.After(sessionsEnd) {
+				sessionsEnd = end
+			}
+		}
+	}
+	return sessionsEnd, true
+}
+
+func newProgramClassesHistory(before, after *models.ProgramClass) *models.ProgramClassesHistory {
+	return &models.ProgramClassesHistory{
+		ParentRefID:  after.ID,
+		NameTable:    "program_classes",
+		BeforeUpdate: classHistoryJSON(*before),
+		AfterUpdate:  classHistoryJSON(*after),
+		CreatedAt:    time.Now(),
+	}
+}
+
+// only the columns of the class are recorded, like the rows written by the postgres trigger
+func classHistoryJSON(class models.ProgramClass) json.RawMessage {
+	class.Program, class.Facility, class.FacilityProg = nil, nil, nil
+	class.Events, class.Enrollments = nil, nil
+	row, err := json.Marshal(class)
+	if err != nil {
+		logrus.Errorf("error marshaling program class %d: %v", class.ID, err)
+	}
+	return row
+}
+
+func equalFlags(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
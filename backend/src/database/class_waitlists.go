@@ -0,0 +1,166 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockProgramClass locks the class row until the transaction ends, so concurrent enrollments
+// count the seats and waitlist positions one at a time
+func lockProgramClass(tx *gorm.DB, classID int) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&models.ProgramClass{}, classID).Error; err != nil {
+		return newNotFoundDBError(err, "program_classes")
+	}
+	return nil
+}
+
+// availableSeats returns the capacity of the class minus its enrolled residents, when called
+// in a transaction the class row stays locked until it ends
+func availableSeats(tx *gorm.DB, classID int) (int, error) {
+	if err := lockProgramClass(tx, classID); err != nil {
+		return 0, err
+	}
+	var result struct {
+		Available int
+	}
+	err := tx.
+		Table("program_classes").
+		Select("program_classes.capacity - COALESCE(COUNT(pce.id), 0) AS available").
+		Joins(`LEFT JOIN program_class_enrollments pce ON pce.class_id = program_classes.id
+			and pce.enrollment_status = 'Enrolled' and pce.deleted_at IS NULL`).
+		Where("program_classes.id = ?", classID).
+		Group("program_classes.id, program_classes.capacity").
+		Scan(&result).Error
+	if err != nil {
+		return 0, newNotFoundDBError(err, "program_classes")
+	}
+	return result.Available, nil
+}
+
+func nextWaitlistPosition(tx *gorm.DB, classID int) (int, error) {
+	var position int
+	if err := tx.Model(&models.ProgramClassEnrollment{}).
+		Select("COALESCE(MAX(waitlist_position), 0)").
+		Where("class_id = ? AND enrollment_status = ?", classID, models.EnrollmentWaitlisted).
+		Scan(&position).Error; err != nil {
+		return 0, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	return position + 1, nil
+}
+
+// compactWaitlist renumbers the class waitlist from 1, keeping its order, after residents leave it
+func compactWaitlist(tx *gorm.DB, classID int) error {
+	waitlisted := make([]models.ProgramClassEnrollment, 0)
+	if err := tx.Select("id", "waitlist_position").
+		Where("class_id = ? AND enrollment_status = ?", classID, models.EnrollmentWaitlisted).
+		Order("waitlist_position, created_at").
+		Find(&waitlisted).Error; err != nil {
+		return newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	for idx, enrollment := range waitlisted {
+		if enrollment.WaitlistPosition != nil && *enrollment.WaitlistPosition == idx+1 {
+			continue
+		}
+		if err := tx.Model(&models.ProgramClassEnrollment{}).
+			Where("id = ?", enrollment.ID).
+			Update("waitlist_position", idx+1).Error; err != nil {
+			return newUpdateDBError(err, "program_class_enrollments")
+		}
+	}
+	return nil
+}
+
+// promoteWaitlistedEnrollments enrolls residents from the front of the class waitlist until the
+// open seats are filled, and records each promotion in the resident's account history. adminID is
+// the admin whose change opened the seats
+func promoteWaitlistedEnrollments(tx *gorm.DB, classID int, adminID *uint) (int, error) {
+	available, err := availableSeats(tx, classID)
+	if err != nil || available <= 0 {
+		return 0, err
+	}
+	promoted := make([]models.ProgramClassEnrollment, 0, available)
+	if err := tx.Preload("Class").
+		Where("class_id = ? AND enrollment_status = ?", classID, models.EnrollmentWaitlisted).
+		Order("waitlist_position, created_at").
+		Limit(available).
+		Find(&promoted).Error; err != nil {
+		return 0, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	if len(promoted) == 0 {
+		return 0, nil
+	}
+	ids := make([]uint, 0, len(promoted))
+	history := make([]models.UserAccountHistory, 0, len(promoted))
+	for _, enrollment := range promoted {
+		ids = append(ids, enrollment.ID)
+		promotion := models.NewUserAccountHistory(enrollment.UserID, models.WaitlistPromoted, adminID, nil, &enrollment.Class.FacilityID)
+		promotion.ProgramClassID = &enrollment.ClassID
+		history = append(history, *promotion)
+	}
+	if err := tx.Model(&models.ProgramClassEnrollment{}).
+		Where("id IN (?)", ids).
+		Updates(map[string]any{"enrollment_status": models.Enrolled, "waitlist_position": nil}).Error; err != nil {
+		return 0, newUpdateDBError(err, "program_class_enrollments")
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return 0, newCreateDBError(err, "user_account_history")
+	}
+	if err := compactWaitlist(tx, classID); err != nil {
+		return 0, err
+	}
+	return len(promoted), nil
+}
+
+func (db *DB) GetClassWaitlist(args *models.QueryContext, classID int) ([]EnrollmentDetails, error) {
+	content := make([]EnrollmentDetails, 0, args.PerPage)
+	tx := db.WithContext(args.Ctx).Table("program_class_enrollments pse").
+		Select("pse.*, u.name_last || ', ' || u.name_first as name_full, u.doc_id, c.name as class_name, c.start_dt").
+		Joins("JOIN program_classes c ON pse.class_id = c.id AND c.deleted_at IS NULL").
+		Joins("JOIN users u ON pse.user_id = u.id AND u.deleted_at IS NULL").
+		Where("pse.class_id = ? AND pse.enrollment_status = ? AND pse.deleted_at IS NULL", classID, models.EnrollmentWaitlisted)
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	if err := tx.Order("pse.waitlist_position, pse.created_at").
+		Limit(args.PerPage).
+		Offset(args.CalcOffset()).
+		Find(&content).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	return content, nil
+}
+
+func (db *DB) GetClassWaitlistUserIDs(args *models.QueryContext, classID int) ([]int, error) {
+	userIDs := []int{}
+	if err := db.WithContext(args.Ctx).Model(&models.ProgramClassEnrollment{}).
+		Where("class_id = ? AND enrollment_status = ?", classID, models.EnrollmentWaitlisted).
+		Order("waitlist_position, created_at").
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	return userIDs, nil
+}
+
+// ReorderClassWaitlist renumbers the class waitlist from 1 in the order of userIDs
+func (db *DB) ReorderClassWaitlist(args *models.QueryContext, classID int, userIDs []int) error {
+	trans := db.WithContext(args.Ctx).Begin()
+	if trans.Error != nil {
+		return NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	for idx, userID := range userIDs {
+		if err := trans.Model(&models.ProgramClassEnrollment{}).
+			Where("class_id = ? AND user_id = ? AND enrollment_status = ?", classID, userID, models.EnrollmentWaitlisted).
+			Update("waitlist_position", idx+1).Error; err != nil {
+			trans.Rollback()
+			return newUpdateDBError(err, "program_class_enrollments")
+		}
+	}
+	if err := trans.Commit().Error; err != nil {
+		return NewDBError(err, "unable to commit the database transaction")
+	}
+	return nil
+}
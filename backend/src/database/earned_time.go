@@ -0,0 +1,192 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"errors"
+	"math"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (db *DB) GetEarnedTimeRule(ctx context.Context, programID int) (*models.EarnedTimeRule, error) {
+	rule := models.EarnedTimeRule{}
+	if err := db.WithContext(ctx).Where("program_id = ?", programID).First(&rule).Error; err != nil {
+		return nil, newNotFoundDBError(err, "earned_time_rules")
+	}
+	return &rule, nil
+}
+
+// SaveEarnedTimeRule creates or replaces the earned-time rule of the program, which must award earned-time credit
+func (db *DB) SaveEarnedTimeRule(ctx context.Context, rule *models.EarnedTimeRule) error {
+	if err := Validate().Struct(rule); err != nil {
+		return NewDBError(err, "earned-time rule validation error")
+	}
+	var count int64
+	if err := db.WithContext(ctx).Model(&models.ProgramCreditType{}).
+		Where("program_id = ? AND credit_type = ?", rule.ProgramID, models.EarnedTime).
+		Count(&count).Error; err != nil {
+		return newGetRecordsDBError(err, "program_credit_types")
+	}
+	if count == 0 {
+		return NewDBError(gorm.ErrInvalidData, "the program does not award earned-time credit")
+	}
+	existing := models.EarnedTimeRule{}
+	err := db.WithContext(ctx).Where("program_id = ?", rule.ProgramID).First(&existing).Error
+	switch {
+	case err == nil:
+		rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return newGetRecordsDBError(err, "earned_time_rules")
+	}
+	if err := db.WithContext(ctx).Save(rule).Error; err != nil {
+		return newUpdateDBError(err, "earned_time_rules")
+	}
+	return nil
+}
+
+/*
+postEarnedTimeCredits posts the days earned by the completions of the class to the ledgers of the residents, as part of
+the transaction creating the completions. Nothing is posted when the program has no earned-time rule or the class has
+no credit hours
+*/
+func postEarnedTimeCredits(tx *gorm.DB, class *models.ProgramClass, completions []models.ProgramCompletion) error {
+	if class.CreditHours == nil || *class.CreditHours <= 0 || len(completions) == 0 {
+		return nil
+	}
+	rules := []models.EarnedTimeRule{}
+	if err := tx.Where("program_id = ?", class.ProgramID).Limit(1).Find(&rules).Error; err != nil {
+		return newGetRecordsDBError(err, "earned_time_rules")
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	creditHours := float64(*class.CreditHours)
+	entries := make([]models.EarnedTimeEntry, 0, len(completions))
+	for idx := range completions {
+		entries = append(entries, models.EarnedTimeEntry{
+			UserID:              completions[idx].UserID,
+			EntryType:           models.EarnedTimeCompletion,
+			Days:                rules[0].Days(creditHours),
+			CreditHours:         creditHours,
+			ProgramCompletionID: &completions[idx].ID,
+			Reason:              "Completed " + completions[idx].ProgramName + " (" + completions[idx].ProgramClassName + ")",
+		})
+	}
+	if err := tx.Create(&entries).Error; err != nil {
+		return newCreateDBError(err, "earned_time_entries")
+	}
+	logrus.Infof("posted earned time for %d completions of class %d", len(entries), class.ID)
+	return nil
+}
+
+func (db *DB) GetEarnedTimeBalance(ctx context.Context, userID int) (*models.EarnedTimeBalance, error) {
+	balance := models.EarnedTimeBalance{UserID: uint(userID)}
+	if err := db.WithContext(ctx).Model(&models.EarnedTimeEntry{}).
+		Select("COALESCE(SUM(days), 0) AS balance_days, COALESCE(SUM(credit_hours), 0) AS credit_hours").
+		Where("user_id = ?", userID).
+		Scan(&balance).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "earned_time_entries")
+	}
+	balance.BalanceDays = math.Round(balance.BalanceDays*100) / 100
+	balance.CreditHours = math.Round(balance.CreditHours*100) / 100
+	return &balance, nil
+}
+
+// GetEarnedTimeEntries returns the ledger of the user, the latest entries first
+func (db *DB) GetEarnedTimeEntries(args *models.QueryContext, userID int) ([]models.EarnedTimeEntry, error) {
+	entries := []models.EarnedTimeEntry{}
+	tx := db.WithContext(args.Ctx).Model(&models.EarnedTimeEntry{}).Where("user_id = ?", userID)
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "earned_time_entries")
+	}
+	if err := tx.Preload("ProgramCompletion").
+		Preload("CreateUser", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("id", "username", "name_first", "name_last")
+		}).
+		Order("created_at DESC, id DESC").
+		Limit(args.PerPage).Offset(args.CalcOffset()).
+		Find(&entries).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "earned_time_entries")
+	}
+	return entries, nil
+}
+
+// AdjustEarnedTime posts a manual adjustment, which can't take the balance of the resident below zero
+func (db *DB) AdjustEarnedTime(ctx context.Context, entry *models.EarnedTimeEntry) error {
+	entry.EntryType = models.EarnedTimeAdjustment
+	entry.Days = math.Round(entry.Days*100) / 100
+	entry.CreditHours, entry.ProgramCompletionID, entry.ReversedEntryID = 0, nil, nil
+	if entry.Days == 0 || entry.Reason == "" {
+		return NewDBError(gorm.ErrInvalidData, "adjustments need a number of days and a reason")
+	}
+	return db.postEntry(ctx, entry)
+}
+
+// ReverseEarnedTimeEntry posts the opposite of an entry of the user, every entry can only be reversed once
+func (db *DB) ReverseEarnedTimeEntry(ctx context.Context, userID, entryID int, reason string, adminID uint) (*models.EarnedTimeEntry, error) {
+	reversed := models.EarnedTimeEntry{}
+	if err := db.WithContext(ctx).Where("id = ? AND user_id = ?", entryID, userID).First(&reversed).Error; err != nil {
+		return nil, newNotFoundDBError(err, "earned_time_entries")
+	}
+	if reversed.EntryType == models.EarnedTimeReversal {
+		return nil, NewDBError(gorm.ErrInvalidData, "reversals can't be reversed")
+	}
+	var count int64
+	if err := db.WithContext(ctx).Model(&models.EarnedTimeEntry{}).Where("reversed_entry_id = ?", reversed.ID).Count(&count).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "earned_time_entries")
+	}
+	if count > 0 {
+		return nil, NewDBError(gorm.ErrDuplicatedKey, "the entry was already reversed")
+	}
+	entry := models.EarnedTimeEntry{
+		UserID:          reversed.UserID,
+		EntryType:       models.EarnedTimeReversal,
+		Days:            -reversed.Days,
+		CreditHours:     -reversed.CreditHours,
+		ReversedEntryID: &reversed.ID,
+		Reason:          reason,
+		CreateUserID:    &adminID,
+	}
+	if entry.Reason == "" {
+		return nil, NewDBError(gorm.ErrInvalidData, "reversals need a reason")
+	}
+	if err := db.postEntry(ctx, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// the balance is checked in the same transaction the entry is posted in
+func (db *DB) postEntry(ctx context.Context, entry *models.EarnedTimeEntry) error {
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	// the resident's row is locked so concurrent entries can't each pass the balance check
+	if err := trans.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&models.User{}, entry.UserID).Error; err != nil {
+		trans.Rollback()
+		return newNotFoundDBError(err, "users")
+	}
+	var balance float64
+	if err := trans.Model(&models.EarnedTimeEntry{}).Select("COALESCE(SUM(days), 0)").Where("user_id = ?", entry.UserID).Scan(&balance).Error; err != nil {
+		trans.Rollback()
+		return newGetRecordsDBError(err, "earned_time_entries")
+	}
+	if math.Round((balance+entry.Days)*100) < 0 {
+		trans.Rollback()
+		return NewDBError(gorm.ErrCheckConstraintViolated, "the earned-time balance can't be negative")
+	}
+	if err := trans.Create(entry).Error; err != nil {
+		trans.Rollback()
+		return newCreateDBError(err, "earned_time_entries")
+	}
+	if err := trans.Commit().Error; err != nil {
+		return NewDBError(err, "unable to commit the database transaction")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type storedSecret struct {
+	ID     uint
+	Secret string
+}
+
+// the raw column values are selected so the AfterFind hooks don't decrypt them
+func getStoredSecrets(tx *gorm.DB, table, column string) ([]storedSecret, error) {
+	var secrets []storedSecret
+	if err := tx.Table(table).Select("id, " + column + " AS secret").
+		Where(column + " IS NOT NULL AND " + column + " <> ''").Scan(&secrets).Error; err != nil {
+		return nil, newGetRecordsDBError(err, table)
+	}
+	return secrets, nil
+}
+
+func (db *DB) GetEncryptionKeyUsage() (*models.EncryptionKeyUsage, error) {
+	accessKeys, err := countSecretKeyIDs(db.DB, "provider_platforms", "access_key")
+	if err != nil {
+		return nil, err
+	}
+	clientSecrets, err := countSecretKeyIDs(db.DB, "oidc_clients", "client_secret")
+	if err != nil {
+		return nil, err
+	}
+	return &models.EncryptionKeyUsage{
+		CurrentKeyID:       models.CurrentEncryptionKeyID(),
+		ProviderAccessKeys: accessKeys,
+		OidcClientSecrets:  clientSecrets,
+	}, nil
+}
+
+func countSecretKeyIDs(tx *gorm.DB, table, column string) (map[string]int, error) {
+	secrets, err := getStoredSecrets(tx, table, column)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, secret := range secrets {
+		id, ok := models.SecretKeyID(secret.Secret)
+		if !ok {
+			id = models.UnprefixedSecretKeyID
+		}
+		counts[id]++
+	}
+	return counts, nil
+}
+
+// RotateEncryptedSecrets re-encrypts every provider access key and OIDC client secret that is not
+// already encrypted with the current key. Nothing is written unless every secret can be decrypted, and
+// nothing at all on a dry run. Unprefixed access keys that don't decrypt with the legacy key are only
+// re-encrypted as plain text when allowPlaintext is set, since a wrong legacy key looks the same.
+func (db *DB) RotateEncryptedSecrets(dryRun, allowPlaintext bool) (*models.SecretRotationResult, error) {
+	result := &models.SecretRotationResult{KeyID: models.CurrentEncryptionKeyID(), DryRun: dryRun}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		accessKeys, err := getStoredSecrets(tx, "provider_platforms", "access_key")
+		if err != nil {
+			return err
+		}
+		for _, accessKey := range accessKeys {
+			if id, ok := models.SecretKeyID(accessKey.Secret); ok && id == result.KeyID {
+				continue
+			}
+			plaintext, err := models.DecryptAccessKey(accessKey.Secret)
+			if err != nil {
+				_, prefixed := models.SecretKeyID(accessKey.Secret)
+				if prefixed || errors.Is(err, models.ErrUnknownEncryptionKey) || !allowPlaintext {
+					log.Errorf("unable to decrypt access key for provider platform %d: %v", accessKey.ID, err)
+					return NewDBError(fmt.Errorf("%w: %v", gorm.ErrInvalidData, err),
+						fmt.Sprintf("the access key for provider platform %d could not be decrypted, check APP_PREVIOUS_KEYS or allow it to be re-encrypted as plain text", accessKey.ID))
+				}
+				// the admin confirmed the access keys that aren't ciphertext were saved as plain text
+				plaintext = accessKey.Secret
+				result.PlaintextAccessKeys++
+			}
+			if !dryRun {
+				if err := updateStoredSecret(tx, "provider_platforms", "access_key", accessKey.ID, plaintext); err != nil {
+					return err
+				}
+			}
+			result.ProviderAccessKeys++
+		}
+		clientSecrets, err := getStoredSecrets(tx, "oidc_clients", "client_secret")
+		if err != nil {
+			return err
+		}
+		for _, clientSecret := range clientSecrets {
+			plaintext := clientSecret.Secret
+			if id, ok := models.SecretKeyID(clientSecret.Secret); ok {
+				if id == result.KeyID {
+					continue
+				}
+				if plaintext, err = models.DecryptSecret(clientSecret.Secret); err != nil {
+					log.Errorf("unable to decrypt client secret for oidc client %d: %v", clientSecret.ID, err)
+					return newUpdateDBError(err, "oidc_clients")
+				}
+			}
+			if !dryRun {
+				if err := updateStoredSecret(tx, "oidc_clients", "client_secret", clientSecret.ID, plaintext); err != nil {
+					return err
+				}
+			}
+			result.OidcClientSecrets++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func updateStoredSecret(tx *gorm.DB, table, column string, id uint, plaintext string) error {
+	secret, err := models.EncryptSecret(plaintext)
+	if err != nil {
+		return newUpdateDBError(err, table)
+	}
+	if err := tx.Table(table).Where("id = ?", id).UpdateColumn(column, secret).Error; err != nil {
+		return newUpdateDBError(err, table)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+
+	"gorm.io/gorm"
+)
+
+// streamRows scans the result of the query one row at a time into write, so an export never
+// holds the whole result set in memory
+func streamRows[T any](tx *gorm.DB, table string, write func(*T) error) error {
+	rows, err := tx.Rows()
+	if err != nil {
+		return newGetRecordsDBError(err, table)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row T
+		if err := tx.ScanRows(rows, &row); err != nil {
+			return newGetRecordsDBError(err, table)
+		}
+		if err := write(&row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return newGetRecordsDBError(err, table)
+	}
+	return nil
+}
+
+func (db *DB) ExportUsers(args *models.QueryContext, role string, write func(*models.User) error) error {
+	tx := db.currentUsersQuery(args, role, false).Order(adjustUserOrderBy(args.OrderClause("users.name_last desc")))
+	return streamRows(tx, "users", write)
+}
+
+func (db *DB) ExportClassEnrollments(args *models.QueryContext, classId int, status string, write func(*EnrollmentDetails) error) error {
+	tx := db.classEnrollmentsQuery(args, classId, status).Order(adjustUserOrderBy(args.OrderClause("pse.created_at desc")))
+	return streamRows(tx, "program_class_enrollments", write)
+}
+
+func (db *DB) ExportUserAccountHistory(args *models.QueryContext, userID uint, write func(*models.ActivityHistoryResponse) error) error {
+	return streamRows(db.userAccountHistoryQuery(args, userID).Order("uah.created_at desc"), "user_account_history", write)
+}
+
+type AttendanceExportRow struct {
+	Date             string            `json:"date"`
+	ClassName        string            `json:"class_name"`
+	UserID           uint              `json:"user_id"`
+	NameFull         string            `json:"name_full"`
+	DocID            string            `json:"doc_id"`
+	AttendanceStatus models.Attendance `json:"attendance_status"`
+	Note             string            `json:"note"`
+}
+
+// ExportClassAttendance filters on the optional start_dt, end_dt (YYYY-MM-DD) and user_id query params
+func (db *DB) ExportClassAttendance(args *models.QueryContext, classID int, write func(*AttendanceExportRow) error) error {
+	tx := db.WithContext(args.Ctx).Table("program_class_event_attendance att").
+		Select("att.date, c.name AS class_name, att.user_id, u.name_last || ', ' || u.name_first AS name_full, u.doc_id, att.attendance_status, att.note").
+		Joins("JOIN program_class_events evt ON att.event_id = evt.id AND evt.deleted_at IS NULL").
+		Joins("JOIN program_classes c ON evt.class_id = c.id AND c.deleted_at IS NULL").
+		Joins("JOIN users u ON att.user_id = u.id AND u.deleted_at IS NULL").
+		Where("evt.class_id = ? AND att.deleted_at IS NULL", classID)
+	if start := args.Params.Get("start_dt"); start != "" {
+		tx = tx.Where("att.date >= ?", start)
+	}
+	if end := args.Params.Get("end_dt"); end != "" {
+		tx = tx.Where("att.date <= ?", end)
+	}
+	if userID := args.MaybeID("user_id"); userID != nil {
+		tx = tx.Where("att.user_id = ?", *userID)
+	}
+	if search := args.SearchQuery(); search != "" {
+		tx = tx.Where("u.name_last ILIKE ? OR u.name_first ILIKE ? OR u.doc_id ILIKE ?", search, search, search)
+	}
+	return streamRows(tx.Order("att.date, u.name_last, u.name_first"), "program_class_event_attendance", write)
+}
+
+type CompletionExportRow struct {
+	models.ProgramCompletion
+	NameFull string `json:"name_full"`
+	DocID    string `json:"doc_id"`
+}
+
+// ExportProgramCompletions exports the completions of the classes in the facility, filtered on
+// the optional program_id and class_id query params
+func (db *DB) ExportProgramCompletions(args *models.QueryContext, write func(*CompletionExportRow) error) error {
+	tx := db.WithContext(args.Ctx).Table("program_completions pc").
+		Select("pc.*, u.name_last || ', ' || u.name_first AS name_full, u.doc_id").
+		Joins("JOIN program_classes c ON pc.program_class_id = c.id").
+		Joins("JOIN users u ON pc.user_id = u.id").
+		Where("c.facility_id = ? AND pc.deleted_at IS NULL", args.FacilityID)
+	if programID := args.MaybeID("program_id"); programID != nil {
+		tx = tx.Where("pc.program_id = ?", *programID)
+	}
+	if classID := args.MaybeID("class_id"); classID != nil {
+		tx = tx.Where("pc.program_class_id = ?", *classID)
+	}
+	if search := args.SearchQuery(); search != "" {
+		tx = tx.Where("u.name_last ILIKE ? OR u.name_first ILIKE ? OR u.doc_id ILIKE ?", search, search, search)
+	}
+	return streamRows(tx.Order("pc.created_at desc"), "program_completions", write)
+}
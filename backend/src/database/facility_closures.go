@@ -0,0 +1,166 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+func (db *DB) GetFacilityClosures(args *models.QueryContext) ([]models.FacilityClosure, error) {
+	closures := []models.FacilityClosure{}
+	tx := db.WithContext(args.Ctx).Model(&models.FacilityClosure{}).Where("facility_id = ?", args.FacilityID)
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "facility_closures")
+	}
+	if err := tx.Order("start_dt DESC").Limit(args.PerPage).Offset(args.CalcOffset()).Find(&closures).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "facility_closures")
+	}
+	return closures, nil
+}
+
+// GetFacilityClosuresBetween returns the closures of the facility that overlap the date range
+func (db *DB) GetFacilityClosuresBetween(ctx context.Context, facilityID uint, dtRng *models.DateRange) ([]models.FacilityClosure, error) {
+	closures := []models.FacilityClosure{}
+	startDt := time.Date(dtRng.Start.Year(), dtRng.Start.Month(), dtRng.Start.Day(), 0, 0, 0, 0, time.UTC)
+	endDt := time.Date(dtRng.End.Year(), dtRng.End.Month(), dtRng.End.Day(), 0, 0, 0, 0, time.UTC)
+	if err := db.WithContext(ctx).
+		Where("facility_id = ? AND start_dt <= ? AND end_dt >= ?", facilityID, endDt, startDt).
+		Order("start_dt").
+		Find(&closures).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "facility_closures")
+	}
+	return closures, nil
+}
+
+/*
+CreateFacilityClosure saves the closure and cancels every session of the facility's classes during it, in the
+closure's rooms when it has any. Sessions that are already cancelled, and sessions that already have attendance
+recorded (e.g. when the closure is backdated), are left alone, so removing the closure only restores the sessions
+it cancelled and never loses attendance
+*/
+func (db *DB) CreateFacilityClosure(args *models.QueryContext, closure *models.FacilityClosure) error {
+	if err := Validate().Struct(closure); err != nil {
+		return NewDBError(err, "facility closure validation error")
+	}
+	facility := models.Facility{}
+	if err := db.WithContext(args.Ctx).First(&facility, closure.FacilityID).Error; err != nil {
+		return newNotFoundDBError(err, "facilities")
+	}
+	loc, err := time.LoadLocation(facility.Timezone)
+	if err != nil {
+		return NewDBError(err, "invalid facility timezone")
+	}
+	trans := db.WithContext(args.Ctx).Begin()
+	if trans.Error != nil {
+		return NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	if err := trans.Create(closure).Error; err != nil {
+		trans.Rollback()
+		return newCreateDBError(err, "facility_closures")
+	}
+	events := []models.ProgramClassEvent{}
+	if err := trans.Preload("Overrides").
+		Joins("JOIN program_classes c ON c.id = program_class_events.class_id AND c.deleted_at IS NULL AND c.archived_at IS NULL").
+		Where("c.facility_id = ? AND c.status IN ?", closure.FacilityID, []models.ClassStatus{models.Scheduled, models.Active, models.Paused}).
+		Find(&events).Error; err != nil {
+		trans.Rollback()
+		return newGetRecordsDBError(err, "program_class_events")
+	}
+	start, end := closure.Window(loc)
+	type eventDate struct {
+		EventID uint
+		Date    string
+	}
+	heldSessions := map[eventDate]bool{}
+	if len(events) > 0 {
+		eventIDs := make([]uint, 0, len(events))
+		for _, event := range events {
+			eventIDs = append(eventIDs, event.ID)
+		}
+		attended := []eventDate{}
+		if err := trans.Model(&models.ProgramClassEventAttendance{}).
+			Distinct("event_id", "date").
+			Where("event_id IN (?) AND date BETWEEN ? AND ?", eventIDs, start.UTC().Format("2006-01-02"), end.UTC().Format("2006-01-02")).
+			Scan(&attended).Error; err != nil {
+			trans.Rollback()
+			return newGetRecordsDBError(err, "program_class_event_attendance")
+		}
+		for _, session := range attended {
+			heldSessions[session] = true
+		}
+	}
+	overrides := []models.ProgramClassEventOverride{}
+	changeLogEntries := []models.ChangeLogEntry{}
+	for _, event := range events {
+		for _, instance := range applyOverrides(event, start, end) {
+			if !instance.StartTime.Before(end) || !closure.AffectsRoom(instance.Room) ||
+				heldSessions[eventDate{EventID: event.ID, Date: instance.StartTime.UTC().Format("2006-01-02")}] {
+				continue
+			}
+			override := models.ProgramClassEventOverride{
+				EventID:       event.ID,
+				Duration:      instance.Duration.String(),
+				OverrideRrule: fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=1", instance.StartTime.UTC().Format("20060102T150405Z")),
+				IsCancelled:   true,
+				Room:          instance.Room,
+				Reason:        closure.Reason,
+				ClosureID:     &closure.ID,
+			}
+			overrides = append(overrides, override)
+			changeLogEntries = append(changeLogEntries, *models.NewChangeLogEntry("program_classes", "event_cancelled", models.StringPtr(""), &override.OverrideRrule, event.ClassID, args.UserID))
+		}
+	}
+	if len(overrides) > 0 {
+		if err := trans.Create(&overrides).Error; err != nil {
+			trans.Rollback()
+			return newCreateDBError(err, "program_class_event_overrides")
+		}
+		if err := trans.Create(&changeLogEntries).Error; err != nil {
+			trans.Rollback()
+			return newCreateDBError(err, "change_log_entries")
+		}
+	}
+	if err := trans.Commit().Error; err != nil {
+		return NewDBError(err, "unable to commit the database transaction")
+	}
+	closure.CancelledSessions = len(overrides)
+	return nil
+}
+
+// DeleteFacilityClosure removes the closure and the cancellations it created, restoring the sessions
+func (db *DB) DeleteFacilityClosure(args *models.QueryContext, id int) error {
+	trans := db.WithContext(args.Ctx).Begin()
+	if trans.Error != nil {
+		return NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	closure := models.FacilityClosure{}
+	if err := trans.Preload("Overrides.Event").Where("facility_id = ?", args.FacilityID).First(&closure, id).Error; err != nil {
+		trans.Rollback()
+		return newNotFoundDBError(err, "facility_closures")
+	}
+	changeLogEntries := make([]models.ChangeLogEntry, 0, len(closure.Overrides))
+	for idx := range closure.Overrides {
+		if event := closure.Overrides[idx].Event; event != nil {
+			changeLogEntries = append(changeLogEntries, *models.NewChangeLogEntry("program_classes", "event_restored", &closure.Overrides[idx].OverrideRrule, models.StringPtr(""), event.ClassID, args.UserID))
+		}
+	}
+	if err := trans.Unscoped().Where("closure_id = ?", closure.ID).Delete(&models.ProgramClassEventOverride{}).Error; err != nil {
+		trans.Rollback()
+		return newDeleteDBError(err, "program_class_event_overrides")
+	}
+	if len(changeLogEntries) > 0 {
+		if err := trans.Create(&changeLogEntries).Error; err != nil {
+			trans.Rollback()
+			return newCreateDBError(err, "change_log_entries")
+		}
+	}
+	if err := trans.Delete(&closure).Error; err != nil {
+		trans.Rollback()
+		return newDeleteDBError(err, "facility_closures")
+	}
+	if err := trans.Commit().Error; err != nil {
+		return NewDBError(err, "unable to commit the database transaction")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"slices"
+)
+
+func (db *DB) GetJobRuns(args *models.QueryContext, providerPlatformID, openContentProviderID *int, jobName string, status models.JobStatus) ([]models.JobRun, error) {
+	fields := []string{"started_at", "ended_at", "status"}
+	if !slices.Contains(fields, args.OrderBy) {
+		args.OrderBy = ""
+	}
+	tx := db.WithContext(args.Ctx).Model(&models.JobRun{})
+	if providerPlatformID != nil {
+		tx = tx.Where("job_runs.provider_platform_id = ?", *providerPlatformID)
+	}
+	if openContentProviderID != nil {
+		tx = tx.Where("job_runs.open_content_provider_id = ?", *openContentProviderID)
+	}
+	if jobName != "" {
+		tx = tx.Joins("JOIN cron_jobs cj ON cj.id = job_runs.job_id").Where("cj.name = ?", jobName)
+	}
+	if status != "" {
+		tx = tx.Where("job_runs.status = ?", status)
+	}
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "job_runs")
+	}
+	runs := make([]models.JobRun, 0, args.PerPage)
+	if err := tx.Preload("Job").
+		Order(args.OrderClause("job_runs.started_at DESC")).
+		Offset(args.CalcOffset()).
+		Limit(args.PerPage).
+		Find(&runs).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "job_runs")
+	}
+	return runs, nil
+}
+
+func (db *DB) GetJobRunByID(id int) (*models.JobRun, error) {
+	var run models.JobRun
+	if err := db.Preload("Job").First(&run, id).Error; err != nil {
+		return nil, newNotFoundDBError(err, "job_runs")
+	}
+	return &run, nil
+}
@@ -18,7 +18,16 @@ func (db *DB) GetAllRegisteredClients() ([]models.OidcClient, error) {
 }
 
 func (db *DB) RegisterClient(client *models.OidcClient) error {
-	if err := db.Create(client).Error; err != nil {
+	// the caller still needs the plain text secret to hand back to the admin
+	secret := client.ClientSecret
+	encrypted, err := models.EncryptSecret(secret)
+	if err != nil {
+		return newCreateDBError(err, "oidc_clients")
+	}
+	client.ClientSecret = encrypted
+	err = db.Create(client).Error
+	client.ClientSecret = secret
+	if err != nil {
 		return newCreateDBError(err, "oidc_clients")
 	}
 	return nil
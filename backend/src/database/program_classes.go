@@ -58,15 +58,22 @@ func (db *DB) UpdateProgramClass(ctx context.Context, content *models.ProgramCla
 		return nil, NewDBError(trans.Error, "unable to start the database transaction")
 	}
 
-	ignoredFieldNames := []string{"create_user_id", "update_user_id", "enrollments", "facility", "facilities", "events", "facility_program", "program_id", "start_dt", "end_dt", "program", "enrolled"}
+	ignoredFieldNames := []string{"create_user_id", "update_user_id", "enrollments", "facility", "facilities", "events", "facility_program", "program_id", "start_dt", "end_dt", "program", "enrolled", "flagged_reason"}
 	classLogEntries := models.GenerateChangeLogEntries(existing, content, "program_classes", existing.ID, content.UpdateUserID, ignoredFieldNames)
 	allChanges = append(allChanges, classLogEntries...)
 
+	previousCapacity := existing.Capacity
 	models.UpdateStruct(existing, content)
 	if err := trans.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&existing).Error; err != nil {
 		trans.Rollback()
 		return nil, newUpdateDBError(err, "program classes")
 	}
+	if existing.Capacity > previousCapacity {
+		if _, err := promoteWaitlistedEnrollments(trans, id, &content.UpdateUserID); err != nil {
+			trans.Rollback()
+			return nil, err
+		}
+	}
 
 	if len(allChanges) > 0 {
 		if err := trans.Create(&allChanges).Error; err != nil {
@@ -142,7 +149,8 @@ func (db *DB) GetProgramClassOutcomes(id int, args *models.QueryContext) ([]Prog
 	incompleteStatuses := []models.ProgramEnrollmentStatus{
 		models.EnrollmentIncompleteDropped,
 		models.EnrollmentIncompleteFailedToComplete,
-		models.EnrollmentIncompleteTransfered}
+		models.EnrollmentIncompleteTransfered,
+		models.EnrollmentIncompleteReleased}
 
 	// Create a set that includes the last 6 months, excluding the current month, of program outcomes
 	const lastSixMonthsSubquery = `(SELECT TO_CHAR(
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+func (db *DB) GetProgramPrerequisites(ctx context.Context, programID int) ([]models.ProgramPrerequisite, error) {
+	prerequisites := []models.ProgramPrerequisite{}
+	if err := db.WithContext(ctx).Preload("RequiredProgram").
+		Where("program_id = ?", programID).
+		Order("id").
+		Find(&prerequisites).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_prerequisites")
+	}
+	return prerequisites, nil
+}
+
+// ReplaceProgramPrerequisites replaces every prerequisite of the program with prerequisites
+func (db *DB) ReplaceProgramPrerequisites(ctx context.Context, programID int, prerequisites []models.ProgramPrerequisite) ([]models.ProgramPrerequisite, error) {
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return nil, NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	if err := trans.Where("program_id = ?", programID).Delete(&models.ProgramPrerequisite{}).Error; err != nil {
+		trans.Rollback()
+		return nil, newDeleteDBError(err, "program_prerequisites")
+	}
+	for idx := range prerequisites {
+		prerequisites[idx].ProgramID = uint(programID)
+		if err := Validate().Struct(&prerequisites[idx]); err != nil {
+			trans.Rollback()
+			return nil, NewDBError(err, "program prerequisite validation error")
+		}
+	}
+	if len(prerequisites) > 0 {
+		if err := trans.Create(&prerequisites).Error; err != nil {
+			trans.Rollback()
+			return nil, newCreateDBError(err, "program_prerequisites")
+		}
+	}
+	if err := trans.Commit().Error; err != nil {
+		return nil, NewDBError(err, "unable to commit the database transaction")
+	}
+	return db.GetProgramPrerequisites(ctx, programID)
+}
+
+func (db *DB) getClassPrerequisites(ctx context.Context, classID int) ([]models.ProgramPrerequisite, error) {
+	prerequisites := []models.ProgramPrerequisite{}
+	if err := db.WithContext(ctx).Preload("RequiredProgram").
+		Joins("JOIN program_classes c ON c.program_id = program_prerequisites.program_id AND c.deleted_at IS NULL").
+		Where("c.id = ?", classID).
+		Order("program_prerequisites.id").
+		Find(&prerequisites).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_prerequisites")
+	}
+	return prerequisites, nil
+}
+
+// meetsPrerequisite filters the users in tx to the residents that meet the prerequisite. A completion's
+// credit_type is the comma separated list of the credit types of the program when it was completed
+func meetsPrerequisite(tx *gorm.DB, prerequisite *models.ProgramPrerequisite) *gorm.DB {
+	switch prerequisite.PrerequisiteType {
+	case models.CompletedProgramPrerequisite:
+		return tx.Where(`EXISTS (SELECT 1 FROM program_completions pc
+			WHERE pc.user_id = users.id AND pc.program_id = ? AND pc.deleted_at IS NULL)`, prerequisite.RequiredProgramID)
+	case models.CreditTypePrerequisite:
+		creditType := ""
+		if prerequisite.CreditType != nil {
+			creditType = string(*prerequisite.CreditType)
+		}
+		return tx.Where(`EXISTS (SELECT 1 FROM program_completions pc
+			WHERE pc.user_id = users.id AND (',' || pc.credit_type || ',') LIKE ? AND pc.deleted_at IS NULL)`, "%,"+creditType+",%")
+	}
+	return tx.Where("1 = 0")
+}
+
+// GetUnmetPrerequisites returns the residents that don't meet every prerequisite of the class's program
+func (db *DB) GetUnmetPrerequisites(ctx context.Context, classID int, userIDs []int) ([]models.UnmetPrerequisites, error) {
+	unmet := []models.UnmetPrerequisites{}
+	if len(userIDs) == 0 {
+		return unmet, nil
+	}
+	prerequisites, err := db.getClassPrerequisites(ctx, classID)
+	if err != nil || len(prerequisites) == 0 {
+		return unmet, err
+	}
+	users := []models.User{}
+	if err := db.WithContext(ctx).Select("id, name_first, name_last").Where("id IN (?)", userIDs).Order("id").Find(&users).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "users")
+	}
+	descriptions := make(map[uint][]string, len(users))
+	for idx := range prerequisites {
+		met := []uint{}
+		tx := db.WithContext(ctx).Model(&models.User{}).Where("users.id IN (?)", userIDs)
+		if err := meetsPrerequisite(tx, &prerequisites[idx]).Pluck("users.id", &met).Error; err != nil {
+			return nil, newGetRecordsDBError(err, "program_completions")
+		}
+		for _, user := range users {
+			if !slices.Contains(met, user.ID) {
+				descriptions[user.ID] = append(descriptions[user.ID], prerequisites[idx].Description())
+			}
+		}
+	}
+	for _, user := range users {
+		if len(descriptions[user.ID]) > 0 {
+			unmet = append(unmet, models.UnmetPrerequisites{
+				UserID:        user.ID,
+				NameFull:      fmt.Sprintf("%s, %s", user.NameLast, user.NameFirst),
+				Prerequisites: descriptions[user.ID],
+			})
+		}
+	}
+	return unmet, nil
+}
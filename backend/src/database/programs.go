@@ -14,7 +14,7 @@ import (
 
 func (db *DB) GetProgramByID(id int) (*models.Program, error) {
 	content := &models.Program{}
-	if err := db.Preload("ProgramTypes").Preload("ProgramCreditTypes").Preload("FacilitiesPrograms.Facility").First(content, id).Error; err != nil {
+	if err := db.Preload("ProgramTypes").Preload("ProgramCreditTypes").Preload("FacilitiesPrograms.Facility").Preload("Prerequisites.RequiredProgram").First(content, id).Error; err != nil {
 		return nil, newNotFoundDBError(err, "programs")
 	}
 
@@ -119,7 +119,7 @@ func (db *DB) UpdateProgram(ctx context.Context, program *models.Program, facili
 	if trans.Error != nil {
 		return nil, NewDBError(trans.Error, "unable to start the database transaction")
 	}
-	ignoredFieldNames := []string{"create_user_id", "update_user_id", "program_types", "credit_types", "facilities", "archived_at"}
+	ignoredFieldNames := []string{"create_user_id", "update_user_id", "program_types", "credit_types", "facilities", "archived_at", "prerequisites"}
 	programLogEntries := models.GenerateChangeLogEntries(updatePrg, program, "programs", updatePrg.ID, program.UpdateUserID, ignoredFieldNames)
 	allChanges = append(allChanges, programLogEntries...)
 	models.UpdateStruct(&updatePrg, &program)
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// the change reason recorded on the enrollments closed by a release
+const releaseChangeReason = "Released from custody"
+
+// ScheduleResidentRelease sets the day the resident is released, or clears it when releaseDate is nil
+func (db *DB) ScheduleResidentRelease(ctx context.Context, userID int, releaseDate *time.Time) (*models.User, error) {
+	user := models.User{}
+	if err := db.WithContext(ctx).First(&user, "id = ? AND role = ?", userID, models.Student).Error; err != nil {
+		return nil, newNotFoundDBError(err, "users")
+	}
+	if user.ReleasedAt != nil {
+		return nil, NewDBError(gorm.ErrInvalidData, "the resident was already released")
+	}
+	if err := db.WithContext(ctx).Model(&user).Update("release_date", releaseDate).Error; err != nil {
+		return nil, newUpdateDBError(err, "users")
+	}
+	user.ReleaseDate = releaseDate
+	return &user, nil
+}
+
+// GetDueReleases returns the residents whose release date has come in the timezone of their facility
+func (db *DB) GetDueReleases(ctx context.Context, now time.Time) ([]models.User, error) {
+	residents := []models.User{}
+	// a day ahead of UTC covers the facilities whose day has already started, the rest are filtered out below
+	if err := db.WithContext(ctx).Preload("Facility").
+		Where("role = ? AND released_at IS NULL AND release_date IS NOT NULL AND release_date <= ?", models.Student, now.UTC().AddDate(0, 0, 1)).
+		Order("id").
+		Find(&residents).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "users")
+	}
+	due := make([]models.User, 0, len(residents))
+	for _, resident := range residents {
+		loc := time.UTC
+		if resident.Facility != nil {
+			if facilityLoc, err := time.LoadLocation(resident.Facility.Timezone); err == nil {
+				loc = facilityLoc
+			}
+		}
+		local := now.In(loc)
+		today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		if !resident.ReleaseDate.After(today) {
+			due = append(due, resident)
+		}
+	}
+	return due, nil
+}
+
+/*
+ReleaseResident closes the open enrollments of the resident with the release as their reason, gives their seats to
+the next residents on each waitlist and saves their transcript as it is on the day of the release. The resident's
+account is deactivated, and it and their history are kept for reporting
+*/
+func (db *DB) ReleaseResident(ctx context.Context, userID uint, now time.Time) (*models.ResidentTranscript, error) {
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return nil, NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	user := models.User{}
+	if err := trans.First(&user, "id = ? AND role = ? AND released_at IS NULL", userID, models.Student).Error; err != nil {
+		trans.Rollback()
+		return nil, newNotFoundDBError(err, "users")
+	}
+	var classIDs []int
+	if err := trans.Model(&models.ProgramClassEnrollment{}).
+		Where("user_id = ? AND enrollment_status = ?", userID, models.Enrolled).
+		Pluck("class_id", &classIDs).Error; err != nil {
+		trans.Rollback()
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	if err := trans.Model(&models.ProgramClassEnrollment{}).
+		Where("user_id = ? AND enrollment_status = ?", userID, models.Enrolled).
+		Updates(map[string]any{"enrollment_status": models.EnrollmentIncompleteReleased, "change_reason": releaseChangeReason}).Error; err != nil {
+		trans.Rollback()
+		return nil, newUpdateDBError(err, "program_class_enrollments")
+	}
+	if err := trans.Model(&models.ProgramClassEnrollment{}).
+		Where("user_id = ? AND enrollment_status = ?", userID, models.EnrollmentWaitlisted).
+		Updates(map[string]any{"enrollment_status": models.EnrollmentCancelled, "change_reason": releaseChangeReason, "waitlist_position": nil}).Error; err != nil {
+		trans.Rollback()
+		return nil, newUpdateDBError(err, "program_class_enrollments")
+	}
+	for _, classID := range classIDs {
+		if _, err := promoteWaitlistedEnrollments(trans, classID, nil); err != nil {
+			trans.Rollback()
+			return nil, err
+		}
+	}
+	// the release job has already deactivated their login in kratos
+	if err := trans.Model(&user).Updates(map[string]any{"released_at": now, "deactivated_at": now}).Error; err != nil {
+		trans.Rollback()
+		return nil, newUpdateDBError(err, "users")
+	}
+	contents, err := buildTranscript(trans, userID, now)
+	if err != nil {
+		trans.Rollback()
+		return nil, err
+	}
+	transcript := models.ResidentTranscript{UserID: userID, Reason: models.TranscriptRelease, Contents: *contents}
+	if err := trans.Create(&transcript).Error; err != nil {
+		trans.Rollback()
+		return nil, newCreateDBError(err, "resident_transcripts")
+	}
+	release := models.NewUserAccountHistory(userID, models.ResidentRelease, nil, nil, &user.FacilityID)
+	if err := trans.Create(release).Error; err != nil {
+		trans.Rollback()
+		return nil, newCreateDBError(err, "user_account_history")
+	}
+	if err := trans.Commit().Error; err != nil {
+		return nil, NewDBError(err, "unable to commit the database transaction")
+	}
+	logrus.Infof("released resident %d, closed %d enrollments", userID, len(classIDs))
+	return &transcript, nil
+}
+
+// GetReleasedProviderMappings returns the provider accounts of released residents that haven't been deactivated yet,
+// on the providers that can deactivate them
+func (db *DB) GetReleasedProviderMappings(ctx context.Context) ([]models.ProviderUserMapping, error) {
+	mappings := []models.ProviderUserMapping{}
+	if err := db.WithContext(ctx).Preload("ProviderPlatform").
+		Joins("JOIN users u ON u.id = provider_user_mappings.user_id").
+		Joins("JOIN provider_platforms pp ON pp.id = provider_user_mappings.provider_platform_id AND pp.deleted_at IS NULL").
+		Where("u.released_at IS NOT NULL AND provider_user_mappings.deactivated_at IS NULL AND pp.type IN (?)", models.AccountDeactivatingProviders).
+		Find(&mappings).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "provider_user_mappings")
+	}
+	return mappings, nil
+}
+
+func (db *DB) SetProviderMappingDeactivated(ctx context.Context, mappingID uint, deactivatedAt time.Time) error {
+	if err := db.WithContext(ctx).Model(&models.ProviderUserMapping{}).Where("id = ?", mappingID).Update("deactivated_at", deactivatedAt).Error; err != nil {
+		return newUpdateDBError(err, "provider_user_mappings")
+	}
+	return nil
+}
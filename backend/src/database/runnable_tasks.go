@@ -0,0 +1,60 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+)
+
+func (db *DB) GetCronJobs() ([]models.CronJob, error) {
+	jobs := make([]models.CronJob, 0, 10)
+	if err := db.Model(&models.CronJob{}).Order("category, name").Find(&jobs).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "cron_jobs")
+	}
+	return jobs, nil
+}
+
+func (db *DB) GetRunnableTasks(args *models.QueryContext, providerPlatformID, openContentProviderID *int) ([]models.RunnableTask, error) {
+	tx := db.WithContext(args.Ctx).Model(&models.RunnableTask{})
+	if providerPlatformID != nil {
+		tx = tx.Where("provider_platform_id = ?", *providerPlatformID)
+	}
+	if openContentProviderID != nil {
+		tx = tx.Where("open_content_provider_id = ?", *openContentProviderID)
+	}
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "runnable_tasks")
+	}
+	tasks := make([]models.RunnableTask, 0, args.PerPage)
+	if err := tx.Preload("Job").
+		Order("id ASC").
+		Offset(args.CalcOffset()).
+		Limit(args.PerPage).
+		Find(&tasks).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "runnable_tasks")
+	}
+	return tasks, nil
+}
+
+func (db *DB) GetRunnableTaskByID(id int) (*models.RunnableTask, error) {
+	var task models.RunnableTask
+	if err := db.Preload("Job").First(&task, id).Error; err != nil {
+		return nil, newNotFoundDBError(err, "runnable_tasks")
+	}
+	return &task, nil
+}
+
+// a nil argument leaves that column unchanged, an empty schedule resets the task to the schedule of its job
+func (db *DB) UpdateRunnableTask(id int, paused *bool, schedule *string) (*models.RunnableTask, error) {
+	updates := map[string]any{}
+	if paused != nil {
+		updates["paused"] = *paused
+	}
+	if schedule != nil {
+		updates["schedule"] = *schedule
+	}
+	if len(updates) > 0 {
+		if err := db.Model(&models.RunnableTask{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return nil, newUpdateDBError(err, "runnable_tasks")
+		}
+	}
+	return db.GetRunnableTaskByID(id)
+}
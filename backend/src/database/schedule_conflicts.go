@@ -0,0 +1,194 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// the furthest ahead a series without an end date is checked for conflicts
+const scheduleConflictHorizon = 365 * 24 * time.Hour
+
+// a session of another class, with the residents of the class being scheduled that are enrolled in it
+type scheduledInstance struct {
+	models.EventInstance
+	class     *models.ProgramClass
+	residents []models.ConflictingResident
+}
+
+/*
+CheckScheduleConflicts expands the proposed events of the class from now until they end, and reports every session
+that overlaps a session of another active class: in the same room at the facility, taught by the same instructor,
+or attended by a resident enrolled in the class. Sessions of other classes are expanded with their overrides applied
+*/
+func (db *DB) CheckScheduleConflicts(ctx context.Context, classID int, proposed []models.ProgramClassEvent) ([]models.ScheduleConflict, error) {
+	class := models.ProgramClass{}
+	if err := db.WithContext(ctx).First(&class, classID).Error; err != nil {
+		return nil, newNotFoundDBError(err, "program_classes")
+	}
+	start, end, err := conflictWindow(&class, proposed)
+	if err != nil {
+		return nil, NewDBError(err, "invalid recurrence rule")
+	}
+	conflicts := []models.ScheduleConflict{}
+	if !start.Before(end) {
+		return conflicts, nil
+	}
+	residents, err := db.enrolledResidents(ctx, []uint{class.ID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uint, 0, len(residents[class.ID]))
+	for _, resident := range residents[class.ID] {
+		userIDs = append(userIDs, resident.UserID)
+	}
+
+	events := []models.ProgramClassEvent{}
+	tx := db.WithContext(ctx).Preload("Overrides").Preload("Class").
+		Joins("JOIN program_classes c ON c.id = program_class_events.class_id AND c.deleted_at IS NULL AND c.archived_at IS NULL").
+		Where("c.id <> ? AND c.status IN ?", classID, []models.ClassStatus{models.Scheduled, models.Active})
+	if len(userIDs) > 0 {
+		enrolledClasses := db.Model(&models.ProgramClassEnrollment{}).Select("class_id").Where("user_id IN ? AND enrollment_status = ?", userIDs, models.Enrolled)
+		tx = tx.Where("(c.facility_id = ? OR c.id IN (?))", class.FacilityID, enrolledClasses)
+	} else {
+		tx = tx.Where("c.facility_id = ?", class.FacilityID)
+	}
+	if err := tx.Find(&events).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_events")
+	}
+	if len(events) == 0 {
+		return conflicts, nil
+	}
+	classIDs := make([]uint, 0, len(events))
+	for _, event := range events {
+		classIDs = append(classIDs, event.ClassID)
+	}
+	sharedResidents := map[uint][]models.ConflictingResident{}
+	if len(userIDs) > 0 {
+		if sharedResidents, err = db.enrolledResidents(ctx, classIDs, userIDs); err != nil {
+			return nil, err
+		}
+	}
+	scheduled := []scheduledInstance{}
+	for _, event := range events {
+		for _, instance := range applyOverrides(event, start, end) {
+			scheduled = append(scheduled, scheduledInstance{EventInstance: instance, class: event.Class, residents: sharedResidents[event.ClassID]})
+		}
+	}
+	for _, event := range proposed {
+		event.ClassID = class.ID
+		event.Overrides = nil
+		conflicts = append(conflicts, findScheduleConflicts(&class, applyOverrides(event, start, end), scheduled)...)
+	}
+	slices.SortStableFunc(conflicts, func(a, b models.ScheduleConflict) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return conflicts, nil
+}
+
+// conflicts are only checked from now until the last proposed event ends, the end of the class, or the horizon
+func conflictWindow(class *models.ProgramClass, proposed []models.ProgramClassEvent) (time.Time, time.Time, error) {
+	start := time.Now().UTC()
+	var end time.Time
+	for idx := range proposed {
+		rule, err := proposed[idx].GetRRule()
+		if err != nil {
+			return start, start, err
+		}
+		until := rule.GetUntil()
+		if until.IsZero() && rule.OrigOptions.Count > 0 {
+			if occurrences := rule.All(); len(occurrences) > 0 {
+				until = occurrences[len(occurrences)-1]
+			}
+		}
+		if until.IsZero() {
+			until = start.Add(scheduleConflictHorizon)
+			if class.EndDt != nil {
+				until = class.EndDt.AddDate(0, 0, 1)
+			}
+		}
+		duration, err := time.ParseDuration(proposed[idx].Duration)
+		if err != nil {
+			return start, start, err
+		}
+		if until = until.Add(duration); until.After(end) {
+			end = until
+		}
+	}
+	return start, end, nil
+}
+
+// enrolledResidents returns the residents enrolled in each class, limited to userIDs when set
+func (db *DB) enrolledResidents(ctx context.Context, classIDs []uint, userIDs []uint) (map[uint][]models.ConflictingResident, error) {
+	rows := []struct {
+		ClassID   uint
+		UserID    uint
+		NameFirst string
+		NameLast  string
+	}{}
+	tx := db.WithContext(ctx).Table("program_class_enrollments e").
+		Select("e.class_id, e.user_id, u.name_first, u.name_last").
+		Joins("JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL").
+		Where("e.class_id IN ? AND e.enrollment_status = ? AND e.deleted_at IS NULL", classIDs, models.Enrolled)
+	if userIDs != nil {
+		tx = tx.Where("e.user_id IN ?", userIDs)
+	}
+	if err := tx.Order("u.name_last, u.name_first").Scan(&rows).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	residents := make(map[uint][]models.ConflictingResident, len(classIDs))
+	for _, row := range rows {
+		residents[row.ClassID] = append(residents[row.ClassID], models.ConflictingResident{UserID: row.UserID, NameFull: fmt.Sprintf("%s, %s", row.NameLast, row.NameFirst)})
+	}
+	return residents, nil
+}
+
+func findScheduleConflicts(class *models.ProgramClass, proposed []models.EventInstance, scheduled []scheduledInstance) []models.ScheduleConflict {
+	conflicts := []models.ScheduleConflict{}
+	for _, instance := range proposed {
+		end := instance.StartTime.Add(instance.Duration)
+		for _, other := range scheduled {
+			otherEnd := other.StartTime.Add(other.Duration)
+			if !instance.StartTime.Before(otherEnd) || !other.StartTime.Before(end) || other.class == nil {
+				continue
+			}
+			conflict := models.ScheduleConflict{
+				StartTime:            instance.StartTime,
+				EndTime:              end,
+				ConflictingClassID:   other.class.ID,
+				ConflictingClassName: other.class.Name,
+				ConflictingEventID:   other.EventID,
+				ConflictingStartTime: other.StartTime,
+				ConflictingEndTime:   otherEnd,
+			}
+			if other.class.FacilityID == class.FacilityID && isBookableRoom(instance.Room) && strings.EqualFold(strings.TrimSpace(instance.Room), strings.TrimSpace(other.Room)) {
+				roomConflict := conflict
+				roomConflict.ConflictType = models.RoomConflict
+				roomConflict.Room = instance.Room
+				conflicts = append(conflicts, roomConflict)
+			}
+			if name := strings.TrimSpace(class.InstructorName); name != "" && strings.EqualFold(name, strings.TrimSpace(other.class.InstructorName)) {
+				instructorConflict := conflict
+				instructorConflict.ConflictType = models.InstructorConflict
+				instructorConflict.InstructorName = class.InstructorName
+				conflicts = append(conflicts, instructorConflict)
+			}
+			if len(other.residents) > 0 {
+				residentConflict := conflict
+				residentConflict.ConflictType = models.ResidentConflict
+				residentConflict.Residents = other.residents
+				conflicts = append(conflicts, residentConflict)
+			}
+		}
+	}
+	return conflicts
+}
+
+// rooms that haven't been assigned yet can't be double-booked
+func isBookableRoom(room string) bool {
+	room = strings.TrimSpace(room)
+	return room != "" && !strings.EqualFold(room, "TBD")
+}
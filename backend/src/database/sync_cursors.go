@@ -0,0 +1,31 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+)
+
+func (db *DB) GetProviderSyncCursors(providerPlatformID int) ([]models.ProviderSyncCursor, error) {
+	cursors := make([]models.ProviderSyncCursor, 0, 10)
+	if err := db.Where("provider_platform_id = ?", providerPlatformID).Order("entity").Find(&cursors).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "provider_sync_cursors")
+	}
+	return cursors, nil
+}
+
+// ResetProviderSyncCursors removes the cursors of a provider platform so its next sync processes everything.
+// When entities are given only those cursors (and their per-course cursors) are removed.
+func (db *DB) ResetProviderSyncCursors(providerPlatformID int, entities []string) (int64, error) {
+	tx := db.Where("provider_platform_id = ?", providerPlatformID)
+	if len(entities) > 0 {
+		conditions := db.Where("entity IN (?)", entities)
+		for _, entity := range entities {
+			conditions = conditions.Or("entity LIKE ?", entity+":%")
+		}
+		tx = tx.Where(conditions)
+	}
+	result := tx.Delete(&models.ProviderSyncCursor{})
+	if result.Error != nil {
+		return 0, newDeleteDBError(result.Error, "provider_sync_cursors")
+	}
+	return result.RowsAffected, nil
+}
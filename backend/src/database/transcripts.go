@@ -0,0 +1,384 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type attendanceTally struct {
+	present  int
+	recorded int
+}
+
+// percentage of the recorded sessions the resident was present for, rounded to hundredths
+func (tally attendanceTally) percentage() float64 {
+	if tally.recorded == 0 {
+		return 0
+	}
+	return math.Round(float64(tally.present)/float64(tally.recorded)*10000) / 100
+}
+
+// attendanceByClass tallies the attendance taken for the user in each of the classes
+func attendanceByClass(tx *gorm.DB, userID uint, classIDs []uint) (map[uint]attendanceTally, error) {
+	tallies := make(map[uint]attendanceTally, len(classIDs))
+	if len(classIDs) == 0 {
+		return tallies, nil
+	}
+	attendance := []struct {
+		ClassID          uint
+		AttendanceStatus models.Attendance
+	}{}
+	if err := tx.Table("program_class_event_attendance a").
+		Select("e.class_id, a.attendance_status").
+		Joins("JOIN program_class_events e ON e.id = a.event_id").
+		Where("a.user_id = ? AND e.class_id IN (?) AND a.deleted_at IS NULL", userID, classIDs).
+		Scan(&attendance).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_event_attendance")
+	}
+	for _, record := range attendance {
+		if record.AttendanceStatus == "" {
+			continue
+		}
+		tally := tallies[record.ClassID]
+		tally.recorded++
+		if record.AttendanceStatus == models.Present {
+			tally.present++
+		}
+		tallies[record.ClassID] = tally
+	}
+	return tallies, nil
+}
+
+// classCreditHours returns the credit hours of the classes, including deleted classes
+func classCreditHours(tx *gorm.DB, classIDs []uint) (map[uint]int64, error) {
+	creditHours := make(map[uint]int64, len(classIDs))
+	if len(classIDs) == 0 {
+		return creditHours, nil
+	}
+	classes := []models.ProgramClass{}
+	if err := tx.Unscoped().Select("id", "credit_hours").Where("id IN (?)", classIDs).Find(&classes).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_classes")
+	}
+	for _, class := range classes {
+		if class.CreditHours != nil {
+			creditHours[class.ID] = *class.CreditHours
+		}
+	}
+	return creditHours, nil
+}
+
+// addCreditTotals adds a completion to the totals of each of its credit types, which are stored comma separated
+func addCreditTotals(totals []models.CreditTotal, creditTypes string, creditHours int64) []models.CreditTotal {
+	for _, creditType := range strings.Split(creditTypes, ",") {
+		creditType = strings.TrimSpace(creditType)
+		if creditType == "" {
+			continue
+		}
+		idx := slices.IndexFunc(totals, func(total models.CreditTotal) bool {
+			return string(total.CreditType) == creditType
+		})
+		if idx < 0 {
+			totals = append(totals, models.CreditTotal{CreditType: models.CreditType(creditType)})
+			idx = len(totals) - 1
+		}
+		totals[idx].Completions++
+		totals[idx].CreditHours += creditHours
+	}
+	return totals
+}
+
+func earnedTimeBalance(tx *gorm.DB, userID uint) (float64, error) {
+	var balance float64
+	if err := tx.Model(&models.EarnedTimeEntry{}).Select("COALESCE(SUM(days), 0)").Where("user_id = ?", userID).Scan(&balance).Error; err != nil {
+		return 0, newGetRecordsDBError(err, "earned_time_entries")
+	}
+	return math.Round(balance*100) / 100, nil
+}
+
+// providerCoursesForTranscript gathers the provider platform courses the user has milestones or outcomes in
+func providerCoursesForTranscript(tx *gorm.DB, userID uint) ([]models.TranscriptProviderCourse, error) {
+	courses := []struct {
+		ID                      uint
+		Name                    string
+		ProviderName            string
+		TotalProgressMilestones uint
+	}{}
+	if err := tx.Table("courses c").
+		Select("c.id, c.name, pp.name AS provider_name, c.total_progress_milestones").
+		Joins("JOIN provider_platforms pp ON pp.id = c.provider_platform_id").
+		Where("c.id IN (?) OR c.id IN (?)",
+			tx.Model(&models.Milestone{}).Select("course_id").Where("user_id = ?", userID),
+			tx.Model(&models.Outcome{}).Select("course_id").Where("user_id = ?", userID)).
+		Order("pp.name, c.name").
+		Scan(&courses).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "courses")
+	}
+	transcriptCourses := make([]models.TranscriptProviderCourse, 0, len(courses))
+	if len(courses) == 0 {
+		return transcriptCourses, nil
+	}
+	courseIDs := make([]uint, 0, len(courses))
+	for _, course := range courses {
+		courseIDs = append(courseIDs, course.ID)
+	}
+	totals := []struct {
+		CourseID uint
+		Total    int64
+	}{}
+	if err := tx.Model(&models.Milestone{}).Select("course_id, COUNT(*) AS total").
+		Where("user_id = ? AND course_id IN (?)", userID, courseIDs).
+		Group("course_id").
+		Scan(&totals).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "milestones")
+	}
+	milestones := make(map[uint]int64, len(totals))
+	for _, total := range totals {
+		milestones[total.CourseID] = total.Total
+	}
+	if err := tx.Model(&models.Activity{}).Select("course_id, COALESCE(SUM(time_delta), 0) AS total").
+		Where("user_id = ? AND course_id IN (?)", userID, courseIDs).
+		Group("course_id").
+		Scan(&totals).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "activities")
+	}
+	seconds := make(map[uint]int64, len(totals))
+	for _, total := range totals {
+		seconds[total.CourseID] = total.Total
+	}
+	outcomes := []models.Outcome{}
+	if err := tx.Where("user_id = ? AND course_id IN (?)", userID, courseIDs).Order("created_at").Find(&outcomes).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "outcomes")
+	}
+
+	for _, course := range courses {
+		transcriptCourse := models.TranscriptProviderCourse{
+			ProviderName: course.ProviderName,
+			CourseName:   course.Name,
+			Hours:        math.Round(float64(seconds[course.ID])/36) / 100,
+		}
+		idx := slices.IndexFunc(outcomes, func(outcome models.Outcome) bool { return outcome.CourseID == course.ID })
+		switch {
+		case idx >= 0:
+			transcriptCourse.Progress = 100
+			transcriptCourse.OutcomeType = outcomes[idx].Type
+			transcriptCourse.OutcomeValue = outcomes[idx].Value
+			transcriptCourse.CompletedAt = &outcomes[idx].CreatedAt
+		case course.TotalProgressMilestones > 0:
+			// only an outcome completes the course, like on the resident's course list
+			progress := math.Round(float64(milestones[course.ID])*10000/float64(course.TotalProgressMilestones)) / 100
+			transcriptCourse.Progress = math.Min(progress, 99.99)
+		}
+		transcriptCourses = append(transcriptCourses, transcriptCourse)
+	}
+	return transcriptCourses, nil
+}
+
+// openContentForTranscript totals the visits to each library and video, returning them with the total hours spent
+func openContentForTranscript(tx *gorm.DB, userID uint) ([]models.TranscriptOpenContent, float64, error) {
+	visits := []struct {
+		ContentType string
+		ContentID   uint
+		Title       string
+		RequestTS   time.Time
+		StopTS      *time.Time
+	}{}
+	if err := tx.Table("open_content_activities oca").
+		Select(`CASE WHEN v.id IS NOT NULL THEN 'video' ELSE 'library' END AS content_type, oca.content_id,
+			COALESCE(v.title, l.title) AS title, oca.request_ts, oca.stop_ts`).
+		Joins("LEFT JOIN videos v ON v.id = oca.content_id AND v.open_content_provider_id = oca.open_content_provider_id").
+		Joins("LEFT JOIN libraries l ON l.id = oca.content_id AND l.open_content_provider_id = oca.open_content_provider_id").
+		Where("oca.user_id = ? AND (v.id IS NOT NULL OR l.id IS NOT NULL)", userID).
+		Order("oca.request_ts").
+		Scan(&visits).Error; err != nil {
+		return nil, 0, newGetRecordsDBError(err, "open_content_activities")
+	}
+	content := []models.TranscriptOpenContent{}
+	indexes := map[string]int{}
+	var totalHours float64
+	for _, visit := range visits {
+		key := visit.ContentType + ":" + strconv.Itoa(int(visit.ContentID))
+		idx, ok := indexes[key]
+		if !ok {
+			content = append(content, models.TranscriptOpenContent{Title: visit.Title, ContentType: visit.ContentType})
+			idx = len(content) - 1
+			indexes[key] = idx
+		}
+		content[idx].Visits++
+		content[idx].LastVisitedAt = visit.RequestTS
+		// a visit that was never stopped has no duration
+		if visit.StopTS != nil && visit.StopTS.After(visit.RequestTS) {
+			hours := visit.StopTS.Sub(visit.RequestTS).Hours()
+			content[idx].Hours += hours
+			totalHours += hours
+		}
+	}
+	for idx := range content {
+		content[idx].Hours = math.Round(content[idx].Hours*100) / 100
+	}
+	slices.SortStableFunc(content, func(a, b models.TranscriptOpenContent) int {
+		return cmp.Compare(b.Hours, a.Hours)
+	})
+	return content, math.Round(totalHours*100) / 100, nil
+}
+
+/*
+buildTranscript gathers every class the user was enrolled in, at any facility, with their completions and attendance,
+along with their provider platform courses and the open content they visited.
+Waitlist spots and enrollments that were cancelled are left out, as are the credit hours of classes that weren't
+completed
+*/
+func buildTranscript(tx *gorm.DB, userID uint, now time.Time) (*models.Transcript, error) {
+	user := models.User{}
+	if err := tx.Preload("Facility").First(&user, userID).Error; err != nil {
+		return nil, newNotFoundDBError(err, "users")
+	}
+	transcript := models.Transcript{
+		UserID:      user.ID,
+		NameFirst:   user.NameFirst,
+		NameLast:    user.NameLast,
+		DocID:       user.DocID,
+		ReleasedAt:  user.ReleasedAt,
+		GeneratedAt: now,
+		Courses:     []models.TranscriptCourse{},
+		Credits:     []models.CreditTotal{},
+	}
+	if user.Facility != nil {
+		transcript.FacilityName = user.Facility.Name
+	}
+
+	enrollments := []models.ProgramClassEnrollment{}
+	if err := tx.Preload("Class", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Preload("Class.Program", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Preload("Class.Program.ProgramCreditTypes").
+		Preload("Class.Facility").
+		Where("user_id = ? AND enrollment_status NOT IN (?)", userID, []models.ProgramEnrollmentStatus{models.EnrollmentWaitlisted, models.EnrollmentCancelled}).
+		Order("created_at").
+		Find(&enrollments).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	completions := []models.ProgramCompletion{}
+	if err := tx.Where("user_id = ?", userID).Order("created_at").Find(&completions).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_completions")
+	}
+	classIDs := make([]uint, 0, len(enrollments)+len(completions))
+	for _, enrollment := range enrollments {
+		classIDs = append(classIDs, enrollment.ClassID)
+	}
+	completionIDs := make([]uint, 0, len(completions))
+	for _, completion := range completions {
+		completionIDs = append(completionIDs, completion.ID)
+		if !slices.Contains(classIDs, completion.ProgramClassID) {
+			classIDs = append(classIDs, completion.ProgramClassID)
+		}
+	}
+	creditHours, err := classCreditHours(tx, classIDs)
+	if err != nil {
+		return nil, err
+	}
+	attendance, err := attendanceByClass(tx, userID, classIDs)
+	if err != nil {
+		return nil, err
+	}
+	certificates := []models.CompletionCertificate{}
+	if len(completionIDs) > 0 {
+		if err := tx.Where("program_completion_id IN (?)", completionIDs).Find(&certificates).Error; err != nil {
+			return nil, newGetRecordsDBError(err, "completion_certificates")
+		}
+	}
+
+	courseFromCompletion := func(course *models.TranscriptCourse, completion *models.ProgramCompletion) {
+		course.CreditTypes = completion.CreditType
+		course.CreditHours = creditHours[completion.ProgramClassID]
+		course.CompletedAt = &completion.CreatedAt
+		for _, certificate := range certificates {
+			if certificate.ProgramCompletionID == completion.ID {
+				course.CertificateCode = certificate.VerificationCode
+			}
+		}
+		transcript.Credits = addCreditTotals(transcript.Credits, completion.CreditType, course.CreditHours)
+	}
+	matched := make([]bool, len(completions))
+	for _, enrollment := range enrollments {
+		course := models.TranscriptCourse{EnrollmentStatus: enrollment.EnrollmentStatus, EnrolledAt: &enrollment.CreatedAt}
+		if class := enrollment.Class; class != nil {
+			course.ClassName = class.Name
+			if class.Program != nil {
+				course.ProgramName = class.Program.Name
+				creditTypes := make([]string, 0, len(class.Program.ProgramCreditTypes))
+				for _, creditType := range class.Program.ProgramCreditTypes {
+					creditTypes = append(creditTypes, string(creditType.CreditType))
+				}
+				course.CreditTypes = strings.Join(creditTypes, ",")
+			}
+			if class.Facility != nil {
+				course.FacilityName = class.Facility.Name
+			}
+		}
+		if tally, ok := attendance[enrollment.ClassID]; ok && tally.recorded > 0 {
+			percentage := tally.percentage()
+			course.AttendancePercentage = &percentage
+		}
+		for idx := range completions {
+			if !matched[idx] && completions[idx].ProgramClassID == enrollment.ClassID {
+				matched[idx] = true
+				courseFromCompletion(&course, &completions[idx])
+				break
+			}
+		}
+		transcript.Courses = append(transcript.Courses, course)
+	}
+	for idx := range completions {
+		if matched[idx] {
+			continue
+		}
+		completion := &completions[idx]
+		course := models.TranscriptCourse{
+			ProgramName:      completion.ProgramName,
+			ClassName:        completion.ProgramClassName,
+			FacilityName:     completion.FacilityName,
+			EnrollmentStatus: models.EnrollmentCompleted,
+		}
+		courseFromCompletion(&course, completion)
+		transcript.Courses = append(transcript.Courses, course)
+	}
+
+	if transcript.EarnedTimeDays, err = earnedTimeBalance(tx, userID); err != nil {
+		return nil, err
+	}
+	if transcript.ProviderCourses, err = providerCoursesForTranscript(tx, userID); err != nil {
+		return nil, err
+	}
+	if transcript.OpenContent, transcript.OpenContentHours, err = openContentForTranscript(tx, userID); err != nil {
+		return nil, err
+	}
+	return &transcript, nil
+}
+
+// GetTranscript builds the transcript of the resident as it is now
+func (db *DB) GetTranscript(ctx context.Context, userID int) (*models.Transcript, error) {
+	return buildTranscript(db.WithContext(ctx), uint(userID), time.Now())
+}
+
+func (db *DB) GetResidentTranscripts(ctx context.Context, userID int) ([]models.ResidentTranscript, error) {
+	transcripts := []models.ResidentTranscript{}
+	if err := db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&transcripts).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "resident_transcripts")
+	}
+	return transcripts, nil
+}
+
+func (db *DB) GetResidentTranscript(ctx context.Context, userID, transcriptID int) (*models.ResidentTranscript, error) {
+	transcript := models.ResidentTranscript{}
+	if err := db.WithContext(ctx).Where("id = ? AND user_id = ?", transcriptID, userID).First(&transcript).Error; err != nil {
+		return nil, newNotFoundDBError(err, "resident_transcripts")
+	}
+	return &transcript, nil
+}
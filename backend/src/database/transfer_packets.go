@@ -0,0 +1,308 @@
+package database
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// buildTransferPacket snapshots the education history of the user at the facility they are transferred out of
+func buildTransferPacket(tx *gorm.DB, userID, facilityID uint) (*models.TransferPacketContents, error) {
+	contents := models.TransferPacketContents{
+		Completions:      []models.TransferPacketCompletion{},
+		InProgress:       []models.TransferPacketClass{},
+		Credits:          []models.CreditTotal{},
+		ProviderMappings: []models.TransferPacketProviderMapping{},
+		Favorites:        []models.TransferPacketFavorite{},
+	}
+	completions := []models.ProgramCompletion{}
+	if err := tx.Where("user_id = ?", userID).Order("created_at").Find(&completions).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_completions")
+	}
+	classIDs := make([]uint, 0, len(completions))
+	for _, completion := range completions {
+		classIDs = append(classIDs, completion.ProgramClassID)
+	}
+	creditHours, err := classCreditHours(tx, classIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, completion := range completions {
+		hours := creditHours[completion.ProgramClassID]
+		contents.Completions = append(contents.Completions, models.TransferPacketCompletion{
+			ProgramCompletionID: completion.ID,
+			ProgramID:           completion.ProgramID,
+			ProgramName:         completion.ProgramName,
+			ClassName:           completion.ProgramClassName,
+			FacilityName:        completion.FacilityName,
+			CreditType:          completion.CreditType,
+			CreditHours:         hours,
+			CompletedAt:         completion.CreatedAt,
+		})
+		contents.Credits = addCreditTotals(contents.Credits, completion.CreditType, hours)
+	}
+
+	enrollments := []models.ProgramClassEnrollment{}
+	if err := tx.Preload("Class.Program").
+		Joins("JOIN program_classes pc ON pc.id = program_class_enrollments.class_id").
+		Where("program_class_enrollments.user_id = ? AND pc.facility_id = ?", userID, facilityID).
+		Where("program_class_enrollments.enrollment_status IN (?)", []models.ProgramEnrollmentStatus{models.Enrolled, models.EnrollmentWaitlisted}).
+		Order("program_class_enrollments.created_at").
+		Find(&enrollments).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	enrolledClassIDs := make([]uint, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		enrolledClassIDs = append(enrolledClassIDs, enrollment.ClassID)
+	}
+	attendance, err := attendanceByClass(tx, userID, enrolledClassIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, enrollment := range enrollments {
+		tally := attendance[enrollment.ClassID]
+		contents.InProgress = append(contents.InProgress, models.TransferPacketClass{
+			ClassID:              enrollment.ClassID,
+			ProgramID:            enrollment.Class.ProgramID,
+			ProgramName:          enrollment.Class.Program.Name,
+			ClassName:            enrollment.Class.Name,
+			EnrollmentStatus:     enrollment.EnrollmentStatus,
+			EnrolledAt:           enrollment.CreatedAt,
+			PresentSessions:      tally.present,
+			RecordedSessions:     tally.recorded,
+			AttendancePercentage: tally.percentage(),
+		})
+	}
+
+	if contents.EarnedTimeDays, err = earnedTimeBalance(tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Table("provider_user_mappings pum").
+		Select("pum.provider_platform_id, pp.name AS provider_platform_name, pum.external_user_id, pum.external_username").
+		Joins("JOIN provider_platforms pp ON pp.id = pum.provider_platform_id").
+		Where("pum.user_id = ? AND pum.deleted_at IS NULL", userID).
+		Order("pp.name").
+		Scan(&contents.ProviderMappings).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "provider_user_mappings")
+	}
+
+	programFavorites := []struct {
+		ProgramID uint
+		Name      string
+	}{}
+	if err := tx.Table("program_favorites pf").
+		Select("pf.program_id, p.name").
+		Joins("JOIN programs p ON p.id = pf.program_id").
+		Where("pf.user_id = ?", userID).
+		Scan(&programFavorites).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_favorites")
+	}
+	for _, favorite := range programFavorites {
+		contents.Favorites = append(contents.Favorites, models.TransferPacketFavorite{
+			Type:      models.ProgramFavoriteType,
+			ProgramID: &favorite.ProgramID,
+			Name:      favorite.Name,
+		})
+	}
+	// favorites with a facility_id are featured by the facility, not the resident's own
+	contentFavorites := []models.OpenContentFavorite{}
+	if err := tx.Where("user_id = ? AND facility_id IS NULL", userID).Order("created_at").Find(&contentFavorites).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "open_content_favorites")
+	}
+	for _, favorite := range contentFavorites {
+		contents.Favorites = append(contents.Favorites, models.TransferPacketFavorite{
+			Type:                  models.OpenContentFavoriteType,
+			ContentID:             &favorite.ContentID,
+			OpenContentProviderID: &favorite.OpenContentProviderID,
+			Name:                  favorite.Name,
+		})
+	}
+	return &contents, nil
+}
+
+func (db *DB) GetTransferPackets(args *models.QueryContext, userID int) ([]models.TransferPacket, error) {
+	packets := make([]models.TransferPacket, 0, args.PerPage)
+	tx := db.WithContext(args.Ctx).Model(&models.TransferPacket{}).Where("user_id = ?", userID)
+	if err := tx.Count(&args.Total).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "transfer_packets")
+	}
+	if err := tx.Preload("FromFacility").Preload("ToFacility").
+		Order("created_at DESC").
+		Limit(args.PerPage).Offset(args.CalcOffset()).
+		Find(&packets).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "transfer_packets")
+	}
+	return packets, nil
+}
+
+// GetTransferPacket returns the packet along with the classes at the receiving facility that are equivalent to the
+// classes the transfer took the resident out of, classes of the same program that are scheduled or active
+func (db *DB) GetTransferPacket(ctx context.Context, userID, packetID int) (*models.TransferPacketDetails, error) {
+	details := models.TransferPacketDetails{EquivalentClasses: []models.TransferEquivalentClass{}}
+	if err := db.WithContext(ctx).Preload("FromFacility").Preload("ToFacility").
+		Where("id = ? AND user_id = ?", packetID, userID).
+		First(&details.TransferPacket).Error; err != nil {
+		return nil, newNotFoundDBError(err, "transfer_packets")
+	}
+	programIDs := []uint{}
+	for _, class := range details.Contents.InProgress {
+		if !slices.Contains(programIDs, class.ProgramID) {
+			programIDs = append(programIDs, class.ProgramID)
+		}
+	}
+	if len(programIDs) == 0 {
+		return &details, nil
+	}
+	classes := []models.ProgramClass{}
+	if err := db.WithContext(ctx).Preload("Program").
+		Joins("JOIN facilities_programs fp ON fp.program_id = program_classes.program_id AND fp.facility_id = program_classes.facility_id AND fp.deleted_at IS NULL").
+		Where("program_classes.facility_id = ? AND program_classes.program_id IN (?)", details.ToFacilityID, programIDs).
+		Where("program_classes.status IN (?) AND program_classes.archived_at IS NULL", []models.ClassStatus{models.Scheduled, models.Active}).
+		Order("program_classes.start_dt, program_classes.id").
+		Find(&classes).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_classes")
+	}
+	if len(classes) == 0 {
+		return &details, nil
+	}
+	classIDs := make([]uint, 0, len(classes))
+	for _, class := range classes {
+		classIDs = append(classIDs, class.ID)
+	}
+	enrolledIDs := []uint{}
+	if err := db.WithContext(ctx).Model(&models.ProgramClassEnrollment{}).
+		Where("user_id = ? AND class_id IN (?)", userID, classIDs).
+		Pluck("class_id", &enrolledIDs).Error; err != nil {
+		return nil, newGetRecordsDBError(err, "program_class_enrollments")
+	}
+	for _, packetClass := range details.Contents.InProgress {
+		for _, class := range classes {
+			if class.ProgramID != packetClass.ProgramID {
+				continue
+			}
+			available, err := availableSeats(db.WithContext(ctx), int(class.ID))
+			if err != nil {
+				return nil, err
+			}
+			details.EquivalentClasses = append(details.EquivalentClasses, models.TransferEquivalentClass{
+				ReplacesClassID: packetClass.ClassID,
+				ClassID:         class.ID,
+				ProgramID:       class.ProgramID,
+				ProgramName:     class.Program.Name,
+				ClassName:       class.Name,
+				Status:          class.Status,
+				StartDt:         class.StartDt,
+				AvailableSeats:  max(available, 0),
+				Enrolled:        slices.Contains(enrolledIDs, class.ID),
+			})
+		}
+	}
+	return &details, nil
+}
+
+/*
+ReEnrollFromTransferPacket enrolls the resident in equivalent classes of the packet's receiving facility, or waitlists
+them when the class is full. Without classIDs, the resident is re-enrolled in one equivalent class for each class they
+were taken out of, preferring classes with open seats. Prerequisites aren't checked again since the resident was
+already enrolled in the same programs. Every re-enrollment is recorded as a facility transfer step in the resident's
+account history
+*/
+func (db *DB) ReEnrollFromTransferPacket(ctx context.Context, userID, packetID int, classIDs []int, adminID uint) ([]models.ProgramClassEnrollment, error) {
+	details, err := db.GetTransferPacket(ctx, userID, packetID)
+	if err != nil {
+		return nil, err
+	}
+	var facilityID uint
+	if err := db.WithContext(ctx).Model(&models.User{}).Select("facility_id").Where("id = ?", userID).Scan(&facilityID).Error; err != nil {
+		return nil, newNotFoundDBError(err, "users")
+	}
+	if facilityID != details.ToFacilityID {
+		return nil, NewDBError(gorm.ErrInvalidData, "the resident is no longer at the facility they were transferred to")
+	}
+	selected := []uint{}
+	if len(classIDs) == 0 {
+		for _, packetClass := range details.Contents.InProgress {
+			var choice *models.TransferEquivalentClass
+			for idx := range details.EquivalentClasses {
+				class := &details.EquivalentClasses[idx]
+				if class.ReplacesClassID != packetClass.ClassID || class.Enrolled || slices.Contains(selected, class.ClassID) {
+					continue
+				}
+				if choice == nil || (choice.AvailableSeats == 0 && class.AvailableSeats > 0) {
+					choice = class
+				}
+			}
+			if choice != nil {
+				selected = append(selected, choice.ClassID)
+			}
+		}
+	} else {
+		for _, classID := range classIDs {
+			idx := slices.IndexFunc(details.EquivalentClasses, func(class models.TransferEquivalentClass) bool {
+				return class.ClassID == uint(classID)
+			})
+			if idx < 0 || details.EquivalentClasses[idx].Enrolled {
+				return nil, NewDBError(gorm.ErrInvalidData, "the resident can only be re-enrolled in equivalent classes they aren't enrolled in")
+			}
+			if !slices.Contains(selected, uint(classID)) {
+				selected = append(selected, uint(classID))
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return nil, NewDBError(gorm.ErrInvalidData, "there are no equivalent classes to re-enroll the resident in")
+	}
+
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return nil, NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	enrollments := make([]models.ProgramClassEnrollment, 0, len(selected))
+	history := make([]models.UserAccountHistory, 0, len(selected))
+	now := time.Now()
+	for idx, classID := range selected {
+		available, err := availableSeats(trans, int(classID))
+		if err != nil {
+			trans.Rollback()
+			return nil, err
+		}
+		enrollment := models.ProgramClassEnrollment{ClassID: classID, UserID: uint(userID), EnrollmentStatus: models.Enrolled}
+		if available <= 0 {
+			position, err := nextWaitlistPosition(trans, int(classID))
+			if err != nil {
+				trans.Rollback()
+				return nil, err
+			}
+			enrollment.EnrollmentStatus = models.EnrollmentWaitlisted
+			enrollment.WaitlistPosition = &position
+		}
+		enrollments = append(enrollments, enrollment)
+		step := models.NewUserAccountHistory(uint(userID), models.FacilityTransfer, &adminID, nil, &details.ToFacilityID)
+		step.ProgramClassID = &selected[idx]
+		step.TransferPacketID = &details.ID
+		history = append(history, *step)
+	}
+	if err := trans.Create(&enrollments).Error; err != nil {
+		trans.Rollback()
+		return nil, newCreateDBError(err, "program_class_enrollments")
+	}
+	if err := trans.Create(&history).Error; err != nil {
+		trans.Rollback()
+		return nil, newCreateDBError(err, "user_account_history")
+	}
+	if details.ReceivedAt == nil {
+		if err := trans.Model(&models.TransferPacket{}).Where("id = ?", details.ID).
+			Updates(map[string]any{"received_at": now, "receive_user_id": adminID}).Error; err != nil {
+			trans.Rollback()
+			return nil, newUpdateDBError(err, "transfer_packets")
+		}
+	}
+	if err := trans.Commit().Error; err != nil {
+		return nil, NewDBError(err, "unable to commit the database transaction")
+	}
+	return enrollments, nil
+}
@@ -18,8 +18,14 @@ func calcOffset(page, perPage int) int {
 	return (page - 1) * perPage
 }
 
-func (db *DB) GetCurrentUsers(args *models.QueryContext, role string) ([]models.User, error) {
+// currentUsersQuery selects the users at the facility, either the active ones or only the deactivated ones
+func (db *DB) currentUsersQuery(args *models.QueryContext, role string, deactivated bool) *gorm.DB {
 	tx := db.WithContext(args.Ctx).Model(&models.User{}).Where("facility_id = ?", args.FacilityID)
+	if deactivated {
+		tx = tx.Where("users.deactivated_at IS NOT NULL")
+	} else {
+		tx = tx.Where("users.deactivated_at IS NULL")
+	}
 	switch role {
 	case "system_admin":
 		tx = tx.Where("role IN ('system_admin',  'department_admin', 'facility_admin')")
@@ -29,10 +35,24 @@ func (db *DB) GetCurrentUsers(args *models.QueryContext, role string) ([]models.
 		tx = tx.Where("role = 'facility_admin'")
 	case "student":
 		tx = tx.Where("role = 'student'")
+	case "instructor":
+		tx = tx.Where("role = 'instructor'")
 	}
 	if args.Search != "" {
 		tx = fuzzySearchUsers(tx, args)
 	}
+	return tx
+}
+
+func (db *DB) GetCurrentUsers(args *models.QueryContext, role string) ([]models.User, error) {
+	return findUsersPage(db.currentUsersQuery(args, role, false), args)
+}
+
+func (db *DB) GetDeactivatedUsers(args *models.QueryContext, role string) ([]models.User, error) {
+	return findUsersPage(db.currentUsersQuery(args, role, true), args)
+}
+
+func findUsersPage(tx *gorm.DB, args *models.QueryContext) ([]models.User, error) {
 	if err := tx.Count(&args.Total).Error; err != nil {
 		return nil, newGetRecordsDBError(err, "users")
 	}
@@ -86,10 +106,36 @@ func (db *DB) GetTransferProgramConflicts(ctx context.Context, id uint, transfer
 	return programNames, nil
 }
 
-func (db *DB) TransferResident(ctx *models.QueryContext, userID int, currFacilityID int, transFacilityID int) (*gorm.DB, error) {
+// TransferResident builds the resident's transfer packet before their open enrollments are marked as transferred, records
+// the transfer in their account history, and returns the uncommitted transaction along with the packet
+func (db *DB) TransferResident(ctx *models.QueryContext, userID int, currFacilityID int, transFacilityID int) (*gorm.DB, *models.TransferPacket, error) {
 	trans := db.Begin()
 	if trans.Error != nil {
-		return nil, NewDBError(trans.Error, "unable to start DB transaction")
+		return nil, nil, NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	contents, err := buildTransferPacket(trans, uint(userID), uint(currFacilityID))
+	if err != nil {
+		trans.Rollback()
+		return nil, nil, err
+	}
+	packet := models.TransferPacket{
+		UserID:         uint(userID),
+		FromFacilityID: uint(currFacilityID),
+		ToFacilityID:   uint(transFacilityID),
+		CreateUserID:   ctx.UserID,
+		Contents:       *contents,
+	}
+	if err := trans.Create(&packet).Error; err != nil {
+		trans.Rollback()
+		return nil, nil, newCreateDBError(err, "transfer_packets")
+	}
+	var classIDs []int
+	if err := trans.Table("program_class_enrollments pce").
+		Joins("JOIN program_classes pc ON pce.class_id = pc.id").
+		Where("pce.user_id = ? AND pc.facility_id = ? AND pce.enrollment_status = 'Enrolled'", userID, currFacilityID).
+		Pluck("pce.class_id", &classIDs).Error; err != nil {
+		trans.Rollback()
+		return nil, nil, newGetRecordsDBError(err, "program_class_enrollments")
 	}
 	updateQuery := `UPDATE program_class_enrollments AS pce SET enrollment_status = ?
 		FROM program_classes pc
@@ -100,24 +146,58 @@ func (db *DB) TransferResident(ctx *models.QueryContext, userID int, currFacilit
 
 	if err := trans.Exec(updateQuery, "Incomplete: Transferred", userID, currFacilityID).Error; err != nil {
 		trans.Rollback()
-		return nil, newUpdateDBError(err, "program_class_enrollments")
+		return nil, nil, newUpdateDBError(err, "program_class_enrollments")
+	}
+	waitlistQuery := `UPDATE program_class_enrollments AS pce SET enrollment_status = ?, waitlist_position = NULL
+		FROM program_classes pc
+		WHERE pce.class_id = pc.id
+			AND pce.user_id = ?
+			AND pc.facility_id = ?
+			AND pce.enrollment_status = ?`
+	if err := trans.Exec(waitlistQuery, models.EnrollmentCancelled, userID, currFacilityID, models.EnrollmentWaitlisted).Error; err != nil {
+		trans.Rollback()
+		return nil, nil, newUpdateDBError(err, "program_class_enrollments")
+	}
+	// the seats the resident leaves behind go to the next residents on each waitlist
+	for _, classID := range classIDs {
+		if _, err := promoteWaitlistedEnrollments(trans, classID, &ctx.UserID); err != nil {
+			trans.Rollback()
+			return nil, nil, err
+		}
 	}
 	if err := trans.Model(&models.User{}).
 		Where("id = ?", userID).
 		Update("facility_id", transFacilityID).Error; err != nil {
 		trans.Rollback()
-		return nil, newUpdateDBError(err, "users")
+		return nil, nil, newUpdateDBError(err, "users")
+	}
+	toFacilityID := uint(transFacilityID)
+	transfer := models.NewUserAccountHistory(uint(userID), models.FacilityTransfer, &ctx.UserID, nil, &toFacilityID)
+	transfer.TransferPacketID = &packet.ID
+	if err := trans.Create(transfer).Error; err != nil {
+		trans.Rollback()
+		return nil, nil, newCreateDBError(err, "user_account_history")
 	}
-	return trans, nil
+	return trans, &packet, nil
 }
 
-func (db *DB) GetEligibleResidentsForClass(args *models.QueryContext, classId int) ([]models.User, error) {
+// when onlyMeetsPrerequisites is set, residents that don't meet every prerequisite of the class's program are left out
+func (db *DB) GetEligibleResidentsForClass(args *models.QueryContext, classId int, onlyMeetsPrerequisites bool) ([]models.User, error) {
 	tx := db.WithContext(args.Ctx).Model(&models.User{}).
 		Joins("LEFT JOIN program_class_enrollments pse ON users.id = pse.user_id AND pse.class_id = ?", classId).
 		Joins("JOIN facilities_programs fp ON users.facility_id = fp.facility_id").
 		Joins("JOIN program_classes c ON c.program_id = fp.program_id AND c.id = ?", classId).
 		Where("pse.user_id IS NULL"). //not enrolled in class
-		Where("users.role = 'student' AND users.facility_id = ?", args.FacilityID)
+		Where("users.role = 'student' AND users.facility_id = ? AND users.deactivated_at IS NULL", args.FacilityID)
+	if onlyMeetsPrerequisites {
+		prerequisites, err := db.getClassPrerequisites(args.Ctx, classId)
+		if err != nil {
+			return nil, err
+		}
+		for idx := range prerequisites {
+			tx = meetsPrerequisite(tx, &prerequisites[idx])
+		}
+	}
 
 	if args.SearchQuery() != "" {
 		tx = fuzzySearchUsers(tx, args)
@@ -179,6 +259,26 @@ func (db *DB) CreateUser(user *models.User) error {
 	return nil
 }
 
+// ImportResidents creates the residents and their account creation history in a single transaction,
+// if any of them fail to insert none of them are created
+func (db *DB) ImportResidents(ctx context.Context, users []models.User, adminID, facilityID uint) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&users, 100).Error; err != nil {
+			return newCreateDBError(err, "users")
+		}
+		history := make([]models.UserAccountHistory, 0, len(users)*2)
+		for _, user := range users {
+			history = append(history,
+				*models.NewUserAccountHistory(user.ID, models.AccountCreation, &adminID, nil, nil),
+				*models.NewUserAccountHistory(user.ID, models.FacilityTransfer, &adminID, nil, &facilityID))
+		}
+		if err := tx.CreateInBatches(&history, 100).Error; err != nil {
+			return newCreateDBError(err, "user_account_history")
+		}
+		return nil
+	})
+}
+
 func (db *DB) DeleteUser(id int) error {
 	result := db.Model(&models.User{}).Where("id = ?", id).Delete(&models.User{})
 	if result.Error != nil {
@@ -190,6 +290,67 @@ func (db *DB) DeleteUser(id int) error {
 	return nil
 }
 
+// DeactivateUser keeps the user and everything that references them, but they can no longer log in
+func (db *DB) DeactivateUser(ctx context.Context, userID int, adminID uint) (*models.User, error) {
+	return db.setUserDeactivated(ctx, userID, adminID, true)
+}
+
+// ReactivateUser lets a deactivated user log in again, and for a released resident who returned to custody,
+// clears the release so they can be released again
+func (db *DB) ReactivateUser(ctx context.Context, userID int, adminID uint) (*models.User, error) {
+	return db.setUserDeactivated(ctx, userID, adminID, false)
+}
+
+func (db *DB) setUserDeactivated(ctx context.Context, userID int, adminID uint, deactivate bool) (*models.User, error) {
+	trans := db.WithContext(ctx).Begin()
+	if trans.Error != nil {
+		return nil, NewDBError(trans.Error, "unable to start DB transaction")
+	}
+	user := models.User{}
+	if err := trans.First(&user, userID).Error; err != nil {
+		trans.Rollback()
+		return nil, newNotFoundDBError(err, "users")
+	}
+	updates := map[string]any{}
+	action := models.AccountDeactivation
+	if deactivate {
+		if user.DeactivatedAt != nil {
+			trans.Rollback()
+			return nil, NewDBError(gorm.ErrInvalidData, "the user is already deactivated")
+		}
+		if user.ID == adminID {
+			trans.Rollback()
+			return nil, NewDBError(gorm.ErrInvalidData, "users can't deactivate their own account")
+		}
+		now := time.Now()
+		updates["deactivated_at"] = now
+		user.DeactivatedAt = &now
+	} else {
+		if user.DeactivatedAt == nil {
+			trans.Rollback()
+			return nil, NewDBError(gorm.ErrInvalidData, "the user is not deactivated")
+		}
+		action = models.AccountReactivation
+		updates["deactivated_at"] = nil
+		updates["released_at"] = nil
+		updates["release_date"] = nil
+		user.DeactivatedAt, user.ReleasedAt, user.ReleaseDate = nil, nil, nil
+	}
+	if err := trans.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		trans.Rollback()
+		return nil, newUpdateDBError(err, "users")
+	}
+	history := models.NewUserAccountHistory(user.ID, action, &adminID, nil, &user.FacilityID)
+	if err := trans.Create(history).Error; err != nil {
+		trans.Rollback()
+		return nil, newCreateDBError(err, "user_account_history")
+	}
+	if err := trans.Commit().Error; err != nil {
+		return nil, NewDBError(err, "unable to commit the database transaction")
+	}
+	return &user, nil
+}
+
 func (db *DB) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
 	if err := db.Model(models.User{}).First(&user, "username = ?", username).Error; err != nil {
@@ -489,20 +650,26 @@ func (db *DB) InsertUserAccountHistoryAction(ctx context.Context, accountHistory
 	return nil
 }
 
-func (db *DB) GetUserAccountHistory(args *models.QueryContext, userID uint) ([]models.ActivityHistoryResponse, error) {
-	history := make([]models.ActivityHistoryResponse, 0, args.PerPage)
-	tx := db.WithContext(args.Ctx).
+func (db *DB) userAccountHistoryQuery(args *models.QueryContext, userID uint) *gorm.DB {
+	return db.WithContext(args.Ctx).
 		Table("user_account_history uah").
-		Select(`uah.action, uah.created_at, uah.user_id, 
+		Select(`uah.action, uah.created_at, uah.user_id, uah.transfer_packet_id,
 				users.username AS user_username, 
 				admins.username AS admin_username, 
 				facilities.name AS facility_name, 
+				program_classes.name AS class_name,
 				psh.*`).
 		Joins("INNER JOIN users ON uah.user_id = users.id").
 		Joins("LEFT JOIN users admins ON uah.admin_id = admins.id").
 		Joins("LEFT JOIN facilities ON uah.facility_id = facilities.id").
+		Joins("LEFT JOIN program_classes ON uah.program_class_id = program_classes.id").
 		Joins("LEFT JOIN program_classes_history psh ON uah.program_classes_history_id = psh.id").
 		Where("uah.user_id = ?", userID)
+}
+
+func (db *DB) GetUserAccountHistory(args *models.QueryContext, userID uint) ([]models.ActivityHistoryResponse, error) {
+	history := make([]models.ActivityHistoryResponse, 0, args.PerPage)
+	tx := db.userAccountHistoryQuery(args, userID)
 	if err := tx.Count(&args.Total).Error; err != nil {
 		return nil, newGetRecordsDBError(err, "user_account_history")
 	}
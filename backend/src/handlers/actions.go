@@ -17,8 +17,8 @@ func (srv *Server) registerActionsRoutes() []routeDef {
 	// returns the users for mapping on the client
 	axx := models.ProviderAccess
 	return []routeDef{
-		adminFeatureRoute("GET /api/actions/provider-platforms/{id}/get-users", srv.handleGetUsers, axx),
-		adminFeatureRoute("POST /api/actions/provider-platforms/{id}/import-users", srv.handleImportUsers, axx),
+		permissionFeatureRoute("GET /api/actions/provider-platforms/{id}/get-users", srv.handleGetUsers, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/actions/provider-platforms/{id}/import-users", srv.handleImportUsers, models.ProvidersManage, axx),
 	}
 }
 
@@ -16,7 +16,7 @@ func (srv *Server) registerActivityRoutes() []routeDef {
 		newRoute("POST /api/analytics/faq-click", srv.handleUserFAQClick),
 		validatedFeatureRoute("GET /api/users/{id}/daily-activity", srv.handleGetDailyActivityByUserID, axx, UserRoleResolver("id")),
 		/* admin */
-		adminFeatureRoute("GET /api/courses/{id}/activity", srv.handleGetCourseActivity, axx),
+		permissionFeatureRoute("GET /api/courses/{id}/activity", srv.handleGetCourseActivity, models.ReportsRead, axx),
 	}
 }
 
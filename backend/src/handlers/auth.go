@@ -39,7 +39,11 @@ type (
 )
 
 func (c *Claims) canSwitchFacility() bool {
-	return slices.Contains([]models.UserRole{models.SystemAdmin, models.DepartmentAdmin}, c.Role)
+	return c.can(models.FacilitiesSwitch)
+}
+
+func (c *Claims) can(permissions ...models.Permission) bool {
+	return c.Role.Can(permissions...)
 }
 
 func (srv *Server) registerAuthRoutes() []routeDef {
@@ -68,6 +72,10 @@ func (claims *Claims) isAdmin() bool {
 	return slices.Contains(models.AdminRoles, claims.Role)
 }
 
+func (claims *Claims) isInstructor() bool {
+	return claims.Role == models.Instructor
+}
+
 func claimsFromUser(user *models.User) *Claims {
 	return &Claims{
 		Username:   user.Username,
@@ -140,19 +148,20 @@ func userIsAdmin(r *http.Request) bool {
 	return claims.isAdmin()
 }
 
-func userIsSystemAdmin(r *http.Request) bool {
-	return r.Context().Value(ClaimsKey).(*Claims).Role == models.SystemAdmin
+func userCan(r *http.Request, permissions ...models.Permission) bool {
+	return r.Context().Value(ClaimsKey).(*Claims).can(permissions...)
 }
 
-func (srv *Server) adminMiddleware(next http.Handler) http.Handler {
+// permissionMiddleware only lets through the users whose role is granted every permission the route requires
+func (srv *Server) permissionMiddleware(next http.Handler, permissions ...models.Permission) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		claims, ok := r.Context().Value(ClaimsKey).(*Claims)
 		if !ok {
 			srv.errorResponse(w, http.StatusUnauthorized, "Unauthorized - no claims")
 			return
 		}
-		if !claims.isAdmin() {
-			srv.errorResponse(w, http.StatusUnauthorized, "Unauthorized - not admin")
+		if !claims.can(permissions...) {
+			srv.errorResponse(w, http.StatusUnauthorized, "Unauthorized - missing permission")
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -251,6 +260,9 @@ func (srv *Server) validateOrySession(r *http.Request) (*Claims, bool, error) {
 					log.WithFields(fields).Errorln("error fetching user found from kratos session")
 					return nil, hasCookie, err
 				}
+				if user.DeactivatedAt != nil {
+					return nil, hasCookie, errors.New("user account is deactivated")
+				}
 				traits := identity["traits"].(map[string]any)
 				fields["user"] = user
 				log.WithFields(fields).Trace("found user from ory session")
@@ -258,7 +270,7 @@ func (srv *Server) validateOrySession(r *http.Request) (*Claims, bool, error) {
 				if !ok {
 					facilityId = float64(user.FacilityID)
 				}
-				if uint(facilityId) != user.FacilityID && !slices.Contains([]models.UserRole{models.DepartmentAdmin, models.SystemAdmin}, user.Role) {
+				if uint(facilityId) != user.FacilityID && !user.Role.Can(models.FacilitiesSwitch) {
 					return nil, hasCookie, errors.New("user is not allowed to switch facilities")
 				}
 
@@ -268,7 +280,7 @@ func (srv *Server) validateOrySession(r *http.Request) (*Claims, bool, error) {
 				}
 				facilityName := user.Facility.Name
 				tz := user.Facility.Timezone
-				if user.FacilityID != uint(facilityId) && slices.Contains([]models.UserRole{models.DepartmentAdmin, models.SystemAdmin}, user.Role) {
+				if user.FacilityID != uint(facilityId) && user.Role.Can(models.FacilitiesSwitch) {
 					var nameTz struct {
 						Name     string `json:"name"`
 						Timezone string `json:"timezone"`
@@ -295,7 +307,8 @@ func (srv *Server) validateOrySession(r *http.Request) (*Claims, bool, error) {
 					SessionID:     sessionID,
 					TimeZone:      tz,
 				}
-				if string(user.Role) != traits["role"].(string) {
+				// keep the role trait in kratos in sync with the user's role, e.g. when an admin makes them an instructor
+				if role, _ := traits["role"].(string); role != string(user.Role) {
 					err := srv.updateUserTraitsInKratos(claims)
 					if err != nil {
 						log.WithFields(fields).Errorf("Error updating user traits in kratos: %v", err)
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (srv *Server) registerCalendarFeedRoutes() []routeDef {
+	axx := models.ProgramAccess
+	/* calendar clients can't log in, the token in the path is the only credential */
+	srv.Mux.Handle("GET /api/calendar-feeds/{token}/feed.ics", srv.checkFeatureAccessMiddleware(srv.handleError(srv.handleGetCalendarFeed), axx))
+	return []routeDef{
+		permissionFeatureRoute("GET /api/calendar-feeds", srv.handleIndexCalendarFeeds, models.CalendarFeedsManage, axx),
+		permissionFeatureRoute("POST /api/calendar-feeds", srv.handleCreateCalendarFeed, models.CalendarFeedsManage, axx),
+		permissionFeatureRoute("DELETE /api/calendar-feeds/{id}", srv.handleDeleteCalendarFeed, models.CalendarFeedsManage, axx),
+	}
+}
+
+func (srv *Server) handleIndexCalendarFeeds(w http.ResponseWriter, r *http.Request, log sLog) error {
+	args := srv.getQueryContext(r)
+	feeds, err := srv.Db.GetCalendarFeeds(&args)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, feeds, args.IntoMeta())
+}
+
+/**
+* POST: /api/calendar-feeds
+* feeds are for the facility of the admin, the response has the feed's token,
+* which is needed for the feed URL and can't be retrieved again
+**/
+func (srv *Server) handleCreateCalendarFeed(w http.ResponseWriter, r *http.Request, log sLog) error {
+	feed := models.CalendarFeed{}
+	if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	feed.FacilityID = claims.FacilityID
+	feed.CreateUserID = claims.UserID
+	if err := srv.Db.CreateCalendarFeed(r.Context(), &feed); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("calendar_feed_id", feed.ID)
+	log.auditDetails("calendar_feed_created")
+	return writeJsonResponse(w, http.StatusCreated, feed)
+}
+
+func (srv *Server) handleDeleteCalendarFeed(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "calendar feed ID")
+	}
+	log.add("calendar_feed_id", id)
+	if err := srv.Db.DeleteCalendarFeed(r.Context(), id, srv.getFacilityID(r)); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.auditDetails("calendar_feed_revoked")
+	return writeJsonResponse(w, http.StatusOK, "Calendar feed revoked successfully")
+}
+
+// GET: /api/calendar-feeds/{token}/feed.ics
+func (srv *Server) handleGetCalendarFeed(w http.ResponseWriter, r *http.Request, log sLog) error {
+	feed, err := srv.Db.GetCalendarFeedByToken(r.Context(), r.PathValue("token"))
+	if err != nil {
+		return NewServiceError(err, http.StatusNotFound, "calendar feed not found")
+	}
+	log.add("calendar_feed_id", feed.ID)
+	events, err := srv.Db.GetCalendarFeedEvents(r.Context(), feed)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	loc := time.UTC
+	if feed.Facility != nil {
+		if loc, err = time.LoadLocation(feed.Facility.Timezone); err != nil {
+			log.warnf("unable to load timezone %s for calendar feed, using UTC: %v", feed.Facility.Timezone, err)
+			loc = time.UTC
+		}
+	}
+	calendar := calendarFeed{name: calendarFeedName(feed, events), loc: loc, now: time.Now(), events: events, log: log}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=\"classes.ics\"")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(calendar.render())); err != nil {
+		return newResponseServiceError(err)
+	}
+	return nil
+}
+
+func calendarFeedName(feed *models.CalendarFeed, events []models.ProgramClassEvent) string {
+	facilityName := ""
+	if feed.Facility != nil {
+		facilityName = feed.Facility.Name
+	}
+	switch feed.FeedType {
+	case models.ClassCalendarFeed:
+		if len(events) > 0 && events[0].Class != nil {
+			return fmt.Sprintf("%s (%s)", events[0].Class.Name, facilityName)
+		}
+	case models.InstructorCalendarFeed:
+		return fmt.Sprintf("%s classes (%s)", *feed.InstructorName, facilityName)
+	}
+	return fmt.Sprintf("%s classes", facilityName)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// renderCertificatePDF lays out the certificate on a landscape letter page
+func renderCertificatePDF(tmpl *models.CertificateTemplate, data *models.CertificateData) ([]byte, error) {
+	body, err := tmpl.RenderBody(data)
+	if err != nil {
+		return nil, err
+	}
+	pdf := gofpdf.New("L", "mm", "Letter", "")
+	// the core fonts are latin-1, names with accents are translated to it
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(tmpl.Title, true)
+	pdf.SetMargins(30, 30, 30)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	width, height := pdf.GetPageSize()
+
+	pdf.SetDrawColor(24, 171, 160)
+	pdf.SetLineWidth(2)
+	pdf.Rect(10, 10, width-20, height-20, "D")
+	pdf.SetLineWidth(0.5)
+	pdf.Rect(14, 14, width-28, height-28, "D")
+
+	pdf.SetY(36)
+	pdf.SetFont("Helvetica", "B", 30)
+	pdf.CellFormat(0, 14, tr(tmpl.Title), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, 8, "presented to", "", 1, "C", false, 0, "")
+	pdf.SetFont("Times", "BI", 32)
+	pdf.CellFormat(0, 16, tr(data.ResidentName), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "", 13)
+	pdf.MultiCell(0, 7, tr(body), "", "C", false)
+	pdf.Ln(6)
+
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetFont("Helvetica", "", 11)
+	details := fmt.Sprintf("%s  |  %s credit  |  %s hours attended", data.FacilityName, data.CreditType, formatHours(data.Hours))
+	pdf.CellFormat(0, 6, tr(details), "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 6, "Completed on "+data.CompletedAt.Format("January 2, 2006"), "", 1, "C", false, 0, "")
+
+	footerY := height - 46
+	if tmpl.SignerName != "" {
+		pdf.SetDrawColor(60, 60, 60)
+		pdf.Line(35, footerY, 115, footerY)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetXY(35, footerY+2)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(80, 6, tr(tmpl.SignerName), "", 2, "C", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(80, 5, tr(tmpl.SignerTitle), "", 0, "C", false, 0, "")
+	}
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetXY(width-135, footerY+2)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(100, 6, "Verification code: "+data.VerificationCode, "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(100, 5, os.Getenv("APP_URL")+"/api/certificates/verify/"+data.VerificationCode, "", 0, "R", false, 0, "")
+
+	certificate := bytes.Buffer{}
+	if err := pdf.Output(&certificate); err != nil {
+		return nil, err
+	}
+	return certificate.Bytes(), nil
+}
+
+// formatHours rounds to a tenth of an hour, without trailing zeros
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(math.Round(hours*10)/10, 'f', -1, 64)
+}
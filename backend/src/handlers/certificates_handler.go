@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func (srv *Server) registerCertificateRoutes() []routeDef {
+	axx := models.ProgramAccess
+	/* whoever is handed a certificate can check it without an account, the code is the only credential */
+	srv.Mux.Handle("GET /api/certificates/verify/{code}", srv.checkFeatureAccessMiddleware(srv.handleError(srv.handleVerifyCertificate), axx))
+	return []routeDef{
+		validatedFeatureRoute("GET /api/users/{id}/program-completions/{completion_id}/certificate", srv.handleGetCompletionCertificate, axx, UserRoleResolver("id")),
+		permissionFeatureRoute("GET /api/programs/{id}/certificate-template", srv.handleGetCertificateTemplate, models.ProgramsRead, axx),
+		permissionFeatureRoute("PUT /api/programs/{id}/certificate-template", srv.handleUpdateCertificateTemplate, models.ProgramsWrite, axx),
+	}
+}
+
+/**
+* GET: /api/users/{id}/program-completions/{completion_id}/certificate
+* the certificate is issued with its verification code the first time it is downloaded
+**/
+func (srv *Server) handleGetCompletionCertificate(w http.ResponseWriter, r *http.Request, log sLog) error {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	completionID, err := strconv.Atoi(r.PathValue("completion_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "program completion ID")
+	}
+	log.add("user_id", userID)
+	log.add("program_completion_id", completionID)
+	data, tmpl, err := srv.Db.IssueCertificate(r.Context(), userID, completionID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	certificate, err := renderCertificatePDF(tmpl, data)
+	if err != nil {
+		return newInternalServerServiceError(err, "unable to render certificate")
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("certificate-%s.pdf", data.VerificationCode)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(certificate); err != nil {
+		return newResponseServiceError(err)
+	}
+	return nil
+}
+
+// GET: /api/certificates/verify/{code}
+func (srv *Server) handleVerifyCertificate(w http.ResponseWriter, r *http.Request, log sLog) error {
+	data, err := srv.Db.VerifyCertificate(r.Context(), r.PathValue("code"))
+	if err != nil {
+		return NewServiceError(err, http.StatusNotFound, "certificate not found")
+	}
+	log.add("verification_code", data.VerificationCode)
+	return writeJsonResponse(w, http.StatusOK, data)
+}
+
+func (srv *Server) handleGetCertificateTemplate(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "program ID")
+	}
+	tmpl, err := srv.Db.GetCertificateTemplate(r.Context(), uint(id))
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, tmpl)
+}
+
+/**
+* PUT: /api/programs/{id}/certificate-template
+* the body is a text/template of the fields of models.CertificateData, e.g. {{.ResidentName}}
+**/
+func (srv *Server) handleUpdateCertificateTemplate(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "program ID")
+	}
+	log.add("program_id", id)
+	tmpl := models.CertificateTemplate{}
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	tmpl.ProgramID = uint(id)
+	if _, err := tmpl.RenderBody(&models.CertificateData{}); err != nil {
+		return newBadRequestServiceError(err, "certificate body is not a valid template: "+err.Error())
+	}
+	if err := srv.Db.SaveCertificateTemplate(r.Context(), &tmpl); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.auditDetails("certificate_template_updated")
+	return writeJsonResponse(w, http.StatusOK, tmpl)
+}
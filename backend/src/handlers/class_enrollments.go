@@ -1,23 +1,29 @@
 package handlers
 
 import (
+	"UnlockEdv2/src/database"
 	"UnlockEdv2/src/models"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 func (srv *Server) registerProgramClassEnrollmentsRoutes() []routeDef {
 	axx := models.ProgramAccess
 	resolve := FacilityAdminResolver("program_classes", "class_id")
+	instructorResolve := ClassInstructorResolver("class_id")
 	return []routeDef{
-		adminFeatureRoute("GET /api/program-classes/{class_id}/enrollments", srv.handleGetEnrollmentsForProgram, axx),
-		adminValidatedFeatureRoute("POST /api/program-classes/{class_id}/enrollments", srv.handleEnrollUsersInClass, axx, resolve),
-		adminValidatedFeatureRoute("PATCH /api/program-classes/{class_id}/enrollments", srv.handleUpdateProgramClassEnrollments, axx, resolve),
-		adminValidatedFeatureRoute("DELETE /api/programs/{id}/classes/{class_id}/enrollments", srv.handleDeleteProgramClassEnrollments, axx, resolve),
-		adminValidatedFeatureRoute("GET /api/programs/{id}/classes/{class_id}/enrollments/{enrollment_id}/attendance", srv.handleGetProgramClassEnrollmentsAttendance, axx, resolve),
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/enrollments", srv.handleGetEnrollmentsForProgram, models.ClassesRosterRead, axx, instructorResolve),
+		validatedPermissionFeatureRoute("POST /api/program-classes/{class_id}/enrollments", srv.handleEnrollUsersInClass, models.ClassesRosterWrite, axx, resolve),
+		validatedPermissionFeatureRoute("PATCH /api/program-classes/{class_id}/enrollments", srv.handleUpdateProgramClassEnrollments, models.ClassesRosterWrite, axx, resolve),
+		validatedPermissionFeatureRoute("DELETE /api/programs/{id}/classes/{class_id}/enrollments", srv.handleDeleteProgramClassEnrollments, models.ClassesRosterWrite, axx, resolve),
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/waitlist", srv.handleGetClassWaitlist, models.ClassesRead, axx, resolve),
+		validatedPermissionFeatureRoute("PUT /api/program-classes/{class_id}/waitlist", srv.handleReorderClassWaitlist, models.ClassesRosterWrite, axx, resolve),
+		validatedPermissionFeatureRoute("GET /api/programs/{id}/classes/{class_id}/enrollments/{enrollment_id}/attendance", srv.handleGetProgramClassEnrollmentsAttendance, models.ClassesAttendanceRead, axx, instructorResolve),
 		validatedFeatureRoute("GET /api/users/{id}/program-completions", srv.handleGetUserProgramCompletions, axx, UserRoleResolver("id")),
 	}
 }
@@ -68,19 +74,45 @@ func (srv *Server) handleEnrollUsersInClass(w http.ResponseWriter, r *http.Reque
 		return newBadRequestServiceError(err, "cannot perform action on class that is completed cancelled or archived")
 	}
 	enrollment := struct {
-		UserIDs []int `json:"user_ids"`
+		UserIDs               []int  `json:"user_ids"`
+		OverridePrerequisites bool   `json:"override_prerequisites"`
+		OverrideReason        string `json:"override_reason"`
 	}{}
 	err = json.NewDecoder(r.Body).Decode(&enrollment)
 	if err != nil {
 		return newJSONReqBodyServiceError(err)
 	}
-	skipped, err := srv.Db.CreateProgramClassEnrollments(classID, enrollment.UserIDs)
+	unmet, err := srv.Db.GetUnmetPrerequisites(r.Context(), classID, enrollment.UserIDs)
 	if err != nil {
 		return newDatabaseServiceError(err)
 	}
+	var override *database.PrerequisiteOverride
+	if len(unmet) > 0 {
+		if !enrollment.OverridePrerequisites {
+			log.add("unmet_prerequisites", len(unmet))
+			return writeJsonResponse(w, http.StatusUnprocessableEntity, unmet)
+		}
+		reason := strings.TrimSpace(enrollment.OverrideReason)
+		if reason == "" || len(reason) > 255 {
+			return newBadRequestServiceError(errors.New("invalid override reason"), "a reason of at most 255 characters is required to override the program's prerequisites")
+		}
+		claims := r.Context().Value(ClaimsKey).(*Claims)
+		override = &database.PrerequisiteOverride{AdminID: claims.UserID, Reason: reason}
+		for _, resident := range unmet {
+			override.UserIDs = append(override.UserIDs, int(resident.UserID))
+		}
+		log.add("override_reason", reason)
+		log.add("overridden_user_ids", override.UserIDs)
+		log.auditDetails("prerequisites_overridden")
+	}
+	waitlisted, err := srv.Db.CreateProgramClassEnrollments(classID, enrollment.UserIDs, override)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("waitlisted", waitlisted)
 	response := "users enrolled"
-	if skipped > 0 {
-		response = fmt.Sprintf("%d users were enrolled, %d were not added because capacity is full.", len(enrollment.UserIDs)-skipped, skipped)
+	if waitlisted > 0 {
+		response = fmt.Sprintf("%d users were enrolled, %d were added to the waitlist because capacity is full.", len(enrollment.UserIDs)-waitlisted, waitlisted)
 	}
 	return writeJsonResponse(w, http.StatusCreated, response)
 }
@@ -124,18 +156,71 @@ func (srv *Server) handleUpdateProgramClassEnrollments(w http.ResponseWriter, r
 	if enrollment.EnrollmentStatus == "" {
 		return newInvalidIdServiceError(errors.New("enrollment status is required"), "enrollment status")
 	}
+	if enrollment.EnrollmentStatus == string(models.EnrollmentWaitlisted) {
+		return newBadRequestServiceError(errors.New("cannot waitlist enrollment"), "residents are only added to the waitlist when the class is full")
+	}
+	promoted := 0
 	switch enrollment.EnrollmentStatus {
 	case "Completed":
 		err = srv.Db.GraduateEnrollments(r.Context(), adminEmail, enrollment.UserIDs, classId)
 	default:
-		err = srv.Db.UpdateProgramClassEnrollments(classId, enrollment.UserIDs, enrollment.EnrollmentStatus, enrollment.ChangeReason)
+		promoted, err = srv.Db.UpdateProgramClassEnrollments(r.Context(), classId, enrollment.UserIDs, enrollment.EnrollmentStatus, enrollment.ChangeReason, &claims.UserID)
 	}
 	if err != nil {
 		return newDatabaseServiceError(err)
 	}
+	if promoted > 0 {
+		log.add("promoted_from_waitlist", promoted)
+		log.info("residents promoted from the class waitlist")
+	}
 	return writeJsonResponse(w, http.StatusOK, "updated")
 }
 
+func (srv *Server) handleGetClassWaitlist(w http.ResponseWriter, r *http.Request, log sLog) error {
+	classID, err := strconv.Atoi(r.PathValue("class_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "class ID")
+	}
+	log.add("class_id", classID)
+	args := srv.getQueryContext(r)
+	waitlist, err := srv.Db.GetClassWaitlist(&args, classID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, waitlist, args.IntoMeta())
+}
+
+// PUT: /api/program-classes/{class_id}/waitlist
+// the body lists every waitlisted resident in their new order: {"user_ids": [3, 1, 2]}
+func (srv *Server) handleReorderClassWaitlist(w http.ResponseWriter, r *http.Request, log sLog) error {
+	classID, err := strconv.Atoi(r.PathValue("class_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "class ID")
+	}
+	log.add("class_id", classID)
+	waitlist := struct {
+		UserIDs []int `json:"user_ids"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&waitlist); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	args := srv.getQueryContext(r)
+	current, err := srv.Db.GetClassWaitlistUserIDs(&args, classID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	ordered := slices.Clone(waitlist.UserIDs)
+	slices.Sort(ordered)
+	slices.Sort(current)
+	if !slices.Equal(ordered, current) {
+		return newBadRequestServiceError(errors.New("waitlist mismatch"), "user_ids must include every waitlisted resident exactly once")
+	}
+	if err := srv.Db.ReorderClassWaitlist(&args, classID, waitlist.UserIDs); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, "waitlist updated")
+}
+
 func (srv *Server) handleGetProgramClassEnrollmentsAttendance(w http.ResponseWriter, r *http.Request, log sLog) error {
 	id, err := strconv.Atoi(r.PathValue("class_id"))
 	if err != nil {
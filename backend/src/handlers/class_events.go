@@ -15,11 +15,12 @@ func (srv *Server) registerClassEventsRoutes() []routeDef {
 		featureRoute("GET /api/student-calendar", srv.handleGetStudentCalendar, axx),
 		featureRoute("GET /api/student-attendance", srv.handleGetStudentAttendanceData, axx),
 		/* admin */
-		adminFeatureRoute("GET /api/admin-calendar", srv.handleGetAdminCalendar, axx),
-		adminValidatedFeatureRoute("GET /api/program-classes/{class_id}/events", srv.handleGetProgramClassEvents, axx, resolver),
-		adminValidatedFeatureRoute("PUT /api/program-classes/{class_id}/events/{event_id}", srv.handleEventOverrides, axx, resolver),
-		adminValidatedFeatureRoute("POST /api/program-classes/{class_id}/events", srv.handleCreateEvent, axx, resolver),
-		adminValidatedFeatureRoute("PUT /api/program-classes/{class_id}/events", srv.handleRescheduleEventSeries, axx, resolver),
+		validatedPermissionFeatureRoute("GET /api/admin-calendar", srv.handleGetAdminCalendar, models.ClassesScheduleRead, axx, nil),
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/events", srv.handleGetProgramClassEvents, models.ClassesScheduleRead, axx, ClassInstructorResolver("class_id")),
+		validatedPermissionFeatureRoute("PUT /api/program-classes/{class_id}/events/{event_id}", srv.handleEventOverrides, models.ClassesScheduleWrite, axx, resolver),
+		validatedPermissionFeatureRoute("POST /api/program-classes/{class_id}/events", srv.handleCreateEvent, models.ClassesScheduleWrite, axx, resolver),
+		validatedPermissionFeatureRoute("PUT /api/program-classes/{class_id}/events", srv.handleRescheduleEventSeries, models.ClassesScheduleWrite, axx, resolver),
+		validatedPermissionFeatureRoute("POST /api/program-classes/{class_id}/events/check-conflicts", srv.handleCheckEventConflicts, models.ClassesScheduleWrite, axx, resolver),
 	}
 }
 
@@ -37,6 +38,10 @@ func (srv *Server) handleGetAdminCalendar(w http.ResponseWriter, r *http.Request
 		}
 	}
 	args := srv.getQueryContext(r)
+	if claims := r.Context().Value(ClaimsKey).(*Claims); claims.isInstructor() {
+		// the calendar of an instructor only has their own classes, see GetFacilityCalendar
+		args.FacilityID, args.UserID = claims.FacilityID, claims.UserID
+	}
 	events, err := srv.Db.GetFacilityCalendar(&args, dtRng, classID)
 	if err != nil {
 		return newDatabaseServiceError(err)
@@ -54,6 +59,9 @@ func (srv *Server) handleGetStudentCalendar(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		return newDatabaseServiceError(err)
 	}
+	if calendar.Closures, err = srv.Db.GetFacilityClosuresBetween(r.Context(), claims.FacilityID, dtRng); err != nil {
+		return newDatabaseServiceError(err)
+	}
 	return writeJsonResponse(w, http.StatusOK, calendar)
 }
 
@@ -85,6 +93,13 @@ func (srv *Server) handleCreateEvent(w http.ResponseWriter, r *http.Request, log
 	if err := json.NewDecoder(r.Body).Decode(event); err != nil {
 		return newJSONReqBodyServiceError(err)
 	}
+	conflicts, err := srv.checkEventConflicts(r, classID, log, *event)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return writeJsonResponse(w, http.StatusConflict, conflicts)
+	}
 	_, err = srv.Db.CreateNewEvent(classID, event)
 	if err != nil {
 		return newDatabaseServiceError(err)
@@ -112,6 +127,13 @@ func (srv *Server) handleRescheduleEventSeries(w http.ResponseWriter, r *http.Re
 	}
 	eventSeriesRequest.EventSeries.ClassID = uint(classID)
 	eventSeriesRequest.ClosedEventSeries.ClassID = uint(classID)
+	conflicts, err := srv.checkEventConflicts(r, classID, log, eventSeriesRequest.EventSeries)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return writeJsonResponse(w, http.StatusConflict, conflicts)
+	}
 	events := []models.ProgramClassEvent{
 		eventSeriesRequest.EventSeries,
 		eventSeriesRequest.ClosedEventSeries,
@@ -136,6 +158,44 @@ func (srv *Server) handleRescheduleEventSeries(w http.ResponseWriter, r *http.Re
 	return writeJsonResponse(w, http.StatusCreated, "Event rescheduled successfully")
 }
 
+// POST: /api/program-classes/{class_id}/events/check-conflicts
+// takes the same event body as creating an event, and reports the conflicts without saving it
+func (srv *Server) handleCheckEventConflicts(w http.ResponseWriter, r *http.Request, log sLog) error {
+	classID, err := strconv.Atoi(r.PathValue("class_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "class_id")
+	}
+	log.add("class_id", classID)
+	event := models.ProgramClassEvent{}
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	conflicts, err := srv.Db.CheckScheduleConflicts(r.Context(), classID, []models.ProgramClassEvent{event})
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, conflicts)
+}
+
+// checkEventConflicts returns the conflicts of the events, unless the request sets ?force=true to schedule
+// them anyway, in which case the conflicts are audited and none are returned
+func (srv *Server) checkEventConflicts(r *http.Request, classID int, log sLog, events ...models.ProgramClassEvent) ([]models.ScheduleConflict, error) {
+	conflicts, err := srv.Db.CheckScheduleConflicts(r.Context(), classID, events)
+	if err != nil {
+		return nil, newDatabaseServiceError(err)
+	}
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+	log.add("class_id", classID)
+	log.add("conflicts", len(conflicts))
+	if r.URL.Query().Get("force") != "true" {
+		return conflicts, nil
+	}
+	log.auditDetails("schedule_conflicts_forced")
+	return nil, nil
+}
+
 func (srv *Server) handleGetStudentAttendanceData(w http.ResponseWriter, r *http.Request, log sLog) error {
 	userId := r.Context().Value(ClaimsKey).(*Claims).UserID
 	programData, err := srv.Db.GetStudentProgramAttendanceData(userId)
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+func (srv *Server) registerClassInstructorRoutes() []routeDef {
+	axx := models.ProgramAccess
+	resolver := FacilityAdminResolver("program_classes", "class_id")
+	return []routeDef{
+		validatedFeatureRoute("GET /api/users/{id}/instructor-classes", srv.handleGetInstructorClasses, axx, UserRoleResolver("id")),
+		/* admin */
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/instructors", srv.handleGetClassInstructors, models.ClassesRead, axx, resolver),
+		validatedPermissionFeatureRoute("PUT /api/program-classes/{class_id}/instructors", srv.handleSetClassInstructors, models.ClassesWrite, axx, resolver),
+	}
+}
+
+func (srv *Server) handleGetInstructorClasses(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	args := srv.getQueryContext(r)
+	classes, err := srv.Db.GetInstructorClasses(&args, id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, classes, args.IntoMeta())
+}
+
+func (srv *Server) handleGetClassInstructors(w http.ResponseWriter, r *http.Request, log sLog) error {
+	classID, err := strconv.Atoi(r.PathValue("class_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "class ID")
+	}
+	instructors, err := srv.Db.GetClassInstructors(r.Context(), classID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, instructors)
+}
+
+/**
+* PUT: /api/program-classes/{class_id}/instructors
+* replaces the instructors assigned to the class with the users in user_ids
+**/
+func (srv *Server) handleSetClassInstructors(w http.ResponseWriter, r *http.Request, log sLog) error {
+	classID, err := strconv.Atoi(r.PathValue("class_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "class ID")
+	}
+	log.add("class_id", classID)
+	var form struct {
+		UserIDs []uint `json:"user_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	if err := srv.Db.SetClassInstructors(r.Context(), classID, form.UserIDs); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("instructor_ids", form.UserIDs)
+	log.auditDetails("class_instructors_updated")
+	instructors, err := srv.Db.GetClassInstructors(r.Context(), classID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, instructors)
+}
@@ -29,17 +29,17 @@ func (srv *Server) registerClassesRoutes() []routeDef {
 		featureRoute("GET /api/programs/{id}/classes", srv.handleGetClassesForProgram, axx),
 		featureRoute("GET /api/program-classes", srv.handleIndexClassesForFacility, axx),
 		/* admin */
-		adminValidatedFeatureRoute("POST /api/programs/{program_id}/classes", srv.handleCreateClass, axx, validateFacility("is_active = true AND archived_at IS NULL AND")),
+		validatedPermissionFeatureRoute("POST /api/programs/{program_id}/classes", srv.handleCreateClass, models.ClassesWrite, axx, validateFacility("is_active = true AND archived_at IS NULL AND")),
 		validatedFeatureRoute("GET /api/program-classes/{class_id}", srv.handleGetClass, axx, resolver),
-		adminValidatedFeatureRoute("GET /api/programs/{program_id}/classes/outcomes", srv.handleGetProgramClassOutcomes, axx, validateFacility("")),
-		adminValidatedFeatureRoute("GET /api/program-classes/{class_id}/attendance-flags", srv.handleGetAttendanceFlagsForClass, axx, resolver),
-		adminValidatedFeatureRoute("GET /api/program-classes/{class_id}/history", srv.handleGetClassHistory, axx, resolver),
-		adminValidatedFeatureRoute("PATCH /api/program-classes", srv.handleUpdateClasses, axx, func(tx *database.DB, r *http.Request) bool {
+		validatedPermissionFeatureRoute("GET /api/programs/{program_id}/classes/outcomes", srv.handleGetProgramClassOutcomes, models.ReportsRead, axx, validateFacility("")),
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/attendance-flags", srv.handleGetAttendanceFlagsForClass, models.ClassesRead, axx, resolver),
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/history", srv.handleGetClassHistory, models.ClassesRead, axx, resolver),
+		validatedPermissionFeatureRoute("PATCH /api/program-classes", srv.handleUpdateClasses, models.ClassesWrite, axx, func(tx *database.DB, r *http.Request) bool {
 			return tx.WithContext(r.Context()).Table("program_classes").Select("facility_id").Where("id IN (?)", r.URL.Query()["id"]).
 				Where("facility_id <> ?", r.Context().Value(ClaimsKey).(*Claims).FacilityID).
 				First(&models.ProgramClass{}).Error != nil
 		}),
-		adminValidatedFeatureRoute("PATCH /api/programs/{id}/classes/{class_id}", srv.handleUpdateClass, axx, resolver),
+		validatedPermissionFeatureRoute("PATCH /api/programs/{id}/classes/{class_id}", srv.handleUpdateClass, models.ClassesWrite, axx, resolver),
 	}
 }
 
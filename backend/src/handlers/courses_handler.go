@@ -11,7 +11,7 @@ func (srv *Server) registerCoursesRoutes() []routeDef {
 	return []routeDef{
 		featureRoute("GET /api/courses/{id}", srv.handleShowCourse, axx),
 		/* admin */
-		adminFeatureRoute("GET /api/courses", srv.handleIndexCourses, axx),
+		permissionFeatureRoute("GET /api/courses", srv.handleIndexCourses, models.ProvidersManage, axx),
 	}
 }
 
@@ -17,8 +17,8 @@ func (srv *Server) registerDashboardRoutes() []routeDef {
 	axx := models.ProviderAccess
 	resolver := UserRoleResolver("id")
 	return []routeDef{
-		newAdminRoute("GET /api/login-metrics", srv.handleLoginMetrics),
-		newAdminRoute("GET /api/users/{id}/admin-layer2", srv.handleAdminLayer2),
+		permissionRoute("GET /api/login-metrics", srv.handleLoginMetrics, models.ReportsRead),
+		permissionRoute("GET /api/users/{id}/admin-layer2", srv.handleAdminLayer2, models.ReportsRead),
 		validatedFeatureRoute("GET /api/users/{id}/catalog", srv.handleUserCatalog, axx, resolver),
 		validatedFeatureRoute("GET /api/users/{id}/courses", srv.handleUserCourses, axx, resolver),
 		validatedRoute("GET /api/users/{id}/profile", srv.handleResidentProfile, resolver),
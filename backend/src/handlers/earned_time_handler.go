@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (srv *Server) registerEarnedTimeRoutes() []routeDef {
+	axx := models.ProgramAccess
+	resolver := FacilityAdminResolver("users", "id")
+	return []routeDef{
+		validatedFeatureRoute("GET /api/users/{id}/earned-time", srv.handleGetEarnedTimeBalance, axx, UserRoleResolver("id")),
+		validatedFeatureRoute("GET /api/users/{id}/earned-time/entries", srv.handleIndexEarnedTimeEntries, axx, UserRoleResolver("id")),
+		validatedPermissionFeatureRoute("POST /api/users/{id}/earned-time/adjustments", srv.handleAdjustEarnedTime, models.EarnedTimeWrite, axx, resolver),
+		validatedPermissionFeatureRoute("POST /api/users/{id}/earned-time/entries/{entry_id}/reversal", srv.handleReverseEarnedTimeEntry, models.EarnedTimeWrite, axx, resolver),
+		permissionFeatureRoute("GET /api/programs/{id}/earned-time-rule", srv.handleGetEarnedTimeRule, models.ProgramsRead, axx),
+		permissionFeatureRoute("PUT /api/programs/{id}/earned-time-rule", srv.handleUpdateEarnedTimeRule, models.ProgramsWrite, axx),
+	}
+}
+
+func (srv *Server) handleGetEarnedTimeBalance(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	balance, err := srv.Db.GetEarnedTimeBalance(r.Context(), id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, balance)
+}
+
+func (srv *Server) handleIndexEarnedTimeEntries(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	args := srv.getQueryContext(r)
+	entries, err := srv.Db.GetEarnedTimeEntries(&args, id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, entries, args.IntoMeta())
+}
+
+/**
+* POST: /api/users/{id}/earned-time/adjustments
+* days can be negative to take earned time away, a reason is required either way
+**/
+func (srv *Server) handleAdjustEarnedTime(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	log.add("user_id", id)
+	form := struct {
+		Days   float64 `json:"days"`
+		Reason string  `json:"reason"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	entry := models.EarnedTimeEntry{UserID: uint(id), Days: form.Days, Reason: strings.TrimSpace(form.Reason), CreateUserID: &claims.UserID}
+	if err := srv.Db.AdjustEarnedTime(r.Context(), &entry); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("earned_time_entry_id", entry.ID)
+	log.add("days", entry.Days)
+	log.auditDetails("earned_time_adjusted")
+	return writeJsonResponse(w, http.StatusCreated, entry)
+}
+
+func (srv *Server) handleReverseEarnedTimeEntry(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	entryID, err := strconv.Atoi(r.PathValue("entry_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "earned-time entry ID")
+	}
+	log.add("user_id", id)
+	log.add("reversed_entry_id", entryID)
+	form := struct {
+		Reason string `json:"reason"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	entry, err := srv.Db.ReverseEarnedTimeEntry(r.Context(), id, entryID, strings.TrimSpace(form.Reason), claims.UserID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("earned_time_entry_id", entry.ID)
+	log.auditDetails("earned_time_reversed")
+	return writeJsonResponse(w, http.StatusCreated, entry)
+}
+
+func (srv *Server) handleGetEarnedTimeRule(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "program ID")
+	}
+	rule, err := srv.Db.GetEarnedTimeRule(r.Context(), id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, rule)
+}
+
+// PUT: /api/programs/{id}/earned-time-rule, the rule only applies to the completions posted after it is saved
+func (srv *Server) handleUpdateEarnedTimeRule(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "program ID")
+	}
+	log.add("program_id", id)
+	rule := models.EarnedTimeRule{}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	rule.ProgramID = uint(id)
+	rule.CreateUserID = r.Context().Value(ClaimsKey).(*Claims).UserID
+	if err := srv.Db.SaveEarnedTimeRule(r.Context(), &rule); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.auditDetails("earned_time_rule_updated")
+	return writeJsonResponse(w, http.StatusOK, rule)
+}
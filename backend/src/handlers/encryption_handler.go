@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"net/http"
+	"strings"
+)
+
+func (srv *Server) registerEncryptionRoutes() []routeDef {
+	return []routeDef{
+		permissionRoute("GET /api/secrets/keys", srv.handleGetEncryptionKeyUsage, models.SystemManage),
+		permissionRoute("POST /api/secrets/rotate", srv.handleRotateEncryptedSecrets, models.SystemManage),
+	}
+}
+
+func (srv *Server) handleGetEncryptionKeyUsage(w http.ResponseWriter, r *http.Request, log sLog) error {
+	usage, err := srv.Db.GetEncryptionKeyUsage()
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, usage)
+}
+
+/**
+* POST: /api/secrets/rotate?dry_run=true&allow_plaintext=true
+* re-encrypts the stored secrets with the current APP_KEY, the keys they were encrypted with must still be
+* configured in APP_PREVIOUS_KEYS. A dry run reports what would be re-encrypted without writing anything,
+* allow_plaintext confirms that the access keys which don't decrypt were saved as plain text
+**/
+func (srv *Server) handleRotateEncryptedSecrets(w http.ResponseWriter, r *http.Request, log sLog) error {
+	dryRun := strings.ToLower(r.URL.Query().Get("dry_run")) == "true"
+	allowPlaintext := strings.ToLower(r.URL.Query().Get("allow_plaintext")) == "true"
+	log.add("dry_run", dryRun)
+	log.add("allow_plaintext", allowPlaintext)
+	result, err := srv.Db.RotateEncryptedSecrets(dryRun, allowPlaintext)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	if dryRun {
+		return writeJsonResponse(w, http.StatusOK, result)
+	}
+	log.add("keyID", result.KeyID)
+	log.add("providerAccessKeys", result.ProviderAccessKeys)
+	log.add("plaintextAccessKeys", result.PlaintextAccessKeys)
+	log.add("oidcClientSecrets", result.OidcClientSecrets)
+	log.auditDetails("secrets_rotated")
+	return writeJsonResponse(w, http.StatusOK, result)
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/database"
+	"UnlockEdv2/src/models"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func (srv *Server) registerExportRoutes() []routeDef {
+	axx := models.ProgramAccess
+	resolver := FacilityAdminResolver("program_classes", "class_id")
+	return []routeDef{
+		permissionRoute("GET /api/users/export", srv.handleExportUsers, models.ReportsExport),
+		validatedPermissionRoute("GET /api/users/{id}/account-history/export", srv.handleExportUserAccountHistory, models.ReportsExport, UserRoleResolver("id")),
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/enrollments/export", srv.handleExportClassEnrollments, models.ReportsExport, axx, resolver),
+		validatedPermissionFeatureRoute("GET /api/program-classes/{class_id}/attendance/export", srv.handleExportClassAttendance, models.ReportsExport, axx, resolver),
+		permissionFeatureRoute("GET /api/program-completions/export", srv.handleExportProgramCompletions, models.ReportsExport, axx),
+	}
+}
+
+/**
+* GET: /api/users/export?format=csv|xlsx
+* takes the same role, search and order filters as GET /api/users
+**/
+func (srv *Server) handleExportUsers(w http.ResponseWriter, r *http.Request, log sLog) error {
+	args := srv.getQueryContext(r)
+	role := r.URL.Query().Get("role")
+	header := []string{"id", "username", "name_first", "name_last", "email", "doc_id", "role", "created_at"}
+	return srv.exportTable(w, r, log, "users", header, func(writeRow func([]string) error) error {
+		return srv.Db.ExportUsers(&args, role, func(user *models.User) error {
+			return writeRow([]string{formatUint(user.ID), user.Username, user.NameFirst, user.NameLast, user.Email, user.DocID, string(user.Role), formatTime(&user.CreatedAt)})
+		})
+	})
+}
+
+func (srv *Server) handleExportUserAccountHistory(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	log.add("user_id", id)
+	args := srv.getQueryContext(r)
+	header := []string{"created_at", "action", "username", "admin_username", "facility_name", "field_name", "old_value", "new_value"}
+	return srv.exportTable(w, r, log, "account-history", header, func(writeRow func([]string) error) error {
+		return srv.Db.ExportUserAccountHistory(&args, uint(id), func(history *models.ActivityHistoryResponse) error {
+			return writeRow([]string{formatTime(history.CreatedAt), string(history.Action), derefString(history.UserUsername), derefString(history.AdminUsername),
+				derefString(history.FacilityName), derefString(history.FieldName), derefString(history.OldValue), derefString(history.NewValue)})
+		})
+	})
+}
+
+// takes the same status and search filters as GET /api/program-classes/{class_id}/enrollments
+func (srv *Server) handleExportClassEnrollments(w http.ResponseWriter, r *http.Request, log sLog) error {
+	classID, err := strconv.Atoi(r.PathValue("class_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "class ID")
+	}
+	log.add("class_id", classID)
+	status := r.URL.Query().Get("status")
+	if status == "all" {
+		status = ""
+	}
+	args := srv.getQueryContext(r)
+	header := []string{"user_id", "name_full", "doc_id", "class_name", "enrollment_status", "change_reason", "enrolled_at", "start_dt", "completion_dt"}
+	return srv.exportTable(w, r, log, "enrollments", header, func(writeRow func([]string) error) error {
+		return srv.Db.ExportClassEnrollments(&args, classID, status, func(enrollment *database.EnrollmentDetails) error {
+			return writeRow([]string{formatUint(enrollment.UserID), enrollment.NameFull, enrollment.DocID, enrollment.ClassName, string(enrollment.EnrollmentStatus),
+				enrollment.ChangeReason, formatTime(&enrollment.CreatedAt), enrollment.StartDt, enrollment.CompletionDt})
+		})
+	})
+}
+
+// GET: /api/program-classes/{class_id}/attendance/export?start_dt=2025-01-01&end_dt=2025-01-31
+func (srv *Server) handleExportClassAttendance(w http.ResponseWriter, r *http.Request, log sLog) error {
+	classID, err := strconv.Atoi(r.PathValue("class_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "class ID")
+	}
+	log.add("class_id", classID)
+	args := srv.getQueryContext(r)
+	header := []string{"date", "class_name", "user_id", "name_full", "doc_id", "attendance_status", "note"}
+	return srv.exportTable(w, r, log, "attendance", header, func(writeRow func([]string) error) error {
+		return srv.Db.ExportClassAttendance(&args, classID, func(attendance *database.AttendanceExportRow) error {
+			return writeRow([]string{attendance.Date, attendance.ClassName, formatUint(attendance.UserID), attendance.NameFull, attendance.DocID, string(attendance.AttendanceStatus), attendance.Note})
+		})
+	})
+}
+
+// GET: /api/program-completions/export?program_id=1&class_id=2
+func (srv *Server) handleExportProgramCompletions(w http.ResponseWriter, r *http.Request, log sLog) error {
+	args := srv.getQueryContext(r)
+	header := []string{"user_id", "name_full", "doc_id", "program_name", "class_name", "credit_type", "facility_name", "program_owner", "enrolled_on_dt", "class_start_dt", "completed_at"}
+	return srv.exportTable(w, r, log, "program-completions", header, func(writeRow func([]string) error) error {
+		return srv.Db.ExportProgramCompletions(&args, func(completion *database.CompletionExportRow) error {
+			return writeRow([]string{formatUint(completion.UserID), completion.NameFull, completion.DocID, completion.ProgramName, completion.ProgramClassName, completion.CreditType,
+				completion.FacilityName, completion.ProgramOwner, formatTime(&completion.EnrolledOnDt), formatTime(&completion.ProgramClassStartDt), formatTime(&completion.CreatedAt)})
+		})
+	})
+}
+
+// exportTable streams the rows produced by export to the response as a CSV or XLSX attachment
+// and writes an audit log entry for the export. Once rows have been flushed to the client the
+// status can no longer change, so a failure part way through can only be logged
+func (srv *Server) exportTable(w http.ResponseWriter, r *http.Request, log sLog, name string, header []string, export func(writeRow func([]string) error) error) error {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	log.add("export", name)
+	log.add("format", format)
+	var exporter tableExporter
+	switch format {
+	case "csv":
+		exporter = newCSVExporter(w)
+	case "xlsx":
+		exporter = newXLSXExporter(w)
+	default:
+		return newBadRequestServiceError(errors.New("invalid export format"), "format must be csv or xlsx")
+	}
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", exporter.contentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	rows := 0
+	err := exporter.writeRow(header)
+	if err == nil {
+		err = export(func(row []string) error {
+			rows++
+			return exporter.writeRow(row)
+		})
+	}
+	if err == nil {
+		err = exporter.close()
+	}
+	log.add("rows", rows)
+	if err != nil {
+		if !exporter.flushed() {
+			w.Header().Del("Content-Disposition")
+			return newDatabaseServiceError(err)
+		}
+		log.error("export failed after rows were sent: ", err)
+		return nil
+	}
+	// exports are GET requests, which aren't audited by default
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	log.addAuditFields(claims, r)
+	log.auditDetails("export_" + name)
+	log.adminAudit()
+	return nil
+}
+
+type tableExporter interface {
+	contentType() string
+	writeRow(row []string) error
+	// reports whether any part of the export has been written to the client
+	flushed() bool
+	close() error
+}
+
+// the number of rows buffered before they're flushed to the client
+const csvExportFlushRows = 500
+
+type csvExporter struct {
+	w       http.ResponseWriter
+	writer  *csv.Writer
+	rows    int
+	flushes int
+}
+
+func newCSVExporter(w http.ResponseWriter) *csvExporter {
+	return &csvExporter{w: w, writer: csv.NewWriter(w)}
+}
+
+func (exp *csvExporter) contentType() string { return "text/csv" }
+
+func (exp *csvExporter) writeRow(row []string) error {
+	if err := exp.writer.Write(row); err != nil {
+		return err
+	}
+	exp.rows++
+	if exp.rows%csvExportFlushRows == 0 {
+		exp.flush()
+	}
+	return exp.writer.Error()
+}
+
+func (exp *csvExporter) flush() {
+	exp.writer.Flush()
+	exp.flushes++
+	if flusher, ok := exp.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (exp *csvExporter) flushed() bool { return exp.flushes > 0 }
+
+func (exp *csvExporter) close() error {
+	exp.flush()
+	return exp.writer.Error()
+}
+
+// excelize's stream writer spills rows to a temporary file once it passes its memory
+// limit, the workbook is only written to the client when the export is closed
+type xlsxExporter struct {
+	w       http.ResponseWriter
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	rows    int
+	written bool
+}
+
+func newXLSXExporter(w http.ResponseWriter) *xlsxExporter {
+	return &xlsxExporter{w: w, file: excelize.NewFile()}
+}
+
+func (exp *xlsxExporter) contentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (exp *xlsxExporter) writeRow(row []string) error {
+	if exp.stream == nil {
+		stream, err := exp.file.NewStreamWriter("Sheet1")
+		if err != nil {
+			return err
+		}
+		exp.stream = stream
+	}
+	exp.rows++
+	cell, err := excelize.CoordinatesToCellName(1, exp.rows)
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(row))
+	for idx, value := range row {
+		values[idx] = value
+	}
+	return exp.stream.SetRow(cell, values)
+}
+
+func (exp *xlsxExporter) flushed() bool { return exp.written }
+
+func (exp *xlsxExporter) close() error {
+	defer exp.file.Close()
+	if exp.stream != nil {
+		if err := exp.stream.Flush(); err != nil {
+			return err
+		}
+	}
+	exp.written = true
+	_, err := exp.file.WriteTo(exp.w)
+	return err
+}
+
+func formatUint(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func formatTime(value *time.Time) string {
+	if value == nil || value.IsZero() {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
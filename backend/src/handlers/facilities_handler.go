@@ -9,12 +9,12 @@ import (
 
 func (srv *Server) registerFacilitiesRoutes() []routeDef {
 	return []routeDef{
-		newAdminRoute("GET /api/facilities", srv.handleIndexFacilities),
-		newAdminRoute("GET /api/facilities/{id}", srv.handleShowFacility),
-		newSystemAdminRoute("POST /api/facilities", srv.handleCreateFacility),
-		newSystemAdminRoute("DELETE /api/facilities/{id}", srv.handleDeleteFacility),
-		newSystemAdminRoute("PATCH /api/facilities/{id}", srv.handleUpdateFacility),
-		newDeptAdminRoute("PUT /api/admin/facility-context/{id}", srv.handleChangeAdminFacility),
+		permissionRoute("GET /api/facilities", srv.handleIndexFacilities, models.FacilitiesRead),
+		permissionRoute("GET /api/facilities/{id}", srv.handleShowFacility, models.FacilitiesRead),
+		permissionRoute("POST /api/facilities", srv.handleCreateFacility, models.FacilitiesWrite),
+		permissionRoute("DELETE /api/facilities/{id}", srv.handleDeleteFacility, models.FacilitiesWrite),
+		permissionRoute("PATCH /api/facilities/{id}", srv.handleUpdateFacility, models.FacilitiesWrite),
+		permissionRoute("PUT /api/admin/facility-context/{id}", srv.handleChangeAdminFacility, models.FacilitiesSwitch),
 	}
 }
 
@@ -80,7 +80,7 @@ func (srv *Server) handleCreateFacility(w http.ResponseWriter, r *http.Request,
 }
 
 func (srv *Server) handleUpdateFacility(w http.ResponseWriter, r *http.Request, log sLog) error {
-	if !userIsSystemAdmin(r) {
+	if !userCan(r, models.FacilitiesWrite) {
 		return newUnauthorizedServiceError()
 	}
 	id, err := strconv.Atoi(r.PathValue("id"))
@@ -105,7 +105,7 @@ func (srv *Server) handleUpdateFacility(w http.ResponseWriter, r *http.Request,
 * DELETE: /api/facility/{id}
  */
 func (srv *Server) handleDeleteFacility(w http.ResponseWriter, r *http.Request, log sLog) error {
-	if !userIsSystemAdmin(r) {
+	if !userCan(r, models.FacilitiesWrite) {
 		return newUnauthorizedServiceError()
 	}
 	id, err := strconv.Atoi(r.PathValue("id"))
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (srv *Server) registerFacilityClosureRoutes() []routeDef {
+	axx := models.ProgramAccess
+	return []routeDef{
+		permissionFeatureRoute("GET /api/facility-closures", srv.handleIndexFacilityClosures, models.FacilitiesRead, axx),
+		permissionFeatureRoute("POST /api/facility-closures", srv.handleCreateFacilityClosure, models.FacilitiesClosuresWrite, axx),
+		permissionFeatureRoute("DELETE /api/facility-closures/{id}", srv.handleDeleteFacilityClosure, models.FacilitiesClosuresWrite, axx),
+	}
+}
+
+func (srv *Server) handleIndexFacilityClosures(w http.ResponseWriter, r *http.Request, log sLog) error {
+	args := srv.getQueryContext(r)
+	closures, err := srv.Db.GetFacilityClosures(&args)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, closures, args.IntoMeta())
+}
+
+/**
+* POST: /api/facility-closures
+* closes the admin's facility from start_dt through end_dt (YYYY-MM-DD), cancelling every class session
+* during the closure, or only the sessions in the given rooms
+**/
+func (srv *Server) handleCreateFacilityClosure(w http.ResponseWriter, r *http.Request, log sLog) error {
+	var form struct {
+		StartDt string   `json:"start_dt"`
+		EndDt   string   `json:"end_dt"`
+		Reason  string   `json:"reason"`
+		Rooms   []string `json:"rooms"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	startDt, err := time.Parse("2006-01-02", form.StartDt)
+	if err != nil {
+		return newBadRequestServiceError(err, "start_dt must be a date in the format YYYY-MM-DD")
+	}
+	endDt, err := time.Parse("2006-01-02", form.EndDt)
+	if err != nil {
+		return newBadRequestServiceError(err, "end_dt must be a date in the format YYYY-MM-DD")
+	}
+	if endDt.Before(startDt) {
+		return newBadRequestServiceError(errors.New("end date before start date"), "end_dt cannot be before start_dt")
+	}
+	args := srv.getQueryContext(r)
+	closure := models.FacilityClosure{
+		FacilityID:   args.FacilityID,
+		StartDt:      startDt,
+		EndDt:        endDt,
+		Reason:       form.Reason,
+		Rooms:        form.Rooms,
+		CreateUserID: args.UserID,
+	}
+	if err := srv.Db.CreateFacilityClosure(&args, &closure); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("facility_closure_id", closure.ID)
+	log.add("cancelled_sessions", closure.CancelledSessions)
+	log.auditDetails("facility_closure_created")
+	return writeJsonResponse(w, http.StatusCreated, closure)
+}
+
+// DELETE: /api/facility-closures/{id} restores the sessions cancelled by the closure
+func (srv *Server) handleDeleteFacilityClosure(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "facility closure ID")
+	}
+	log.add("facility_closure_id", id)
+	args := srv.getQueryContext(r)
+	if err := srv.Db.DeleteFacilityClosure(&args, id); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.auditDetails("facility_closure_removed")
+	return writeJsonResponse(w, http.StatusOK, "Facility closure removed successfully")
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"UnlockEdv2/src/database"
 	"UnlockEdv2/src/models"
 	"errors"
 	"net/http"
@@ -9,16 +8,14 @@ import (
 
 func (srv *Server) registerFeatureFlagRoutes() []routeDef {
 	return []routeDef{
-		newAdminRoute("PUT /api/auth/features/{feature}", srv.handleToggleFeatureFlag),
-		validatedAdminRoute("POST /api/auth/demo-seed", srv.handleRunDemoSeed, func(db *database.DB, r *http.Request) bool {
-			return userIsSystemAdmin(r)
-		}),
+		permissionRoute("PUT /api/auth/features/{feature}", srv.handleToggleFeatureFlag, models.FeatureFlagsManage),
+		permissionRoute("POST /api/auth/demo-seed", srv.handleRunDemoSeed, models.SystemManage),
 	}
 }
 
 func (srv *Server) handleToggleFeatureFlag(w http.ResponseWriter, r *http.Request, log sLog) error {
 	user := r.Context().Value(ClaimsKey).(*Claims)
-	if user.Role != models.SystemAdmin {
+	if !user.can(models.FeatureFlagsManage) {
 		return newUnauthorizedServiceError()
 	}
 	feature := r.PathValue("feature")
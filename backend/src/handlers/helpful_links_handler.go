@@ -15,12 +15,12 @@ import (
 func (srv *Server) registerLeftMenuRoutes() []routeDef {
 	return []routeDef{
 		newRoute("GET /api/helpful-links", srv.handleGetHelpfulLinks),
-		newAdminRoute("PUT /api/helpful-links", srv.handleAddHelpfulLink),
-		newAdminRoute("PATCH /api/helpful-links/{id}/edit", srv.handleEditLink),
-		newAdminRoute("PUT /api/helpful-links/toggle/{id}", srv.handleToggleVisibilityStatus),
-		newAdminRoute("DELETE /api/helpful-links/{id}", srv.handleDeleteLink),
+		permissionRoute("PUT /api/helpful-links", srv.handleAddHelpfulLink, models.ContentWrite),
+		permissionRoute("PATCH /api/helpful-links/{id}/edit", srv.handleEditLink, models.ContentWrite),
+		permissionRoute("PUT /api/helpful-links/toggle/{id}", srv.handleToggleVisibilityStatus, models.ContentWrite),
+		permissionRoute("DELETE /api/helpful-links/{id}", srv.handleDeleteLink, models.ContentWrite),
 		newRoute("PUT /api/helpful-links/activity/{id}", srv.handleAddUserActivity),
-		newAdminRoute("PUT /api/helpful-links/sort", srv.changeSortOrder),
+		permissionRoute("PUT /api/helpful-links/sort", srv.changeSortOrder, models.ContentWrite),
 		newRoute("PUT /api/helpful-links/favorite/{id}", srv.handleFavoriteLink),
 	}
 }
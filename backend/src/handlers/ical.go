@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/teambition/rrule-go"
+)
+
+/*
+iCalendar (RFC 5545) rendering of class schedules. Recurrence rules are stored and expanded in UTC,
+so the series are written in UTC as well: a series in local time would keep its wall clock time across
+daylight saving changes while the app keeps the UTC time, and the EXDATEs and RECURRENCE-IDs would no
+longer match a session. Single sessions are written in the facility's local time with a VTIMEZONE.
+Cancelled sessions become EXDATEs and rescheduled sessions are written as RECURRENCE-ID overrides
+of the series, so calendar clients show the moved session in place of the original
+*/
+
+const (
+	icalLocalFormat = "20060102T150405"
+	icalUTCFormat   = "20060102T150405Z"
+	// lines longer than this many octets are folded
+	icalLineLength = 75
+)
+
+var icalWeekdays = []rrule.Weekday{rrule.MO, rrule.TU, rrule.WE, rrule.TH, rrule.FR, rrule.SA, rrule.SU}
+
+type icalWriter struct {
+	sb strings.Builder
+}
+
+// line writes a content line, folding it so no line is longer than 75 octets
+func (iw *icalWriter) line(name, value string) {
+	content := name + ":" + value
+	limit := icalLineLength
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		iw.sb.WriteString(content[:cut])
+		iw.sb.WriteString("\r\n ")
+		content = content[cut:]
+		limit = icalLineLength - 1
+	}
+	iw.sb.WriteString(content)
+	iw.sb.WriteString("\r\n")
+}
+
+func (iw *icalWriter) String() string { return iw.sb.String() }
+
+func icalText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+func icalDuration(duration time.Duration) string {
+	if duration <= 0 {
+		return "PT0S"
+	}
+	var sb strings.Builder
+	sb.WriteString("PT")
+	if hours := duration / time.Hour; hours > 0 {
+		fmt.Fprintf(&sb, "%dH", hours)
+	}
+	if minutes := duration % time.Hour / time.Minute; minutes > 0 {
+		fmt.Fprintf(&sb, "%dM", minutes)
+	}
+	if seconds := duration % time.Minute / time.Second; seconds > 0 {
+		fmt.Fprintf(&sb, "%dS", seconds)
+	}
+	return sb.String()
+}
+
+func icalOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	if seconds := offset % 60; seconds != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, offset/3600, offset%3600/60, seconds)
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// calendarFeed renders the events of a feed, loc is the timezone of the facility
+type calendarFeed struct {
+	name   string
+	loc    *time.Location
+	now    time.Time
+	events []models.ProgramClassEvent
+	log    sLog
+}
+
+func (feed *calendarFeed) localTime(t time.Time) string {
+	return t.In(feed.loc).Format(icalLocalFormat)
+}
+
+func (feed *calendarFeed) tzid() string {
+	return "TZID=" + feed.loc.String()
+}
+
+func (feed *calendarFeed) render() string {
+	iw := &icalWriter{}
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//UnlockEd//Class Schedules//EN")
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.line("METHOD", "PUBLISH")
+	iw.line("X-WR-CALNAME", icalText(feed.name))
+	iw.line("X-WR-TIMEZONE", feed.loc.String())
+	series := make([]*rrule.ROption, len(feed.events))
+	earliest := feed.now
+	for idx := range feed.events {
+		rule, err := feed.events[idx].GetRRule()
+		if err != nil {
+			feed.log.warn("skipping event with invalid recurrence rule in calendar feed: ", err)
+			continue
+		}
+		series[idx] = &rule.OrigOptions
+		if series[idx].Dtstart.Before(earliest) {
+			earliest = series[idx].Dtstart
+		}
+	}
+	writeVTimezone(iw, feed.loc, earliest, feed.now)
+	for idx := range feed.events {
+		if series[idx] != nil {
+			feed.writeEvent(iw, &feed.events[idx], series[idx])
+		}
+	}
+	iw.line("END", "VCALENDAR")
+	return iw.String()
+}
+
+type rescheduledSession struct {
+	original time.Time
+	override *models.ProgramClassEventOverride
+}
+
+func (feed *calendarFeed) writeEvent(iw *icalWriter, event *models.ProgramClassEvent, options *rrule.ROption) {
+	duration, err := time.ParseDuration(event.Duration)
+	if err != nil {
+		duration = time.Hour
+	}
+	dtstart := options.Dtstart.In(time.UTC)
+	utcOptions := *options
+	utcOptions.Dtstart = time.Time{}
+
+	uid := fmt.Sprintf("class-event-%d@unlocked", event.ID)
+	cancelled, rescheduled, added := feed.sortOverrides(event.Overrides)
+	feed.beginEvent(iw, uid, event, duration, event.Room)
+	iw.line("DTSTART", dtstart.Format(icalUTCFormat))
+	iw.line("RRULE", utcOptions.RRuleString())
+	for _, date := range cancelled {
+		iw.line("EXDATE", date.UTC().Format(icalUTCFormat))
+	}
+	iw.line("END", "VEVENT")
+
+	for _, session := range rescheduled {
+		feed.writeOverride(iw, uid, event, session.override, duration)
+		iw.line("RECURRENCE-ID", session.original.UTC().Format(icalUTCFormat))
+		iw.line("END", "VEVENT")
+	}
+	for _, override := range added {
+		feed.writeOverride(iw, fmt.Sprintf("class-event-%d-override-%d@unlocked", event.ID, override.ID), event, override, duration)
+		iw.line("END", "VEVENT")
+	}
+}
+
+func (feed *calendarFeed) beginEvent(iw *icalWriter, uid string, event *models.ProgramClassEvent, duration time.Duration, room string) {
+	iw.line("BEGIN", "VEVENT")
+	iw.line("UID", uid)
+	iw.line("DTSTAMP", feed.now.UTC().Format(icalUTCFormat))
+	if event.Class != nil {
+		iw.line("SUMMARY", icalText(event.Class.Name))
+		description := "Instructor: " + event.Class.InstructorName
+		if event.Class.Program != nil {
+			description = event.Class.Program.Name + "\n" + description
+		}
+		iw.line("DESCRIPTION", icalText(description))
+	}
+	if room != "" {
+		iw.line("LOCATION", icalText(room))
+	}
+	iw.line("DURATION", icalDuration(duration))
+}
+
+func (feed *calendarFeed) writeOverride(iw *icalWriter, uid string, event *models.ProgramClassEvent, override *models.ProgramClassEventOverride, duration time.Duration) {
+	if overrideDuration, err := time.ParseDuration(override.Duration); err == nil {
+		duration = overrideDuration
+	}
+	room := event.Room
+	if override.Room != "" {
+		room = override.Room
+	}
+	start, _ := overrideStart(override)
+	feed.beginEvent(iw, uid, event, duration, room)
+	iw.line("DTSTART;"+feed.tzid(), feed.localTime(start))
+}
+
+func overrideStart(override *models.ProgramClassEventOverride) (time.Time, error) {
+	options, err := rrule.StrToROption(override.OverrideRrule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return options.Dtstart.In(time.UTC), nil
+}
+
+/*
+sortOverrides splits the overrides of an event into the cancelled sessions, the rescheduled sessions and the sessions
+that were added without replacing one. A reschedule is saved as a cancelled override with the reason "rescheduled"
+immediately followed by the override at the new time, so they are paired up in the order they were created
+*/
+func (feed *calendarFeed) sortOverrides(overrides []models.ProgramClassEventOverride) ([]time.Time, []rescheduledSession, []*models.ProgramClassEventOverride) {
+	sorted := make([]*models.ProgramClassEventOverride, 0, len(overrides))
+	for idx := range overrides {
+		if _, err := overrideStart(&overrides[idx]); err != nil {
+			feed.log.warn("skipping event override with invalid recurrence rule in calendar feed: ", err)
+			continue
+		}
+		sorted = append(sorted, &overrides[idx])
+	}
+	slices.SortFunc(sorted, func(a, b *models.ProgramClassEventOverride) int {
+		return int(a.ID) - int(b.ID)
+	})
+	var (
+		cancelled   []time.Time
+		rescheduled []rescheduledSession
+		added       []*models.ProgramClassEventOverride
+		pending     []time.Time
+	)
+	for _, override := range sorted {
+		start, _ := overrideStart(override)
+		switch {
+		case override.IsCancelled && override.Reason == "rescheduled":
+			pending = append(pending, start)
+		case override.IsCancelled:
+			cancelled = append(cancelled, start)
+		case len(pending) > 0:
+			rescheduled = append(rescheduled, rescheduledSession{original: pending[len(pending)-1], override: override})
+			pending = pending[:len(pending)-1]
+		default:
+			added = append(added, override)
+		}
+	}
+	// a reschedule missing its new session is only a cancellation
+	cancelled = append(cancelled, pending...)
+	// rescheduled and added sessions can be cancelled as well, e.g. by a facility closure
+	rescheduled = slices.DeleteFunc(rescheduled, func(session rescheduledSession) bool {
+		start, _ := overrideStart(session.override)
+		if slices.ContainsFunc(cancelled, start.Equal) {
+			cancelled = append(cancelled, session.original)
+			return true
+		}
+		return false
+	})
+	added = slices.DeleteFunc(added, func(override *models.ProgramClassEventOverride) bool {
+		start, _ := overrideStart(override)
+		return slices.ContainsFunc(cancelled, start.Equal)
+	})
+	slices.SortFunc(cancelled, func(a, b time.Time) int { return a.Compare(b) })
+	return cancelled, rescheduled, added
+}
+
+type tzTransition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+	isDST      bool
+}
+
+// transitions finds every change of UTC offset in loc between from and to
+func transitions(loc *time.Location, from, to time.Time) []tzTransition {
+	found := []tzTransition{}
+	const step = 24 * time.Hour
+	for lo := from; lo.Before(to); lo = lo.Add(step) {
+		hi := lo.Add(step)
+		_, offsetFrom := lo.In(loc).Zone()
+		if _, offsetTo := hi.In(loc).Zone(); offsetFrom == offsetTo {
+			continue
+		}
+		// narrow it down to the second the offset changes
+		low, high := lo, hi
+		for high.Sub(low) > time.Second {
+			mid := low.Add(high.Sub(low) / 2)
+			if _, offset := mid.In(loc).Zone(); offset == offsetFrom {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+		name, offsetTo := high.In(loc).Zone()
+		found = append(found, tzTransition{at: high, offsetFrom: offsetFrom, offsetTo: offsetTo, name: name, isDST: high.In(loc).IsDST()})
+	}
+	return found
+}
+
+// the wall clock time a transition happens at, before the clocks change
+func (tr *tzTransition) onset() time.Time {
+	return tr.at.Add(time.Duration(tr.offsetFrom) * time.Second).UTC()
+}
+
+// the yearly rule a transition follows, e.g. the second sunday in march is FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+func (tr *tzTransition) yearlyRule() string {
+	onset := tr.onset()
+	daysInMonth := time.Date(onset.Year(), onset.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	ordinal := (onset.Day()-1)/7 + 1
+	if onset.Day()+7 > daysInMonth {
+		ordinal = -1
+	}
+	weekday := icalWeekdays[(int(onset.Weekday())+6)%7]
+	return fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", onset.Month(), ordinal, weekday.String())
+}
+
+/*
+writeVTimezone describes the offsets of loc from the year of the earliest event on. The transitions of the
+coming year repeat yearly when they follow the same rule as the year before, so the timezone stays correct
+for series without an end date, otherwise each transition is written out
+*/
+func writeVTimezone(iw *icalWriter, loc *time.Location, earliest, now time.Time) {
+	from := time.Date(earliest.UTC().Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	lastYear := now.UTC().Year() + 1
+	found := transitions(loc, from, time.Date(lastYear+1, time.January, 1, 0, 0, 0, 0, time.UTC))
+	iw.line("BEGIN", "VTIMEZONE")
+	iw.line("TZID", loc.String())
+	name, offset := from.In(loc).Zone()
+	writeObservance(iw, &tzTransition{at: from, offsetFrom: offset, offsetTo: offset, name: name, isDST: from.In(loc).IsDST()}, "")
+	for idx := range found {
+		rule := ""
+		if found[idx].at.Year() == lastYear {
+			for prev := idx - 1; prev >= 0; prev-- {
+				if found[prev].at.Year() < lastYear-1 {
+					break
+				}
+				if found[prev].at.Year() == lastYear-1 && found[prev].isDST == found[idx].isDST && found[prev].offsetTo == found[idx].offsetTo &&
+					found[prev].yearlyRule() == found[idx].yearlyRule() && found[prev].onset().Format("150405") == found[idx].onset().Format("150405") {
+					rule = found[idx].yearlyRule()
+					break
+				}
+			}
+		}
+		writeObservance(iw, &found[idx], rule)
+	}
+	iw.line("END", "VTIMEZONE")
+}
+
+func writeObservance(iw *icalWriter, tr *tzTransition, rule string) {
+	observance := "STANDARD"
+	if tr.isDST {
+		observance = "DAYLIGHT"
+	}
+	iw.line("BEGIN", observance)
+	iw.line("DTSTART", tr.onset().Format(icalLocalFormat))
+	iw.line("TZOFFSETFROM", icalOffset(tr.offsetFrom))
+	iw.line("TZOFFSETTO", icalOffset(tr.offsetTo))
+	if tr.name != "" {
+		iw.line("TZNAME", icalText(tr.name))
+	}
+	if rule != "" {
+		iw.line("RRULE", rule)
+	}
+	iw.line("END", observance)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"UnlockEdv2/src/tasks"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func (srv *Server) registerJobsRoutes() []routeDef {
+	return []routeDef{
+		permissionRoute("GET /api/jobs", srv.handleIndexCronJobs, models.JobsManage),
+		permissionRoute("GET /api/jobs/tasks", srv.handleIndexRunnableTasks, models.JobsManage),
+		permissionRoute("PATCH /api/jobs/tasks/{id}", srv.handleUpdateRunnableTask, models.JobsManage),
+		permissionRoute("POST /api/jobs/tasks/{id}/run", srv.handleRunTaskNow, models.JobsManage),
+		permissionRoute("GET /api/jobs/runs", srv.handleIndexJobRuns, models.JobsManage),
+		permissionRoute("GET /api/jobs/runs/{id}", srv.handleShowJobRun, models.JobsManage),
+		permissionFeatureRoute("GET /api/provider-platforms/{id}/job-runs", srv.handleIndexProviderJobRuns, models.ProvidersManage, models.ProviderAccess),
+		permissionFeatureRoute("GET /api/provider-platforms/{id}/sync-cursors", srv.handleIndexProviderSyncCursors, models.ProvidersManage, models.ProviderAccess),
+		permissionFeatureRoute("POST /api/provider-platforms/{id}/resync", srv.handleProviderFullResync, models.ProvidersManage, models.ProviderAccess),
+	}
+}
+
+/****
+ * @Query Params:
+ * ?provider_platform_id=: filter to a single provider platform
+ * ?open_content_provider_id=: filter to a single open content provider
+ * ?job=: "get_courses", "get_milestones", "get_activity", "scrape_kiwix", etc
+ * ?status=: "running", "succeeded", "failed"
+ ****/
+func (srv *Server) handleIndexJobRuns(w http.ResponseWriter, r *http.Request, log sLog) error {
+	args := srv.getQueryContext(r)
+	jobName := r.URL.Query().Get("job")
+	status := models.JobStatus(r.URL.Query().Get("status"))
+	runs, err := srv.Db.GetJobRuns(&args, args.MaybeID("provider_platform_id"), args.MaybeID("open_content_provider_id"), jobName, status)
+	if err != nil {
+		log.add("job", jobName)
+		log.add("status", status)
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, runs, args.IntoMeta())
+}
+
+func (srv *Server) handleShowJobRun(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "job run ID")
+	}
+	log.add("job_run_id", id)
+	run, err := srv.Db.GetJobRunByID(id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, run)
+}
+
+func (srv *Server) handleIndexProviderJobRuns(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "provider platform ID")
+	}
+	log.add("provider_platform_id", id)
+	args := srv.getQueryContext(r)
+	jobName := r.URL.Query().Get("job")
+	status := models.JobStatus(r.URL.Query().Get("status"))
+	runs, err := srv.Db.GetJobRuns(&args, &id, nil, jobName, status)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, runs, args.IntoMeta())
+}
+
+func (srv *Server) handleIndexProviderSyncCursors(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "provider platform ID")
+	}
+	log.add("provider_platform_id", id)
+	cursors, err := srv.Db.GetProviderSyncCursors(id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, cursors)
+}
+
+type ProviderResyncRequest struct {
+	// courses, milestones and/or activity, all of them when empty
+	Entities []string `json:"entities"`
+}
+
+var resyncEntityJobs = map[string]models.JobType{
+	models.CoursesSyncEntity:    models.GetCoursesJob,
+	models.MilestonesSyncEntity: models.GetMilestonesJob,
+	models.ActivitySyncEntity:   models.GetActivityJob,
+}
+
+/****
+ * Clears the sync cursors of a provider platform so the next sync processes everything
+ * instead of only what has changed, then runs the affected tasks right away
+ ****/
+func (srv *Server) handleProviderFullResync(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "provider platform ID")
+	}
+	log.add("provider_platform_id", id)
+	var form ProviderResyncRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			return newJSONReqBodyServiceError(err)
+		}
+	}
+	jobs := make([]models.JobType, 0, len(resyncEntityJobs))
+	if len(form.Entities) == 0 {
+		jobs = append(jobs, models.AllDefaultProviderJobs...)
+	}
+	for _, entity := range form.Entities {
+		job, ok := resyncEntityJobs[entity]
+		if !ok {
+			return newBadRequestServiceError(errors.New("invalid entity"), "invalid sync entity: "+entity)
+		}
+		jobs = append(jobs, job)
+	}
+	provider, err := srv.Db.GetProviderPlatformByID(id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	removed := int64(0)
+	if slices.Contains(models.FullSyncOnlyProviders, provider.Type) {
+		// every sync of the provider is already a full sync
+		log.add("full_sync_only", true)
+	} else if removed, err = srv.Db.ResetProviderSyncCursors(id, form.Entities); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("cursors_removed", removed)
+	queued := 0
+	if srv.scheduler != nil && provider.State == models.Enabled {
+		queued, err = srv.scheduler.RunProviderTasks(provider.ID, jobs)
+		if err != nil {
+			return newInternalServerServiceError(err, "sync cursors were reset but the tasks could not be run")
+		}
+	}
+	return writeJsonResponse(w, http.StatusAccepted, map[string]any{"cursors_removed": removed, "tasks_queued": queued})
+}
+
+func (srv *Server) handleIndexCronJobs(w http.ResponseWriter, r *http.Request, log sLog) error {
+	jobs, err := srv.Db.GetCronJobs()
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, jobs)
+}
+
+/****
+ * @Query Params:
+ * ?provider_platform_id=: filter to a single provider platform
+ * ?open_content_provider_id=: filter to a single open content provider
+ ****/
+func (srv *Server) handleIndexRunnableTasks(w http.ResponseWriter, r *http.Request, log sLog) error {
+	args := srv.getQueryContext(r)
+	tasks, err := srv.Db.GetRunnableTasks(&args, args.MaybeID("provider_platform_id"), args.MaybeID("open_content_provider_id"))
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, tasks, args.IntoMeta())
+}
+
+type RunnableTaskUpdate struct {
+	Paused   *bool   `json:"paused"`
+	Schedule *string `json:"schedule"`
+}
+
+func (srv *Server) handleUpdateRunnableTask(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "task ID")
+	}
+	log.add("task_id", id)
+	var form RunnableTaskUpdate
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	if form.Schedule != nil {
+		*form.Schedule = strings.TrimSpace(*form.Schedule)
+		if *form.Schedule != "" {
+			if err := models.ValidateCronSchedule(*form.Schedule); err != nil {
+				return newBadRequestServiceError(err, "invalid cron schedule: "+err.Error())
+			}
+		}
+		log.add("schedule", *form.Schedule)
+	}
+	if form.Paused != nil {
+		log.add("paused", *form.Paused)
+	}
+	task, err := srv.Db.UpdateRunnableTask(id, form.Paused, form.Schedule)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	if srv.scheduler != nil {
+		if err := srv.scheduler.RescheduleTask(task.ID); err != nil {
+			return newInternalServerServiceError(err, "task was updated but could not be rescheduled")
+		}
+	}
+	return writeJsonResponse(w, http.StatusOK, *task)
+}
+
+func (srv *Server) handleRunTaskNow(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "task ID")
+	}
+	log.add("task_id", id)
+	if srv.scheduler == nil {
+		return NewServiceError(errors.New("scheduler not initialized"), http.StatusServiceUnavailable, "scheduler is not running")
+	}
+	if _, err := srv.Db.GetRunnableTaskByID(id); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	if err := srv.scheduler.RunTaskNow(uint(id)); err != nil {
+		if errors.Is(err, tasks.ErrTaskAlreadyRunning) {
+			return NewServiceError(err, http.StatusConflict, "task is already running")
+		}
+		return newInternalServerServiceError(err, "unable to run task")
+	}
+	return writeJsonResponse(w, http.StatusAccepted, "task queued successfully")
+}
+
+// registers (or removes) the scheduled tasks of a provider platform based on its current state
+func (srv *Server) syncProviderTasks(provider *models.ProviderPlatform, log sLog) {
+	if srv.scheduler == nil {
+		return
+	}
+	if err := srv.scheduler.SyncProviderTasks(provider); err != nil {
+		log.errorf("unable to sync scheduled tasks for provider platform %d: %v", provider.ID, err)
+	}
+}
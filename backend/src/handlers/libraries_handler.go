@@ -24,7 +24,7 @@ func (srv *Server) registerLibraryRoutes() []routeDef {
 		featureRoute("GET /api/libraries/{id}", srv.handleGetLibrary, axx),
 		featureRoute("PUT /api/libraries/{id}/favorite", srv.handleToggleFavoriteLibrary, axx),
 		/* admin */
-		adminFeatureRoute("PUT /api/libraries/{id}/toggle", srv.handleToggleLibraryVisibility, axx),
+		permissionFeatureRoute("PUT /api/libraries/{id}/toggle", srv.handleToggleLibraryVisibility, models.ContentWrite, axx),
 	}
 }
 
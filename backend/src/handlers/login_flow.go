@@ -56,6 +56,10 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request, log sLog) e
 	if err != nil {
 		return newUnauthorizedServiceError()
 	}
+	if user.DeactivatedAt != nil {
+		log.infof("Deactivated user %d attempted to log in", user.ID)
+		return newForbiddenServiceError(errors.New("user account is deactivated"), "Account deactivated. Contact your facility administrator.")
+	}
 	lockedStatus, err := s.Db.IsAccountLocked(user.ID)
 	if err != nil {
 		return newDatabaseServiceError(err)
@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"regexp"
-	"slices"
 	"strconv"
 	"strings"
 
@@ -29,11 +28,11 @@ func (srv *Server) applyMiddleware(h HttpFunc, resolver RouteResolver, accessLev
 			srv.handleError(h), accessLevel...), resolver)
 }
 
-func (srv *Server) applyAdminMiddleware(h HttpFunc, resolver RouteResolver, accessLevel ...models.FeatureAccess) http.Handler {
+func (srv *Server) applyPermissionMiddleware(h HttpFunc, resolver RouteResolver, permissions []models.Permission, accessLevel ...models.FeatureAccess) http.Handler {
 	return srv.applyStandardMiddleware(
-		srv.adminMiddleware(
+		srv.permissionMiddleware(
 			srv.checkFeatureAccessMiddleware(
-				srv.handleError(h), accessLevel...)), resolver)
+				srv.handleError(h), accessLevel...), permissions...), resolver)
 }
 
 func (srv *Server) applyStandardMiddleware(next http.Handler, resolver RouteResolver) http.Handler {
@@ -197,8 +196,8 @@ func UserRoleResolver(routeId string) RouteResolver {
 			// it's the user referenced in the path
 			return true
 		}
-		if !slices.Contains(models.AdminRoles, claims.Role) {
-			// if not the specific user and not an admin:
+		if !claims.can(models.UsersRead) {
+			// if not the specific user and not allowed to see other users:
 			return false
 		}
 		user, err := tx.GetUserByID(uint(id))
@@ -206,3 +205,28 @@ func UserRoleResolver(routeId string) RouteResolver {
 		return err == nil && user.FacilityID == claims.FacilityID
 	}
 }
+
+/*
+ClassInstructorResolver limits instructors to the classes they are assigned to, and to the events of those classes.
+Everyone else is resolved by the facility of the class
+*/
+func ClassInstructorResolver(param string) RouteResolver {
+	facilityResolver := FacilityAdminResolver("program_classes", param)
+	return func(tx *database.DB, r *http.Request) bool {
+		claims := r.Context().Value(ClaimsKey).(*Claims)
+		if !claims.isInstructor() {
+			return facilityResolver(tx, r)
+		}
+		classID, err := strconv.Atoi(r.PathValue(param))
+		if err != nil || !tx.IsClassInstructor(r.Context(), classID, claims.UserID) {
+			return false
+		}
+		if eventID := r.PathValue("event_id"); eventID != "" {
+			var count int64
+			return tx.WithContext(r.Context()).Model(&models.ProgramClassEvent{}).
+				Where("id = ? AND class_id = ?", eventID, classID).
+				Count(&count).Error == nil && count > 0
+		}
+		return true
+	}
+}
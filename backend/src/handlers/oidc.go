@@ -11,9 +11,9 @@ import (
 func (srv *Server) registerOidcRoutes() []routeDef {
 	axx := models.ProviderAccess
 	return []routeDef{
-		adminFeatureRoute("GET /api/oidc/clients", srv.handleGetAllClients, axx),
-		adminFeatureRoute("POST /api/oidc/clients", srv.handleRegisterClient, axx),
-		adminFeatureRoute("GET /api/oidc/clients/{id}", srv.handleGetOidcClient, axx),
+		permissionFeatureRoute("GET /api/oidc/clients", srv.handleGetAllClients, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/oidc/clients", srv.handleRegisterClient, models.ProvidersManage, axx),
+		permissionFeatureRoute("GET /api/oidc/clients/{id}", srv.handleGetOidcClient, models.ProvidersManage, axx),
 	}
 }
 
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"UnlockEdv2/src/models"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -14,7 +15,7 @@ import (
 )
 
 func (srv *Server) registerOryRoutes() []routeDef {
-	return []routeDef{newSystemAdminRoute("DELETE /api/identities/sync", srv.handleDeleteAllKratosIdentities)}
+	return []routeDef{permissionRoute("DELETE /api/identities/sync", srv.handleDeleteAllKratosIdentities, models.SystemManage)}
 }
 
 func (srv *Server) handleDeleteAllKratosIdentities(w http.ResponseWriter, r *http.Request, log sLog) error {
@@ -60,6 +61,35 @@ func (srv *Server) deleteIdentityInKratos(ctx context.Context, kratosId *string)
 	return nil
 }
 
+// setIdentityStateInKratos activates or deactivates the login of the identity, deactivating it also ends its sessions
+func (srv *Server) setIdentityStateInKratos(ctx context.Context, kratosID string, active bool) error {
+	state := "inactive"
+	if active {
+		state = "active"
+	}
+	patch := client.NewJsonPatch("replace", "/state")
+	patch.SetValue(state)
+	_, resp, err := srv.OryClient.IdentityAPI.PatchIdentity(ctx, kratosID).JsonPatch([]client.JsonPatch{*patch}).Execute()
+	if err != nil {
+		log.WithField("identity", kratosID).Errorf("unable to set identity state to %s: %v", state, err)
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.WithField("identity", kratosID).Errorf("unable to set identity state to %s: %s", state, resp.Status)
+		return errors.New("unable to update identity state in Ory instance")
+	}
+	if active {
+		return nil
+	}
+	// kratos responds with a 404 when the identity has no sessions
+	resp, err = srv.OryClient.IdentityAPI.DeleteIdentitySessions(ctx, kratosID).Execute()
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		log.WithField("identity", kratosID).Errorf("unable to delete identity sessions: %v", err)
+		return err
+	}
+	return nil
+}
+
 func (srv *Server) handleFindKratosIdentities(ctx context.Context) ([]client.Identity, error) {
 	identities, resp, err := srv.OryClient.IdentityAPI.ListIdentities(ctx).Execute()
 	if err != nil {
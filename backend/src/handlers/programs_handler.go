@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 )
 
@@ -17,13 +18,15 @@ func (srv *Server) registerProgramsRoutes() []routeDef {
 		featureRoute("GET /api/programs/{id}", srv.handleShowProgram, axx),
 		featureRoute("PUT /api/programs/{id}/save", srv.handleFavoriteProgram, axx),
 		/* admin */
-		adminFeatureRoute("GET /api/programs/detailed-list", srv.handleIndexProgramsOverviewTable, axx),
-		adminFeatureRoute("GET /api/programs/stats", srv.handleIndexProgramsFacilitiesStats, axx),
-		adminFeatureRoute("GET /api/programs/{id}/history", srv.handleGetProgramHistory, axx),
-		adminFeatureRoute("POST /api/programs", srv.handleCreateProgram, axx),
-		adminFeatureRoute("DELETE /api/programs/{id}", srv.handleDeleteProgram, axx),
-		adminFeatureRoute("PATCH /api/programs/{id}/status", srv.handleUpdateProgramStatus, axx),
-		adminFeatureRoute("PATCH /api/programs/{id}", srv.handleUpdateProgram, axx),
+		permissionFeatureRoute("GET /api/programs/detailed-list", srv.handleIndexProgramsOverviewTable, models.ProgramsRead, axx),
+		permissionFeatureRoute("GET /api/programs/stats", srv.handleIndexProgramsFacilitiesStats, models.ProgramsRead, axx),
+		permissionFeatureRoute("GET /api/programs/{id}/history", srv.handleGetProgramHistory, models.ProgramsRead, axx),
+		permissionFeatureRoute("POST /api/programs", srv.handleCreateProgram, models.ProgramsWrite, axx),
+		permissionFeatureRoute("DELETE /api/programs/{id}", srv.handleDeleteProgram, models.ProgramsWrite, axx),
+		permissionFeatureRoute("PATCH /api/programs/{id}/status", srv.handleUpdateProgramStatus, models.ProgramsWrite, axx),
+		permissionFeatureRoute("PATCH /api/programs/{id}", srv.handleUpdateProgram, models.ProgramsWrite, axx),
+		permissionFeatureRoute("GET /api/programs/{id}/prerequisites", srv.handleGetProgramPrerequisites, models.ProgramsRead, axx),
+		permissionFeatureRoute("PUT /api/programs/{id}/prerequisites", srv.handleUpdateProgramPrerequisites, models.ProgramsWrite, axx),
 	}
 }
 
@@ -190,6 +193,72 @@ func (srv *Server) handleUpdateProgram(w http.ResponseWriter, r *http.Request, l
 	return writeJsonResponse(w, http.StatusOK, updated)
 }
 
+func (srv *Server) handleGetProgramPrerequisites(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "program ID")
+	}
+	log.add("program_id", id)
+	prerequisites, err := srv.Db.GetProgramPrerequisites(r.Context(), id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, prerequisites)
+}
+
+/**
+* PUT: /api/programs/{id}/prerequisites
+* replaces the program's prerequisites: {"prerequisites": [{"prerequisite_type": "completed_program", "required_program_id": 2},
+* {"prerequisite_type": "credit_type", "credit_type": "Education"}]}
+**/
+func (srv *Server) handleUpdateProgramPrerequisites(w http.ResponseWriter, r *http.Request, log sLog) error {
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "program ID")
+	}
+	log.add("program_id", id)
+	form := struct {
+		Prerequisites []models.ProgramPrerequisite `json:"prerequisites"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	if _, err := srv.Db.GetProgramByID(id); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	prerequisites := make([]models.ProgramPrerequisite, 0, len(form.Prerequisites))
+	for _, prerequisite := range form.Prerequisites {
+		rule := models.ProgramPrerequisite{PrerequisiteType: prerequisite.PrerequisiteType, CreateUserID: claims.UserID}
+		switch prerequisite.PrerequisiteType {
+		case models.CompletedProgramPrerequisite:
+			if prerequisite.RequiredProgramID == nil || *prerequisite.RequiredProgramID == uint(id) {
+				return newBadRequestServiceError(errors.New("invalid required program"), "completed_program prerequisites require a required_program_id of another program")
+			}
+			if _, err := srv.Db.GetProgramByID(int(*prerequisite.RequiredProgramID)); err != nil {
+				return newBadRequestServiceError(err, "required program not found")
+			}
+			rule.RequiredProgramID = prerequisite.RequiredProgramID
+		case models.CreditTypePrerequisite:
+			if prerequisite.CreditType == nil || !slices.Contains(validCreditTypes, *prerequisite.CreditType) {
+				return newBadRequestServiceError(errors.New("invalid credit type"), "credit_type prerequisites require a valid credit_type")
+			}
+			rule.CreditType = prerequisite.CreditType
+		default:
+			return newBadRequestServiceError(errors.New("invalid prerequisite type"), "prerequisite_type must be completed_program or credit_type")
+		}
+		prerequisites = append(prerequisites, rule)
+	}
+	updated, err := srv.Db.ReplaceProgramPrerequisites(r.Context(), id, prerequisites)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("prerequisites", len(updated))
+	return writeJsonResponse(w, http.StatusOK, updated)
+}
+
+var validCreditTypes = []models.CreditType{models.Completion, models.Participation, models.EarnedTime, models.Education}
+
 func (srv *Server) handleUpdateProgramStatus(w http.ResponseWriter, r *http.Request, log sLog) error {
 	programUpdate := make(map[string]any)
 	if err := json.NewDecoder(r.Body).Decode(&programUpdate); err != nil {
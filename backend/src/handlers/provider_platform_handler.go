@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"UnlockEdv2/src"
 	"UnlockEdv2/src/models"
 	"context"
 	"encoding/json"
@@ -10,6 +11,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 )
@@ -17,13 +19,15 @@ import (
 func (srv *Server) registerProviderPlatformRoutes() []routeDef {
 	axx := models.ProviderAccess
 	return []routeDef{
-		adminFeatureRoute("GET /api/provider-platforms", srv.handleIndexProviders, axx),
-		adminFeatureRoute("GET /api/provider-platforms/{id}", srv.handleShowProvider, axx),
-		adminFeatureRoute("POST /api/provider-platforms", srv.handleCreateProvider, axx),
-		adminFeatureRoute("GET /api/provider-platforms/callback", srv.handleOAuthProviderCallback, axx),
-		adminFeatureRoute("GET /api/provider-platforms/{id}/refresh", srv.handleOAuthRefreshToken, axx),
-		adminFeatureRoute("PATCH /api/provider-platforms/{id}", srv.handleUpdateProvider, axx),
-		adminFeatureRoute("DELETE /api/provider-platforms/{id}", srv.handleDeleteProvider, axx),
+		permissionFeatureRoute("GET /api/provider-platforms", srv.handleIndexProviders, models.ProvidersManage, axx),
+		permissionFeatureRoute("GET /api/provider-platforms/{id}", srv.handleShowProvider, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/provider-platforms", srv.handleCreateProvider, models.ProvidersManage, axx),
+		permissionFeatureRoute("GET /api/provider-platforms/callback", srv.handleOAuthProviderCallback, models.ProvidersManage, axx),
+		permissionFeatureRoute("GET /api/provider-platforms/{id}/refresh", srv.handleOAuthRefreshToken, models.ProvidersManage, axx),
+		permissionFeatureRoute("PATCH /api/provider-platforms/{id}", srv.handleUpdateProvider, models.ProvidersManage, axx),
+		permissionFeatureRoute("DELETE /api/provider-platforms/{id}", srv.handleDeleteProvider, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/provider-platforms/test", srv.handleTestNewProviderConnection, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/provider-platforms/{id}/test", srv.handleTestProviderConnection, models.ProvidersManage, axx),
 	}
 }
 
@@ -92,6 +96,7 @@ func (srv *Server) handleCreateProvider(w http.ResponseWriter, r *http.Request,
 	if err != nil {
 		return newDatabaseServiceError(err)
 	}
+	srv.syncProviderTasks(&platform, log)
 	return writeJsonResponse(w, http.StatusCreated, map[string]interface{}{
 		"platform": platform,
 	})
@@ -162,6 +167,7 @@ func (srv *Server) handleOAuthProviderCallback(w http.ResponseWriter, r *http.Re
 		}
 		action = "created"
 	}
+	srv.syncProviderTasks(&provider, log)
 	http.Redirect(w, r, fmt.Sprintf(successRedirectUrl, action), http.StatusTemporaryRedirect)
 	return nil
 }
@@ -217,6 +223,7 @@ func (srv *Server) handleUpdateProvider(w http.ResponseWriter, r *http.Request,
 	if err != nil {
 		return newDatabaseServiceError(err)
 	}
+	srv.syncProviderTasks(updated, log)
 	return writeJsonResponse(w, http.StatusOK, map[string]interface{}{
 		"platform": *updated,
 	})
@@ -231,9 +238,57 @@ func (srv *Server) handleDeleteProvider(w http.ResponseWriter, r *http.Request,
 	if err = srv.Db.DeleteProviderPlatform(id); err != nil {
 		return newDatabaseServiceError(err)
 	}
+	if srv.scheduler != nil {
+		if err := srv.scheduler.UnscheduleProviderTasks(uint(id)); err != nil {
+			log.errorf("unable to unschedule tasks for provider platform %d: %v", id, err)
+		}
+	}
 	return writeJsonResponse(w, http.StatusNoContent, "Provider platform deleted successfully")
 }
 
+// the middleware gives up on a connection test after a minute
+const providerConnectionTestTimeout = 90 * time.Second
+
+func (srv *Server) handleTestProviderConnection(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "provider platform ID")
+	}
+	log.add("providerPlatformID", id)
+	service, err := srv.getService(r)
+	if err != nil {
+		return newBadRequestServiceError(err, err.Error())
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), providerConnectionTestTimeout)
+	defer cancel()
+	report, err := service.TestConnection(ctx)
+	if err != nil {
+		return NewServiceError(err, http.StatusBadGateway, "unable to test the connection to the provider platform")
+	}
+	log.add("success", report.Success)
+	return writeJsonResponse(w, http.StatusOK, report)
+}
+
+// tests the settings of a provider platform before it is created
+func (srv *Server) handleTestNewProviderConnection(w http.ResponseWriter, r *http.Request, log sLog) error {
+	var platform models.ProviderPlatform
+	if err := json.NewDecoder(r.Body).Decode(&platform); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	if platform.Type == "" || platform.BaseUrl == "" {
+		return newBadRequestServiceError(errors.New("missing type or base_url"), "type and base_url are required")
+	}
+	log.add("type", platform.Type)
+	ctx, cancel := context.WithTimeout(r.Context(), providerConnectionTestTimeout)
+	defer cancel()
+	report, err := src.TestProviderConnection(ctx, &platform, srv.Client)
+	if err != nil {
+		return NewServiceError(err, http.StatusBadGateway, "unable to test the connection to the provider platform")
+	}
+	log.add("success", report.Success)
+	return writeJsonResponse(w, http.StatusOK, report)
+}
+
 func (srv *Server) getOAuthUrl(platform *models.ProviderPlatform) (string, error) {
 	var (
 		brightspaceConfig = platform.GetOAuth2Config()
@@ -15,9 +15,9 @@ func (srv *Server) registerProviderUserRoutes() []routeDef {
 	// these are not 'actions' routes because they do not directly interact with the middleware
 	axx := models.ProviderAccess
 	return []routeDef{
-		adminValidatedFeatureRoute("POST /api/provider-platforms/{id}/map-user/{user_id}", srv.handleMapProviderUser, axx, FacilityAdminResolver("users", "user_id")),
-		adminFeatureRoute("POST /api/provider-platforms/{id}/users/import", srv.handleImportProviderUsers, axx),
-		adminFeatureRoute("POST /api/provider-platforms/{id}/create-user/{user_id}", srv.handleCreateProviderUserAccount, axx),
+		validatedPermissionFeatureRoute("POST /api/provider-platforms/{id}/map-user/{user_id}", srv.handleMapProviderUser, models.ProvidersManage, axx, FacilityAdminResolver("users", "user_id")),
+		permissionFeatureRoute("POST /api/provider-platforms/{id}/users/import", srv.handleImportProviderUsers, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/provider-platforms/{id}/create-user/{user_id}", srv.handleCreateProviderUserAccount, models.ProvidersManage, axx),
 	}
 }
 
@@ -10,10 +10,10 @@ import (
 func (srv *Server) registerProviderMappingRoutes() []routeDef {
 	axx := models.ProviderAccess
 	return []routeDef{
-		adminFeatureRoute("GET /api/users/{id}/logins", srv.handleGetMappingsForUser, axx),
-		adminFeatureRoute("POST /api/users/{id}/logins", srv.handleCreateProviderUserMapping, axx),
-		adminFeatureRoute("POST /api/provider-platforms/{id}/user-accounts/{user_id}", srv.handleCreateProviderUserAccount, axx),
-		adminFeatureRoute("DELETE /api/users/{userId}/logins/{providerId}", srv.handleDeleteProviderUserMapping, axx),
+		permissionFeatureRoute("GET /api/users/{id}/logins", srv.handleGetMappingsForUser, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/users/{id}/logins", srv.handleCreateProviderUserMapping, models.ProvidersManage, axx),
+		permissionFeatureRoute("POST /api/provider-platforms/{id}/user-accounts/{user_id}", srv.handleCreateProviderUserAccount, models.ProvidersManage, axx),
+		permissionFeatureRoute("DELETE /api/users/{userId}/logins/{providerId}", srv.handleDeleteProviderUserMapping, models.ProvidersManage, axx),
 	}
 }
 
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (srv *Server) registerResidentReleaseRoutes() []routeDef {
+	resolver := FacilityAdminResolver("users", "id")
+	return []routeDef{
+		validatedPermissionRoute("PUT /api/users/{id}/release", srv.handleScheduleResidentRelease, models.UsersWrite, resolver),
+		validatedPermissionRoute("GET /api/users/{id}/transcripts", srv.handleIndexResidentTranscripts, models.UsersRead, UserRoleResolver("id")),
+		validatedPermissionRoute("GET /api/users/{id}/transcripts/{transcript_id}/export", srv.handleExportResidentTranscript, models.ReportsExport, UserRoleResolver("id")),
+	}
+}
+
+/**
+* PUT: /api/users/{id}/release {"release_date": "2025-06-30"}
+* the resident release job releases the resident on that day, a null release_date cancels the release
+**/
+func (srv *Server) handleScheduleResidentRelease(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	log.add("user_id", id)
+	form := struct {
+		ReleaseDate *string `json:"release_date"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		return newJSONReqBodyServiceError(err)
+	}
+	var releaseDate *time.Time
+	if form.ReleaseDate != nil {
+		date, err := time.Parse("2006-01-02", *form.ReleaseDate)
+		if err != nil {
+			return newBadRequestServiceError(err, "release_date must be formatted as YYYY-MM-DD")
+		}
+		releaseDate = &date
+		log.add("release_date", *form.ReleaseDate)
+	}
+	user, err := srv.Db.ScheduleResidentRelease(r.Context(), id, releaseDate)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	if releaseDate == nil {
+		log.auditDetails("resident_release_cancelled")
+	} else {
+		log.auditDetails("resident_release_scheduled")
+	}
+	return writeJsonResponse(w, http.StatusOK, user)
+}
+
+func (srv *Server) handleIndexResidentTranscripts(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	transcripts, err := srv.Db.GetResidentTranscripts(r.Context(), id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, transcripts)
+}
+
+// GET: /api/users/{id}/transcripts/{transcript_id}/export?format=csv|xlsx|pdf, the spreadsheets have one row per class
+func (srv *Server) handleExportResidentTranscript(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	transcriptID, err := strconv.Atoi(r.PathValue("transcript_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "transcript ID")
+	}
+	log.add("user_id", id)
+	log.add("transcript_id", transcriptID)
+	transcript, err := srv.Db.GetResidentTranscript(r.Context(), id, transcriptID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	if strings.EqualFold(r.URL.Query().Get("format"), "pdf") {
+		return writeTranscriptPDF(w, r, log, &transcript.Contents)
+	}
+	header := []string{"doc_id", "name_full", "program_name", "class_name", "facility_name", "enrollment_status", "credit_types", "credit_hours",
+		"attendance_percentage", "enrolled_at", "completed_at", "certificate_code"}
+	contents := &transcript.Contents
+	nameFull := contents.NameLast + ", " + contents.NameFirst
+	return srv.exportTable(w, r, log, "transcript", header, func(writeRow func([]string) error) error {
+		for _, course := range contents.Courses {
+			attendance := ""
+			if course.AttendancePercentage != nil {
+				attendance = strconv.FormatFloat(*course.AttendancePercentage, 'f', 2, 64)
+			}
+			if err := writeRow([]string{contents.DocID, nameFull, course.ProgramName, course.ClassName, course.FacilityName, string(course.EnrollmentStatus),
+				course.CreditTypes, strconv.FormatInt(course.CreditHours, 10), attendance, formatTime(course.EnrolledAt), formatTime(course.CompletedAt), course.CertificateCode}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
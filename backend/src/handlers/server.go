@@ -33,6 +33,7 @@ type Server struct {
 	OryClient   *ory.APIClient
 	Client      *http.Client
 	nats        *nats.Conn
+	js          nats.JetStreamContext
 	dev         bool
 	buckets     map[string]nats.KeyValue
 	features    []models.FeatureAccess
@@ -47,7 +48,7 @@ type Server struct {
 type routeDef struct {
 	routeMethod string
 	handler     HttpFunc
-	admin       bool
+	permissions []models.Permission // the role of the user must be granted all of them, see models.RolePermissions
 	features    []models.FeatureAccess
 	resolver    RouteResolver
 }
@@ -55,9 +56,9 @@ type routeDef struct {
 func (srv *Server) register(routes func() []routeDef) {
 	for _, route := range routes() {
 		h := route.handler
-		if route.admin {
+		if len(route.permissions) > 0 {
 			srv.Mux.Handle(route.routeMethod,
-				srv.applyAdminMiddleware(h, route.resolver, route.features...),
+				srv.applyPermissionMiddleware(h, route.resolver, route.permissions, route.features...),
 			)
 		} else {
 			srv.Mux.Handle(route.routeMethod,
@@ -109,6 +110,17 @@ func (srv *Server) RegisterRoutes() {
 		srv.registerFeatureFlagRoutes,
 		srv.registerOpenContentActivityRoutes,
 		srv.registerTagRoutes,
+		srv.registerJobsRoutes,
+		srv.registerEncryptionRoutes,
+		srv.registerExportRoutes,
+		srv.registerCalendarFeedRoutes,
+		srv.registerFacilityClosureRoutes,
+		srv.registerClassInstructorRoutes,
+		srv.registerCertificateRoutes,
+		srv.registerEarnedTimeRoutes,
+		srv.registerTransferPacketRoutes,
+		srv.registerResidentReleaseRoutes,
+		srv.registerTranscriptRoutes,
 	} {
 		srv.register(route)
 	}
@@ -182,13 +194,16 @@ func newServer(ctx context.Context) *Server {
 	if err != nil {
 		log.Fatalf("Failed to setup JetStream KV store: %v", err)
 	}
+	if err := models.EnsureTaskStreams(server.js); err != nil {
+		log.Fatalf("Failed to setup JetStream task streams: %v", err)
+	}
 	server.initAwsConfig(ctx)
 	server.RegisterRoutes()
 	if err := server.setupDefaultAdminInKratos(ctx); err != nil {
 		log.Fatal("Error setting up default admin in Kratos")
 	}
 	server.wsClient = newClientManager()
-	server.scheduler = tasks.InitScheduling(dev, server.nats, server.Db.DB)
+	server.scheduler = tasks.InitScheduling(dev, server.js, server.Db.DB)
 	return &server
 }
 
@@ -259,6 +274,7 @@ func (srv *Server) setupNatsKvBuckets() error {
 		log.Fatalf("Error initializing JetStream: %v", err)
 		return err
 	}
+	srv.js = js
 	buckets := map[string]nats.KeyValue{}
 	for _, bucket := range []string{CachedUsers, LibraryPaths, LoginMetrics, OAuthState, AdminLayer2} {
 		kv, err := js.KeyValue(bucket)
@@ -354,7 +370,7 @@ func (srv *Server) generateKolibriOidcClient() error {
 		log.WithFields(fields).Errorln("error creating kolibri auth client")
 		return err
 	}
-	return srv.Db.Create(client).Error
+	return srv.Db.RegisterClient(client)
 }
 
 func (srv *Server) syncKratosAdminDB(ctx context.Context) error {
@@ -463,21 +479,10 @@ func (svr *Server) handleError(handler HttpFunc) http.Handler {
 		claims, ok := r.Context().Value(ClaimsKey).(*Claims)
 		audit := false
 		if ok {
-			if claims.isAdmin() && r.Method != http.MethodGet {
+			// instructors write attendance and enrollments, so their changes are audited too
+			if (claims.isAdmin() || claims.isInstructor()) && r.Method != http.MethodGet {
 				audit = true
-				ip := r.Header.Get("X-Forwarded-For")
-				if ip == "" {
-					ip = r.RemoteAddr
-				} else {
-					ip = strings.Split(ip, ",")[0]
-				}
-				log.add("admin_id", claims.UserID)
-				log.add("username", claims.Username)
-				log.add("role", claims.Role)
-				log.add("session_id", claims.SessionID)
-				log.add("facility_id", claims.FacilityID)
-				log.add("facility_name", claims.FacilityName)
-				log.add("ip_address", ip)
+				log.addAuditFields(claims, r)
 			}
 		}
 		if err := handler(w, r, log); err != nil {
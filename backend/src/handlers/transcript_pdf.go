@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const transcriptDateFormat = "Jan 2, 2006"
+
+type transcriptColumn struct {
+	title string
+	width float64
+	align string
+}
+
+// renderTranscriptPDF lays out the transcript on portrait letter pages, with the facility letterhead on every page
+func renderTranscriptPDF(transcript *models.Transcript) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "Letter", "")
+	// the core fonts are latin-1, names with accents are translated to it
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	residentName := transcript.NameFirst + " " + transcript.NameLast
+	pdf.SetTitle("Education Transcript - "+residentName, true)
+	pdf.SetMargins(15, 36, 15)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+	width, _ := pdf.GetPageSize()
+
+	pdf.SetHeaderFunc(func() {
+		pdf.SetY(12)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Helvetica", "B", 16)
+		pdf.CellFormat(0, 8, tr(transcript.FacilityName), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.CellFormat(0, 6, "Education Transcript", "", 1, "L", false, 0, "")
+		pdf.SetDrawColor(24, 171, 160)
+		pdf.SetLineWidth(0.8)
+		pdf.Line(15, 30, width-15, 30)
+		pdf.SetY(36)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-14)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.CellFormat(0, 5, tr(residentName)+"  |  Generated "+transcript.GeneratedAt.Format(transcriptDateFormat), "", 0, "L", false, 0, "")
+		pdf.SetX(15)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 8, tr(residentName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	details := "DOC ID: " + transcript.DocID
+	if transcript.ReleasedAt != nil {
+		details += "  |  Released " + transcript.ReleasedAt.Format(transcriptDateFormat)
+	}
+	pdf.CellFormat(0, 6, tr(details), "", 1, "L", false, 0, "")
+	summary := fmt.Sprintf("Earned time: %s days  |  Open content: %s hours", formatHours(transcript.EarnedTimeDays), formatHours(transcript.OpenContentHours))
+	pdf.CellFormat(0, 6, summary, "", 1, "L", false, 0, "")
+
+	transcriptSection(pdf, tr, "Credits", []transcriptColumn{{"Credit type", 90, "L"}, {"Completions", 45, "R"}, {"Credit hours", 45, "R"}},
+		len(transcript.Credits), func(idx int) []string {
+			credit := transcript.Credits[idx]
+			return []string{string(credit.CreditType), strconv.Itoa(credit.Completions), strconv.FormatInt(credit.CreditHours, 10)}
+		})
+	transcriptSection(pdf, tr, "Programs", []transcriptColumn{{"Program", 39, "L"}, {"Class", 36, "L"}, {"Facility", 30, "L"}, {"Status", 27, "L"},
+		{"Credit hrs", 16, "R"}, {"Attendance", 17, "R"}, {"Completed", 20, "R"}},
+		len(transcript.Courses), func(idx int) []string {
+			course := transcript.Courses[idx]
+			attendance := ""
+			if course.AttendancePercentage != nil {
+				attendance = strconv.FormatFloat(*course.AttendancePercentage, 'f', -1, 64) + "%"
+			}
+			return []string{course.ProgramName, course.ClassName, course.FacilityName, string(course.EnrollmentStatus),
+				strconv.FormatInt(course.CreditHours, 10), attendance, formatTranscriptDate(course.CompletedAt)}
+		})
+	transcriptSection(pdf, tr, "Courses", []transcriptColumn{{"Provider", 34, "L"}, {"Course", 55, "L"}, {"Progress", 20, "R"},
+		{"Hours", 16, "R"}, {"Outcome", 35, "L"}, {"Completed", 25, "R"}},
+		len(transcript.ProviderCourses), func(idx int) []string {
+			course := transcript.ProviderCourses[idx]
+			return []string{course.ProviderName, course.CourseName, strconv.FormatFloat(course.Progress, 'f', -1, 64) + "%",
+				formatHours(course.Hours), string(course.OutcomeType), formatTranscriptDate(course.CompletedAt)}
+		})
+	transcriptSection(pdf, tr, "Open Content", []transcriptColumn{{"Title", 95, "L"}, {"Type", 20, "L"}, {"Visits", 18, "R"},
+		{"Hours", 18, "R"}, {"Last visited", 34, "R"}},
+		len(transcript.OpenContent), func(idx int) []string {
+			content := transcript.OpenContent[idx]
+			return []string{content.Title, content.ContentType, strconv.Itoa(content.Visits), formatHours(content.Hours),
+				formatTranscriptDate(&content.LastVisitedAt)}
+		})
+
+	document := bytes.Buffer{}
+	if err := pdf.Output(&document); err != nil {
+		return nil, err
+	}
+	return document.Bytes(), nil
+}
+
+// transcriptSection writes a titled table, repeating its header row at the top of each page it continues on
+func transcriptSection(pdf *gofpdf.Fpdf, tr func(string) string, title string, columns []transcriptColumn, rows int, row func(int) []string) {
+	const rowHeight = 6
+	_, height := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.SetFillColor(230, 245, 244)
+		for _, column := range columns {
+			pdf.CellFormat(column.width, rowHeight, column.title, "B", 0, column.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 8)
+	}
+	pdf.Ln(6)
+	// keep the title with the header and first row
+	if pdf.GetY()+8+2*rowHeight > height-bottom {
+		pdf.AddPage()
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, title, "", 1, "L", false, 0, "")
+	header()
+	if rows == 0 {
+		pdf.SetTextColor(90, 90, 90)
+		pdf.CellFormat(0, rowHeight, "None recorded", "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		return
+	}
+	for idx := range rows {
+		if pdf.GetY()+rowHeight > height-bottom {
+			pdf.AddPage()
+			header()
+		}
+		for col, value := range row(idx) {
+			pdf.CellFormat(columns[col].width, rowHeight, fitTranscriptCell(pdf, tr(value), columns[col].width), "", 0, columns[col].align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// fitTranscriptCell shortens the text with an ellipsis until it fits in the column
+func fitTranscriptCell(pdf *gofpdf.Fpdf, text string, width float64) string {
+	const padding = 2
+	if pdf.GetStringWidth(text) <= width-padding {
+		return text
+	}
+	// the text was translated to latin-1, so each byte is a character
+	for len(text) > 0 && pdf.GetStringWidth(text+"...") > width-padding {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+func formatTranscriptDate(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(transcriptDateFormat)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (srv *Server) registerTranscriptRoutes() []routeDef {
+	return []routeDef{
+		validatedRoute("GET /api/users/{id}/transcript", srv.handleGetTranscript, UserRoleResolver("id")),
+	}
+}
+
+/**
+* GET: /api/users/{id}/transcript?format=json|pdf
+* the resident's programs, credits, earned time, provider courses and open content, as they are now
+**/
+func (srv *Server) handleGetTranscript(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	log.add("user_id", id)
+	transcript, err := srv.Db.GetTranscript(r.Context(), id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "", "json":
+		return writeJsonResponse(w, http.StatusOK, transcript)
+	case "pdf":
+		return writeTranscriptPDF(w, r, log, transcript)
+	default:
+		return newBadRequestServiceError(fmt.Errorf("unsupported transcript format %q", r.URL.Query().Get("format")), "format must be json or pdf")
+	}
+}
+
+func writeTranscriptPDF(w http.ResponseWriter, r *http.Request, log sLog, transcript *models.Transcript) error {
+	document, err := renderTranscriptPDF(transcript)
+	if err != nil {
+		return newInternalServerServiceError(err, "unable to render transcript")
+	}
+	// transcripts are GET requests, which aren't audited by default
+	if claims, ok := r.Context().Value(ClaimsKey).(*Claims); ok && claims.isAdmin() {
+		log.addAuditFields(claims, r)
+		log.auditDetails("transcript_pdf")
+		log.adminAudit()
+	}
+	filename := fmt.Sprintf("transcript-%d-%s.pdf", transcript.UserID, transcript.GeneratedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(document); err != nil {
+		return newResponseServiceError(err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func (srv *Server) registerTransferPacketRoutes() []routeDef {
+	axx := models.ProgramAccess
+	resolver := UserRoleResolver("id")
+	return []routeDef{
+		validatedPermissionRoute("GET /api/users/{id}/transfer-packets", srv.handleIndexTransferPackets, models.UsersRead, resolver),
+		validatedPermissionRoute("GET /api/users/{id}/transfer-packets/{packet_id}", srv.handleGetTransferPacket, models.UsersRead, resolver),
+		validatedPermissionFeatureRoute("POST /api/users/{id}/transfer-packets/{packet_id}/re-enrollments", srv.handleReEnrollFromTransferPacket, models.ClassesRosterWrite, axx, FacilityAdminResolver("users", "id")),
+	}
+}
+
+func (srv *Server) handleIndexTransferPackets(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	args := srv.getQueryContext(r)
+	packets, err := srv.Db.GetTransferPackets(&args, id)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writePaginatedResponse(w, http.StatusOK, packets, args.IntoMeta())
+}
+
+func (srv *Server) handleGetTransferPacket(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	packetID, err := strconv.Atoi(r.PathValue("packet_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "transfer packet ID")
+	}
+	packet, err := srv.Db.GetTransferPacket(r.Context(), id, packetID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	return writeJsonResponse(w, http.StatusOK, packet)
+}
+
+/**
+* POST: /api/users/{id}/transfer-packets/{packet_id}/re-enrollments
+* without class_ids the resident is re-enrolled in an equivalent class for every class the transfer took them out of
+**/
+func (srv *Server) handleReEnrollFromTransferPacket(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	packetID, err := strconv.Atoi(r.PathValue("packet_id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "transfer packet ID")
+	}
+	log.add("user_id", id)
+	log.add("transfer_packet_id", packetID)
+	form := struct {
+		ClassIDs []int `json:"class_ids"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil && !errors.Is(err, io.EOF) {
+		return newJSONReqBodyServiceError(err)
+	}
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	enrollments, err := srv.Db.ReEnrollFromTransferPacket(r.Context(), id, packetID, form.ClassIDs, claims.UserID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	classIDs := make([]uint, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		classIDs = append(classIDs, enrollment.ClassID)
+	}
+	log.add("class_ids", classIDs)
+	log.auditDetails("transfer_re_enrollment")
+	return writeJsonResponse(w, http.StatusCreated, enrollments)
+}
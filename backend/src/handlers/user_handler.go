@@ -19,18 +19,20 @@ func (srv *Server) registerUserRoutes() []routeDef {
 		validatedRoute("GET /api/users/{id}", srv.handleShowUser, resolver),
 		validatedRoute("GET /api/users/{id}/programs", srv.handleGetUserPrograms, resolver),
 		/* admin */
-		newAdminRoute("GET /api/users", srv.handleIndexUsers),
-		newAdminRoute("POST /api/users", srv.handleCreateUser),
-		newAdminRoute("GET /api/users/resident-verify", srv.handleResidentVerification),
-		newDeptAdminRoute("PATCH /api/users/resident-transfer", srv.handleResidentTransfer),
-		validatedAdminRoute("POST /api/users/{id}/student-password", srv.handleResetStudentPassword, func(tx *database.DB, r *http.Request) bool {
+		permissionRoute("GET /api/users", srv.handleIndexUsers, models.UsersRead),
+		permissionRoute("POST /api/users", srv.handleCreateUser, models.UsersWrite),
+		permissionRoute("POST /api/users/import", srv.handleImportResidents, models.UsersWrite),
+		permissionRoute("GET /api/users/resident-verify", srv.handleResidentVerification, models.UsersRead),
+		permissionRoute("PATCH /api/users/resident-transfer", srv.handleResidentTransfer, models.UsersTransfer),
+		validatedPermissionRoute("POST /api/users/{id}/student-password", srv.handleResetStudentPassword, models.UsersWrite, func(tx *database.DB, r *http.Request) bool {
 			var role string
 			return tx.WithContext(r.Context()).Model(&models.User{}).Select("role").Where("id = ?", r.PathValue("id")).First(&role).Error == nil &&
 				canResetUserPassword(r.Context().Value(ClaimsKey).(*Claims), models.UserRole(role))
 		}),
-		validatedAdminRoute("DELETE /api/users/{id}", srv.handleDeleteUser, FacilityAdminResolver("users", "id")),
-		validatedAdminRoute("PATCH /api/users/{id}", srv.handleUpdateUser, FacilityAdminResolver("users", "id")),
-		validatedAdminRoute("GET /api/users/{id}/account-history", srv.handleGetUserAccountHistory, resolver),
+		validatedPermissionRoute("DELETE /api/users/{id}", srv.handleDeleteUser, models.UsersWrite, FacilityAdminResolver("users", "id")),
+		validatedPermissionRoute("PATCH /api/users/{id}", srv.handleUpdateUser, models.UsersWrite, FacilityAdminResolver("users", "id")),
+		validatedPermissionRoute("PUT /api/users/{id}/reactivate", srv.handleReactivateUser, models.UsersWrite, FacilityAdminResolver("users", "id")),
+		validatedPermissionRoute("GET /api/users/{id}/account-history", srv.handleGetUserAccountHistory, models.UsersRead, resolver),
 	}
 }
 
@@ -45,12 +47,18 @@ func (srv *Server) handleIndexUsers(w http.ResponseWriter, r *http.Request, log
 		providerId := r.URL.Query().Get("provider_id")
 		return srv.handleGetUnmappedUsers(w, r, providerId, log)
 
+	case slices.Contains(include, "only_deactivated"):
+		users, err = srv.Db.GetDeactivatedUsers(&args, role)
+		if err != nil {
+			log.add("facility_id", args.FacilityID)
+			return newDatabaseServiceError(err)
+		}
 	case slices.Contains(include, "only_unenrolled"):
 		classID, err := strconv.Atoi(r.URL.Query().Get("class_id"))
 		if err != nil {
 			return newInvalidIdServiceError(err, "class ID")
 		}
-		users, err = srv.Db.GetEligibleResidentsForClass(&args, classID)
+		users, err = srv.Db.GetEligibleResidentsForClass(&args, classID, slices.Contains(include, "only_eligible"))
 		if err != nil {
 			log.add("facility_id", args.FacilityID)
 			log.add("search", args.Search)
@@ -188,6 +196,7 @@ func (srv *Server) handleCreateUser(w http.ResponseWriter, r *http.Request, log
 
 /**
 * DELETE: /api/users/{id}
+* users are deactivated rather than deleted, so their attendance, completions and history stay in reports
  */
 func (srv *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request, log sLog) error {
 	id, err := strconv.Atoi(r.PathValue("id"))
@@ -195,19 +204,49 @@ func (srv *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request, log
 	if err != nil {
 		return newInvalidIdServiceError(err, "user ID")
 	}
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	user, err := srv.Db.DeactivateUser(r.Context(), id, claims.UserID)
+	if err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.add("deactivated_username", user.Username)
+	log.auditDetails("user_deactivated")
+	// deactivated users are turned away by the auth middleware, this also ends the sessions they have open
+	if !srv.testingMode && user.KratosID != "" {
+		if err := srv.setIdentityStateInKratos(r.Context(), user.KratosID, false); err != nil {
+			log.add("kratos_id", user.KratosID)
+			log.error("error deactivating user in kratos")
+		}
+	}
+	return writeJsonResponse(w, http.StatusNoContent, "User deactivated successfully")
+}
+
+// PUT: /api/users/{id}/reactivate
+func (srv *Server) handleReactivateUser(w http.ResponseWriter, r *http.Request, log sLog) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newInvalidIdServiceError(err, "user ID")
+	}
+	log.add("user_id", id)
+	claims := r.Context().Value(ClaimsKey).(*Claims)
 	user, err := srv.Db.GetUserByID(uint(id))
 	if err != nil {
 		return newDatabaseServiceError(err)
 	}
-	log.add("deleted_username", user.Username)
-	if err := srv.deleteIdentityInKratos(r.Context(), &user.KratosID); err != nil {
-		log.add("deleted_kratos_id", user.KratosID)
-		return newInternalServerServiceError(err, "error deleting user in kratos")
+	// the login is activated first, it stays blocked by the auth middleware until the user is reactivated below
+	if !srv.testingMode && user.DeactivatedAt != nil && user.KratosID != "" {
+		if err := srv.setIdentityStateInKratos(r.Context(), user.KratosID, true); err != nil {
+			log.add("kratos_id", user.KratosID)
+			return newInternalServerServiceError(err, "error reactivating user in kratos")
+		}
 	}
-	if err := srv.Db.DeleteUser(id); err != nil {
+	user, err = srv.Db.ReactivateUser(r.Context(), id, claims.UserID)
+	if err != nil {
 		return newDatabaseServiceError(err)
 	}
-	return writeJsonResponse(w, http.StatusNoContent, "User deleted successfully")
+	log.add("reactivated_username", user.Username)
+	log.auditDetails("user_reactivated")
+	return writeJsonResponse(w, http.StatusOK, user)
 }
 
 /**
@@ -298,11 +337,11 @@ func (srv *Server) handleResetStudentPassword(w http.ResponseWriter, r *http.Req
 func canResetUserPassword(currentUser *Claims, toUpdate models.UserRole) bool {
 	switch toUpdate {
 	case models.DepartmentAdmin: // department admin can only reset password of users in their department
-		return currentUser.canSwitchFacility()
+		return currentUser.can(models.UsersManageDepartmentAdmins)
 	case models.SystemAdmin: // system admin can only reset password of other system admins
-		return currentUser.Role == toUpdate
-	default: // user is garaunteed to be admin already at this point, can reset facility + student passwords
-		return currentUser.isAdmin()
+		return currentUser.can(models.UsersManageSystemAdmins)
+	default: // facility admin, instructor + student passwords
+		return currentUser.can(models.UsersWrite)
 	}
 }
 
@@ -380,7 +419,7 @@ func (srv *Server) handleResidentTransfer(w http.ResponseWriter, r *http.Request
 	log.add("admin_id", args.UserID)
 	log.add("transfer_facility_id", transRequest.TransFacilityID)
 	log.add("current_facility_id", transRequest.CurrFacilityID)
-	tx, err := srv.Db.TransferResident(&args, transRequest.UserID, transRequest.CurrFacilityID, transRequest.TransFacilityID)
+	tx, packet, err := srv.Db.TransferResident(&args, transRequest.UserID, transRequest.CurrFacilityID, transRequest.TransFacilityID)
 	if err != nil {
 		return newDatabaseServiceError(err)
 	}
@@ -389,13 +428,8 @@ func (srv *Server) handleResidentTransfer(w http.ResponseWriter, r *http.Request
 		tx.Rollback()
 		return newInternalServerServiceError(err, "error updating facility in kratos")
 	}
+	log.add("transfer_packet_id", packet.ID)
 	log.info("successfully transferred resident")
-	transFacilityID := uint(transRequest.TransFacilityID)
-	facilityTransfer := models.NewUserAccountHistory(uint(transRequest.UserID), models.FacilityTransfer, &args.UserID, nil, &transFacilityID)
-	if err := srv.Db.InsertUserAccountHistoryAction(r.Context(), facilityTransfer); err != nil {
-		tx.Rollback()
-		return newCreateRequestServiceError(err)
-	}
 	if err := tx.Commit().Error; err != nil {
 		// transfer back to original facility if we cannot commit tx
 		err = srv.updateFacilityInKratosIdentity(transRequest.UserID, transRequest.CurrFacilityID)
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"UnlockEdv2/src/database"
+	"UnlockEdv2/src/models"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	maxResidentImportFileSize = 5 << 20
+	maxResidentImportRows     = 1000
+)
+
+// the columns accepted in a resident import CSV, the header row is required
+var residentImportColumns = map[string]bool{
+	"username":   true,
+	"name_first": true,
+	"name_last":  true,
+	"doc_id":     false,
+}
+
+type ResidentImportRowError struct {
+	Row      int      `json:"row"`
+	Username string   `json:"username"`
+	Errors   []string `json:"errors"`
+}
+
+type ResidentImportResult struct {
+	DryRun    bool                     `json:"dry_run"`
+	TotalRows int                      `json:"total_rows"`
+	ValidRows int                      `json:"valid_rows"`
+	Errors    []ResidentImportRowError `json:"errors"`
+}
+
+type residentImportRow struct {
+	row  int
+	user models.User
+}
+
+/**
+* POST: /api/users/import?dry_run=true
+* multipart form with the CSV in the "file" field. A dry run only validates the rows, otherwise
+* every row must be valid before any resident is created, and the response is a CSV of the
+* created usernames with their temporary passwords
+**/
+func (srv *Server) handleImportResidents(w http.ResponseWriter, r *http.Request, log sLog) error {
+	claims := r.Context().Value(ClaimsKey).(*Claims)
+	dryRun := strings.ToLower(r.URL.Query().Get("dry_run")) == "true"
+	log.add("dry_run", dryRun)
+	log.add("facility_id", claims.FacilityID)
+	r.Body = http.MaxBytesReader(w, r.Body, maxResidentImportFileSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return newBadRequestServiceError(err, "a CSV file is required in the file field")
+	}
+	defer func() {
+		if file.Close() != nil {
+			log.error("Failed to close file")
+		}
+	}()
+	log.add("filename", header.Filename)
+	rows, err := parseResidentImportCSV(file, claims.FacilityID)
+	if err != nil {
+		return newBadRequestServiceError(err, err.Error())
+	}
+	result := ResidentImportResult{
+		DryRun:    dryRun,
+		TotalRows: len(rows),
+		Errors:    srv.validateResidentImportRows(rows),
+	}
+	result.ValidRows = result.TotalRows - len(result.Errors)
+	log.add("total_rows", result.TotalRows)
+	log.add("valid_rows", result.ValidRows)
+	if dryRun {
+		return writeJsonResponse(w, http.StatusOK, result)
+	}
+	if len(result.Errors) > 0 {
+		return writeJsonResponse(w, http.StatusUnprocessableEntity, result)
+	}
+	users := make([]models.User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, row.user)
+	}
+	if err := srv.Db.ImportResidents(r.Context(), users, claims.UserID, claims.FacilityID); err != nil {
+		return newDatabaseServiceError(err)
+	}
+	log.auditDetails("residents_imported")
+	return srv.writeResidentCredentials(w, users, log)
+}
+
+func parseResidentImportCSV(file io.Reader, facilityID uint) ([]residentImportRow, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("unable to read the header row of the CSV file")
+	}
+	columns := make(map[string]int, len(header))
+	for idx, name := range header {
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))), " ", "_")
+		if _, ok := residentImportColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q, expected username, name_first, name_last and optionally doc_id", name)
+		}
+		columns[name] = idx
+	}
+	for name, required := range residentImportColumns {
+		if _, ok := columns[name]; required && !ok {
+			return nil, fmt.Errorf("missing required column %q", name)
+		}
+	}
+	value := func(record []string, column string) string {
+		if idx, ok := columns[column]; ok && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+	rows := []residentImportRow{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse row %d of the CSV file: %w", line, err)
+		}
+		if len(rows) == maxResidentImportRows {
+			return nil, fmt.Errorf("a single import is limited to %d residents", maxResidentImportRows)
+		}
+		rows = append(rows, residentImportRow{
+			row: line,
+			user: models.User{
+				Username:   value(record, "username"),
+				NameFirst:  value(record, "name_first"),
+				NameLast:   value(record, "name_last"),
+				DocID:      value(record, "doc_id"),
+				Role:       models.Student,
+				FacilityID: facilityID,
+			},
+		})
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the CSV file does not contain any residents")
+	}
+	return rows, nil
+}
+
+// validates each row with the same rules as creating a single user, including usernames
+// and DOC IDs that are duplicated within the file
+func (srv *Server) validateResidentImportRows(rows []residentImportRow) []ResidentImportRowError {
+	rowErrors := []ResidentImportRowError{}
+	usernames := make(map[string]int, len(rows))
+	docIDs := make(map[string]int, len(rows))
+	for _, row := range rows {
+		user := row.user
+		errs := []string{}
+		if user.Username == "" || user.NameFirst == "" || user.NameLast == "" {
+			errs = append(errs, "username, name_first and name_last are required")
+		}
+		if validateUser(&user) != "" {
+			errs = append(errs, "username must only contain letters and numbers, names must only contain letters, spaces and hyphens")
+		} else if err := database.Validate().Struct(&user); err != nil {
+			var validationErrs validator.ValidationErrors
+			if errors.As(err, &validationErrs) {
+				for _, fieldErr := range validationErrs {
+					errs = append(errs, fmt.Sprintf("%s failed the %s validation", fieldErr.Field(), fieldErr.Tag()))
+				}
+			}
+		}
+		if len(user.DocID) > 25 {
+			errs = append(errs, "doc_id must be at most 25 characters")
+		}
+		if user.Username != "" {
+			if first, ok := usernames[strings.ToLower(user.Username)]; ok {
+				errs = append(errs, "username is a duplicate of row "+strconv.Itoa(first))
+			} else {
+				usernames[strings.ToLower(user.Username)] = row.row
+			}
+		}
+		if user.DocID != "" {
+			if first, ok := docIDs[user.DocID]; ok {
+				errs = append(errs, "doc_id is a duplicate of row "+strconv.Itoa(first))
+			} else {
+				docIDs[user.DocID] = row.row
+			}
+		}
+		if user.Username != "" || user.DocID != "" {
+			userNameExists, docExists := srv.Db.UserIdentityExists(user.Username, user.DocID)
+			if userNameExists {
+				errs = append(errs, "username already exists")
+			}
+			if docExists {
+				errs = append(errs, "doc_id already exists")
+			}
+		}
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, ResidentImportRowError{Row: row.row, Username: user.Username, Errors: errs})
+		}
+	}
+	return rowErrors
+}
+
+// registers the imported residents with kratos and kolibri the same way as handleCreateUser,
+// and writes their temporary passwords as a CSV attachment. The residents are already committed,
+// so a failed registration is reported in the file instead of failing the request. The file is
+// buffered so nothing is sent until the status can no longer change
+func (srv *Server) writeResidentCredentials(w http.ResponseWriter, users []models.User, log sLog) error {
+	var kolibri *models.ProviderPlatform
+	if !srv.testingMode {
+		var err error
+		if kolibri, err = srv.Db.FindKolibriInstance(); err != nil {
+			log.error("error getting kolibri instance")
+			kolibri = nil
+		}
+	}
+	credentials := bytes.Buffer{}
+	writer := csv.NewWriter(&credentials)
+	if err := writer.Write([]string{"username", "name_first", "name_last", "doc_id", "temp_password", "error"}); err != nil {
+		return newResponseServiceError(err)
+	}
+	for idx := range users {
+		user := &users[idx]
+		tempPw := user.CreateTempPassword()
+		registrationErr := ""
+		if !srv.testingMode {
+			if err := srv.HandleCreateUserKratos(user.Username, tempPw); err != nil {
+				log.add("user_id", user.ID)
+				log.errorf("Error creating user in kratos: %v", err)
+				registrationErr = "unable to create login, reset the resident's password"
+			}
+			if kolibri != nil {
+				if err := srv.CreateUserInKolibri(user, kolibri); err != nil {
+					log.add("user_id", user.ID)
+					log.error("error creating user in kolibri")
+				}
+			}
+		}
+		if err := writer.Write([]string{user.Username, user.NameFirst, user.NameLast, user.DocID, tempPw, registrationErr}); err != nil {
+			return newResponseServiceError(err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return newResponseServiceError(err)
+	}
+	filename := fmt.Sprintf("resident-import-%s.csv", time.Now().Format("2006-01-02-150405"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusCreated)
+	if _, err := w.Write(credentials.Bytes()); err != nil {
+		// the status was already sent, so the error can only be logged
+		log.errorf("unable to write the resident credentials: %v", err)
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"UnlockEdv2/src/database"
 	"UnlockEdv2/src/models"
 	"context"
 	"errors"
@@ -26,6 +25,23 @@ func (log sLog) adminAudit() {
 	logrus.WithFields(log.f).Println()
 }
 
+// adds who performed an admin action, and from where, to the audit log entry
+func (slog *sLog) addAuditFields(claims *Claims, r *http.Request) {
+	ip := r.Header.Get("X-Forwarded-For")
+	if ip == "" {
+		ip = r.RemoteAddr
+	} else {
+		ip = strings.Split(ip, ",")[0]
+	}
+	slog.add("admin_id", claims.UserID)
+	slog.add("username", claims.Username)
+	slog.add("role", claims.Role)
+	slog.add("session_id", claims.SessionID)
+	slog.add("facility_id", claims.FacilityID)
+	slog.add("facility_name", claims.FacilityName)
+	slog.add("ip_address", ip)
+}
+
 func (slog sLog) auditDetails(action string) {
 	slog.f["action"] = action
 }
@@ -154,40 +170,16 @@ func newRoute(method string, handler HttpFunc) routeDef {
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       false,
-		features:    []models.FeatureAccess{},
-	}
-}
-
-func newAdminRoute(method string, handler HttpFunc) routeDef {
-	return routeDef{
-		routeMethod: method,
-		handler:     handler,
-		admin:       true,
 		features:    []models.FeatureAccess{},
 	}
 }
 
-func newDeptAdminRoute(method string, handler HttpFunc) routeDef {
-	return routeDef{
-		routeMethod: method,
-		handler:     handler,
-		admin:       true,
-		features:    []models.FeatureAccess{},
-		resolver: func(tx *database.DB, r *http.Request) bool {
-			return r.Context().Value(ClaimsKey).(*Claims).canSwitchFacility()
-		},
-	}
-}
-func newSystemAdminRoute(method string, handler HttpFunc) routeDef {
+func permissionRoute(method string, handler HttpFunc, permission models.Permission) routeDef {
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       true,
+		permissions: []models.Permission{permission},
 		features:    []models.FeatureAccess{},
-		resolver: func(tx *database.DB, r *http.Request) bool {
-			return r.Context().Value(ClaimsKey).(*Claims).Role == models.SystemAdmin
-		},
 	}
 }
 
@@ -195,17 +187,16 @@ func validatedRoute(method string, handler HttpFunc, validate RouteResolver) rou
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       false,
 		features:    []models.FeatureAccess{},
 		resolver:    validate,
 	}
 }
 
-func validatedAdminRoute(method string, handler HttpFunc, validate RouteResolver) routeDef {
+func validatedPermissionRoute(method string, handler HttpFunc, permission models.Permission, validate RouteResolver) routeDef {
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       true,
+		permissions: []models.Permission{permission},
 		features:    []models.FeatureAccess{},
 		resolver:    validate,
 	}
@@ -215,16 +206,15 @@ func featureRoute(method string, handler HttpFunc, features ...models.FeatureAcc
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       false,
 		features:    features,
 	}
 }
 
-func adminFeatureRoute(method string, handler HttpFunc, features ...models.FeatureAccess) routeDef {
+func permissionFeatureRoute(method string, handler HttpFunc, permission models.Permission, features ...models.FeatureAccess) routeDef {
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       true,
+		permissions: []models.Permission{permission},
 		features:    features,
 		resolver:    nil,
 	}
@@ -234,18 +224,17 @@ func validatedFeatureRoute(method string, handler HttpFunc, feature models.Featu
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       false,
 		features:    []models.FeatureAccess{feature},
 		resolver:    resolver,
 	}
 }
 
-func adminValidatedFeatureRoute(method string, handler HttpFunc, features models.FeatureAccess, validate RouteResolver) routeDef {
+func validatedPermissionFeatureRoute(method string, handler HttpFunc, permission models.Permission, feature models.FeatureAccess, validate RouteResolver) routeDef {
 	return routeDef{
 		routeMethod: method,
 		handler:     handler,
-		admin:       true,
-		features:    []models.FeatureAccess{features},
+		permissions: []models.Permission{permission},
+		features:    []models.FeatureAccess{feature},
 		resolver:    validate,
 	}
 }
@@ -17,9 +17,9 @@ func (srv *Server) registerVideoRoutes() []routeDef {
 		featureRoute("GET /api/videos", srv.handleGetVideos, axx),
 		featureRoute("GET /api/videos/{id}", srv.handleGetVideoById, axx),
 		featureRoute("PUT /api/videos/{id}/favorite", srv.handleFavoriteVideo, axx),
-		adminFeatureRoute("POST /api/videos", srv.handlePostVideos, axx),
-		adminFeatureRoute("PUT /api/videos/{id}/{action}", srv.handleVideoAction, axx),
-		adminFeatureRoute("DELETE /api/videos/{id}", srv.handleDeleteVideo, axx),
+		permissionFeatureRoute("POST /api/videos", srv.handlePostVideos, models.ContentWrite, axx),
+		permissionFeatureRoute("PUT /api/videos/{id}/{action}", srv.handleVideoAction, models.ContentWrite, axx),
+		permissionFeatureRoute("DELETE /api/videos/{id}", srv.handleDeleteVideo, models.ContentWrite, axx),
 	}
 }
 
@@ -117,7 +117,7 @@ func (srv *Server) handleVideoAction(w http.ResponseWriter, r *http.Request, log
 			return newInternalServerServiceError(err, "error marshalling video")
 		}
 		msg.Data = bodyBytes
-		if err := srv.nats.PublishMsg(msg); err != nil {
+		if _, err := srv.js.PublishMsg(msg); err != nil {
 			return newInternalServerServiceError(err, "error publishing retry job")
 		}
 		return writeJsonResponse(w, http.StatusOK, "retry job published, please wait...")
@@ -140,7 +140,7 @@ func (srv *Server) handlePostVideos(w http.ResponseWriter, r *http.Request, log
 	if err != nil {
 		return newInternalServerServiceError(err, "error publishing add_video job")
 	}
-	if err = srv.nats.PublishMsg(msg); err != nil {
+	if _, err = srv.js.PublishMsg(msg); err != nil {
 		return newInternalServerServiceError(err, "error publishing video")
 	}
 	return writeJsonResponse(w, http.StatusCreated, "videos added, processing")
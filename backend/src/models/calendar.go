@@ -9,12 +9,14 @@ const (
 )
 
 type Calendar struct {
-	Days []Day `json:"days"`
+	Days     []Day             `json:"days"`
+	Closures []FacilityClosure `json:"closures"`
 }
 
 func NewCalendar(events []Day) *Calendar {
 	return &Calendar{
-		Days: events,
+		Days:     events,
+		Closures: []FacilityClosure{},
 	}
 }
 
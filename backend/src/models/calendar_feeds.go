@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+type CalendarFeedType string
+
+const (
+	// every class at the facility
+	FacilityCalendarFeed CalendarFeedType = "facility"
+	// a single class
+	ClassCalendarFeed CalendarFeedType = "class"
+	// every class at the facility taught by InstructorName
+	InstructorCalendarFeed CalendarFeedType = "instructor"
+)
+
+/*
+CalendarFeed is a subscription to the class schedules of a facility as an iCalendar (.ics) feed. Calendar
+clients can't log in, so the feed is looked up by a random token in its URL, only the hash of which is stored
+*/
+type CalendarFeed struct {
+	DatabaseFields
+	TokenHash      string           `json:"-" gorm:"size:64;not null;unique"`
+	FeedType       CalendarFeedType `json:"feed_type" gorm:"size:255;not null" validate:"required,oneof=facility class instructor"`
+	FacilityID     uint             `json:"facility_id" gorm:"not null"`
+	ClassID        *uint            `json:"class_id,omitempty" validate:"required_if=FeedType class"`
+	InstructorName *string          `json:"instructor_name,omitempty" gorm:"size:255" validate:"required_if=FeedType instructor,omitempty,max=255"`
+	CreateUserID   uint             `json:"create_user_id"`
+	// only set when the feed is created, the token can't be recovered afterwards
+	Token string `json:"token,omitempty" gorm:"-"`
+
+	Facility *Facility     `json:"-" gorm:"foreignKey:FacilityID;references:ID;constraint:OnDelete:CASCADE"`
+	Class    *ProgramClass `json:"class,omitempty" gorm:"foreignKey:ClassID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (CalendarFeed) TableName() string { return "calendar_feeds" }
+
+// NewCalendarFeedToken returns a random token for a feed URL along with the hash to store
+func NewCalendarFeedToken() (string, string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(randomBytes)
+	return token, HashCalendarFeedToken(token), nil
+}
+
+func HashCalendarFeedToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
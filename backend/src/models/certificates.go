@@ -0,0 +1,84 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"text/template"
+	"time"
+)
+
+/*
+CertificateTemplate is how the certificates of a program are worded. The body is a text/template
+executed with the CertificateData of the completion, e.g. "{{.ResidentName}} completed {{.ProgramName}}"
+*/
+type CertificateTemplate struct {
+	DatabaseFields
+	ProgramID   uint   `json:"program_id" gorm:"not null;unique"`
+	Title       string `json:"title" gorm:"size:255;not null" validate:"required,max=255"`
+	Body        string `json:"body" gorm:"type:text;not null" validate:"required,max=2000"`
+	SignerName  string `json:"signer_name" gorm:"size:255" validate:"max=255"`
+	SignerTitle string `json:"signer_title" gorm:"size:255" validate:"max=255"`
+
+	Program *Program `json:"-" gorm:"foreignKey:ProgramID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (CertificateTemplate) TableName() string { return "certificate_templates" }
+
+// DefaultCertificateTemplate is used for the programs an admin hasn't configured a template for
+func DefaultCertificateTemplate(programID uint) *CertificateTemplate {
+	return &CertificateTemplate{
+		ProgramID: programID,
+		Title:     "Certificate of Completion",
+		Body:      "This certifies that {{.ResidentName}} has successfully completed {{.ProgramName}} ({{.ClassName}}) at {{.FacilityName}}.",
+	}
+}
+
+// RenderBody executes the template body with the data of a certificate
+func (tmpl *CertificateTemplate) RenderBody(data *CertificateData) (string, error) {
+	body, err := template.New("certificate").Parse(tmpl.Body)
+	if err != nil {
+		return "", err
+	}
+	rendered := strings.Builder{}
+	if err := body.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+/*
+CompletionCertificate is issued the first time the certificate of a completion is downloaded, so that every copy
+printed carries the same verification code
+*/
+type CompletionCertificate struct {
+	DatabaseFields
+	ProgramCompletionID uint   `json:"program_completion_id" gorm:"not null;unique"`
+	VerificationCode    string `json:"verification_code" gorm:"size:32;not null;unique"`
+
+	ProgramCompletion *ProgramCompletion `json:"program_completion,omitempty" gorm:"foreignKey:ProgramCompletionID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (CompletionCertificate) TableName() string { return "completion_certificates" }
+
+// CertificateData is what a certificate shows, and what the body of a template can use
+type CertificateData struct {
+	ResidentName     string    `json:"resident_name"`
+	ProgramName      string    `json:"program_name"`
+	ClassName        string    `json:"class_name"`
+	CreditType       string    `json:"credit_type"`
+	Hours            float64   `json:"hours"`
+	FacilityName     string    `json:"facility_name"`
+	CompletedAt      time.Time `json:"completed_at"`
+	VerificationCode string    `json:"verification_code"`
+}
+
+// NewCertificateVerificationCode returns a random code that is easy to read out, e.g. "K3F9-2MXQ-7HDA-PW4T"
+func NewCertificateVerificationCode() (string, error) {
+	randomBytes := make([]byte, 10)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(randomBytes)
+	return strings.Join([]string{code[0:4], code[4:8], code[8:12], code[12:16]}, "-"), nil
+}
@@ -103,9 +103,12 @@ type ProgramClassEventOverride struct {
 	IsCancelled   bool   `json:"is_cancelled"`
 	Room          string `json:"room"`
 	Reason        string `json:"reason"`
+	// set when the override was created by a facility closure, and removed along with it
+	ClosureID *uint `json:"closure_id,omitempty"`
 
 	/* Foreign keys */
-	Event *ProgramClassEvent `json:"event" gorm:"foreignKey:EventID;references:ID"`
+	Event   *ProgramClassEvent `json:"event" gorm:"foreignKey:EventID;references:ID"`
+	Closure *FacilityClosure   `json:"-" gorm:"foreignKey:ClosureID;references:ID;constraint:OnDelete:CASCADE"`
 }
 
 func (ProgramClassEventOverride) TableName() string { return "program_class_event_overrides" }
@@ -220,4 +223,5 @@ type FacilityProgramClassEvent struct {
 	StartTime      *time.Time `json:"start"`
 	EndTime        *time.Time `json:"end"`
 	Frequency      string     `json:"frequency"`
+	ClosureReason  *string    `json:"closure_reason,omitempty"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+/*
+ClassInstructor assigns a user with the instructor role to a class, giving them access to the
+attendance, roster and calendar of that class only
+*/
+type ClassInstructor struct {
+	ClassID   uint      `json:"class_id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Class *ProgramClass `json:"class,omitempty" gorm:"foreignKey:ClassID;references:ID;constraint:OnDelete:CASCADE"`
+	User  *User         `json:"user,omitempty" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (ClassInstructor) TableName() string { return "class_instructors" }
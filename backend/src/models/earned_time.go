@@ -0,0 +1,66 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+type EarnedTimeEntryType string
+
+const (
+	// posted when a resident completes a class of a program with an earned-time rule
+	EarnedTimeCompletion EarnedTimeEntryType = "completion"
+	EarnedTimeAdjustment EarnedTimeEntryType = "adjustment"
+	EarnedTimeReversal   EarnedTimeEntryType = "reversal"
+)
+
+// EarnedTimeRule converts the credit hours of the completed classes of a program into days of earned time
+type EarnedTimeRule struct {
+	DatabaseFields
+	ProgramID    uint     `json:"program_id" gorm:"not null;unique"`
+	HoursPerDay  float64  `json:"hours_per_day" gorm:"type:numeric(10,2);not null" validate:"gt=0"`
+	MaxDays      *float64 `json:"max_days" gorm:"type:numeric(10,2)" validate:"omitempty,gt=0"` // the most days a single completion earns
+	CreateUserID uint     `json:"create_user_id"`
+
+	Program *Program `json:"-" gorm:"foreignKey:ProgramID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (EarnedTimeRule) TableName() string { return "earned_time_rules" }
+
+// Days returns the days earned for the credit hours, rounded to hundredths
+func (rule *EarnedTimeRule) Days(creditHours float64) float64 {
+	days := creditHours / rule.HoursPerDay
+	if rule.MaxDays != nil && days > *rule.MaxDays {
+		days = *rule.MaxDays
+	}
+	return math.Round(days*100) / 100
+}
+
+/*
+EarnedTimeEntry is a posting in the earned-time ledger of a resident, whose balance is the sum of the days of
+their entries. Entries are never changed or deleted, mistakes are corrected with a reversal or an adjustment
+*/
+type EarnedTimeEntry struct {
+	ID                  uint                `json:"id" gorm:"primaryKey"`
+	CreatedAt           time.Time           `json:"created_at"`
+	UserID              uint                `json:"user_id" gorm:"not null;index"`
+	EntryType           EarnedTimeEntryType `json:"entry_type" gorm:"size:32;not null"`
+	Days                float64             `json:"days" gorm:"type:numeric(10,2);not null"`
+	CreditHours         float64             `json:"credit_hours" gorm:"type:numeric(10,2);not null;default:0"`
+	ProgramCompletionID *uint               `json:"program_completion_id" gorm:"unique"`
+	ReversedEntryID     *uint               `json:"reversed_entry_id" gorm:"unique"`
+	Reason              string              `json:"reason" gorm:"size:255"`
+	CreateUserID        *uint               `json:"create_user_id"` // nil for the entries posted on completion
+
+	ProgramCompletion *ProgramCompletion `json:"program_completion,omitempty" gorm:"foreignKey:ProgramCompletionID;references:ID"`
+	CreateUser        *User              `json:"create_user,omitempty" gorm:"foreignKey:CreateUserID;references:ID"`
+	User              *User              `json:"-" gorm:"foreignKey:UserID;references:ID"`
+}
+
+func (EarnedTimeEntry) TableName() string { return "earned_time_entries" }
+
+type EarnedTimeBalance struct {
+	UserID      uint    `json:"user_id"`
+	BalanceDays float64 `json:"balance_days"`
+	CreditHours float64 `json:"credit_hours"`
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/**
+* Secrets stored in the database (provider access keys and OIDC client secrets) are encrypted with
+* AES-GCM and prefixed with the ID of the key that encrypted them: "enc:<key_id>:<base64 ciphertext>".
+*
+* APP_KEY is the current key and APP_KEY_ID is its ID (default "v1"). To rotate, the old key is moved
+* into APP_PREVIOUS_KEYS ("v1=<old key>,v2=<older key>") and a new APP_KEY/APP_KEY_ID is set. Secrets
+* encrypted with a previous key keep decrypting until POST /api/secrets/rotate re-encrypts them with
+* the current key, after which the previous key can be removed.
+**/
+const (
+	LegacyKeyID           = "v1"
+	encryptedSecretPrefix = "enc:"
+)
+
+var (
+	ErrUnknownEncryptionKey = errors.New("secret was encrypted with a key that is not configured")
+	ErrUndecryptableSecret  = errors.New("secret did not decrypt to printable text")
+)
+
+type encryptionKeyring struct {
+	currentID string
+	keys      map[string][32]byte
+}
+
+func loadEncryptionKeyring() *encryptionKeyring {
+	keyring := &encryptionKeyring{currentID: os.Getenv("APP_KEY_ID"), keys: make(map[string][32]byte)}
+	if keyring.currentID == "" {
+		keyring.currentID = LegacyKeyID
+	}
+	for _, pair := range strings.Split(os.Getenv("APP_PREVIOUS_KEYS"), ",") {
+		id, key, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || id == "" {
+			continue
+		}
+		keyring.keys[id] = sha256.Sum256([]byte(key))
+	}
+	keyring.keys[keyring.currentID] = sha256.Sum256([]byte(os.Getenv("APP_KEY")))
+	return keyring
+}
+
+func (keyring *encryptionKeyring) key(id string) ([]byte, error) {
+	key, ok := keyring.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncryptionKey, id)
+	}
+	return key[:], nil
+}
+
+func CurrentEncryptionKeyID() string {
+	return loadEncryptionKeyring().currentID
+}
+
+// SecretKeyID returns the ID of the key a secret was encrypted with. Ciphertext written before keys
+// had IDs, and plain text values, have no prefix and return false
+func SecretKeyID(secret string) (string, bool) {
+	rest, found := strings.CutPrefix(secret, encryptedSecretPrefix)
+	if !found {
+		return "", false
+	}
+	id, _, found := strings.Cut(rest, ":")
+	return id, found
+}
+
+// EncryptSecret encrypts the value with the current APP_KEY, prefixed with APP_KEY_ID
+func EncryptSecret(value string) (string, error) {
+	keyring := loadEncryptionKeyring()
+	key, err := keyring.key(keyring.currentID)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedSecretPrefix + keyring.currentID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts a value produced by EncryptSecret with whichever configured key encrypted it
+func DecryptSecret(secret string) (string, error) {
+	id, ok := SecretKeyID(secret)
+	if !ok {
+		return "", errors.New("secret is not prefixed with an encryption key ID")
+	}
+	key, err := loadEncryptionKeyring().key(id)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, encryptedSecretPrefix+id+":"))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// decryptLegacySecret decrypts the unprefixed AES-CFB ciphertext written before keys had IDs,
+// which was always encrypted with the key now identified by LegacyKeyID. CFB isn't authenticated,
+// so the wrong key returns garbage instead of an error: access keys are printable tokens, anything
+// else is rejected as undecryptable
+func decryptLegacySecret(secret string) (string, error) {
+	key, err := loadEncryptionKeyring().key(LegacyKeyID)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < aes.BlockSize {
+		return "", errors.New("encrypted secret is too short")
+	}
+	iv := ciphertext[:aes.BlockSize]
+	ciphertext = ciphertext[aes.BlockSize:]
+	stream := cipher.NewCFBDecrypter(block, iv)
+	stream.XORKeyStream(ciphertext, ciphertext)
+	plaintext := string(ciphertext)
+	if plaintext == "" || !utf8.ValidString(plaintext) || strings.IndexFunc(plaintext, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return "", ErrUndecryptableSecret
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptionKeyUsage counts the stored secrets encrypted with each key ID, rotation is
+// finished once every secret uses CurrentKeyID
+type EncryptionKeyUsage struct {
+	CurrentKeyID       string         `json:"current_key_id"`
+	ProviderAccessKeys map[string]int `json:"provider_access_keys"`
+	OidcClientSecrets  map[string]int `json:"oidc_client_secrets"`
+}
+
+// SecretRotationResult counts the secrets re-encrypted with KeyID, or that would be on a dry run.
+// PlaintextAccessKeys are the access keys that were stored unencrypted
+type SecretRotationResult struct {
+	KeyID               string `json:"key_id"`
+	DryRun              bool   `json:"dry_run"`
+	ProviderAccessKeys  int    `json:"provider_access_keys"`
+	PlaintextAccessKeys int    `json:"plaintext_access_keys"`
+	OidcClientSecrets   int    `json:"oidc_client_secrets"`
+}
+
+// UnprefixedSecretKeyID is reported for secrets without a key ID prefix, either legacy ciphertext
+// or plain text
+const UnprefixedSecretKeyID = "unprefixed"
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+/*
+FacilityClosure is a holiday, lockdown or other closure of a facility from StartDt through EndDt, in the facility's
+timezone. Every class session at the facility during the closure is cancelled with a ProgramClassEventOverride that
+references the closure, or only the sessions in Rooms when any are given
+*/
+type FacilityClosure struct {
+	DatabaseFields
+	FacilityID   uint      `json:"facility_id" gorm:"not null"`
+	StartDt      time.Time `json:"start_dt" gorm:"type:date;not null"`
+	EndDt        time.Time `json:"end_dt" gorm:"type:date;not null"`
+	Reason       string    `json:"reason" gorm:"size:255;not null" validate:"required,max=255"`
+	Rooms        []string  `json:"rooms" gorm:"serializer:json"`
+	CreateUserID uint      `json:"create_user_id"`
+	// the number of sessions the closure cancelled, only set when the closure is created
+	CancelledSessions int `json:"cancelled_sessions" gorm:"-"`
+
+	Facility  *Facility                   `json:"-" gorm:"foreignKey:FacilityID;references:ID;constraint:OnDelete:CASCADE"`
+	Overrides []ProgramClassEventOverride `json:"-" gorm:"foreignKey:ClosureID;references:ID"`
+}
+
+func (FacilityClosure) TableName() string { return "facility_closures" }
+
+// Window returns the start of the first day and the end of the last day of the closure in loc
+func (closure *FacilityClosure) Window(loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(closure.StartDt.Year(), closure.StartDt.Month(), closure.StartDt.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(closure.EndDt.Year(), closure.EndDt.Month(), closure.EndDt.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	return start, end
+}
+
+// AffectsRoom reports whether sessions in room are cancelled by the closure, rooms are matched ignoring case
+func (closure *FacilityClosure) AffectsRoom(room string) bool {
+	if len(closure.Rooms) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(closure.Rooms, func(closed string) bool {
+		return strings.EqualFold(strings.TrimSpace(closed), strings.TrimSpace(room))
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
 
@@ -57,10 +58,18 @@ type RunnableTask struct {
 	ProviderPlatformID    *uint          `json:"provider_platform_id"`
 	OpenContentProviderID *uint          `json:"open_content_provider_id"`
 	Status                JobStatus      `json:"status"`
+	// overrides the schedule of the CronJob for this task when set
+	Schedule string `gorm:"size:60" json:"schedule"`
+	Paused   bool   `gorm:"default:false" json:"paused"`
 
 	Provider        *ProviderPlatform    `gorm:"foreignKey:ProviderPlatformID" json:"-"`
 	ContentProvider *OpenContentProvider `gorm:"foreignKey:OpenContentProviderID" json:"-"`
-	Job             *CronJob             `gorm:"foreignKey:JobID" json:"-"`
+	Job             *CronJob             `gorm:"foreignKey:JobID" json:"job,omitempty"`
+}
+
+func ValidateCronSchedule(schedule string) error {
+	_, err := cron.ParseStandard(schedule)
+	return err
 }
 
 func (task *RunnableTask) Prepare(provId *uint) {
@@ -82,6 +91,62 @@ func (task *RunnableTask) Prepare(provId *uint) {
 
 func (RunnableTask) TableName() string { return "runnable_tasks" }
 
+// JobRun is a single execution of a RunnableTask. A row is created by the scheduler
+// when the task is published and closed out by the provider-middleware once the job finishes,
+// or once every delivery attempt has failed.
+type JobRun struct {
+	ID                    uint       `gorm:"primaryKey" json:"id"`
+	TaskID                uint       `gorm:"not null" json:"task_id"`
+	JobID                 string     `gorm:"size:50" json:"job_id"`
+	ProviderPlatformID    *uint      `json:"provider_platform_id"`
+	OpenContentProviderID *uint      `json:"open_content_provider_id"`
+	Status                JobStatus  `gorm:"size:20" json:"status"`
+	StartedAt             time.Time  `json:"started_at"`
+	EndedAt               *time.Time `json:"ended_at"`
+	ErrorMessage          string     `gorm:"size:1024" json:"error_message"`
+	Attempts              int        `gorm:"default:0" json:"attempts"`
+	CoursesImported       int64      `json:"courses_imported"`
+	MilestonesUpserted    int64      `json:"milestones_upserted"`
+	VideosDownloaded      int64      `json:"videos_downloaded"`
+
+	Task *RunnableTask `gorm:"foreignKey:TaskID" json:"-"`
+	Job  *CronJob      `gorm:"foreignKey:JobID" json:"job,omitempty"`
+}
+
+func (JobRun) TableName() string { return "job_runs" }
+
+const maxJobRunErrorLen = 1024
+
+func NewJobRun(task *RunnableTask) *JobRun {
+	return &JobRun{
+		TaskID:                task.ID,
+		JobID:                 task.JobID,
+		ProviderPlatformID:    task.ProviderPlatformID,
+		OpenContentProviderID: task.OpenContentProviderID,
+		Status:                StatusQueued,
+		StartedAt:             time.Now(),
+	}
+}
+
+// Finish marks the run as ended, recording the outcome and the error (if any)
+func (run *JobRun) Finish(jobErr error) {
+	now := time.Now()
+	run.EndedAt = &now
+	if jobErr == nil {
+		run.Status = StatusSucceeded
+		return
+	}
+	run.Status = StatusFailed
+	run.SetError(jobErr)
+}
+
+func (run *JobRun) SetError(jobErr error) {
+	run.ErrorMessage = jobErr.Error()
+	if len(run.ErrorMessage) > maxJobRunErrorLen {
+		run.ErrorMessage = run.ErrorMessage[:maxJobRunErrorLen]
+	}
+}
+
 const (
 	ProviderPlatformJob  = 1
 	OpenContentJob       = 2
@@ -92,6 +157,8 @@ const (
 	GetActivityJob   JobType = "get_activity"
 
 	DailyProgHistoryJob    JobType   = "daily_prog_history"
+	ClassStatusJob         JobType   = "class_status_transitions"
+	ResidentReleaseJob     JobType   = "resident_releases"
 	ScrapeKiwixJob         JobType   = "scrape_kiwix"
 	RetryVideoDownloadsJob JobType   = "retry_video_downloads"
 	RetryManualDownloadJob JobType   = "retry_manual_download"
@@ -100,12 +167,15 @@ const (
 	EveryDaytimeHour       string    = "0 6-20 * * *"
 	EverySundayAt8PM       string    = "0 20 * * 6"
 	StatusPending          JobStatus = "pending"
+	StatusQueued           JobStatus = "queued"
 	StatusRunning          JobStatus = "running"
+	StatusSucceeded        JobStatus = "succeeded"
+	StatusFailed           JobStatus = "failed"
 )
 
 var AllDefaultProviderJobs = []JobType{GetCoursesJob, GetMilestonesJob, GetActivityJob}
 var AllContentProviderJobs = []JobType{ScrapeKiwixJob, RetryVideoDownloadsJob, SyncVideoMetadataJob}
-var AllProgramManagementJobs = []JobType{DailyProgHistoryJob}
+var AllProgramManagementJobs = []JobType{DailyProgHistoryJob, ClassStatusJob, ResidentReleaseJob}
 
 func (jt JobType) IsVideoJob() bool {
 	switch jt {
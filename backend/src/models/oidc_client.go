@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type OidcClient struct {
@@ -17,7 +18,7 @@ type OidcClient struct {
 	ProviderPlatformID uint   `json:"provider_platform_id"`
 	ClientID           string `gorm:"size:255" json:"client_id"`
 	ClientName         string `gorm:"size:255" json:"client_name"`
-	ClientSecret       string `gorm:"size:255" json:"client_secret"`
+	ClientSecret       string `gorm:"size:512" json:"client_secret"`
 	RedirectURIs       string `gorm:"size:255" json:"redirect_uris"`
 	Scopes             string `gorm:"size:255" json:"scope"`
 
@@ -38,6 +39,17 @@ func (OidcClient) TableName() string {
 	return "oidc_clients"
 }
 
+// client secrets registered before they were encrypted are still stored as plain text
+func (client *OidcClient) AfterFind(tx *gorm.DB) (err error) {
+	if _, ok := SecretKeyID(client.ClientSecret); !ok {
+		return nil
+	}
+	if secret, secretErr := DecryptSecret(client.ClientSecret); secretErr == nil {
+		client.ClientSecret = secret
+	}
+	return nil
+}
+
 func OidcClientFromProvider(prov *ProviderPlatform, autoRegister bool, client *http.Client) (*OidcClient, string, error) {
 	externalId := ""
 	redirectURI := prov.GetDefaultRedirectURI()
@@ -68,6 +80,9 @@ func OidcClientFromProvider(prov *ProviderPlatform, autoRegister bool, client *h
 	case Kolibri:
 		body["token_endpoint_auth_method"] = "client_secret_basic"
 		body["subject_type"] = "public"
+	case Moodle:
+		body["token_endpoint_auth_method"] = "client_secret_post"
+		body["subject_type"] = "public"
 	}
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
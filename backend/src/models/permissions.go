@@ -0,0 +1,76 @@
+package models
+
+import "slices"
+
+// Permission is a named action that a role can be granted, as "resource.action"
+type Permission string
+
+const (
+	UsersRead                   Permission = "users.read"
+	UsersWrite                  Permission = "users.write"
+	UsersTransfer               Permission = "users.transfer"
+	UsersManageDepartmentAdmins Permission = "users.department_admins.manage"
+	UsersManageSystemAdmins     Permission = "users.system_admins.manage"
+	FacilitiesRead              Permission = "facilities.read"
+	FacilitiesWrite             Permission = "facilities.write"
+	FacilitiesSwitch            Permission = "facilities.switch" // act on the data of every facility
+	FacilitiesClosuresWrite     Permission = "facilities.closures.write"
+	ProgramsRead                Permission = "programs.read"
+	ProgramsWrite               Permission = "programs.write"
+	ClassesRead                 Permission = "classes.read"
+	ClassesWrite                Permission = "classes.write"
+	ClassesRosterRead           Permission = "classes.roster.read"
+	ClassesRosterWrite          Permission = "classes.roster.write"
+	ClassesScheduleRead         Permission = "classes.schedule.read"
+	ClassesScheduleWrite        Permission = "classes.schedule.write"
+	ClassesAttendanceRead       Permission = "classes.attendance.read"
+	ClassesAttendanceWrite      Permission = "classes.attendance.write"
+	EarnedTimeWrite             Permission = "earned_time.write"
+	CalendarFeedsManage         Permission = "calendar_feeds.manage"
+	ContentWrite                Permission = "content.write"
+	ProvidersManage             Permission = "providers.manage"
+	ReportsRead                 Permission = "reports.read"
+	ReportsExport               Permission = "reports.export"
+	JobsManage                  Permission = "jobs.manage"
+	FeatureFlagsManage          Permission = "feature_flags.manage"
+	SystemManage                Permission = "system.manage"
+)
+
+var (
+	instructorPermissions = []Permission{
+		ClassesRosterRead, ClassesScheduleRead, ClassesAttendanceRead, ClassesAttendanceWrite,
+	}
+	facilityAdminPermissions = append(slices.Clone(instructorPermissions),
+		UsersRead, UsersWrite, FacilitiesRead, FacilitiesClosuresWrite, ProgramsRead, ProgramsWrite, ClassesRead, ClassesWrite,
+		ClassesRosterWrite, ClassesScheduleWrite, EarnedTimeWrite, CalendarFeedsManage, ContentWrite, ProvidersManage, ReportsRead, ReportsExport,
+	)
+	departmentAdminPermissions = append(slices.Clone(facilityAdminPermissions),
+		UsersTransfer, UsersManageDepartmentAdmins, FacilitiesSwitch, JobsManage,
+	)
+	systemAdminPermissions = append(slices.Clone(departmentAdminPermissions),
+		UsersManageSystemAdmins, FacilitiesWrite, FeatureFlagsManage, SystemManage,
+	)
+)
+
+/*
+RolePermissions is the policy table mapping every role to the permissions it is granted. Routes declare the
+permissions they require and are checked against this table, so changing what a role can do only happens here
+*/
+var RolePermissions = map[UserRole][]Permission{
+	Student:         {},
+	Instructor:      instructorPermissions,
+	FacilityAdmin:   facilityAdminPermissions,
+	DepartmentAdmin: departmentAdminPermissions,
+	SystemAdmin:     systemAdminPermissions,
+}
+
+// Can reports whether the role is granted every one of the permissions
+func (role UserRole) Can(permissions ...Permission) bool {
+	granted := RolePermissions[role]
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return false
+		}
+	}
+	return true
+}
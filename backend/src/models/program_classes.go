@@ -35,6 +35,8 @@ type ProgramClass struct {
 	Enrolled       int64       `json:"enrolled" gorm:"-"`
 	CreateUserID   uint        `json:"create_user_id"`
 	UpdateUserID   uint        `json:"update_user_id"`
+	// set by the class status job when the class needs an admin to look at its dates or status
+	FlaggedReason *string `json:"flagged_reason" gorm:"size:255"`
 
 	Program      *Program                 `json:"program" gorm:"foreignKey:ProgramID;references:ID"`
 	Enrollments  []ProgramClassEnrollment `json:"enrollments" gorm:"foreignKey:ClassID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
@@ -55,6 +57,10 @@ type ProgramClassEnrollment struct {
 	UserID           uint                    `json:"user_id" gorm:"not null"`
 	EnrollmentStatus ProgramEnrollmentStatus `json:"enrollment_status" gorm:"size:255" validate:"max=255"`
 	ChangeReason     string                  `json:"change_reason" gorm:"size:255" validate:"max=255"`
+	WaitlistPosition *int                    `json:"waitlist_position,omitempty"`
+	// set when an admin enrolled the resident without meeting the program's prerequisites
+	OverrideReason *string `json:"override_reason,omitempty" gorm:"size:255" validate:"omitempty,max=255"`
+	OverrideUserID *uint   `json:"override_user_id,omitempty"`
 
 	User  *User         `json:"user" gorm:"foreignKey:UserID;references:ID"`
 	Class *ProgramClass `json:"class" gorm:"foreignKey:ClassID;references:ID"`
@@ -78,8 +84,18 @@ const (
 	EnrollmentIncompleteDropped          ProgramEnrollmentStatus = "Incomplete: Dropped"
 	EnrollmentIncompleteFailedToComplete ProgramEnrollmentStatus = "Incomplete: Failed to Complete"
 	EnrollmentIncompleteTransfered       ProgramEnrollmentStatus = "Incomplete: Transfered"
+	EnrollmentIncompleteReleased         ProgramEnrollmentStatus = "Incomplete: Released"
+	EnrollmentWaitlisted                 ProgramEnrollmentStatus = "Waitlisted"
 )
 
+// the statuses that give up a resident's seat in a class, the seat goes to the next resident on the waitlist
+var SeatReleasingEnrollmentStatuses = []ProgramEnrollmentStatus{
+	EnrollmentCancelled,
+	EnrollmentIncompleteDropped,
+	EnrollmentIncompleteWithdrawn,
+	EnrollmentIncompleteTransfered,
+}
+
 type ProgramCompletion struct {
 	DatabaseFields
 	UserID              uint      `json:"user_id" gorm:"not null"`
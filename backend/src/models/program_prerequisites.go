@@ -0,0 +1,52 @@
+package models
+
+import "fmt"
+
+type PrerequisiteType string
+
+const (
+	// the resident has a ProgramCompletion for RequiredProgramID
+	CompletedProgramPrerequisite PrerequisiteType = "completed_program"
+	// the resident has a ProgramCompletion that earned CreditType
+	CreditTypePrerequisite PrerequisiteType = "credit_type"
+)
+
+/*
+ProgramPrerequisites are the rules a resident must meet before enrolling in any class of the Program,
+every rule of the program must be met
+*/
+type ProgramPrerequisite struct {
+	DatabaseFields
+	ProgramID         uint             `json:"program_id" gorm:"not null"`
+	PrerequisiteType  PrerequisiteType `json:"prerequisite_type" gorm:"size:255;not null" validate:"required,oneof=completed_program credit_type"`
+	RequiredProgramID *uint            `json:"required_program_id,omitempty"`
+	CreditType        *CreditType      `json:"credit_type,omitempty" gorm:"size:255"`
+	CreateUserID      uint             `json:"create_user_id"`
+
+	Program         *Program `json:"-" gorm:"foreignKey:ProgramID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RequiredProgram *Program `json:"required_program,omitempty" gorm:"foreignKey:RequiredProgramID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (ProgramPrerequisite) TableName() string { return "program_prerequisites" }
+
+func (prereq *ProgramPrerequisite) Description() string {
+	switch prereq.PrerequisiteType {
+	case CompletedProgramPrerequisite:
+		if prereq.RequiredProgram != nil {
+			return fmt.Sprintf("completed the %s program", prereq.RequiredProgram.Name)
+		}
+		return "completed a required program"
+	case CreditTypePrerequisite:
+		if prereq.CreditType != nil {
+			return fmt.Sprintf("earned %s credit", *prereq.CreditType)
+		}
+	}
+	return string(prereq.PrerequisiteType)
+}
+
+// UnmetPrerequisites lists the rules of a program that a resident doesn't meet
+type UnmetPrerequisites struct {
+	UserID        uint     `json:"user_id"`
+	NameFull      string   `json:"name_full"`
+	Prerequisites []string `json:"prerequisites"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the checks run by the middleware when testing the connection to a provider platform
+const (
+	CheckConfiguration  = "configuration"
+	CheckConnectivity   = "connectivity"
+	CheckAuthentication = "authentication"
+	CheckAPIVersion     = "api_version"
+	CheckScopes         = "scopes"
+	CheckDataSets       = "data_sets"
+	CheckUsers          = "users"
+)
+
+type ConnectionCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// ProviderConnectionReport is the diagnostic result of testing a provider platform's base URL and credentials
+type ProviderConnectionReport struct {
+	ProviderPlatformID uint                 `json:"provider_platform_id,omitempty"`
+	Type               ProviderPlatformType `json:"type"`
+	BaseUrl            string               `json:"base_url"`
+	Success            bool                 `json:"success"`
+	Summary            string               `json:"summary"`
+	APIVersion         string               `json:"api_version,omitempty"`
+	UsersVisible       *int                 `json:"users_visible,omitempty"`
+	MissingScopes      []string             `json:"missing_scopes,omitempty"`
+	Checks             []ConnectionCheck    `json:"checks"`
+	TestedAt           time.Time            `json:"tested_at"`
+}
+
+func NewConnectionReport(provider *ProviderPlatform) *ProviderConnectionReport {
+	return &ProviderConnectionReport{
+		ProviderPlatformID: provider.ID,
+		Type:               provider.Type,
+		BaseUrl:            provider.BaseUrl,
+		Success:            true,
+		Checks:             []ConnectionCheck{},
+		TestedAt:           time.Now(),
+	}
+}
+
+func (report *ProviderConnectionReport) Pass(name, format string, args ...any) {
+	report.Checks = append(report.Checks, ConnectionCheck{Name: name, Passed: true, Message: fmt.Sprintf(format, args...)})
+}
+
+// a single failed check fails the whole report
+func (report *ProviderConnectionReport) Fail(name, format string, args ...any) {
+	report.Success = false
+	report.Checks = append(report.Checks, ConnectionCheck{Name: name, Passed: false, Message: fmt.Sprintf(format, args...)})
+}
+
+func (report *ProviderConnectionReport) SetUsersVisible(count int) {
+	report.UsersVisible = &count
+}
+
+// Summarize joins the check messages into a single line, e.g. "token valid, 124 users visible, missing scope X"
+func (report *ProviderConnectionReport) Summarize() {
+	messages := make([]string, 0, len(report.Checks))
+	for _, check := range report.Checks {
+		messages = append(messages, check.Message)
+	}
+	report.Summary = strings.Join(messages, ", ")
+}
@@ -1,13 +1,9 @@
 package models
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
-	"io"
 	mrand "math/rand"
 	"os"
 	"strings"
@@ -25,8 +21,18 @@ const (
 	CanvasCloud ProviderPlatformType = "canvas_cloud"
 	Kolibri     ProviderPlatformType = "kolibri"
 	Brightspace ProviderPlatformType = "brightspace"
+	Moodle      ProviderPlatformType = "moodle"
+	OpenEdx     ProviderPlatformType = "open_edx"
 )
 
+// the provider types that can suspend the account of a released resident, their provider-middleware
+// services implement AccountDeactivator
+var AccountDeactivatingProviders = []ProviderPlatformType{CanvasOSS, CanvasCloud, Moodle}
+
+// the provider types whose APIs can't filter on when a record changed, so their imports process everything on
+// every run and keep no sync cursors. A full resync of these providers only runs their tasks
+var FullSyncOnlyProviders = []ProviderPlatformType{OpenEdx}
+
 type ProviderPlatformState string
 
 const (
@@ -49,7 +55,7 @@ type ProviderPlatform struct {
 	Type                   ProviderPlatformType  `gorm:"size:100"  json:"type"`
 	Name                   string                `gorm:"size:255"  json:"name"`
 	AccountID              string                `gorm:"size:64"   json:"account_id"`
-	AccessKey              string                `gorm:"size:255"  json:"access_key"`
+	AccessKey              string                `gorm:"size:512"  json:"access_key"`
 	BaseUrl                string                `gorm:"size:255"  json:"base_url"`
 	State                  ProviderPlatformState `gorm:"size:100"  json:"state"`
 	ExternalAuthProviderId string                `gorm:"size:128"  json:"external_auth_provider_id"`
@@ -81,43 +87,17 @@ func (provider *ProviderPlatform) AfterFind(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// DecryptAccessKey decrypts an access key encrypted with any configured key, including the
+// unprefixed ciphertext written before encryption keys had IDs
 func DecryptAccessKey(axxKey string) (string, error) {
-	key := os.Getenv("APP_KEY")
-	hashedKey := sha256.Sum256([]byte(key))
-	block, err := aes.NewCipher(hashedKey[:])
-	if err != nil {
-		return "", err
-	}
-	ciphertext, err := base64.StdEncoding.DecodeString(axxKey)
-	if err != nil {
-		return "", err
-	}
-	if len(ciphertext) < aes.BlockSize {
-		return "", err
+	if _, ok := SecretKeyID(axxKey); ok {
+		return DecryptSecret(axxKey)
 	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
-	return string(ciphertext), nil
+	return decryptLegacySecret(axxKey)
 }
 
 func EncryptAccessKey(apiKey string) (string, error) {
-	key := os.Getenv("APP_KEY")
-	hashedKey := sha256.Sum256([]byte(key))
-	block, err := aes.NewCipher(hashedKey[:])
-	if err != nil {
-		return "", err
-	}
-	ciphertext := make([]byte, aes.BlockSize+len(apiKey))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", err
-	}
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], []byte(apiKey))
-	encoded := base64.StdEncoding.EncodeToString(ciphertext)
-	return encoded, nil
+	return EncryptSecret(apiKey)
 }
 
 func (provider *ProviderPlatform) GetDefaultRedirectURI() []string {
@@ -129,6 +109,10 @@ func (provider *ProviderPlatform) GetDefaultRedirectURI() []string {
 		defaultUri := provider.BaseUrl + "/oidccallback/"
 		stripped := strings.Replace(defaultUri, "https", "http", 1)
 		return []string{defaultUri, stripped}
+
+	case Moodle:
+		// the redirect URI for the moodle OpenID Connect plugin (auth_oidc)
+		return []string{provider.BaseUrl + "/auth/oidc/"}
 	}
 	return []string{}
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type ScheduleConflictType string
+
+const (
+	// the room is already booked by another class
+	RoomConflict ScheduleConflictType = "room"
+	// the instructor is already teaching another class, matched on InstructorName
+	InstructorConflict ScheduleConflictType = "instructor"
+	// residents enrolled in the class are already enrolled in another class at the same time
+	ResidentConflict ScheduleConflictType = "resident"
+)
+
+type ConflictingResident struct {
+	UserID   uint   `json:"user_id"`
+	NameFull string `json:"name_full"`
+}
+
+// ScheduleConflict is a single session of a proposed event that overlaps a session of another class
+type ScheduleConflict struct {
+	ConflictType         ScheduleConflictType  `json:"conflict_type"`
+	StartTime            time.Time             `json:"start_time"`
+	EndTime              time.Time             `json:"end_time"`
+	ConflictingClassID   uint                  `json:"conflicting_class_id"`
+	ConflictingClassName string                `json:"conflicting_class_name"`
+	ConflictingEventID   uint                  `json:"conflicting_event_id"`
+	ConflictingStartTime time.Time             `json:"conflicting_start_time"`
+	ConflictingEndTime   time.Time             `json:"conflicting_end_time"`
+	Room                 string                `json:"room,omitempty"`
+	InstructorName       string                `json:"instructor_name,omitempty"`
+	Residents            []ConflictingResident `json:"residents,omitempty"`
+}
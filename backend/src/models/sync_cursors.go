@@ -0,0 +1,33 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProviderSyncCursor records how far a provider platform has been synced for a single entity,
+// so that the middleware only has to request and process what has changed since the last run.
+// The cursor is opaque to the backend: depending on the provider it holds an updated_since
+// timestamp, a page token or the identifier of a data export.
+type ProviderSyncCursor struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	ProviderPlatformID uint      `gorm:"not null;uniqueIndex:idx_provider_sync_cursor" json:"provider_platform_id"`
+	Entity             string    `gorm:"size:100;not null;uniqueIndex:idx_provider_sync_cursor" json:"entity"`
+	Cursor             string    `gorm:"not null" json:"cursor"`
+	UpdatedAt          time.Time `json:"updated_at"`
+
+	ProviderPlatform *ProviderPlatform `gorm:"foreignKey:ProviderPlatformID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (ProviderSyncCursor) TableName() string { return "provider_sync_cursors" }
+
+const (
+	CoursesSyncEntity    = "courses"
+	MilestonesSyncEntity = "milestones"
+	ActivitySyncEntity   = "activity"
+)
+
+// milestones and activity are synced course by course, so their cursors are kept per course
+func CourseSyncEntity(entity string, courseID uint) string {
+	return fmt.Sprintf("%s:%d", entity, courseID)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// TaskStream is the JetStream work queue every tasks.<job_type> message is published to.
+	// Each message is removed once the middleware acks it.
+	TaskStream = "TASKS"
+	// TaskDeadLetterStream keeps the messages that failed on every delivery attempt
+	TaskDeadLetterStream = "TASKS_DEAD_LETTER"
+	MaxTaskDeliveries    = 3
+	// the middleware waits this long before the first redelivery, doubling on each attempt
+	TaskRedeliveryBackoff = time.Minute
+
+	taskDeadLetterRetention = 30 * 24 * time.Hour
+)
+
+func (jt JobType) DeadLetterName() string {
+	return "dead_letter.tasks." + string(jt)
+}
+
+// ConsumerName is the durable consumer the middleware binds for this job type
+func (jt JobType) ConsumerName() string {
+	return "middleware_" + string(jt)
+}
+
+// EnsureTaskStreams creates the task work queue and dead letter streams if they don't exist.
+// It is called by both the backend and the middleware, whichever starts first.
+func EnsureTaskStreams(js nats.JetStreamContext) error {
+	for _, cfg := range []*nats.StreamConfig{
+		{
+			Name:      TaskStream,
+			Subjects:  []string{"tasks.>"},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+		},
+		{
+			Name:      TaskDeadLetterStream,
+			Subjects:  []string{"dead_letter.tasks.>"},
+			Retention: nats.LimitsPolicy,
+			Storage:   nats.FileStorage,
+			MaxAge:    taskDeadLetterRetention,
+		},
+	} {
+		_, err := js.StreamInfo(cfg.Name)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, nats.ErrStreamNotFound) {
+			return err
+		}
+		if _, err := js.AddStream(cfg); err != nil && !errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+			return err
+		}
+	}
+	return nil
+}
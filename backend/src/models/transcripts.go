@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Transcript is the education history of a resident across every facility they have been at
+type Transcript struct {
+	UserID         uint               `json:"user_id"`
+	NameFirst      string             `json:"name_first"`
+	NameLast       string             `json:"name_last"`
+	DocID          string             `json:"doc_id"`
+	FacilityName   string             `json:"facility_name"`
+	ReleasedAt     *time.Time         `json:"released_at,omitempty"`
+	GeneratedAt    time.Time          `json:"generated_at"`
+	Courses        []TranscriptCourse `json:"courses"`
+	Credits        []CreditTotal      `json:"credits"`
+	EarnedTimeDays float64            `json:"earned_time_days"`
+	// courses taken through the provider platforms, e.g. canvas or kolibri
+	ProviderCourses  []TranscriptProviderCourse `json:"provider_courses"`
+	OpenContent      []TranscriptOpenContent    `json:"open_content"`
+	OpenContentHours float64                    `json:"open_content_hours"`
+}
+
+// TranscriptCourse is a class the resident was enrolled in, or a completion recorded without an enrollment
+type TranscriptCourse struct {
+	ProgramName          string                  `json:"program_name"`
+	ClassName            string                  `json:"class_name"`
+	FacilityName         string                  `json:"facility_name"`
+	EnrollmentStatus     ProgramEnrollmentStatus `json:"enrollment_status"`
+	CreditTypes          string                  `json:"credit_types"`
+	CreditHours          int64                   `json:"credit_hours"`
+	AttendancePercentage *float64                `json:"attendance_percentage"` // nil when no attendance was taken
+	EnrolledAt           *time.Time              `json:"enrolled_at"`
+	CompletedAt          *time.Time              `json:"completed_at"`
+	CertificateCode      string                  `json:"certificate_code,omitempty"`
+}
+
+// TranscriptProviderCourse is a provider platform course the resident made progress in or earned an outcome from
+type TranscriptProviderCourse struct {
+	ProviderName string      `json:"provider_name"`
+	CourseName   string      `json:"course_name"`
+	Progress     float64     `json:"progress"` // percentage of the course's milestones, 100 once it has an outcome
+	Hours        float64     `json:"hours"`
+	OutcomeType  OutcomeType `json:"outcome_type,omitempty"`
+	OutcomeValue string      `json:"outcome_value,omitempty"`
+	CompletedAt  *time.Time  `json:"completed_at"`
+}
+
+// TranscriptOpenContent is a library or video the resident visited
+type TranscriptOpenContent struct {
+	Title         string    `json:"title"`
+	ContentType   string    `json:"content_type"`
+	Visits        int       `json:"visits"`
+	Hours         float64   `json:"hours"`
+	LastVisitedAt time.Time `json:"last_visited_at"`
+}
+
+// CreditTotal totals the completions awarding a credit type, and the credit hours of their classes
+type CreditTotal struct {
+	CreditType  CreditType `json:"credit_type"`
+	Completions int        `json:"completions"`
+	CreditHours int64      `json:"credit_hours"`
+}
+
+type TranscriptReason string
+
+const TranscriptRelease TranscriptReason = "release"
+
+// ResidentTranscript is a transcript saved when a resident leaves, so it can still be exported as it was on that day
+type ResidentTranscript struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time        `json:"created_at"`
+	UserID    uint             `json:"user_id" gorm:"not null;index"`
+	Reason    TranscriptReason `json:"reason" gorm:"size:32;not null"`
+	Contents  Transcript       `json:"contents" gorm:"serializer:json"`
+
+	User *User `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (ResidentTranscript) TableName() string { return "resident_transcripts" }
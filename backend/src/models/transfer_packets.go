@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+/*
+TransferPacket hands the education history of a resident from the facility they are transferred out of to the
+receiving facility. Contents is a snapshot taken in the transfer's transaction, before the resident's open
+enrollments are marked as transferred, so it is never rebuilt afterwards
+*/
+type TransferPacket struct {
+	DatabaseFields
+	UserID         uint                   `json:"user_id" gorm:"not null;index"`
+	FromFacilityID uint                   `json:"from_facility_id" gorm:"not null"`
+	ToFacilityID   uint                   `json:"to_facility_id" gorm:"not null;index"`
+	CreateUserID   uint                   `json:"create_user_id"`
+	Contents       TransferPacketContents `json:"contents" gorm:"serializer:json"`
+	// set the first time the receiving facility re-enrolls the resident from the packet
+	ReceivedAt    *time.Time `json:"received_at"`
+	ReceiveUserID *uint      `json:"receive_user_id"`
+
+	User         *User     `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	FromFacility *Facility `json:"from_facility,omitempty" gorm:"foreignKey:FromFacilityID;references:ID"`
+	ToFacility   *Facility `json:"to_facility,omitempty" gorm:"foreignKey:ToFacilityID;references:ID"`
+}
+
+func (TransferPacket) TableName() string { return "transfer_packets" }
+
+type TransferPacketContents struct {
+	Completions      []TransferPacketCompletion      `json:"completions"`
+	InProgress       []TransferPacketClass           `json:"in_progress"`
+	Credits          []CreditTotal                   `json:"credits"`
+	EarnedTimeDays   float64                         `json:"earned_time_days"`
+	ProviderMappings []TransferPacketProviderMapping `json:"provider_mappings"`
+	Favorites        []TransferPacketFavorite        `json:"favorites"`
+}
+
+type TransferPacketCompletion struct {
+	ProgramCompletionID uint      `json:"program_completion_id"`
+	ProgramID           uint      `json:"program_id"`
+	ProgramName         string    `json:"program_name"`
+	ClassName           string    `json:"class_name"`
+	FacilityName        string    `json:"facility_name"`
+	CreditType          string    `json:"credit_type"`
+	CreditHours         int64     `json:"credit_hours"`
+	CompletedAt         time.Time `json:"completed_at"`
+}
+
+// TransferPacketClass is a class the resident was enrolled or waitlisted in when they were transferred
+type TransferPacketClass struct {
+	ClassID              uint                    `json:"class_id"`
+	ProgramID            uint                    `json:"program_id"`
+	ProgramName          string                  `json:"program_name"`
+	ClassName            string                  `json:"class_name"`
+	EnrollmentStatus     ProgramEnrollmentStatus `json:"enrollment_status"`
+	EnrolledAt           time.Time               `json:"enrolled_at"`
+	PresentSessions      int                     `json:"present_sessions"`
+	RecordedSessions     int                     `json:"recorded_sessions"`
+	AttendancePercentage float64                 `json:"attendance_percentage"`
+}
+
+type TransferPacketProviderMapping struct {
+	ProviderPlatformID   uint   `json:"provider_platform_id"`
+	ProviderPlatformName string `json:"provider_platform_name"`
+	ExternalUserID       string `json:"external_user_id"`
+	ExternalUsername     string `json:"external_username"`
+}
+
+type TransferPacketFavoriteType string
+
+const (
+	ProgramFavoriteType     TransferPacketFavoriteType = "program"
+	OpenContentFavoriteType TransferPacketFavoriteType = "open_content"
+)
+
+type TransferPacketFavorite struct {
+	Type                  TransferPacketFavoriteType `json:"type"`
+	ProgramID             *uint                      `json:"program_id,omitempty"`
+	ContentID             *uint                      `json:"content_id,omitempty"`
+	OpenContentProviderID *uint                      `json:"open_content_provider_id,omitempty"`
+	Name                  string                     `json:"name"`
+}
+
+// TransferEquivalentClass is a class at the receiving facility the resident can be re-enrolled in, in place of a
+// class of the same program they were taken out of by the transfer
+type TransferEquivalentClass struct {
+	ReplacesClassID uint        `json:"replaces_class_id"`
+	ClassID         uint        `json:"class_id"`
+	ProgramID       uint        `json:"program_id"`
+	ProgramName     string      `json:"program_name"`
+	ClassName       string      `json:"class_name"`
+	Status          ClassStatus `json:"status"`
+	StartDt         time.Time   `json:"start_dt"`
+	AvailableSeats  int         `json:"available_seats"`
+	Enrolled        bool        `json:"enrolled"` // the resident already has an enrollment in the class
+}
+
+type TransferPacketDetails struct {
+	TransferPacket
+	EquivalentClasses []TransferEquivalentClass `json:"equivalent_classes"`
+}
@@ -15,6 +15,7 @@ const (
 	FacilityAdmin   UserRole = "facility_admin"
 	DepartmentAdmin UserRole = "department_admin"
 	Student         UserRole = "student"
+	Instructor      UserRole = "instructor" // limited to the classes they are assigned to
 )
 
 var AdminRoles = []UserRole{SystemAdmin, FacilityAdmin, DepartmentAdmin}
@@ -36,10 +37,15 @@ type User struct {
 	NameFirst  string   `gorm:"size:255;not null" json:"name_first"  validate:"alphanumspace"`
 	Email      string   `gorm:"size:255;not null;unique" json:"email" validate:"-"`
 	NameLast   string   `gorm:"size:255;not null" json:"name_last"  validate:"alphanumspace"`
-	Role       UserRole `gorm:"size:64;default:student" json:"role" validate:"oneof=student system_admin facility_admin department_admin"`
+	Role       UserRole `gorm:"size:64;default:student" json:"role" validate:"oneof=student system_admin facility_admin department_admin instructor"`
 	KratosID   string   `gorm:"size:255" json:"kratos_id"`
 	FacilityID uint     `json:"facility_id"`
 	DocID      string   `json:"doc_id" gorm:"column:doc_id;size:25"`
+	// the resident is released from custody by the resident release job on ReleaseDate, in their facility's timezone
+	ReleaseDate *time.Time `json:"release_date" gorm:"type:date"`
+	ReleasedAt  *time.Time `json:"released_at"`
+	// deactivated users can't log in and are hidden from the user lists, their history is kept for reporting
+	DeactivatedAt *time.Time `json:"deactivated_at"`
 
 	/* foreign keys */
 	Mappings             []ProviderUserMapping `json:"mappings,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete CASCADE"`
@@ -93,17 +99,22 @@ func (user *User) IsAdmin() bool {
 }
 
 type UserAccountHistory struct {
-	UserID                  uint                  `json:"user_id" gorm:"primaryKey"`
+	ID                      uint                  `json:"id" gorm:"primaryKey"`
+	UserID                  uint                  `json:"user_id"`
 	AdminID                 *uint                 `json:"admin_id"`
-	Action                  ActivityHistoryAction `json:"action" gorm:"size:255;primaryKey"`
+	Action                  ActivityHistoryAction `json:"action" gorm:"size:255"`
 	ProgramClassesHistoryID *uint                 `json:"program_classes_history_id"`
 	FacilityID              *uint                 `json:"facility_id"`
-	CreatedAt               time.Time             `json:"created_at" gorm:"primaryKey"`
+	ProgramClassID          *uint                 `json:"program_class_id"`
+	TransferPacketID        *uint                 `json:"transfer_packet_id"` // set on the steps of a facility transfer
+	CreatedAt               time.Time             `json:"created_at"`
 
 	User                  *User                  `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
 	Admin                 *User                  `json:"admin,omitempty" gorm:"foreignKey:AdminID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
 	ProgramClassesHistory *ProgramClassesHistory `json:"program_classes_history,omitempty" gorm:"foreignKey:ProgramClassesHistoryID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
 	Facility              *Facility              `json:"facility,omitempty" gorm:"foreignKey:FacilityID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	ProgramClass          *ProgramClass          `json:"program_class,omitempty" gorm:"foreignKey:ProgramClassID;constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
+	TransferPacket        *TransferPacket        `json:"-" gorm:"foreignKey:TransferPacketID;constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
 }
 
 func (UserAccountHistory) TableName() string {
@@ -113,11 +124,15 @@ func (UserAccountHistory) TableName() string {
 type ActivityHistoryAction string
 
 const (
-	AccountCreation  ActivityHistoryAction = "account_creation"
-	FacilityTransfer ActivityHistoryAction = "facility_transfer"
-	SetPassword      ActivityHistoryAction = "set_password"
-	ResetPassword    ActivityHistoryAction = "reset_password"
-	ProgClassHistory ActivityHistoryAction = "progclass_history"
+	AccountCreation     ActivityHistoryAction = "account_creation"
+	FacilityTransfer    ActivityHistoryAction = "facility_transfer"
+	SetPassword         ActivityHistoryAction = "set_password"
+	ResetPassword       ActivityHistoryAction = "reset_password"
+	ProgClassHistory    ActivityHistoryAction = "progclass_history"
+	WaitlistPromoted    ActivityHistoryAction = "waitlist_promoted"
+	ResidentRelease     ActivityHistoryAction = "resident_release"
+	AccountDeactivation ActivityHistoryAction = "account_deactivation"
+	AccountReactivation ActivityHistoryAction = "account_reactivation"
 )
 
 type ActivityHistoryResponse struct {
@@ -130,7 +145,9 @@ type ActivityHistoryResponse struct {
 	UserUsername            *string               `json:"user_username"`
 	AdminUsername           *string               `json:"admin_username"`
 	FacilityName            *string               `json:"facility_name"`
+	ClassName               *string               `json:"class_name"`
 	ProgramClassesHistoryID *uint                 `json:"program_classes_history_id"`
+	TransferPacketID        *uint                 `json:"transfer_packet_id"`
 
 	ProgramClassesHistory *ProgramClassesHistory `json:"program_classes_history,omitempty" gorm:"foreignKey:ProgramClassesHistoryID;constraint:OnDelete:SET NULL"`
 }
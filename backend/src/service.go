@@ -2,6 +2,8 @@ package src
 
 import (
 	"UnlockEdv2/src/models"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -101,3 +103,55 @@ func (serv *ProviderService) GetUsers() ([]models.ImportUser, error) {
 	log.Debugf("users received from middleware: %v", users)
 	return users, nil
 }
+
+// TestConnection asks the middleware to check the provider's connection and credentials
+func (serv *ProviderService) TestConnection(ctx context.Context) (*models.ProviderConnectionReport, error) {
+	finalUrl := serv.ServiceURL + "/api/test-connection?id=" + strconv.Itoa(int(serv.ProviderPlatformID))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, finalUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	return serv.requestConnectionReport(request)
+}
+
+// TestProviderConnection checks the settings of a provider platform that has not been created yet
+func TestProviderConnection(ctx context.Context, prov *models.ProviderPlatform, client *http.Client) (*models.ProviderConnectionReport, error) {
+	body, err := json.Marshal(prov)
+	if err != nil {
+		return nil, err
+	}
+	serv := ProviderService{Type: string(prov.Type), ServiceURL: os.Getenv("PROVIDER_SERVICE_URL"), Client: client}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, serv.ServiceURL+"/api/test-connection", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return serv.requestConnectionReport(request)
+}
+
+func (serv *ProviderService) requestConnectionReport(request *http.Request) (*models.ProviderConnectionReport, error) {
+	fields := log.Fields{"handler": "TestConnection", "type": serv.Type}
+	resp, err := serv.Client.Do(request)
+	if err != nil {
+		fields["error"] = err
+		log.WithFields(fields).Errorln("error testing connection Client.Do(req)")
+		return nil, err
+	}
+	defer func() {
+		if resp.Body.Close() != nil {
+			log.WithFields(fields).Errorln("error closing response body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		fields["status_code"] = resp.Status
+		log.WithFields(fields).Errorln("connection test request failed")
+		return nil, errors.New("connection test request failed with status " + resp.Status)
+	}
+	var report models.ProviderConnectionReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		fields["error"] = err
+		log.WithFields(fields).Errorln("error decoding connection report from middleware")
+		return nil, err
+	}
+	return &report, nil
+}
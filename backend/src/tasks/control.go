@@ -0,0 +1,106 @@
+package tasks
+
+import (
+	"UnlockEdv2/src/models"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrTaskAlreadyRunning = errors.New("task is already queued or running")
+
+// RunTaskNow publishes the task immediately, outside of its cron schedule.
+// Paused tasks can still be run manually.
+func (s *Scheduler) RunTaskNow(taskID uint) error {
+	task, err := s.loadTask(taskID)
+	if err != nil {
+		return err
+	}
+	if task.Status == models.StatusRunning || task.Status == models.StatusQueued {
+		return ErrTaskAlreadyRunning
+	}
+	log.Infof("Running task %d on demand", task.ID)
+	s.runTask(task)
+	return nil
+}
+
+// RescheduleTask reloads the task and re-registers it with gocron so that
+// changes to its schedule or paused state take effect without a restart
+func (s *Scheduler) RescheduleTask(taskID uint) error {
+	task, err := s.loadTask(taskID)
+	if err != nil {
+		return err
+	}
+	return s.scheduleTask(task)
+}
+
+// SyncProviderTasks registers the tasks for an enabled provider platform
+// and removes them from the scheduler if it is disabled or archived
+func (s *Scheduler) SyncProviderTasks(provider *models.ProviderPlatform) error {
+	if provider.State != models.Enabled {
+		return s.UnscheduleProviderTasks(provider.ID)
+	}
+	tasks, err := s.generateTasksForProvider(provider)
+	if err != nil {
+		return err
+	}
+	for idx := range tasks {
+		if err := s.scheduleTask(&tasks[idx]); err != nil {
+			log.Errorf("failed to schedule task %d for provider %d: %v", tasks[idx].ID, provider.ID, err)
+			return err
+		}
+	}
+	log.Infof("Scheduled %d tasks for provider %d", len(tasks), provider.ID)
+	return nil
+}
+
+func (s *Scheduler) UnscheduleProviderTasks(providerID uint) error {
+	var taskIDs []uint
+	if err := s.db.Model(&models.RunnableTask{}).Where("provider_platform_id = ?", providerID).Pluck("id", &taskIDs).Error; err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range taskIDs {
+		s.removeJobLocked(id)
+	}
+	return nil
+}
+
+// RunProviderTasks runs the tasks of a provider platform for the given jobs immediately.
+// Tasks that are already queued or running are left alone, they will pick up any reset cursors
+// on their next run. Returns the number of tasks that were queued.
+func (s *Scheduler) RunProviderTasks(providerID uint, jobs []models.JobType) (int, error) {
+	var taskIDs []uint
+	if err := s.db.Model(&models.RunnableTask{}).
+		Joins("JOIN cron_jobs cj ON cj.id = runnable_tasks.job_id").
+		Where("runnable_tasks.provider_platform_id = ? AND cj.name IN (?)", providerID, jobs).
+		Order("runnable_tasks.id").
+		Pluck("runnable_tasks.id", &taskIDs).Error; err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, id := range taskIDs {
+		if err := s.RunTaskNow(id); err != nil {
+			if errors.Is(err, ErrTaskAlreadyRunning) {
+				continue
+			}
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+func (s *Scheduler) loadTask(taskID uint) (*models.RunnableTask, error) {
+	var task models.RunnableTask
+	if err := s.db.Model(&models.RunnableTask{}).Preload("Job").Preload("Provider").First(&task, taskID).Error; err != nil {
+		return nil, err
+	}
+	provID := task.ProviderPlatformID
+	if provID == nil {
+		provID = task.OpenContentProviderID
+	}
+	task.Prepare(provID)
+	return &task, nil
+}
@@ -10,33 +10,57 @@ import (
 )
 
 func (s *Scheduler) runTask(task *models.RunnableTask) {
-	// publish the task to the nats server, update the satus of the task to 'running'
+	// publish the task to the task work queue, the task stays 'queued' until the middleware picks it up
 	task.Parameters["last_run"] = task.LastRun
 	task.Parameters["job_id"] = task.JobID
+	task.Parameters["task_id"] = task.ID
 	jobType := task.Parameters["job_type"].(string)
+	task.Status = models.StatusQueued
+	if err := s.db.Model(&models.RunnableTask{}).Where("id = ?", task.ID).Update("status", models.StatusQueued).Error; err != nil {
+		log.Errorf("failed to update task status: %v", err)
+		return
+	}
+	run := models.NewJobRun(task)
+	if err := s.db.Create(run).Error; err != nil {
+		log.Errorf("failed to create job run for task %d: %v", task.ID, err)
+	}
+	// the middleware only updates the run it was sent, so runs of other messages for the task aren't touched
+	task.Parameters["job_run_id"] = run.ID
 	params, err := json.Marshal(task.Parameters)
 	if err != nil {
 		log.Errorf("failed to marshal params: %v", err)
-		return
-	}
-	task.Status = models.StatusRunning
-	if err := s.db.Model(&models.RunnableTask{}).Where("id = ?", task.ID).Update("status", models.StatusRunning).Error; err != nil {
-		log.Errorf("failed to update task status: %v", err)
+		s.failJobRun(task, run, err)
 		return
 	}
 	msg := nats.NewMsg(models.JobType(jobType).PubName())
 	msg.Data = params
-	if err := s.nats.PublishMsg(msg); err != nil {
+	// the ack from the stream means the job is persisted and will be delivered once the middleware is subscribed
+	if _, err := s.js.PublishMsg(msg); err != nil {
 		log.Errorf("failed to publish job: %v", err)
+		s.failJobRun(task, run, err)
 		return
 	}
 	log.Info("Published job: ", jobType)
 }
 
+// if the job was never handed off to the middleware, nothing else will close out the run
+func (s *Scheduler) failJobRun(task *models.RunnableTask, run *models.JobRun, jobErr error) {
+	run.Finish(jobErr)
+	if run.ID != 0 {
+		if err := s.db.Save(run).Error; err != nil {
+			log.Errorf("failed to update job run: %v", err)
+		}
+	}
+	task.Status = models.StatusPending
+	if err := s.db.Model(&models.RunnableTask{}).Where("id = ?", task.ID).Update("status", models.StatusPending).Error; err != nil {
+		log.Errorf("failed to update task status: %v", err)
+	}
+}
+
 func (s *Scheduler) generateTasks() ([]models.RunnableTask, error) {
 	allTasks := make([]models.RunnableTask, 0, 10)
-	if dailyProgramHistoryTasks, err := s.generateProgramHistoryTasks(); err == nil {
-		allTasks = append(allTasks, dailyProgramHistoryTasks...)
+	if programTasks, err := s.generateProgramManagementTasks(); err == nil {
+		allTasks = append(allTasks, programTasks...)
 	}
 	if ocpTasks, err := s.generateOpenContentProviderTasks(); err == nil {
 		allTasks = append(allTasks, ocpTasks...)
@@ -51,20 +75,22 @@ func (s *Scheduler) generateTasks() ([]models.RunnableTask, error) {
 	return allTasks, nil
 }
 
-func (s *Scheduler) generateProgramHistoryTasks() ([]models.RunnableTask, error) {
-	tasksToRun := make([]models.RunnableTask, 0, 1)
-	job, err := s.createIfNotExists(models.DailyProgHistoryJob)
-	if err != nil {
-		log.Errorf("failed to create job: %v", err)
-		return nil, err
-	}
-	newTask := models.RunnableTask{JobID: job.ID, Status: models.StatusPending}
-	err = s.intoTask(job, nil, &newTask)
-	if err != nil {
-		log.Errorf("failed to create task: %v", err)
-		return nil, err
+func (s *Scheduler) generateProgramManagementTasks() ([]models.RunnableTask, error) {
+	tasksToRun := make([]models.RunnableTask, 0, len(models.AllProgramManagementJobs))
+	for _, jobType := range models.AllProgramManagementJobs {
+		job, err := s.createIfNotExists(jobType)
+		if err != nil {
+			log.Errorf("failed to create job: %v", err)
+			return nil, err
+		}
+		newTask := models.RunnableTask{JobID: job.ID, Status: models.StatusPending}
+		err = s.intoTask(job, nil, &newTask)
+		if err != nil {
+			log.Errorf("failed to create task: %v", err)
+			return nil, err
+		}
+		tasksToRun = append(tasksToRun, newTask)
 	}
-	tasksToRun = append(tasksToRun, newTask)
 	log.Infof("Generated %d total tasks", len(tasksToRun))
 	return tasksToRun, nil
 }
@@ -119,26 +145,36 @@ func (s *Scheduler) generateProviderTasks() ([]models.RunnableTask, error) {
 	log.Infof("Found %d active providers", len(providers))
 	tasksToRun := make([]models.RunnableTask, 0)
 	for _, provider := range providers {
-		provJobs := provider.GetDefaultCronJobs()
-		for _, jobType := range provJobs {
-			created, err := s.createIfNotExists(jobType)
-			if err != nil {
-				log.Errorf("failed to create job: %v", err)
-				return nil, err
-			}
-			newTask := models.RunnableTask{JobID: created.ID, ProviderPlatformID: &provider.ID, Status: models.StatusPending}
-			err = s.intoTask(created, &provider.ID, &newTask)
-			if err != nil {
-				log.Errorf("failed to create task: %v", err)
-				return nil, err
-			}
-			tasksToRun = append(tasksToRun, newTask)
+		provTasks, err := s.generateTasksForProvider(&provider)
+		if err != nil {
+			return nil, err
 		}
+		tasksToRun = append(tasksToRun, provTasks...)
 	}
 	log.Infof("Generated %d total tasks for %d providers", len(tasksToRun), len(providers))
 	return tasksToRun, nil
 }
 
+func (s *Scheduler) generateTasksForProvider(provider *models.ProviderPlatform) ([]models.RunnableTask, error) {
+	provJobs := provider.GetDefaultCronJobs()
+	tasksToRun := make([]models.RunnableTask, 0, len(provJobs))
+	for _, jobType := range provJobs {
+		created, err := s.createIfNotExists(jobType)
+		if err != nil {
+			log.Errorf("failed to create job: %v", err)
+			return nil, err
+		}
+		newTask := models.RunnableTask{JobID: created.ID, ProviderPlatformID: &provider.ID, Status: models.StatusPending}
+		err = s.intoTask(created, &provider.ID, &newTask)
+		if err != nil {
+			log.Errorf("failed to create task: %v", err)
+			return nil, err
+		}
+		tasksToRun = append(tasksToRun, newTask)
+	}
+	return tasksToRun, nil
+}
+
 func (s *Scheduler) intoTask(cj *models.CronJob, provId *uint, task *models.RunnableTask) error {
 	if task.ID == 0 {
 		tx := s.db.Model(&models.RunnableTask{}).Where("job_id = ?", cj.ID)
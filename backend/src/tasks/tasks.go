@@ -3,8 +3,10 @@ package tasks
 import (
 	"UnlockEdv2/src/models"
 	"fmt"
+	"sync"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -12,43 +14,38 @@ import (
 
 type Scheduler struct {
 	gocron.Scheduler
-	nats *nats.Conn
-	db   *gorm.DB
+	js nats.JetStreamContext
+	db *gorm.DB
+	mu sync.Mutex
+	// runnable task ID -> gocron job ID, so tasks can be paused or rescheduled at runtime
+	jobs map[uint]uuid.UUID
+	hour int
 }
 
-func InitScheduling(dev bool, nats *nats.Conn, db *gorm.DB) *Scheduler {
+func InitScheduling(dev bool, js nats.JetStreamContext, db *gorm.DB) *Scheduler {
 	scheduler, err := gocron.NewScheduler()
 	if err != nil {
 		log.Fatalf("Failed to create scheduler: %v", err)
 		return nil
 	}
-	runner := Scheduler{scheduler, nats, db}
+	runner := &Scheduler{Scheduler: scheduler, js: js, db: db, jobs: make(map[uint]uuid.UUID), hour: 1}
 	tasks, err := runner.generateTasks()
 	if err != nil {
 		log.Fatalf("failed to generate tasks: %v", err)
 		return nil
 	}
-	hour := 1
-	for _, task := range tasks {
-		if task.Job == nil {
-			log.Errorf("Task %v has no job", task.ID)
-			continue
-		}
-		_, err := runner.NewJob(gocron.CronJob(getCronSchedule(&task, hour), false), gocron.NewTask(runner.runTask, &task))
-		if err != nil {
+	for idx := range tasks {
+		if err := runner.scheduleTask(&tasks[idx]); err != nil {
 			log.Errorf("Failed to create job: %v", err)
 			continue
 		}
-		hour++
-		if hour > 23 {
-			hour = 1
-		}
 	}
+	runner.startWatchdog()
 	if !dev {
 		runner.execute()
 	}
 	runner.Start()
-	return &runner
+	return runner
 }
 
 func (s *Scheduler) Stop() error {
@@ -63,7 +60,7 @@ func (s *Scheduler) execute() {
 	}
 	log.Infof("Generated %v tasks", tasks)
 	for _, task := range tasks {
-		if task.Provider != nil && task.Provider.Type == models.Brightspace {
+		if task.Paused || (task.Provider != nil && task.Provider.Type == models.Brightspace) {
 			continue
 		}
 		log.Infof("Running task: %v", task.Job.Name)
@@ -71,7 +68,46 @@ func (s *Scheduler) execute() {
 	}
 }
 
+// scheduleTask registers the task with gocron, replacing any job already registered for it.
+// Paused tasks are only removed from the scheduler.
+func (s *Scheduler) scheduleTask(task *models.RunnableTask) error {
+	if task.Job == nil {
+		return fmt.Errorf("task %v has no job", task.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeJobLocked(task.ID)
+	if task.Paused {
+		log.Infof("Task %d is paused, not scheduling", task.ID)
+		return nil
+	}
+	job, err := s.NewJob(gocron.CronJob(getCronSchedule(task, s.hour), false), gocron.NewTask(s.runTask, task))
+	if err != nil {
+		return err
+	}
+	s.jobs[task.ID] = job.ID()
+	s.hour++
+	if s.hour > 23 {
+		s.hour = 1
+	}
+	return nil
+}
+
+func (s *Scheduler) removeJobLocked(taskID uint) {
+	jobID, ok := s.jobs[taskID]
+	if !ok {
+		return
+	}
+	if err := s.RemoveJob(jobID); err != nil {
+		log.Warnf("failed to remove job for task %d: %v", taskID, err)
+	}
+	delete(s.jobs, taskID)
+}
+
 func getCronSchedule(task *models.RunnableTask, hour int) string {
+	if task.Schedule != "" {
+		return task.Schedule
+	}
 	if task.Provider != nil && task.Provider.Type == models.Brightspace {
 		return fmt.Sprintf("0 %d * * 4", hour)
 	} else {
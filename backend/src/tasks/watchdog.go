@@ -0,0 +1,167 @@
+package tasks
+
+import (
+	"UnlockEdv2/src/models"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	watchdogInterval = 5 * time.Minute
+	// the middleware cancels most jobs after 30 minutes, so anything past this was lost
+	defaultStuckTimeout = time.Hour
+	// messages wait in the work queue while the middleware is down, a run still queued after this
+	// long is closed so the task isn't held up. The middleware drops the message if it is delivered later
+	stuckQueuedTimeout   = 6 * time.Hour
+	maxStuckTaskRetries  = 3
+	stuckTaskBaseBackoff = 5 * time.Minute
+)
+
+var (
+	ErrTaskTimedOut       = errors.New("task exceeded its timeout and was marked failed by the watchdog")
+	ErrTaskNeverDelivered = errors.New("task was not picked up by the middleware and was marked failed by the watchdog")
+
+	// only tasks have runs to watch, one off jobs like add_videos are published without one
+	stuckTimeouts = map[models.JobType]time.Duration{
+		// the middleware gives retry_video_downloads 4 hours (VIDEO_CANCEL_TIMEOUT)
+		models.RetryVideoDownloadsJob: 5 * time.Hour,
+	}
+
+	stuckTasks = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "scheduler_stuck_tasks",
+			Help: "Number of tasks found queued or running past their timeout during the last watchdog sweep.",
+		},
+		[]string{"job", "status"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(stuckTasks)
+}
+
+func stuckTimeout(jobType models.JobType, status models.JobStatus) time.Duration {
+	if status == models.StatusQueued {
+		return stuckQueuedTimeout
+	}
+	if timeout, ok := stuckTimeouts[jobType]; ok {
+		return timeout
+	}
+	return defaultStuckTimeout
+}
+
+// retrying stuck tasks is opt-in, as some jobs are not safe to run twice against a slow provider
+func retryStuckTasks() bool {
+	retry, err := strconv.ParseBool(os.Getenv("RETRY_STUCK_TASKS"))
+	return err == nil && retry
+}
+
+func (s *Scheduler) startWatchdog() {
+	_, err := s.NewJob(gocron.DurationJob(watchdogInterval), gocron.NewTask(s.checkStuckTasks),
+		gocron.WithName("stuck_task_watchdog"), gocron.WithSingletonMode(gocron.LimitModeReschedule))
+	if err != nil {
+		log.Errorf("Failed to create stuck task watchdog: %v", err)
+	}
+}
+
+/**
+* checkStuckTasks finds runs that have been queued or running longer than the timeout for their job type and
+* marks the run and its task as failed. The work queue doesn't cover these: once the run is closed the middleware
+* terminates any late delivery of its message. If RETRY_STUCK_TASKS is set the task is re-published with backoff under
+* a new job run, otherwise it runs again on its next schedule
+**/
+func (s *Scheduler) checkStuckTasks() {
+	runs := make([]models.JobRun, 0)
+	if err := s.db.Model(&models.JobRun{}).Preload("Job").
+		Where("status IN ?", []models.JobStatus{models.StatusQueued, models.StatusRunning}).
+		Find(&runs).Error; err != nil {
+		log.Errorf("watchdog failed to fetch running jobs: %v", err)
+		return
+	}
+	stuckTasks.Reset()
+	now := time.Now()
+	for idx := range runs {
+		run := &runs[idx]
+		jobType := models.JobType("")
+		if run.Job != nil {
+			jobType = models.JobType(run.Job.Name)
+		}
+		if now.Sub(run.StartedAt) < stuckTimeout(jobType, run.Status) {
+			continue
+		}
+		stuckTasks.WithLabelValues(string(jobType), string(run.Status)).Inc()
+		log.Warnf("task %d (%s) has been %s since %v, marking failed", run.TaskID, jobType, run.Status, run.StartedAt)
+		if err := s.failStuckRun(run); err != nil {
+			log.Errorf("watchdog failed to update task %d: %v", run.TaskID, err)
+			continue
+		}
+		if retryStuckTasks() {
+			s.retryStuckTask(run.TaskID)
+		}
+	}
+}
+
+func (s *Scheduler) failStuckRun(run *models.JobRun) error {
+	if run.Status == models.StatusQueued {
+		run.Finish(ErrTaskNeverDelivered)
+	} else {
+		run.Finish(ErrTaskTimedOut)
+	}
+	tx := s.db.Begin()
+	if err := tx.Omit("Task", "Job").Save(run).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(&models.RunnableTask{}).
+		Where("id = ? AND status IN ?", run.TaskID, []models.JobStatus{models.StatusQueued, models.StatusRunning}).
+		Update("status", models.StatusFailed).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// backoff doubles with every consecutive stuck run since the task last succeeded
+func (s *Scheduler) retryStuckTask(taskID uint) {
+	var lastSuccess models.JobRun
+	if err := s.db.Model(&models.JobRun{}).
+		Where("task_id = ? AND status = ?", taskID, models.StatusSucceeded).
+		Order("started_at DESC").
+		Limit(1).
+		Find(&lastSuccess).Error; err != nil {
+		log.Errorf("watchdog failed to fetch last successful run for task %d: %v", taskID, err)
+		return
+	}
+	var stuck int64
+	if err := s.db.Model(&models.JobRun{}).
+		Where("task_id = ? AND status = ? AND error_message IN ? AND started_at > ?", taskID, models.StatusFailed,
+			[]string{ErrTaskTimedOut.Error(), ErrTaskNeverDelivered.Error()}, lastSuccess.StartedAt).
+		Count(&stuck).Error; err != nil {
+		log.Errorf("watchdog failed to count stuck runs for task %d: %v", taskID, err)
+		return
+	}
+	if stuck > maxStuckTaskRetries {
+		log.Warnf("task %d has been stuck %d times in a row, not retrying", taskID, stuck)
+		return
+	}
+	backoff := stuckTaskBaseBackoff * time.Duration(math.Pow(2, float64(stuck-1)))
+	// RunTaskNow publishes a new message under a new job run, so the middleware can't mistake it for the closed one
+	_, err := s.NewJob(gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(time.Now().Add(backoff))),
+		gocron.NewTask(func() {
+			if err := s.RunTaskNow(taskID); err != nil {
+				log.Errorf("watchdog failed to retry task %d: %v", taskID, err)
+			}
+		}))
+	if err != nil {
+		log.Errorf("watchdog failed to schedule retry for task %d: %v", taskID, err)
+		return
+	}
+	log.Infof("retrying task %d in %v", taskID, backoff)
+}
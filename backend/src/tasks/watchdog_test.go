@@ -0,0 +1,100 @@
+package tasks
+
+import (
+	"UnlockEdv2/src/database"
+	"UnlockEdv2/src/models"
+	"testing"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStuckTasks(t *testing.T) {
+	db := database.InitDB(true).DB
+	s := &Scheduler{db: db}
+	job := models.CronJob{Name: string(models.GetCoursesJob)}
+	require.NoError(t, db.Create(&job).Error)
+	videoJob := models.CronJob{Name: string(models.RetryVideoDownloadsJob)}
+	require.NoError(t, db.Create(&videoJob).Error)
+
+	newRun := func(job *models.CronJob, status models.JobStatus, startedAt time.Time) (*models.RunnableTask, *models.JobRun) {
+		task := models.RunnableTask{JobID: job.ID, Status: status}
+		require.NoError(t, db.Create(&task).Error)
+		run := models.NewJobRun(&task)
+		run.Status = status
+		run.StartedAt = startedAt
+		require.NoError(t, db.Create(run).Error)
+		return &task, run
+	}
+	now := time.Now()
+	stuckTask, stuckRun := newRun(&job, models.StatusRunning, now.Add(-2*time.Hour))
+	_, recentRun := newRun(&job, models.StatusRunning, now.Add(-10*time.Minute))
+	_, videoRun := newRun(&videoJob, models.StatusRunning, now.Add(-2*time.Hour))
+	lostTask, lostRun := newRun(&job, models.StatusQueued, now.Add(-stuckQueuedTimeout-time.Minute))
+	_, waitingRun := newRun(&job, models.StatusQueued, now.Add(-2*time.Hour))
+
+	s.checkStuckTasks()
+
+	runStatus := func(run *models.JobRun) (models.JobStatus, string) {
+		var found models.JobRun
+		require.NoError(t, db.First(&found, run.ID).Error)
+		return found.Status, found.ErrorMessage
+	}
+	status, message := runStatus(stuckRun)
+	require.Equal(t, models.StatusFailed, status)
+	require.Equal(t, ErrTaskTimedOut.Error(), message)
+	status, message = runStatus(lostRun)
+	require.Equal(t, models.StatusFailed, status, "runs never picked up by the middleware are closed")
+	require.Equal(t, ErrTaskNeverDelivered.Error(), message)
+	for _, run := range []*models.JobRun{recentRun, videoRun, waitingRun} {
+		status, _ = runStatus(run)
+		require.NotEqual(t, models.StatusFailed, status, "run %d is within its timeout", run.ID)
+	}
+	for _, task := range []*models.RunnableTask{stuckTask, lostTask} {
+		var found models.RunnableTask
+		require.NoError(t, db.First(&found, task.ID).Error)
+		require.Equal(t, models.StatusFailed, found.Status)
+	}
+
+	var runs int64
+	require.NoError(t, db.Model(&models.JobRun{}).Where("task_id IN ?", []uint{stuckTask.ID, lostTask.ID}).Count(&runs).Error)
+	require.Equal(t, int64(2), runs, "stuck tasks are only re-published when RETRY_STUCK_TASKS is set")
+}
+
+func TestRetryStuckTask(t *testing.T) {
+	db := database.InitDB(true).DB
+	cron, err := gocron.NewScheduler()
+	require.NoError(t, err)
+	cron.Start()
+	defer func() { _ = cron.Shutdown() }()
+	s := &Scheduler{Scheduler: cron, db: db}
+	job := models.CronJob{Name: string(models.GetCoursesJob)}
+	require.NoError(t, db.Create(&job).Error)
+	task := models.RunnableTask{JobID: job.ID, Status: models.StatusFailed}
+	require.NoError(t, db.Create(&task).Error)
+
+	addRun := func(jobErr error, startedAt time.Time) {
+		run := models.NewJobRun(&task)
+		run.StartedAt = startedAt
+		run.Finish(jobErr)
+		require.NoError(t, db.Create(run).Error)
+	}
+	now := time.Now()
+	addRun(ErrTaskTimedOut, now.Add(-5*time.Hour))
+	addRun(nil, now.Add(-4*time.Hour))
+	addRun(ErrTaskNeverDelivered, now.Add(-3*time.Hour))
+	addRun(ErrTaskTimedOut, now.Add(-2*time.Hour))
+
+	s.retryStuckTask(task.ID)
+	jobs := s.Jobs()
+	require.Len(t, jobs, 1, "the task is retried while under the retry limit")
+	nextRun, err := jobs[0].NextRun()
+	require.NoError(t, err)
+	require.WithinDuration(t, now.Add(2*stuckTaskBaseBackoff), nextRun, time.Minute, "backoff doubles for each stuck run since the last success")
+
+	addRun(ErrTaskTimedOut, now.Add(-time.Hour))
+	addRun(ErrTaskTimedOut, now.Add(-30*time.Minute))
+	s.retryStuckTask(task.ID)
+	require.Len(t, s.Jobs(), 1, "tasks stuck more than the retry limit aren't retried")
+}
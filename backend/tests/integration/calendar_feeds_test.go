@@ -0,0 +1,187 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/teambition/rrule-go"
+)
+
+func TestCalendarFeeds(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("feedadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID}
+
+	program, err := env.CreateTestProgram("Feed Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	class := newClass(program, facility)
+	class.Name = "Evening GED"
+	require.NoError(t, env.DB.Create(&class).Error)
+	other := newClass(program, facility)
+	other.Name = "Morning Math"
+	other.InstructorName = "Another Instructor"
+	require.NoError(t, env.DB.Create(&other).Error)
+
+	// wednesdays at 02:00 UTC are tuesday evenings in Chicago
+	event := models.ProgramClassEvent{ClassID: class.ID, Duration: "1h30m0s", RecurrenceRule: "DTSTART:20250108T020000Z\nRRULE:FREQ=WEEKLY;BYDAY=WE;COUNT=10", Room: "Room 1"}
+	require.NoError(t, env.DB.Create(&event).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEvent{ClassID: other.ID, Duration: "1h0m0s", RecurrenceRule: "DTSTART:20250106T150000Z\nRRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=4", Room: "Room 2"}).Error)
+	for _, override := range []models.ProgramClassEventOverride{
+		{EventID: event.ID, Duration: "1h30m0s", OverrideRrule: "DTSTART:20250115T020000Z\nRRULE:FREQ=DAILY;COUNT=1", IsCancelled: true},
+		{EventID: event.ID, Duration: "1h30m0s", OverrideRrule: "DTSTART:20250122T020000Z\nRRULE:FREQ=DAILY;COUNT=1", IsCancelled: true, Reason: "rescheduled"},
+		{EventID: event.ID, Duration: "2h0m0s", OverrideRrule: "DTSTART:20250123T150000Z\nRRULE:FREQ=DAILY;COUNT=1", Room: "Library"},
+	} {
+		require.NoError(t, env.DB.Create(&override).Error)
+	}
+
+	createFeed := func(t *testing.T, body map[string]any) models.CalendarFeed {
+		feed := NewRequest[models.CalendarFeed](env.Client, t, http.MethodPost, "/api/calendar-feeds", body).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusCreated).
+			GetData()
+		require.NotEmpty(t, feed.Token)
+		return feed
+	}
+	getFeed := func(t *testing.T, token string) string {
+		return NewRequest[string](env.Client, t, http.MethodGet, fmt.Sprintf("/api/calendar-feeds/%s/feed.ics", token), nil).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+	}
+
+	t.Run("Reject feeds missing their class or instructor", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, "/api/calendar-feeds", map[string]any{"feed_type": "class"}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		NewRequest[any](env.Client, t, http.MethodPost, "/api/calendar-feeds", map[string]any{"feed_type": "instructor"}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Class feed has the series in UTC with cancellations and reschedules", func(t *testing.T) {
+		feed := createFeed(t, map[string]any{"feed_type": "class", "class_id": class.ID})
+		body := getFeed(t, feed.Token)
+		lines := strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n")
+		require.Equal(t, "BEGIN:VCALENDAR", lines[0])
+		require.Equal(t, "END:VCALENDAR", lines[len(lines)-1])
+		for _, expected := range []string{
+			"TZID:America/Chicago",
+			"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU",
+			"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU",
+			fmt.Sprintf("UID:class-event-%d@unlocked", event.ID),
+			"SUMMARY:Evening GED",
+			"DTSTART:20250108T020000Z",
+			"RRULE:FREQ=WEEKLY;COUNT=10;BYDAY=WE",
+			"DURATION:PT1H30M",
+			"EXDATE:20250115T020000Z",
+			"RECURRENCE-ID:20250122T020000Z",
+			"DTSTART;TZID=America/Chicago:20250123T090000",
+			"DURATION:PT2H",
+			"LOCATION:Library",
+		} {
+			require.Contains(t, lines, expected)
+		}
+		require.NotContains(t, body, "EXDATE:20250122T020000Z")
+		require.NotContains(t, body, "Morning Math")
+	})
+
+	t.Run("Instructor feed only has the instructor's classes", func(t *testing.T) {
+		feed := createFeed(t, map[string]any{"feed_type": "instructor", "instructor_name": "another instructor"})
+		body := getFeed(t, feed.Token)
+		require.Contains(t, body, "SUMMARY:Morning Math")
+		require.NotContains(t, body, "Evening GED")
+	})
+
+	t.Run("Revoked feeds are no longer served", func(t *testing.T) {
+		feed := createFeed(t, map[string]any{"feed_type": "facility"})
+		body := getFeed(t, feed.Token)
+		require.Contains(t, body, "SUMMARY:Morning Math")
+		require.Contains(t, body, "SUMMARY:Evening GED")
+
+		feeds := NewRequest[[]models.CalendarFeed](env.Client, t, http.MethodGet, "/api/calendar-feeds", nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, feeds, 3)
+		require.Empty(t, feeds[0].Token)
+
+		NewRequest[any](env.Client, t, http.MethodDelete, fmt.Sprintf("/api/calendar-feeds/%d", feed.ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		NewRequest[any](env.Client, t, http.MethodGet, fmt.Sprintf("/api/calendar-feeds/%s/feed.ics", feed.Token), nil).
+			Do().
+			ExpectStatus(http.StatusNotFound)
+	})
+
+	t.Run("Series crossing a daylight saving change keep the sessions the app shows", func(t *testing.T) {
+		spring := newClass(program, facility)
+		spring.Name = "Spring Reading"
+		require.NoError(t, env.DB.Create(&spring).Error)
+		// the clocks change on march 9th, the sessions stay at 02:00 UTC so they move from 8pm to 9pm in Chicago
+		springEvent := models.ProgramClassEvent{ClassID: spring.ID, Duration: "1h0m0s", RecurrenceRule: "DTSTART:20250304T020000Z\nRRULE:FREQ=WEEKLY;BYDAY=TU;COUNT=4"}
+		require.NoError(t, env.DB.Create(&springEvent).Error)
+		for _, override := range []models.ProgramClassEventOverride{
+			{EventID: springEvent.ID, Duration: "1h0m0s", OverrideRrule: "DTSTART:20250318T020000Z\nRRULE:FREQ=DAILY;COUNT=1", IsCancelled: true},
+			{EventID: springEvent.ID, Duration: "1h0m0s", OverrideRrule: "DTSTART:20250325T020000Z\nRRULE:FREQ=DAILY;COUNT=1", IsCancelled: true, Reason: "rescheduled"},
+			{EventID: springEvent.ID, Duration: "1h0m0s", OverrideRrule: "DTSTART:20250326T150000Z\nRRULE:FREQ=DAILY;COUNT=1"},
+		} {
+			require.NoError(t, env.DB.Create(&override).Error)
+		}
+		feed := createFeed(t, map[string]any{"feed_type": "class", "class_id": spring.ID})
+		lines := strings.Split(getFeed(t, feed.Token), "\r\n")
+
+		// the series is the first VEVENT, expand it the way a calendar client would
+		var series []string
+		recurrenceID := ""
+		inSeries := false
+		for _, line := range lines {
+			switch {
+			case line == "BEGIN:VEVENT":
+				inSeries = series == nil
+			case line == "END:VEVENT" && inSeries:
+				inSeries = false
+			case inSeries && (strings.HasPrefix(line, "DTSTART") || strings.HasPrefix(line, "RRULE") || strings.HasPrefix(line, "EXDATE")):
+				series = append(series, line)
+			case strings.HasPrefix(line, "RECURRENCE-ID:"):
+				recurrenceID = strings.TrimPrefix(line, "RECURRENCE-ID:")
+			}
+		}
+		set, err := rrule.StrToRRuleSet(strings.Join(series, "\n"))
+		require.NoError(t, err)
+		sessions := set.All()
+		require.Equal(t, []time.Time{
+			time.Date(2025, 3, 4, 2, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 11, 2, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 25, 2, 0, 0, 0, time.UTC),
+		}, utcTimes(sessions), "the cancelled session after the change is excluded")
+		rescheduled, err := time.Parse("20060102T150405Z", recurrenceID)
+		require.NoError(t, err)
+		require.Contains(t, utcTimes(set.GetRRule().All()), rescheduled, "the reschedule replaces a session of the series")
+		require.Contains(t, lines, "DTSTART;TZID=America/Chicago:20250326T100000")
+	})
+}
+
+func utcTimes(times []time.Time) []time.Time {
+	utc := make([]time.Time, len(times))
+	for idx := range times {
+		utc[idx] = times[idx].UTC()
+	}
+	return utc
+}
@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletionCertificates(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("certificateadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	adminClaims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+	resident, err := env.CreateTestUser("certificateresident", models.Student, facility.ID, "9201")
+	require.NoError(t, err)
+	residentClaims := &handlers.Claims{Role: models.Student, UserID: resident.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+
+	program, err := env.CreateTestProgram("Certificate Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	class := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&class).Error)
+	rule := fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=WEEKLY;COUNT=3", time.Now().UTC().AddDate(0, 0, -21).Format("20060102T150405Z"))
+	event := models.ProgramClassEvent{ClassID: class.ID, Duration: "1h30m0s", RecurrenceRule: rule, Room: "Room 1"}
+	require.NoError(t, env.DB.Create(&event).Error)
+	for week, status := range []models.Attendance{models.Present, models.Present, models.Absent_Excused} {
+		require.NoError(t, env.DB.Create(&models.ProgramClassEventAttendance{
+			EventID:          event.ID,
+			UserID:           resident.ID,
+			Date:             time.Now().AddDate(0, 0, -21+7*week).Format("2006-01-02"),
+			AttendanceStatus: status,
+		}).Error)
+	}
+	completion := models.ProgramCompletion{
+		UserID:           resident.ID,
+		ProgramClassID:   class.ID,
+		FacilityName:     facility.Name,
+		CreditType:       "Completion",
+		AdminEmail:       "certificateadmin@unlocked.v2",
+		ProgramName:      program.Name,
+		ProgramID:        program.ID,
+		ProgramClassName: class.Name,
+	}
+	require.NoError(t, env.DB.Create(&completion).Error)
+	certificateURL := fmt.Sprintf("/api/users/%d/program-completions/%d/certificate", resident.ID, completion.ID)
+
+	t.Run("Reject templates that can't be rendered", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPut, fmt.Sprintf("/api/programs/%d/certificate-template", program.ID), map[string]any{
+			"title": "Certificate of Achievement",
+			"body":  "{{.NotAField}} completed {{.ProgramName}}",
+		}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Admins configure the template of a program", func(t *testing.T) {
+		NewRequest[models.CertificateTemplate](env.Client, t, http.MethodPut, fmt.Sprintf("/api/programs/%d/certificate-template", program.ID), map[string]any{
+			"title":        "Certificate of Achievement",
+			"body":         "{{.ResidentName}} completed {{.Hours}} hours of {{.ProgramName}}",
+			"signer_name":  "Jane Warden",
+			"signer_title": "Education Director",
+		}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		tmpl := NewRequest[models.CertificateTemplate](env.Client, t, http.MethodGet, fmt.Sprintf("/api/programs/%d/certificate-template", program.ID), nil).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, "Certificate of Achievement", tmpl.Title)
+		require.Equal(t, "Jane Warden", tmpl.SignerName)
+	})
+
+	var code string
+	t.Run("Residents download the certificates of their completions", func(t *testing.T) {
+		certificate := NewRequest[string](env.Client, t, http.MethodGet, certificateURL, nil).
+			WithTestClaims(residentClaims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.True(t, strings.HasPrefix(certificate, "%PDF"))
+
+		issued := models.CompletionCertificate{}
+		require.NoError(t, env.DB.Where("program_completion_id = ?", completion.ID).First(&issued).Error)
+		code = issued.VerificationCode
+		require.Len(t, code, 19)
+
+		// every download carries the same code
+		NewRequest[string](env.Client, t, http.MethodGet, certificateURL, nil).
+			WithTestClaims(residentClaims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK)
+		var count int64
+		require.NoError(t, env.DB.Model(&models.CompletionCertificate{}).Count(&count).Error)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("Certificates are verified by their code", func(t *testing.T) {
+		data := NewRequest[models.CertificateData](env.Client, t, http.MethodGet, "/api/certificates/verify/"+strings.ToLower(code), nil).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, resident.NameFirst+" "+resident.NameLast, data.ResidentName)
+		require.Equal(t, program.Name, data.ProgramName)
+		require.Equal(t, 3.0, data.Hours)
+
+		NewRequest[any](env.Client, t, http.MethodGet, "/api/certificates/verify/AAAA-BBBB-CCCC-DDDD", nil).
+			Do().
+			ExpectStatus(http.StatusNotFound)
+	})
+
+	t.Run("Only the completions of the user are found", func(t *testing.T) {
+		other, err := env.CreateTestUser("certificateother", models.Student, facility.ID, "9202")
+		require.NoError(t, err)
+		NewRequest[any](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/program-completions/%d/certificate", other.ID, completion.ID), nil).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassInstructors(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("instructorsadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	adminClaims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+	instructor, err := env.CreateTestUser("volunteerinstructor", models.Instructor, facility.ID, "")
+	require.NoError(t, err)
+	instructorClaims := &handlers.Claims{Role: models.Instructor, UserID: instructor.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+	resident, err := env.CreateTestUser("instructorsresident", models.Student, facility.ID, "8101")
+	require.NoError(t, err)
+
+	program, err := env.CreateTestProgram("Instructor Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	assigned := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&assigned).Error)
+	other := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&other).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: assigned.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+	rule := fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=WEEKLY;COUNT=10", time.Now().UTC().AddDate(0, 0, -7).Format("20060102T150405Z"))
+	assignedEvent := models.ProgramClassEvent{ClassID: assigned.ID, Duration: "1h0m0s", RecurrenceRule: rule, Room: "Room 1"}
+	require.NoError(t, env.DB.Create(&assignedEvent).Error)
+	otherEvent := models.ProgramClassEvent{ClassID: other.ID, Duration: "1h0m0s", RecurrenceRule: rule, Room: "Room 2"}
+	require.NoError(t, env.DB.Create(&otherEvent).Error)
+
+	t.Run("Only instructors can be assigned to a class", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPut, fmt.Sprintf("/api/program-classes/%d/instructors", assigned.ID), map[string]any{"user_ids": []uint{resident.ID}}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+
+		instructors := NewRequest[[]models.User](env.Client, t, http.MethodPut, fmt.Sprintf("/api/program-classes/%d/instructors", assigned.ID), map[string]any{"user_ids": []uint{instructor.ID}}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, instructors, 1)
+		require.Equal(t, instructor.ID, instructors[0].ID)
+	})
+
+	t.Run("Instructors see the classes they are assigned to", func(t *testing.T) {
+		resp := NewRequest[[]models.ProgramClass](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/instructor-classes", instructor.ID), nil).
+			WithTestClaims(instructorClaims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		classes := resp.GetData()
+		require.Len(t, classes, 1)
+		require.Equal(t, assigned.ID, classes[0].ID)
+	})
+
+	t.Run("Instructors can take attendance and view the roster of their classes", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodGet, fmt.Sprintf("/api/program-classes/%d/enrollments", assigned.ID), nil).
+			WithTestClaims(instructorClaims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		attendance := []map[string]any{{"user_id": resident.ID, "date": time.Now().Format("2006-01-02"), "attendance_status": "present"}}
+		NewRequest[any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/program-classes/%d/events/%d/attendance", assigned.ID, assignedEvent.ID), attendance).
+			WithTestClaims(instructorClaims).
+			Do().
+			ExpectStatus(http.StatusOK)
+	})
+
+	t.Run("Instructors are resolved to their own classes and events", func(t *testing.T) {
+		resolve := func(claims *handlers.Claims, classID, eventID uint) bool {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetPathValue("class_id", fmt.Sprint(classID))
+			if eventID != 0 {
+				req.SetPathValue("event_id", fmt.Sprint(eventID))
+			}
+			req = req.WithContext(context.WithValue(req.Context(), handlers.ClaimsKey, claims))
+			return handlers.ClassInstructorResolver("class_id")(env.DB, req)
+		}
+		require.True(t, resolve(instructorClaims, assigned.ID, 0))
+		require.True(t, resolve(instructorClaims, assigned.ID, assignedEvent.ID))
+		require.False(t, resolve(instructorClaims, other.ID, 0))
+		// the event of another class through the path of their own class
+		require.False(t, resolve(instructorClaims, assigned.ID, otherEvent.ID))
+		require.True(t, resolve(adminClaims, other.ID, 0))
+	})
+
+	t.Run("Instructors cannot use admin routes", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodGet, fmt.Sprintf("/api/program-classes/%d/history", assigned.ID), nil).
+			WithTestClaims(instructorClaims).
+			Do().
+			ExpectStatus(http.StatusUnauthorized)
+		NewRequest[any](env.Client, t, http.MethodPut, fmt.Sprintf("/api/program-classes/%d/instructors", assigned.ID), map[string]any{"user_ids": []uint{}}).
+			WithTestClaims(instructorClaims).
+			Do().
+			ExpectStatus(http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,159 @@
+package integration
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestClassStatusTransitions(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	program, err := env.CreateTestProgram("Status Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	require.NoError(t, env.SetFacilitiesToProgram(program.ID, []uint{facility.ID}))
+	resident, err := env.CreateTestUser("statusresident", models.Student, facility.ID, "7101")
+	require.NoError(t, err)
+	waitlisted, err := env.CreateTestUser("statuswaitlisted", models.Student, facility.ID, "7102")
+	require.NoError(t, err)
+
+	now := time.Now()
+	dtstart := func(days int) string {
+		return now.UTC().AddDate(0, 0, days).Truncate(24 * time.Hour).Add(17 * time.Hour).Format("20060102T150405Z")
+	}
+	createClass := func(t *testing.T, status models.ClassStatus, startDays int, endDays *int, rule string) models.ProgramClass {
+		class := newClass(program, facility)
+		class.Status = status
+		class.StartDt = now.AddDate(0, 0, startDays)
+		class.EndDt = nil
+		if endDays != nil {
+			endDt := now.AddDate(0, 0, *endDays)
+			class.EndDt = &endDt
+		}
+		require.NoError(t, env.DB.Create(&class).Error)
+		if rule != "" {
+			require.NoError(t, env.DB.Create(&models.ProgramClassEvent{ClassID: class.ID, Duration: "1h0m0s", RecurrenceRule: rule, Room: "Room 1"}).Error)
+		}
+		return class
+	}
+	days := func(d int) *int { return &d }
+
+	starting := createClass(t, models.Scheduled, -1, days(30), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=30", dtstart(-1)))
+	upcoming := createClass(t, models.Scheduled, 5, days(30), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=25", dtstart(5)))
+	finished := createClass(t, models.Active, -30, days(-2), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=10", dtstart(-30)))
+	overrunning := createClass(t, models.Active, -30, days(-2), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=WEEKLY", dtstart(-30)))
+	endedEarly := createClass(t, models.Active, -30, days(10), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=5", dtstart(-30)))
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: finished.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+	position := 1
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: finished.ID, UserID: waitlisted.ID, EnrollmentStatus: models.EnrollmentWaitlisted, WaitlistPosition: &position}).Error)
+
+	require.NoError(t, env.DB.TransitionClassStatuses(context.Background(), now))
+
+	getClass := func(t *testing.T, id uint) models.ProgramClass {
+		class := models.ProgramClass{}
+		require.NoError(t, env.DB.First(&class, id).Error)
+		return class
+	}
+
+	t.Run("Classes become active on their start date", func(t *testing.T) {
+		require.Equal(t, models.Active, getClass(t, starting.ID).Status)
+		require.Nil(t, getClass(t, starting.ID).FlaggedReason)
+		require.Equal(t, models.Scheduled, getClass(t, upcoming.ID).Status)
+	})
+
+	t.Run("Classes are completed after their last session and end date", func(t *testing.T) {
+		require.Equal(t, models.Completed, getClass(t, finished.ID).Status)
+
+		completions := []models.ProgramCompletion{}
+		require.NoError(t, env.DB.Where("program_class_id = ?", finished.ID).Find(&completions).Error)
+		require.Len(t, completions, 1)
+		require.Equal(t, resident.ID, completions[0].UserID)
+
+		enrollments := []models.ProgramClassEnrollment{}
+		require.NoError(t, env.DB.Where("class_id = ?", finished.ID).Order("user_id").Find(&enrollments).Error)
+		require.Equal(t, models.EnrollmentCompleted, enrollments[0].EnrollmentStatus)
+		require.Equal(t, models.EnrollmentCancelled, enrollments[1].EnrollmentStatus)
+		require.Nil(t, enrollments[1].WaitlistPosition)
+	})
+
+	t.Run("Classes whose sessions and end date disagree are flagged", func(t *testing.T) {
+		overran := getClass(t, overrunning.ID)
+		require.Equal(t, models.Active, overran.Status)
+		require.NotNil(t, overran.FlaggedReason)
+		early := getClass(t, endedEarly.ID)
+		require.Equal(t, models.Active, early.Status)
+		require.NotNil(t, early.FlaggedReason)
+	})
+
+	t.Run("Every automated change is recorded in the class history", func(t *testing.T) {
+		var count int64
+		require.NoError(t, env.DB.Model(&models.ProgramClassesHistory{}).Where("table_name = ?", "program_classes").Count(&count).Error)
+		require.Equal(t, int64(4), count)
+
+		history := models.ProgramClassesHistory{}
+		require.NoError(t, env.DB.Where("parent_ref_id = ?", finished.ID).First(&history).Error)
+		require.Contains(t, string(history.BeforeUpdate), `"status":"Active"`)
+		require.Contains(t, string(history.AfterUpdate), `"status":"Completed"`)
+
+		// nothing changes when the job runs again
+		require.NoError(t, env.DB.TransitionClassStatuses(context.Background(), now))
+		require.NoError(t, env.DB.Model(&models.ProgramClassesHistory{}).Where("table_name = ?", "program_classes").Count(&count).Error)
+		require.Equal(t, int64(4), count)
+	})
+
+	t.Run("Flags are cleared once the class is paused", func(t *testing.T) {
+		require.NoError(t, env.DB.Model(&models.ProgramClass{}).Where("id = ?", endedEarly.ID).Update("status", models.Paused).Error)
+		require.NoError(t, env.DB.TransitionClassStatuses(context.Background(), now))
+		require.Nil(t, getClass(t, endedEarly.ID).FlaggedReason)
+	})
+
+	t.Run("A failed transition does not graduate the class's residents", func(t *testing.T) {
+		ending := createClass(t, models.Active, -30, days(-2), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=10", dtstart(-30)))
+		require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: ending.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+		require.NoError(t, env.DB.Callback().Create().Before("gorm:create").Register("test:fail_class_history", func(tx *gorm.DB) {
+			if tx.Statement.Table == "program_classes_history" {
+				_ = tx.AddError(errors.New("class history is unavailable"))
+			}
+		}))
+		require.Error(t, env.DB.TransitionClassStatuses(context.Background(), now))
+		require.NoError(t, env.DB.Callback().Create().Remove("test:fail_class_history"))
+
+		require.Equal(t, models.Active, getClass(t, ending.ID).Status)
+		var count int64
+		require.NoError(t, env.DB.Model(&models.ProgramCompletion{}).Where("program_class_id = ?", ending.ID).Count(&count).Error)
+		require.Zero(t, count)
+		enrollment := models.ProgramClassEnrollment{}
+		require.NoError(t, env.DB.First(&enrollment, "class_id = ?", ending.ID).Error)
+		require.Equal(t, models.Enrolled, enrollment.EnrollmentStatus)
+
+		// the next run completes the class once
+		require.NoError(t, env.DB.TransitionClassStatuses(context.Background(), now))
+		require.Equal(t, models.Completed, getClass(t, ending.ID).Status)
+		require.NoError(t, env.DB.Model(&models.ProgramCompletion{}).Where("program_class_id = ?", ending.ID).Count(&count).Error)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("A failed transition does not hold up the other classes", func(t *testing.T) {
+		stuck := createClass(t, models.Scheduled, -1, days(30), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=30", dtstart(-1)))
+		other := createClass(t, models.Scheduled, -1, days(30), fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=30", dtstart(-1)))
+		require.NoError(t, env.DB.Callback().Create().Before("gorm:create").Register("test:fail_class_history", func(tx *gorm.DB) {
+			if history, ok := tx.Statement.Dest.(*models.ProgramClassesHistory); ok && history.ParentRefID == stuck.ID {
+				_ = tx.AddError(errors.New("class history is unavailable"))
+			}
+		}))
+		require.ErrorContains(t, env.DB.TransitionClassStatuses(context.Background(), now), "class history is unavailable")
+		require.NoError(t, env.DB.Callback().Create().Remove("test:fail_class_history"))
+
+		require.Equal(t, models.Scheduled, getClass(t, stuck.ID).Status)
+		require.Equal(t, models.Active, getClass(t, other.ID).Status)
+	})
+}
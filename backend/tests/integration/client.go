@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"path"
@@ -62,6 +63,29 @@ func NewRequest[T any](client *Client, t *testing.T, method, endpoint string, bo
 	return &Request[T]{t: t, client: client, req: req, asJson: true}
 }
 
+// creates a multipart/form-data request with a single file in the given field
+func NewMultipartRequest[T any](client *Client, t *testing.T, method, endpoint, field, filename string, content []byte) *Request[T] {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(field, filename)
+	require.NoError(t, err, "failed to create multipart form file")
+	_, err = part.Write(content)
+	require.NoError(t, err, "failed to write multipart form file")
+	require.NoError(t, writer.Close(), "failed to close multipart writer")
+
+	fullURL, err := client.buildURL(endpoint)
+	require.NoError(t, err, "invalid base url or endpoint")
+
+	req, err := http.NewRequest(method, fullURL, body)
+	require.NoError(t, err, "failed to create http request")
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return &Request[T]{t: t, client: client, req: req, asJson: true}
+}
+
 func (r *Request[T]) WithTestClaims(claims any) *Request[T] {
 	r.t.Helper()
 	b, err := json.Marshal(claims)
@@ -169,6 +193,11 @@ func (c *Client) buildURL(endpoint string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("invalid base URL: %w", err)
 	}
-	u.Path = path.Join(u.Path, endpoint)
+	ep, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint: %w", err)
+	}
+	u.Path = path.Join(u.Path, ep.Path)
+	u.RawQuery = ep.RawQuery
 	return u.String(), nil
 }
@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEarnedTimeLedger(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("earnedtimeadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	adminClaims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+	resident, err := env.CreateTestUser("earnedtimeresident", models.Student, facility.ID, "9301")
+	require.NoError(t, err)
+	residentClaims := &handlers.Claims{Role: models.Student, UserID: resident.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+
+	program, err := env.CreateTestProgram("Earned Time Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{{CreditType: models.EarnedTime}}, true)
+	require.NoError(t, err)
+	require.NoError(t, env.SetFacilitiesToProgram(program.ID, []uint{facility.ID}))
+	otherProgram, err := env.CreateTestProgram("Completion Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{{CreditType: models.Completion}}, true)
+	require.NoError(t, err)
+	class := newClass(program, facility)
+	creditHours := int64(25)
+	class.CreditHours = &creditHours
+	require.NoError(t, env.DB.Create(&class).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: class.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+
+	balance := func(t *testing.T) models.EarnedTimeBalance {
+		return NewRequest[models.EarnedTimeBalance](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/earned-time", resident.ID), nil).
+			WithTestClaims(residentClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+	}
+	ruleURL := func(programID uint) string {
+		return fmt.Sprintf("/api/programs/%d/earned-time-rule", programID)
+	}
+
+	t.Run("Rules are only set on programs awarding earned-time credit", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPut, ruleURL(otherProgram.ID), map[string]any{"hours_per_day": 10}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		NewRequest[any](env.Client, t, http.MethodPut, ruleURL(program.ID), map[string]any{"hours_per_day": 0}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		rule := NewRequest[models.EarnedTimeRule](env.Client, t, http.MethodPut, ruleURL(program.ID), map[string]any{"hours_per_day": 10, "max_days": 2}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, 10.0, rule.HoursPerDay)
+	})
+
+	t.Run("Completions post the converted days", func(t *testing.T) {
+		require.NoError(t, env.DB.GraduateEnrollments(context.Background(), "earnedtimeadmin@unlocked.v2", []int{int(resident.ID)}, int(class.ID)))
+		got := balance(t)
+		// 25 hours at 10 hours a day, capped at 2 days
+		require.Equal(t, 2.0, got.BalanceDays)
+		require.Equal(t, 25.0, got.CreditHours)
+	})
+
+	var completionEntry models.EarnedTimeEntry
+	t.Run("Admins adjust the balance with a reason", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/users/%d/earned-time/adjustments", resident.ID), map[string]any{"days": 1.5}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		NewRequest[any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/users/%d/earned-time/adjustments", resident.ID), map[string]any{"days": -5, "reason": "Disciplinary forfeiture"}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusUnprocessableEntity)
+		NewRequest[any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/users/%d/earned-time/adjustments", resident.ID), map[string]any{"days": 1.5, "reason": "Credit for a transferred certificate"}).
+			WithTestClaims(residentClaims).
+			Do().
+			ExpectStatus(http.StatusUnauthorized)
+		NewRequest[any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/users/%d/earned-time/adjustments", resident.ID), map[string]any{"days": 1.5, "reason": "Credit for a transferred certificate"}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusCreated)
+		require.Equal(t, 3.5, balance(t).BalanceDays)
+
+		entries := NewRequest[[]models.EarnedTimeEntry](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/earned-time/entries", resident.ID), nil).
+			WithTestClaims(residentClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, entries, 2)
+		require.Equal(t, models.EarnedTimeAdjustment, entries[0].EntryType)
+		require.NotNil(t, entries[0].CreateUser)
+		require.Equal(t, models.EarnedTimeCompletion, entries[1].EntryType)
+		require.NotNil(t, entries[1].ProgramCompletionID)
+		completionEntry = entries[1]
+	})
+
+	t.Run("Entries are reversed once", func(t *testing.T) {
+		reversalURL := fmt.Sprintf("/api/users/%d/earned-time/entries/%d/reversal", resident.ID, completionEntry.ID)
+		reversal := NewRequest[models.EarnedTimeEntry](env.Client, t, http.MethodPost, reversalURL, map[string]any{"reason": "Graduated by mistake"}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusCreated).
+			GetData()
+		require.Equal(t, -2.0, reversal.Days)
+		require.Equal(t, completionEntry.ID, *reversal.ReversedEntryID)
+		require.Equal(t, 1.5, balance(t).BalanceDays)
+
+		NewRequest[any](env.Client, t, http.MethodPost, reversalURL, map[string]any{"reason": "Graduated by mistake"}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusUnprocessableEntity)
+		NewRequest[any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/users/%d/earned-time/entries/%d/reversal", resident.ID, reversal.ID), map[string]any{"reason": "Undo"}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+}
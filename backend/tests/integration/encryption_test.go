@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateEncryptedSecretsHandler(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	t.Setenv("APP_KEY", "original-key")
+	t.Setenv("APP_KEY_ID", "")
+	t.Setenv("APP_PREVIOUS_KEYS", "")
+	provider := models.ProviderPlatform{Name: "Test Canvas", Type: models.CanvasCloud, State: models.Enabled, AccessKey: "canvas-access-token"}
+	require.NoError(t, env.DB.CreateProviderPlatform(&provider))
+	// secrets registered before they were encrypted are stored as plain text
+	client := models.OidcClient{ProviderPlatformID: provider.ID, ClientID: "client", ClientSecret: "plain-client-secret"}
+	require.NoError(t, env.DB.Create(&client).Error)
+
+	t.Setenv("APP_KEY", "rotated-key")
+	t.Setenv("APP_KEY_ID", "v2")
+	t.Setenv("APP_PREVIOUS_KEYS", models.LegacyKeyID+"=original-key")
+
+	t.Run("Secrets decrypt with the previous key before rotation", func(t *testing.T) {
+		found, err := env.DB.GetProviderPlatformByID(int(provider.ID))
+		require.NoError(t, err)
+		require.Equal(t, "canvas-access-token", found.AccessKey)
+		usage := NewRequest[models.EncryptionKeyUsage](env.Client, t, http.MethodGet, "/api/secrets/keys", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, "v2", usage.CurrentKeyID)
+		require.Equal(t, 1, usage.ProviderAccessKeys[models.LegacyKeyID])
+		require.Equal(t, 1, usage.OidcClientSecrets[models.UnprefixedSecretKeyID])
+	})
+
+	t.Run("Rotation refuses to re-encrypt secrets the previous key can't decrypt", func(t *testing.T) {
+		t.Setenv("APP_PREVIOUS_KEYS", models.LegacyKeyID+"=wrong-key")
+		NewRequest[any](env.Client, t, http.MethodPost, "/api/secrets/rotate?allow_plaintext=true", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("A dry run reports the secrets to rotate without writing them", func(t *testing.T) {
+		result := NewRequest[models.SecretRotationResult](env.Client, t, http.MethodPost, "/api/secrets/rotate?dry_run=true", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, models.SecretRotationResult{KeyID: "v2", DryRun: true, ProviderAccessKeys: 1, OidcClientSecrets: 1}, result)
+		usage, err := env.DB.GetEncryptionKeyUsage()
+		require.NoError(t, err)
+		require.Equal(t, 1, usage.ProviderAccessKeys[models.LegacyKeyID])
+	})
+
+	t.Run("Rotate secrets to the current key", func(t *testing.T) {
+		result := NewRequest[models.SecretRotationResult](env.Client, t, http.MethodPost, "/api/secrets/rotate", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, models.SecretRotationResult{KeyID: "v2", ProviderAccessKeys: 1, OidcClientSecrets: 1}, result)
+
+		t.Setenv("APP_PREVIOUS_KEYS", "")
+		found, err := env.DB.GetProviderPlatformByID(int(provider.ID))
+		require.NoError(t, err)
+		require.Equal(t, "canvas-access-token", found.AccessKey)
+		foundClient, err := env.DB.GetClientForProvider(provider.ID)
+		require.NoError(t, err)
+		require.Equal(t, "plain-client-secret", foundClient.ClientSecret)
+	})
+
+	t.Run("Plain text access keys are only re-encrypted when allowed", func(t *testing.T) {
+		plain := models.ProviderPlatform{Name: "Plain Canvas", Type: models.CanvasCloud, State: models.Enabled}
+		require.NoError(t, env.DB.Create(&plain).Error)
+		require.NoError(t, env.DB.Table("provider_platforms").Where("id = ?", plain.ID).UpdateColumn("access_key", "plain-access-token").Error)
+		t.Setenv("APP_KEY_ID", "v3")
+		t.Setenv("APP_PREVIOUS_KEYS", models.LegacyKeyID+"=original-key,v2=rotated-key")
+		NewRequest[any](env.Client, t, http.MethodPost, "/api/secrets/rotate", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		result := NewRequest[models.SecretRotationResult](env.Client, t, http.MethodPost, "/api/secrets/rotate?allow_plaintext=true", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, models.SecretRotationResult{KeyID: "v3", ProviderAccessKeys: 2, PlaintextAccessKeys: 1, OidcClientSecrets: 1}, result)
+		found, err := env.DB.GetProviderPlatformByID(int(plain.ID))
+		require.NoError(t, err)
+		require.Equal(t, "plain-access-token", found.AccessKey)
+	})
+}
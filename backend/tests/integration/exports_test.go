@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportHandlers(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("exportadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	resident, err := env.CreateTestUser("exportresident", models.Student, facility.ID, "5000")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID}
+
+	t.Run("Export users as CSV", func(t *testing.T) {
+		body := NewRequest[string](env.Client, t, http.MethodGet, "/api/users/export?role=student", nil).
+			WithTestClaims(claims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		lines := strings.Split(strings.TrimSpace(body), "\n")
+		require.Len(t, lines, 2)
+		require.Equal(t, "id,username,name_first,name_last,email,doc_id,role,created_at", lines[0])
+		require.True(t, strings.HasPrefix(lines[1], fmt.Sprintf("%d,exportresident,Test,User,exportresident@example.com,5000,student,", resident.ID)))
+	})
+
+	t.Run("Export users as XLSX", func(t *testing.T) {
+		body := NewRequest[string](env.Client, t, http.MethodGet, "/api/users/export?format=xlsx", nil).
+			WithTestClaims(claims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		file, err := excelize.OpenReader(bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		rows, err := file.GetRows("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		require.Equal(t, "username", rows[0][1])
+	})
+
+	t.Run("Reject unknown export formats", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodGet, "/api/users/export?format=pdf", nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Export class attendance within a date range", func(t *testing.T) {
+		program, err := env.CreateTestProgram("Export Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+		require.NoError(t, err)
+		class := newClass(program, facility)
+		require.NoError(t, env.DB.Create(&class).Error)
+		event := models.ProgramClassEvent{ClassID: class.ID, Duration: "1h0m0s", RecurrenceRule: "DTSTART:20250101T100000Z\nRRULE:FREQ=WEEKLY;BYDAY=WE"}
+		require.NoError(t, env.DB.Create(&event).Error)
+		attendance := []models.ProgramClassEventAttendance{
+			{EventID: event.ID, UserID: resident.ID, Date: "2025-01-01", AttendanceStatus: models.Present},
+			{EventID: event.ID, UserID: resident.ID, Date: "2025-01-08", AttendanceStatus: models.Absent_Excused, Note: "court date"},
+		}
+		require.NoError(t, env.DB.Create(&attendance).Error)
+
+		body := NewRequest[string](env.Client, t, http.MethodGet, fmt.Sprintf("/api/program-classes/%d/attendance/export?start_dt=2025-01-05", class.ID), nil).
+			WithTestClaims(claims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		lines := strings.Split(strings.TrimSpace(body), "\n")
+		require.Len(t, lines, 2)
+		require.Equal(t, fmt.Sprintf("2025-01-08,Test Class,%d,\"User, Test\",5000,absent_excused,court date", resident.ID), lines[1])
+	})
+}
@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFacilityClosures(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("closureadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+	resident, err := env.CreateTestUser("closureresident", models.Student, facility.ID, "9101")
+	require.NoError(t, err)
+	residentClaims := &handlers.Claims{Role: models.Student, UserID: resident.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+
+	program, err := env.CreateTestProgram("Closure Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	gymClass := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&gymClass).Error)
+	libraryClass := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&libraryClass).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: gymClass.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+
+	// daily sessions at 17:00 UTC, which is midday in Chicago
+	firstDay := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour)
+	rule := fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=5", firstDay.Add(17*time.Hour).Format("20060102T150405Z"))
+	gymEvent := models.ProgramClassEvent{ClassID: gymClass.ID, Duration: "1h0m0s", RecurrenceRule: rule, Room: "Gym"}
+	require.NoError(t, env.DB.Create(&gymEvent).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEvent{ClassID: libraryClass.ID, Duration: "1h0m0s", RecurrenceRule: rule, Room: "Library"}).Error)
+	// the gym session on the third day was already cancelled
+	thirdDay := firstDay.AddDate(0, 0, 2)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEventOverride{
+		EventID:       gymEvent.ID,
+		Duration:      "1h0m0s",
+		OverrideRrule: fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=DAILY;COUNT=1", thirdDay.Add(17*time.Hour).Format("20060102T150405Z")),
+		IsCancelled:   true,
+	}).Error)
+
+	studentCalendar := func(t *testing.T) *models.Calendar {
+		return NewRequest[*models.Calendar](env.Client, t, http.MethodGet, fmt.Sprintf("/api/student-calendar?start_dt=%s&end_dt=%s", firstDay.Format("2006-01-02"), firstDay.AddDate(0, 0, 4).Format("2006-01-02")), nil).
+			WithTestClaims(residentClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+	}
+	sessions := func(calendar *models.Calendar) int {
+		count := 0
+		for _, day := range calendar.Days {
+			count += len(day.Events)
+		}
+		return count
+	}
+	require.Equal(t, 4, sessions(studentCalendar(t)))
+
+	t.Run("Reject closures ending before they start", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, "/api/facility-closures", map[string]any{
+			"start_dt": firstDay.AddDate(0, 0, 1).Format("2006-01-02"),
+			"end_dt":   firstDay.Format("2006-01-02"),
+			"reason":   "Holiday",
+		}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	var closure models.FacilityClosure
+	t.Run("Closing rooms cancels their sessions", func(t *testing.T) {
+		closure = NewRequest[models.FacilityClosure](env.Client, t, http.MethodPost, "/api/facility-closures", map[string]any{
+			"start_dt": firstDay.AddDate(0, 0, 1).Format("2006-01-02"),
+			"end_dt":   thirdDay.Format("2006-01-02"),
+			"reason":   "Lockdown",
+			"rooms":    []string{"gym"},
+		}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusCreated).
+			GetData()
+		require.Equal(t, 1, closure.CancelledSessions)
+
+		overrides := []models.ProgramClassEventOverride{}
+		require.NoError(t, env.DB.Where("closure_id = ?", closure.ID).Find(&overrides).Error)
+		require.Len(t, overrides, 1)
+		require.Equal(t, gymEvent.ID, overrides[0].EventID)
+		require.True(t, overrides[0].IsCancelled)
+		require.Equal(t, "Lockdown", overrides[0].Reason)
+
+		calendar := studentCalendar(t)
+		require.Equal(t, 3, sessions(calendar))
+		require.Len(t, calendar.Closures, 1)
+		require.Equal(t, "Lockdown", calendar.Closures[0].Reason)
+	})
+
+	t.Run("Removing the closure restores only its sessions", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodDelete, fmt.Sprintf("/api/facility-closures/%d", closure.ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK)
+
+		var count int64
+		require.NoError(t, env.DB.Model(&models.ProgramClassEventOverride{}).Where("event_id = ?", gymEvent.ID).Count(&count).Error)
+		require.Equal(t, int64(1), count)
+		calendar := studentCalendar(t)
+		require.Equal(t, 4, sessions(calendar))
+		require.Empty(t, calendar.Closures)
+
+		NewRequest[any](env.Client, t, http.MethodDelete, fmt.Sprintf("/api/facility-closures/%d", closure.ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Sessions with attendance are not cancelled", func(t *testing.T) {
+		secondDay := firstDay.AddDate(0, 0, 1)
+		attendance := models.ProgramClassEventAttendance{EventID: gymEvent.ID, UserID: resident.ID, Date: secondDay.Format("2006-01-02"), AttendanceStatus: models.Present}
+		require.NoError(t, env.DB.Create(&attendance).Error)
+		held := NewRequest[models.FacilityClosure](env.Client, t, http.MethodPost, "/api/facility-closures", map[string]any{
+			"start_dt": secondDay.Format("2006-01-02"),
+			"end_dt":   secondDay.Format("2006-01-02"),
+			"reason":   "Backdated lockdown",
+			"rooms":    []string{"gym"},
+		}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusCreated).
+			GetData()
+		require.Zero(t, held.CancelledSessions)
+		require.Equal(t, 4, sessions(studentCalendar(t)))
+
+		NewRequest[any](env.Client, t, http.MethodDelete, fmt.Sprintf("/api/facility-closures/%d", held.ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		require.NoError(t, env.DB.First(&models.ProgramClassEventAttendance{}, attendance.ID).Error, "the attendance is kept")
+	})
+}
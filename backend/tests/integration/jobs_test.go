@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexJobRunsHandler(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	provider := models.ProviderPlatform{Name: "Test Canvas", Type: models.CanvasCloud, State: models.Enabled}
+	require.NoError(t, env.DB.CreateProviderPlatform(&provider))
+	job := models.CronJob{Name: string(models.GetCoursesJob)}
+	require.NoError(t, env.DB.Create(&job).Error)
+	task := models.RunnableTask{JobID: job.ID, ProviderPlatformID: &provider.ID, Status: models.StatusPending}
+	require.NoError(t, env.DB.Create(&task).Error)
+
+	succeeded := models.NewJobRun(&task)
+	succeeded.Finish(nil)
+	failed := models.NewJobRun(&task)
+	failed.Finish(errors.New("canvas returned 401"))
+	require.NoError(t, env.DB.Create(succeeded).Error)
+	require.NoError(t, env.DB.Create(failed).Error)
+
+	t.Run("List all runs for a provider platform", func(t *testing.T) {
+		got := NewRequest[[]models.JobRun](env.Client, t, http.MethodGet, fmt.Sprintf("/api/provider-platforms/%d/job-runs", provider.ID), nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, got, 2)
+	})
+
+	t.Run("Filter runs by status", func(t *testing.T) {
+		resp := NewRequest[[]models.JobRun](env.Client, t, http.MethodGet, "/api/jobs/runs?status=failed", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK)
+		got := resp.GetData()
+		require.Len(t, got, 1)
+		require.Equal(t, models.StatusFailed, got[0].Status)
+		require.Equal(t, "canvas returned 401", got[0].ErrorMessage)
+		require.NotNil(t, got[0].EndedAt)
+	})
+}
+
+func TestUpdateRunnableTaskHandler(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	job := models.CronJob{Name: string(models.DailyProgHistoryJob)}
+	require.NoError(t, env.DB.Create(&job).Error)
+	task := models.RunnableTask{JobID: job.ID, Status: models.StatusPending}
+	require.NoError(t, env.DB.Create(&task).Error)
+	endpoint := fmt.Sprintf("/api/jobs/tasks/%d", task.ID)
+
+	t.Run("Pause task and override schedule", func(t *testing.T) {
+		paused, schedule := true, "0 3 * * *"
+		got := NewRequest[models.RunnableTask](env.Client, t, http.MethodPatch, endpoint, handlers.RunnableTaskUpdate{Paused: &paused, Schedule: &schedule}).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.True(t, got.Paused)
+		require.Equal(t, schedule, got.Schedule)
+	})
+
+	t.Run("Resume task keeps schedule override", func(t *testing.T) {
+		paused := false
+		got := NewRequest[models.RunnableTask](env.Client, t, http.MethodPatch, endpoint, handlers.RunnableTaskUpdate{Paused: &paused}).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.False(t, got.Paused)
+		require.Equal(t, "0 3 * * *", got.Schedule)
+	})
+
+	t.Run("Invalid cron schedule is rejected", func(t *testing.T) {
+		schedule := "every tuesday"
+		NewRequest[models.RunnableTask](env.Client, t, http.MethodPatch, endpoint, handlers.RunnableTaskUpdate{Schedule: &schedule}).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+}
+
+func TestProviderFullResyncHandler(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	provider := models.ProviderPlatform{Name: "Test Canvas", Type: models.CanvasCloud, State: models.Enabled}
+	require.NoError(t, env.DB.CreateProviderPlatform(&provider))
+	cursors := []models.ProviderSyncCursor{
+		{ProviderPlatformID: provider.ID, Entity: models.CoursesSyncEntity, Cursor: "1042"},
+		{ProviderPlatformID: provider.ID, Entity: models.CourseSyncEntity(models.MilestonesSyncEntity, 1), Cursor: "2025-01-01T00:00:00Z"},
+		{ProviderPlatformID: provider.ID, Entity: models.CourseSyncEntity(models.ActivitySyncEntity, 1), Cursor: "2025-01-01T00:00:00Z"},
+	}
+	require.NoError(t, env.DB.Create(&cursors).Error)
+	endpoint := fmt.Sprintf("/api/provider-platforms/%d", provider.ID)
+
+	t.Run("List sync cursors", func(t *testing.T) {
+		got := NewRequest[[]models.ProviderSyncCursor](env.Client, t, http.MethodGet, endpoint+"/sync-cursors", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, got, 3)
+	})
+
+	t.Run("Reject unknown entities", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, endpoint+"/resync", map[string]any{"entities": []string{"users"}}).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Reset only the requested entity", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, endpoint+"/resync", map[string]any{"entities": []string{models.MilestonesSyncEntity}}).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusAccepted)
+		remaining, err := env.DB.GetProviderSyncCursors(int(provider.ID))
+		require.NoError(t, err)
+		require.Len(t, remaining, 2)
+		for _, cursor := range remaining {
+			require.NotEqual(t, models.CourseSyncEntity(models.MilestonesSyncEntity, 1), cursor.Entity)
+		}
+	})
+
+	t.Run("Reset everything", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, endpoint+"/resync", nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusAccepted)
+		remaining, err := env.DB.GetProviderSyncCursors(int(provider.ID))
+		require.NoError(t, err)
+		require.Empty(t, remaining)
+	})
+
+	t.Run("Providers without sync cursors only run their tasks", func(t *testing.T) {
+		openEdx := models.ProviderPlatform{Name: "Test Open edX", Type: models.OpenEdx, State: models.Enabled}
+		require.NoError(t, env.DB.CreateProviderPlatform(&openEdx))
+		resp := NewRequest[map[string]any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/provider-platforms/%d/resync", openEdx.ID), nil).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusAccepted).
+			GetData()
+		require.Equal(t, float64(0), resp["cursors_removed"])
+	})
+}
+
+func TestTestNewProviderConnectionHandler(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	t.Run("Reject provider without a base url", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, "/api/provider-platforms/test", map[string]any{"type": models.CanvasCloud}).
+			WithTestClaims(&handlers.Claims{Role: models.SystemAdmin}).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutePermissions(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	claims := func(role models.UserRole) *handlers.Claims {
+		return &handlers.Claims{Role: role, FacilityID: facility.ID, TimeZone: facility.Timezone}
+	}
+
+	t.Run("Every role has an entry in the policy table", func(t *testing.T) {
+		for _, role := range []models.UserRole{models.Student, models.Instructor, models.FacilityAdmin, models.DepartmentAdmin, models.SystemAdmin} {
+			_, ok := models.RolePermissions[role]
+			require.True(t, ok, role)
+		}
+		require.True(t, models.DepartmentAdmin.Can(models.UsersTransfer, models.FacilitiesSwitch))
+		require.False(t, models.FacilityAdmin.Can(models.UsersRead, models.UsersTransfer))
+		require.False(t, models.Student.Can(models.ClassesAttendanceWrite))
+	})
+
+	t.Run("Routes reject roles without the permission they require", func(t *testing.T) {
+		for _, role := range []models.UserRole{models.Student, models.Instructor, models.FacilityAdmin} {
+			NewRequest[any](env.Client, t, http.MethodGet, "/api/jobs", nil).
+				WithTestClaims(claims(role)).
+				Do().
+				ExpectStatus(http.StatusUnauthorized)
+		}
+		NewRequest[any](env.Client, t, http.MethodGet, "/api/jobs", nil).
+			WithTestClaims(claims(models.DepartmentAdmin)).
+			Do().
+			ExpectStatus(http.StatusOK)
+	})
+
+	t.Run("Only system admins manage facilities", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodGet, "/api/facilities", nil).
+			WithTestClaims(claims(models.FacilityAdmin)).
+			Do().
+			ExpectStatus(http.StatusOK)
+		NewRequest[any](env.Client, t, http.MethodPost, "/api/facilities", map[string]any{"name": "New Facility", "timezone": "America/Chicago"}).
+			WithTestClaims(claims(models.DepartmentAdmin)).
+			Do().
+			ExpectStatus(http.StatusUnauthorized)
+	})
+
+	t.Run("Facility admins verify residents but only department admins transfer them", func(t *testing.T) {
+		resident, err := env.CreateTestUser("verifyresident", models.Student, facility.ID, "8801")
+		require.NoError(t, err)
+		NewRequest[any](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/resident-verify?facility_id=%d&user_id=%d&doc_id=8801", facility.ID, resident.ID), nil).
+			WithTestClaims(claims(models.FacilityAdmin)).
+			Do().
+			ExpectStatus(http.StatusOK)
+		NewRequest[any](env.Client, t, http.MethodPatch, "/api/users/resident-transfer", map[string]any{"user_id": resident.ID, "trans_facility_id": facility.ID}).
+			WithTestClaims(claims(models.FacilityAdmin)).
+			Do().
+			ExpectStatus(http.StatusUnauthorized)
+	})
+}
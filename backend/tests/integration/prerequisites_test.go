@@ -0,0 +1,119 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgramPrerequisites(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("prereqadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID}
+	qualified, err := env.CreateTestUser("qualifiedresident", models.Student, facility.ID, "8001")
+	require.NoError(t, err)
+	unqualified, err := env.CreateTestUser("unqualifiedresident", models.Student, facility.ID, "8002")
+	require.NoError(t, err)
+
+	introProgram, err := env.CreateTestProgram("Intro to Welding", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	advancedProgram, err := env.CreateTestProgram("Advanced Welding", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	require.NoError(t, env.SetFacilitiesToProgram(advancedProgram.ID, []uint{facility.ID}))
+	introClass := newClass(introProgram, facility)
+	require.NoError(t, env.DB.Create(&introClass).Error)
+	advancedClass := newClass(advancedProgram, facility)
+	require.NoError(t, env.DB.Create(&advancedClass).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramCompletion{
+		UserID:         qualified.ID,
+		ProgramClassID: introClass.ID,
+		ProgramID:      introProgram.ID,
+		ProgramName:    introProgram.Name,
+		FacilityName:   facility.Name,
+		CreditType:     "Completion,Education",
+		AdminEmail:     "prereqadmin@example.com",
+		ProgramOwner:   "Test Owner",
+	}).Error)
+
+	prerequisitesEndpoint := fmt.Sprintf("/api/programs/%d/prerequisites", advancedProgram.ID)
+	enrollmentsEndpoint := fmt.Sprintf("/api/program-classes/%d/enrollments", advancedClass.ID)
+
+	t.Run("Reject invalid prerequisites", func(t *testing.T) {
+		rules := map[string]any{"prerequisites": []map[string]any{{"prerequisite_type": "completed_program", "required_program_id": advancedProgram.ID}}}
+		NewRequest[any](env.Client, t, http.MethodPut, prerequisitesEndpoint, rules).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		rules = map[string]any{"prerequisites": []map[string]any{{"prerequisite_type": "credit_type", "credit_type": "Welding"}}}
+		NewRequest[any](env.Client, t, http.MethodPut, prerequisitesEndpoint, rules).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Replace the program prerequisites", func(t *testing.T) {
+		rules := map[string]any{"prerequisites": []map[string]any{
+			{"prerequisite_type": "completed_program", "required_program_id": introProgram.ID},
+			{"prerequisite_type": "credit_type", "credit_type": "Education"},
+		}}
+		got := NewRequest[[]models.ProgramPrerequisite](env.Client, t, http.MethodPut, prerequisitesEndpoint, rules).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, got, 2)
+		require.Equal(t, introProgram.Name, got[0].RequiredProgram.Name)
+	})
+
+	t.Run("Filter eligible residents to those meeting the prerequisites", func(t *testing.T) {
+		users := NewRequest[[]models.User](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users?include=only_unenrolled&include=only_eligible&class_id=%d", advancedClass.ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, users, 1)
+		require.Equal(t, qualified.ID, users[0].ID)
+	})
+
+	t.Run("Enrollment is blocked for residents missing prerequisites", func(t *testing.T) {
+		unmet := NewRequest[[]models.UnmetPrerequisites](env.Client, t, http.MethodPost, enrollmentsEndpoint, map[string]any{"user_ids": []uint{qualified.ID, unqualified.ID}}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusUnprocessableEntity).
+			GetData()
+		require.Len(t, unmet, 1)
+		require.Equal(t, unqualified.ID, unmet[0].UserID)
+		require.Equal(t, []string{"completed the Intro to Welding program", "earned Education credit"}, unmet[0].Prerequisites)
+
+		var count int64
+		require.NoError(t, env.DB.Model(&models.ProgramClassEnrollment{}).Where("class_id = ?", advancedClass.ID).Count(&count).Error)
+		require.Zero(t, count)
+	})
+
+	t.Run("Admins can override prerequisites with a reason", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, enrollmentsEndpoint, map[string]any{"user_ids": []uint{qualified.ID, unqualified.ID}, "override_prerequisites": true}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		NewRequest[any](env.Client, t, http.MethodPost, enrollmentsEndpoint, map[string]any{"user_ids": []uint{qualified.ID, unqualified.ID}, "override_prerequisites": true, "override_reason": "completed welding at previous facility"}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusCreated)
+
+		enrollments := []models.ProgramClassEnrollment{}
+		require.NoError(t, env.DB.Where("class_id = ?", advancedClass.ID).Order("user_id").Find(&enrollments).Error)
+		require.Len(t, enrollments, 2)
+		require.Nil(t, enrollments[0].OverrideReason)
+		require.Equal(t, "completed welding at previous facility", *enrollments[1].OverrideReason)
+		require.Equal(t, admin.ID, *enrollments[1].OverrideUserID)
+	})
+}
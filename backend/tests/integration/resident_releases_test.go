@@ -0,0 +1,163 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResidentReleases(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Release Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("releaseadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID, TimeZone: facility.Timezone}
+	resident, err := env.CreateTestUser("releaseresident", models.Student, facility.ID, "9501")
+	require.NoError(t, err)
+	waitlisted, err := env.CreateTestUser("releasewaitlisted", models.Student, facility.ID, "9502")
+	require.NoError(t, err)
+
+	program, err := env.CreateTestProgram("Release Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{{CreditType: models.Completion}}, true)
+	require.NoError(t, err)
+	completed := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&completed).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: completed.ID, UserID: resident.ID, EnrollmentStatus: models.EnrollmentCompleted}).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramCompletion{
+		UserID:           resident.ID,
+		ProgramClassID:   completed.ID,
+		FacilityName:     facility.Name,
+		CreditType:       "Completion",
+		AdminEmail:       "releaseadmin@unlocked.v2",
+		ProgramName:      program.Name,
+		ProgramID:        program.ID,
+		ProgramClassName: completed.Name,
+	}).Error)
+	full := newClass(program, facility)
+	full.Capacity = 1
+	full.Status = models.Active
+	require.NoError(t, env.DB.Create(&full).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: full.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+	position := 1
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: full.ID, UserID: waitlisted.ID, EnrollmentStatus: models.EnrollmentWaitlisted, WaitlistPosition: &position}).Error)
+	other := newClass(program, facility)
+	other.Capacity = 1
+	require.NoError(t, env.DB.Create(&other).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: other.ID, UserID: waitlisted.ID, EnrollmentStatus: models.Enrolled}).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: other.ID, UserID: resident.ID, EnrollmentStatus: models.EnrollmentWaitlisted, WaitlistPosition: &position}).Error)
+
+	releaseURL := fmt.Sprintf("/api/users/%d/release", resident.ID)
+	today := time.Now().UTC()
+
+	t.Run("Schedule a release date", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPut, releaseURL, map[string]any{"release_date": "06/30/2025"}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		NewRequest[any](env.Client, t, http.MethodPut, fmt.Sprintf("/api/users/%d/release", admin.ID), map[string]any{"release_date": "2025-06-30"}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		user := NewRequest[models.User](env.Client, t, http.MethodPut, releaseURL, map[string]any{"release_date": today.AddDate(0, 0, 3).Format("2006-01-02")}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.NotNil(t, user.ReleaseDate)
+		due, err := env.DB.GetDueReleases(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Empty(t, due, "residents aren't released before their release date")
+
+		NewRequest[models.User](env.Client, t, http.MethodPut, releaseURL, map[string]any{"release_date": today.AddDate(0, 0, -1).Format("2006-01-02")}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		due, err = env.DB.GetDueReleases(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		require.Equal(t, resident.ID, due[0].ID)
+	})
+
+	var transcript *models.ResidentTranscript
+	t.Run("Releasing closes enrollments and saves a transcript", func(t *testing.T) {
+		transcript, err = env.DB.ReleaseResident(context.Background(), resident.ID, time.Now())
+		require.NoError(t, err)
+		_, err = env.DB.ReleaseResident(context.Background(), resident.ID, time.Now())
+		require.Error(t, err, "residents are only released once")
+
+		enrollments := []models.ProgramClassEnrollment{}
+		require.NoError(t, env.DB.Order("class_id, user_id").Find(&enrollments, "class_id IN (?)", []uint{full.ID, other.ID}).Error)
+		statuses := map[string]models.ProgramEnrollmentStatus{}
+		for _, enrollment := range enrollments {
+			statuses[fmt.Sprintf("%d/%d", enrollment.ClassID, enrollment.UserID)] = enrollment.EnrollmentStatus
+		}
+		require.Equal(t, models.EnrollmentIncompleteReleased, statuses[fmt.Sprintf("%d/%d", full.ID, resident.ID)])
+		require.Equal(t, models.Enrolled, statuses[fmt.Sprintf("%d/%d", full.ID, waitlisted.ID)], "the next resident on the waitlist takes the seat")
+		require.Equal(t, models.EnrollmentCancelled, statuses[fmt.Sprintf("%d/%d", other.ID, resident.ID)])
+
+		require.Equal(t, models.TranscriptRelease, transcript.Reason)
+		require.Len(t, transcript.Contents.Courses, 2)
+		require.Equal(t, models.EnrollmentCompleted, transcript.Contents.Courses[0].EnrollmentStatus)
+		require.NotNil(t, transcript.Contents.Courses[0].CompletedAt)
+		require.Equal(t, models.EnrollmentIncompleteReleased, transcript.Contents.Courses[1].EnrollmentStatus)
+		require.Equal(t, []models.CreditTotal{{CreditType: models.Completion, Completions: 1, CreditHours: 2}}, transcript.Contents.Credits)
+		require.NotNil(t, transcript.Contents.ReleasedAt)
+
+		history := models.UserAccountHistory{}
+		require.NoError(t, env.DB.First(&history, "user_id = ? AND action = ?", resident.ID, models.ResidentRelease).Error)
+		released := models.User{}
+		require.NoError(t, env.DB.First(&released, resident.ID).Error)
+		require.NotNil(t, released.DeactivatedAt, "released residents can no longer log in")
+		due, err := env.DB.GetDueReleases(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Empty(t, due)
+		NewRequest[any](env.Client, t, http.MethodPut, releaseURL, map[string]any{"release_date": nil}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Only accounts on providers that can deactivate them are queued", func(t *testing.T) {
+		canvas := models.ProviderPlatform{Name: "Release Canvas", Type: models.CanvasCloud, State: models.Enabled}
+		require.NoError(t, env.DB.Create(&canvas).Error)
+		kolibri := models.ProviderPlatform{Name: "Release Kolibri", Type: models.Kolibri, State: models.Enabled}
+		require.NoError(t, env.DB.Create(&kolibri).Error)
+		require.NoError(t, env.DB.Create(&[]models.ProviderUserMapping{
+			{UserID: resident.ID, ProviderPlatformID: canvas.ID, ExternalUserID: "101", ExternalUsername: "released"},
+			{UserID: resident.ID, ProviderPlatformID: kolibri.ID, ExternalUserID: "102", ExternalUsername: "released"},
+		}).Error)
+		mappings, err := env.DB.GetReleasedProviderMappings(context.Background())
+		require.NoError(t, err)
+		require.Len(t, mappings, 1)
+		require.Equal(t, canvas.ID, mappings[0].ProviderPlatformID)
+	})
+
+	t.Run("Export the transcript", func(t *testing.T) {
+		transcripts := NewRequest[[]models.ResidentTranscript](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/transcripts", resident.ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, transcripts, 1)
+		body := NewRequest[string](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/transcripts/%d/export", resident.ID, transcript.ID), nil).
+			WithTestClaims(claims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		lines := strings.Split(strings.TrimSpace(body), "\n")
+		require.Len(t, lines, 3)
+		require.True(t, strings.HasPrefix(lines[0], "doc_id,name_full,program_name,class_name"))
+		require.True(t, strings.HasPrefix(lines[1], "9501,"))
+		require.Contains(t, lines[2], string(models.EnrollmentIncompleteReleased))
+	})
+}
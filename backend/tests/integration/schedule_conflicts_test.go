@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleConflicts(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("conflictadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID}
+	resident, err := env.CreateTestUser("conflictresident", models.Student, facility.ID, "9001")
+	require.NoError(t, err)
+
+	program, err := env.CreateTestProgram("Conflict Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	booked := newClass(program, facility)
+	booked.Name = "Booked Class"
+	require.NoError(t, env.DB.Create(&booked).Error)
+	proposed := newClass(program, facility)
+	proposed.Name = "Proposed Class"
+	proposed.InstructorName = "Another Instructor"
+	require.NoError(t, env.DB.Create(&proposed).Error)
+	for _, class := range []models.ProgramClass{booked, proposed} {
+		require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: class.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+	}
+
+	start := time.Now().UTC().AddDate(0, 0, 2).Truncate(24 * time.Hour).Add(10 * time.Hour)
+	rule := func(start time.Time) string {
+		return fmt.Sprintf("DTSTART:%s\nRRULE:FREQ=WEEKLY;COUNT=4", start.Format("20060102T150405Z"))
+	}
+	require.NoError(t, env.DB.Create(&models.ProgramClassEvent{ClassID: booked.ID, Duration: "1h0m0s", RecurrenceRule: rule(start), Room: "Room 1"}).Error)
+	eventsEndpoint := fmt.Sprintf("/api/program-classes/%d/events", proposed.ID)
+
+	t.Run("Report room and resident conflicts", func(t *testing.T) {
+		event := models.ProgramClassEvent{ClassID: proposed.ID, Duration: "1h0m0s", RecurrenceRule: rule(start.Add(30 * time.Minute)), Room: "room 1"}
+		conflicts := NewRequest[[]models.ScheduleConflict](env.Client, t, http.MethodPost, eventsEndpoint+"/check-conflicts", event).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, conflicts, 8)
+		types := map[models.ScheduleConflictType]int{}
+		for _, conflict := range conflicts {
+			types[conflict.ConflictType]++
+			require.Equal(t, booked.ID, conflict.ConflictingClassID)
+		}
+		require.Equal(t, map[models.ScheduleConflictType]int{models.RoomConflict: 4, models.ResidentConflict: 4}, types)
+		require.Equal(t, resident.ID, conflicts[1].Residents[0].UserID)
+	})
+
+	t.Run("Sessions that don't overlap have no conflicts", func(t *testing.T) {
+		event := models.ProgramClassEvent{ClassID: proposed.ID, Duration: "1h0m0s", RecurrenceRule: rule(start.Add(time.Hour)), Room: "Room 1"}
+		conflicts := NewRequest[[]models.ScheduleConflict](env.Client, t, http.MethodPost, eventsEndpoint+"/check-conflicts", event).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Empty(t, conflicts)
+	})
+
+	t.Run("Creating a conflicting event requires force", func(t *testing.T) {
+		event := models.ProgramClassEvent{ClassID: proposed.ID, Duration: "1h0m0s", RecurrenceRule: rule(start), Room: "Room 2"}
+		conflicts := NewRequest[[]models.ScheduleConflict](env.Client, t, http.MethodPost, eventsEndpoint, event).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusConflict).
+			GetData()
+		require.Len(t, conflicts, 4)
+		require.Equal(t, models.ResidentConflict, conflicts[0].ConflictType)
+
+		NewRequest[any](env.Client, t, http.MethodPost, eventsEndpoint+"?force=true", event).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusCreated)
+	})
+}
@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResidentTranscript(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Transcript Facility")
+	require.NoError(t, err)
+	resident, err := env.CreateTestUser("transcriptresident", models.Student, facility.ID, "9601")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.Student, UserID: resident.ID, FacilityID: facility.ID}
+
+	program, err := env.CreateTestProgram("Transcript Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{{CreditType: models.Completion}}, true)
+	require.NoError(t, err)
+	class := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&class).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: class.ID, UserID: resident.ID, EnrollmentStatus: models.EnrollmentCompleted}).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramCompletion{
+		UserID:           resident.ID,
+		ProgramClassID:   class.ID,
+		FacilityName:     facility.Name,
+		CreditType:       "Completion",
+		AdminEmail:       "transcriptadmin@unlocked.v2",
+		ProgramName:      program.Name,
+		ProgramID:        program.ID,
+		ProgramClassName: class.Name,
+	}).Error)
+
+	provider := models.ProviderPlatform{Name: "Test Canvas", Type: models.CanvasCloud, State: models.Enabled}
+	require.NoError(t, env.DB.Create(&provider).Error)
+	completedCourse := models.Course{ProviderPlatformID: provider.ID, Name: "Completed Course", TotalProgressMilestones: 2}
+	require.NoError(t, env.DB.Create(&completedCourse).Error)
+	inProgressCourse := models.Course{ProviderPlatformID: provider.ID, Name: "In Progress Course", TotalProgressMilestones: 4}
+	require.NoError(t, env.DB.Create(&inProgressCourse).Error)
+	untouchedCourse := models.Course{ProviderPlatformID: provider.ID, Name: "Untouched Course", TotalProgressMilestones: 4}
+	require.NoError(t, env.DB.Create(&untouchedCourse).Error)
+	require.NoError(t, env.DB.Create(&models.Milestone{UserID: resident.ID, CourseID: inProgressCourse.ID, ExternalID: "transcript-milestone", Type: models.AssignmentSubmission}).Error)
+	require.NoError(t, env.DB.Create(&models.Outcome{UserID: resident.ID, CourseID: completedCourse.ID, Type: models.CourseCompletion, Value: "A"}).Error)
+	require.NoError(t, env.DB.Create(&models.Activity{UserID: resident.ID, CourseID: inProgressCourse.ID, Type: models.ContentInteraction, TotalTime: 5400, TimeDelta: 5400, ExternalID: "transcript-activity"}).Error)
+
+	contentProvider := models.OpenContentProvider{Title: models.Kiwix, Url: "http://kiwix.test"}
+	require.NoError(t, env.DB.Create(&contentProvider).Error)
+	library := models.Library{OpenContentProviderID: contentProvider.ID, Title: "Transcript Library", Url: "/content/transcript"}
+	require.NoError(t, env.DB.Create(&library).Error)
+	visitStart := time.Date(2025, 3, 3, 15, 0, 0, 0, time.UTC)
+	for _, minutes := range []int{30, 60} {
+		require.NoError(t, env.DB.Create(&models.OpenContentActivity{
+			OpenContentProviderID: contentProvider.ID,
+			FacilityID:            facility.ID,
+			UserID:                resident.ID,
+			ContentID:             library.ID,
+			RequestTS:             visitStart,
+			StopTS:                visitStart.Add(time.Duration(minutes) * time.Minute),
+		}).Error)
+		visitStart = visitStart.AddDate(0, 0, 1)
+	}
+	transcriptURL := fmt.Sprintf("/api/users/%d/transcript", resident.ID)
+
+	t.Run("The transcript combines programs, courses and open content", func(t *testing.T) {
+		transcript := NewRequest[models.Transcript](env.Client, t, http.MethodGet, transcriptURL, nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, "9601", transcript.DocID)
+		require.Equal(t, facility.Name, transcript.FacilityName)
+		require.Len(t, transcript.Courses, 1)
+		require.Equal(t, models.EnrollmentCompleted, transcript.Courses[0].EnrollmentStatus)
+		require.Equal(t, []models.CreditTotal{{CreditType: models.Completion, Completions: 1, CreditHours: 2}}, transcript.Credits)
+
+		require.Len(t, transcript.ProviderCourses, 2, "courses without progress are left out")
+		require.Equal(t, "Completed Course", transcript.ProviderCourses[0].CourseName)
+		require.Equal(t, 100.0, transcript.ProviderCourses[0].Progress)
+		require.Equal(t, models.CourseCompletion, transcript.ProviderCourses[0].OutcomeType)
+		require.NotNil(t, transcript.ProviderCourses[0].CompletedAt)
+		require.Equal(t, "In Progress Course", transcript.ProviderCourses[1].CourseName)
+		require.Equal(t, 25.0, transcript.ProviderCourses[1].Progress)
+		require.Equal(t, 1.5, transcript.ProviderCourses[1].Hours)
+		require.Nil(t, transcript.ProviderCourses[1].CompletedAt)
+
+		require.Len(t, transcript.OpenContent, 1)
+		require.Equal(t, "Transcript Library", transcript.OpenContent[0].Title)
+		require.Equal(t, "library", transcript.OpenContent[0].ContentType)
+		require.Equal(t, 2, transcript.OpenContent[0].Visits)
+		require.Equal(t, 1.5, transcript.OpenContent[0].Hours)
+		require.Equal(t, 1.5, transcript.OpenContentHours)
+	})
+
+	t.Run("The transcript renders to a PDF", func(t *testing.T) {
+		document := NewRequest[string](env.Client, t, http.MethodGet, transcriptURL+"?format=pdf", nil).
+			WithTestClaims(claims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.True(t, strings.HasPrefix(document, "%PDF"))
+		NewRequest[any](env.Client, t, http.MethodGet, transcriptURL+"?format=docx", nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,140 @@
+package integration
+
+import (
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferPackets(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	fromFacility, err := env.CreateTestFacility("Sending Facility")
+	require.NoError(t, err)
+	toFacility, err := env.CreateTestFacility("Receiving Facility")
+	require.NoError(t, err)
+	deptAdmin, err := env.CreateTestUser("transferdeptadmin", models.DepartmentAdmin, fromFacility.ID, "")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("transferadmin", models.FacilityAdmin, toFacility.ID, "")
+	require.NoError(t, err)
+	adminClaims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: toFacility.ID, TimeZone: toFacility.Timezone}
+	resident, err := env.CreateTestUser("transferresident", models.Student, fromFacility.ID, "9401")
+	require.NoError(t, err)
+
+	program, err := env.CreateTestProgram("Transfer Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{{CreditType: models.Completion}}, true)
+	require.NoError(t, err)
+	require.NoError(t, env.SetFacilitiesToProgram(program.ID, []uint{fromFacility.ID, toFacility.ID}))
+	completed := newClass(program, fromFacility)
+	require.NoError(t, env.DB.Create(&completed).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramCompletion{
+		UserID:           resident.ID,
+		ProgramClassID:   completed.ID,
+		FacilityName:     fromFacility.Name,
+		CreditType:       "Completion",
+		AdminEmail:       "transferdeptadmin@unlocked.v2",
+		ProgramName:      program.Name,
+		ProgramID:        program.ID,
+		ProgramClassName: completed.Name,
+	}).Error)
+	inProgress := newClass(program, fromFacility)
+	inProgress.Status = models.Active
+	require.NoError(t, env.DB.Create(&inProgress).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: inProgress.ID, UserID: resident.ID, EnrollmentStatus: models.Enrolled}).Error)
+	event := models.ProgramClassEvent{ClassID: inProgress.ID, Duration: "1h0m0s", RecurrenceRule: "DTSTART:20250106T150000Z\nRRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=4", Room: "Room 1"}
+	require.NoError(t, env.DB.Create(&event).Error)
+	for week, status := range []models.Attendance{models.Present, models.Present, models.Present, models.Absent_Unexcused} {
+		require.NoError(t, env.DB.Create(&models.ProgramClassEventAttendance{
+			EventID:          event.ID,
+			UserID:           resident.ID,
+			Date:             time.Date(2025, 1, 6+7*week, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			AttendanceStatus: status,
+		}).Error)
+	}
+	require.NoError(t, env.DB.Create(&models.ProgramFavorite{UserID: resident.ID, ProgramID: program.ID}).Error)
+	equivalent := newClass(program, toFacility)
+	require.NoError(t, env.DB.Create(&equivalent).Error)
+	otherProgram, err := env.CreateTestProgram("Other Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{{CreditType: models.Completion}}, true)
+	require.NoError(t, err)
+	require.NoError(t, env.SetFacilitiesToProgram(otherProgram.ID, []uint{toFacility.ID}))
+	unrelated := newClass(otherProgram, toFacility)
+	require.NoError(t, env.DB.Create(&unrelated).Error)
+
+	args := models.QueryContext{UserID: deptAdmin.ID}
+	tx, packet, err := env.DB.TransferResident(&args, int(resident.ID), int(fromFacility.ID), int(toFacility.ID))
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit().Error)
+	packetURL := fmt.Sprintf("/api/users/%d/transfer-packets/%d", resident.ID, packet.ID)
+
+	t.Run("The packet is a snapshot of the resident's history before the transfer", func(t *testing.T) {
+		details := NewRequest[models.TransferPacketDetails](env.Client, t, http.MethodGet, packetURL, nil).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Equal(t, fromFacility.ID, details.FromFacilityID)
+		require.Equal(t, toFacility.ID, details.ToFacilityID)
+		require.Len(t, details.Contents.Completions, 1)
+		require.Equal(t, int64(2), details.Contents.Completions[0].CreditHours)
+		require.Equal(t, []models.CreditTotal{{CreditType: models.Completion, Completions: 1, CreditHours: 2}}, details.Contents.Credits)
+		require.Len(t, details.Contents.InProgress, 1)
+		require.Equal(t, inProgress.ID, details.Contents.InProgress[0].ClassID)
+		require.Equal(t, models.Enrolled, details.Contents.InProgress[0].EnrollmentStatus)
+		require.Equal(t, 75.0, details.Contents.InProgress[0].AttendancePercentage)
+		require.Len(t, details.Contents.Favorites, 1)
+		require.Equal(t, models.ProgramFavoriteType, details.Contents.Favorites[0].Type)
+
+		require.Len(t, details.EquivalentClasses, 1)
+		require.Equal(t, inProgress.ID, details.EquivalentClasses[0].ReplacesClassID)
+		require.Equal(t, equivalent.ID, details.EquivalentClasses[0].ClassID)
+		require.False(t, details.EquivalentClasses[0].Enrolled)
+
+		packets := NewRequest[[]models.TransferPacket](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/transfer-packets", resident.ID), nil).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, packets, 1)
+	})
+
+	t.Run("Residents are re-enrolled in equivalent classes", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPost, packetURL+"/re-enrollments", map[string]any{"class_ids": []uint{unrelated.ID}}).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		enrollments := NewRequest[[]models.ProgramClassEnrollment](env.Client, t, http.MethodPost, packetURL+"/re-enrollments", nil).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusCreated).
+			GetData()
+		require.Len(t, enrollments, 1)
+		require.Equal(t, equivalent.ID, enrollments[0].ClassID)
+		require.Equal(t, models.Enrolled, enrollments[0].EnrollmentStatus)
+		NewRequest[any](env.Client, t, http.MethodPost, packetURL+"/re-enrollments", nil).
+			WithTestClaims(adminClaims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+
+		received := models.TransferPacket{}
+		require.NoError(t, env.DB.First(&received, packet.ID).Error)
+		require.NotNil(t, received.ReceivedAt)
+		require.Equal(t, admin.ID, *received.ReceiveUserID)
+	})
+
+	t.Run("Each step is in the resident's account history", func(t *testing.T) {
+		history := []models.UserAccountHistory{}
+		require.NoError(t, env.DB.Where("user_id = ? AND action = ?", resident.ID, models.FacilityTransfer).Order("created_at").Find(&history).Error)
+		require.Len(t, history, 2)
+		for _, step := range history {
+			require.Equal(t, packet.ID, *step.TransferPacketID)
+			require.Equal(t, toFacility.ID, *step.FacilityID)
+		}
+		require.Nil(t, history[0].ProgramClassID)
+		require.Equal(t, equivalent.ID, *history[1].ProgramClassID)
+	})
+}
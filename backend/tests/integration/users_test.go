@@ -3,8 +3,12 @@ package integration
 import (
 	"UnlockEdv2/src/handlers"
 	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -51,3 +55,167 @@ func TestCreateUserHandler(t *testing.T) {
 		require.Equal(t, form.User.FacilityID, got.FacilityID)
 	})
 }
+
+func TestDeactivateUserHandler(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Deactivation Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("deactivateadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID}
+	resident, err := env.CreateTestUser("deactivateresident", models.Student, facility.ID, "7100")
+	require.NoError(t, err)
+	program, err := env.CreateTestProgram("Deactivation Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	class := newClass(program, facility)
+	require.NoError(t, env.DB.Create(&class).Error)
+	require.NoError(t, env.DB.Create(&models.ProgramClassEnrollment{ClassID: class.ID, UserID: resident.ID, EnrollmentStatus: models.EnrollmentCompleted}).Error)
+	userURL := fmt.Sprintf("/api/users/%d", resident.ID)
+
+	listResidents := func(t *testing.T, include string) []models.User {
+		return NewRequest[[]models.User](env.Client, t, http.MethodGet, "/api/users?role=student"+include, nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+	}
+
+	t.Run("Deleting a user deactivates them and keeps their records", func(t *testing.T) {
+		NewRequest[string](env.Client, t, http.MethodDelete, userURL, nil).
+			WithTestClaims(claims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusNoContent)
+		NewRequest[any](env.Client, t, http.MethodDelete, userURL, nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+
+		user := models.User{}
+		require.NoError(t, env.DB.First(&user, resident.ID).Error)
+		require.NotNil(t, user.DeactivatedAt)
+		var enrollments int64
+		require.NoError(t, env.DB.Model(&models.ProgramClassEnrollment{}).Where("user_id = ?", resident.ID).Count(&enrollments).Error)
+		require.Equal(t, int64(1), enrollments)
+		require.Empty(t, listResidents(t, ""))
+		deactivated := listResidents(t, "&include=only_deactivated")
+		require.Len(t, deactivated, 1)
+		require.Equal(t, resident.ID, deactivated[0].ID)
+	})
+
+	t.Run("Reactivating a user restores their login", func(t *testing.T) {
+		user := NewRequest[models.User](env.Client, t, http.MethodPut, userURL+"/reactivate", nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Nil(t, user.DeactivatedAt)
+		NewRequest[any](env.Client, t, http.MethodPut, userURL+"/reactivate", nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		require.Len(t, listResidents(t, ""), 1)
+
+		history := []models.UserAccountHistory{}
+		require.NoError(t, env.DB.Where("user_id = ? AND action IN (?)", resident.ID, []models.ActivityHistoryAction{models.AccountDeactivation, models.AccountReactivation}).Order("created_at").Find(&history).Error)
+		require.Len(t, history, 2)
+		require.Equal(t, models.AccountDeactivation, history[0].Action)
+		require.Equal(t, models.AccountReactivation, history[1].Action)
+		require.Equal(t, admin.ID, *history[1].AdminID)
+	})
+
+	t.Run("Released residents are deactivated until they are reactivated", func(t *testing.T) {
+		_, err := env.DB.ReleaseResident(context.Background(), resident.ID, time.Now())
+		require.NoError(t, err)
+		require.Empty(t, listResidents(t, ""))
+		user, err := env.DB.ReactivateUser(context.Background(), int(resident.ID), admin.ID)
+		require.NoError(t, err)
+		require.Nil(t, user.ReleasedAt)
+		due, err := env.DB.GetDueReleases(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Empty(t, due)
+	})
+
+	t.Run("Admins can't deactivate themselves", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodDelete, fmt.Sprintf("/api/users/%d", admin.ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+}
+
+func TestImportResidentsHandler(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("importadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	_, err = env.CreateTestUser("existing", models.Student, facility.ID, "1000")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID}
+
+	t.Run("Dry run reports errors per row", func(t *testing.T) {
+		csv := "username,name_first,name_last,doc_id\n" +
+			"newresident,New,Resident,2000\n" +
+			"existing,Existing,Resident,3000\n" +
+			"bad user,Bad,User,\n" +
+			"newresident,Duplicate,Resident,1000\n"
+		result := NewMultipartRequest[handlers.ResidentImportResult](env.Client, t, http.MethodPost, "/api/users/import?dry_run=true", "file", "residents.csv", []byte(csv)).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.True(t, result.DryRun)
+		require.Equal(t, 4, result.TotalRows)
+		require.Equal(t, 1, result.ValidRows)
+		require.Len(t, result.Errors, 3)
+		require.Equal(t, 3, result.Errors[0].Row)
+		require.Contains(t, result.Errors[0].Errors, "username already exists")
+		require.Contains(t, result.Errors[2].Errors, "username is a duplicate of row 2")
+		require.Contains(t, result.Errors[2].Errors, "doc_id already exists")
+	})
+
+	t.Run("Import is rejected when any row is invalid", func(t *testing.T) {
+		csv := "username,name_first,name_last\nvalidresident,Valid,Resident\nexisting,Existing,Resident\n"
+		NewMultipartRequest[handlers.ResidentImportResult](env.Client, t, http.MethodPost, "/api/users/import", "file", "residents.csv", []byte(csv)).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusUnprocessableEntity)
+		_, err := env.DB.GetUserByUsername("validresident")
+		require.Error(t, err)
+	})
+
+	t.Run("Unknown columns are rejected", func(t *testing.T) {
+		NewMultipartRequest[any](env.Client, t, http.MethodPost, "/api/users/import", "file", "residents.csv", []byte("username,first,last\nabc,A,B\n")).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Import creates residents and returns their temporary passwords", func(t *testing.T) {
+		csv := "Username,Name First,Name Last,DOC ID\nimportone,Import,One,4000\nimporttwo,Import,Two,4001\n"
+		body := NewMultipartRequest[string](env.Client, t, http.MethodPost, "/api/users/import", "file", "residents.csv", []byte(csv)).
+			WithTestClaims(claims).
+			AsRaw().
+			Do().
+			ExpectStatus(http.StatusCreated).
+			GetData()
+		lines := strings.Split(strings.TrimSpace(body), "\n")
+		require.Len(t, lines, 3)
+		require.Equal(t, "username,name_first,name_last,doc_id,temp_password,error", lines[0])
+		require.True(t, strings.HasPrefix(lines[1], "importone,Import,One,4000,"))
+		for _, username := range []string{"importone", "importtwo"} {
+			user, err := env.DB.GetUserByUsername(username)
+			require.NoError(t, err)
+			require.Equal(t, facility.ID, user.FacilityID)
+			require.Equal(t, models.Student, user.Role)
+			var history int64
+			require.NoError(t, env.DB.Model(&models.UserAccountHistory{}).Where("user_id = ?", user.ID).Count(&history).Error)
+			require.Equal(t, int64(2), history)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"UnlockEdv2/src/database"
+	"UnlockEdv2/src/handlers"
+	"UnlockEdv2/src/models"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassWaitlistHandlers(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.CleanupTestEnv()
+
+	facility, err := env.CreateTestFacility("Test Facility")
+	require.NoError(t, err)
+	admin, err := env.CreateTestUser("waitlistadmin", models.FacilityAdmin, facility.ID, "")
+	require.NoError(t, err)
+	claims := &handlers.Claims{Role: models.FacilityAdmin, UserID: admin.ID, FacilityID: facility.ID}
+
+	residents := make([]*models.User, 0, 4)
+	for idx := range 4 {
+		resident, err := env.CreateTestUser(fmt.Sprintf("waitlistresident%d", idx), models.Student, facility.ID, fmt.Sprintf("700%d", idx))
+		require.NoError(t, err)
+		residents = append(residents, resident)
+	}
+
+	program, err := env.CreateTestProgram("Waitlist Program", models.FundingType(models.FederalGrants), []models.ProgramType{}, []models.ProgramCreditType{}, true)
+	require.NoError(t, err)
+	class := newClass(program, facility)
+	class.Capacity = 2
+	require.NoError(t, env.DB.Create(&class).Error)
+	waitlistEndpoint := fmt.Sprintf("/api/program-classes/%d/waitlist", class.ID)
+
+	getWaitlist := func(t *testing.T) []uint {
+		waitlist := NewRequest[[]database.EnrollmentDetails](env.Client, t, http.MethodGet, waitlistEndpoint, nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		userIDs := make([]uint, 0, len(waitlist))
+		for _, enrollment := range waitlist {
+			require.Equal(t, models.EnrollmentWaitlisted, enrollment.EnrollmentStatus)
+			userIDs = append(userIDs, enrollment.UserID)
+		}
+		return userIDs
+	}
+
+	t.Run("Enrolling past capacity adds residents to the waitlist", func(t *testing.T) {
+		userIDs := []int{int(residents[0].ID), int(residents[1].ID), int(residents[2].ID), int(residents[3].ID)}
+		NewRequest[any](env.Client, t, http.MethodPost, fmt.Sprintf("/api/program-classes/%d/enrollments", class.ID), map[string]any{"user_ids": userIDs}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusCreated).
+			ExpectMessage("2 users were enrolled, 2 were added to the waitlist because capacity is full.")
+		require.Equal(t, []uint{residents[2].ID, residents[3].ID}, getWaitlist(t))
+	})
+
+	t.Run("Reorder the waitlist", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPut, waitlistEndpoint, map[string]any{"user_ids": []uint{residents[3].ID}}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		NewRequest[any](env.Client, t, http.MethodPut, waitlistEndpoint, map[string]any{"user_ids": []uint{residents[3].ID, residents[2].ID}}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		require.Equal(t, []uint{residents[3].ID, residents[2].ID}, getWaitlist(t))
+	})
+
+	t.Run("Residents cannot be waitlisted directly", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPatch, fmt.Sprintf("/api/program-classes/%d/enrollments", class.ID), map[string]any{"user_ids": []uint{residents[0].ID}, "enrollment_status": models.EnrollmentWaitlisted}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+	})
+
+	t.Run("Dropping a resident promotes the front of the waitlist", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPatch, fmt.Sprintf("/api/program-classes/%d/enrollments", class.ID), map[string]any{"user_ids": []uint{residents[0].ID}, "enrollment_status": models.EnrollmentIncompleteDropped}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		require.Equal(t, []uint{residents[2].ID}, getWaitlist(t))
+		front := models.ProgramClassEnrollment{}
+		require.NoError(t, env.DB.First(&front, "class_id = ? AND user_id = ?", class.ID, residents[2].ID).Error)
+		require.Equal(t, 1, *front.WaitlistPosition, "the waitlist is renumbered after a promotion")
+
+		enrollment := models.ProgramClassEnrollment{}
+		require.NoError(t, env.DB.First(&enrollment, "class_id = ? AND user_id = ?", class.ID, residents[3].ID).Error)
+		require.Equal(t, models.Enrolled, enrollment.EnrollmentStatus)
+		require.Nil(t, enrollment.WaitlistPosition)
+
+		history := NewRequest[[]models.ActivityHistoryResponse](env.Client, t, http.MethodGet, fmt.Sprintf("/api/users/%d/account-history", residents[3].ID), nil).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK).
+			GetData()
+		require.Len(t, history, 1)
+		require.Equal(t, models.WaitlistPromoted, history[0].Action)
+		require.Equal(t, class.Name, *history[0].ClassName)
+		require.Equal(t, "waitlistadmin", *history[0].AdminUsername)
+	})
+
+	t.Run("Waitlisted residents are only enrolled into open seats", func(t *testing.T) {
+		NewRequest[any](env.Client, t, http.MethodPatch, fmt.Sprintf("/api/program-classes/%d/enrollments", class.ID), map[string]any{"user_ids": []uint{residents[2].ID}, "enrollment_status": models.Enrolled}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		require.Equal(t, []uint{residents[2].ID}, getWaitlist(t))
+	})
+
+	t.Run("Residents moved to the waitlist join its end", func(t *testing.T) {
+		_, err := env.DB.UpdateProgramClassEnrollments(context.Background(), int(class.ID), []int{int(residents[0].ID), int(residents[1].ID)}, string(models.EnrollmentWaitlisted), nil, &admin.ID)
+		require.NoError(t, err)
+		require.Equal(t, []uint{residents[2].ID, residents[0].ID, residents[1].ID}, getWaitlist(t))
+		positions := []int{}
+		require.NoError(t, env.DB.Model(&models.ProgramClassEnrollment{}).
+			Where("class_id = ? AND enrollment_status = ?", class.ID, models.EnrollmentWaitlisted).
+			Order("waitlist_position").
+			Pluck("waitlist_position", &positions).Error)
+		require.Equal(t, []int{1, 2, 3}, positions)
+
+		// one seat is open now, so only the front of the waitlist can be enrolled
+		NewRequest[any](env.Client, t, http.MethodPatch, fmt.Sprintf("/api/program-classes/%d/enrollments", class.ID), map[string]any{"user_ids": []uint{residents[0].ID, residents[1].ID}, "enrollment_status": models.Enrolled}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusBadRequest)
+		NewRequest[any](env.Client, t, http.MethodPatch, fmt.Sprintf("/api/program-classes/%d/enrollments", class.ID), map[string]any{"user_ids": []uint{residents[2].ID}, "enrollment_status": models.Enrolled}).
+			WithTestClaims(claims).
+			Do().
+			ExpectStatus(http.StatusOK)
+		require.Equal(t, []uint{residents[0].ID, residents[1].ID}, getWaitlist(t))
+		require.NoError(t, env.DB.Model(&models.ProgramClassEnrollment{}).
+			Where("class_id = ? AND enrollment_status = ?", class.ID, models.EnrollmentWaitlisted).
+			Order("waitlist_position").
+			Pluck("waitlist_position", &positions).Error)
+		require.Equal(t, []int{1, 2}, positions)
+	})
+}
@@ -53,6 +53,11 @@ function ClassInfoCard({ classInfo }: { classInfo?: Class }) {
                 Description: {classInfo?.description}
             </ClampedText>
             <p className="body mb-1">Class Status: {classInfo?.status}</p>
+            {classInfo?.flagged_reason && (
+                <p className="body mb-1 text-error">
+                    {classInfo.flagged_reason}
+                </p>
+            )}
             <p className="body mt-5  mb-1">
                 Instructor(s): {classInfo?.instructor_name}
             </p>
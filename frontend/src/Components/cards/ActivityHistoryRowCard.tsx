@@ -60,6 +60,9 @@ function ActivityHistoryRowCard({
             case 'event_cancelled':
                 text = `Event on ${parseRRule(activity.new_value, user.timezone)} cancelled by ${activity.admin_username}`;
                 break;
+            case 'event_restored':
+                text = `Event on ${parseRRule(activity.old_value, user.timezone)} restored by ${activity.admin_username}`;
+                break;
             case 'event_rescheduled':
                 text = `Event on ${parseRRule(activity.old_value, user.timezone)} moved to ${activity.new_value} by ${activity.admin_username}`;
                 break;
@@ -87,6 +90,18 @@ function ActivityHistoryRowCard({
         case 'progclass_history':
             introText = getProgramClassesHistoryEventText();
             break;
+        case 'waitlist_promoted':
+            introText = `Enrolled in ${activity.class_name} from the waitlist`;
+            break;
+        case 'resident_release':
+            introText = `Released from ${activity.facility_name}`;
+            break;
+        case 'account_deactivation':
+            introText = `Account deactivated by ${activity.admin_username}`;
+            break;
+        case 'account_reactivation':
+            introText = `Account reactivated by ${activity.admin_username}`;
+            break;
     }
     if (!introText) return;
     return (
@@ -105,6 +105,13 @@ export default function CompletionDetailsModal({
                                 </span>{' '}
                                 {completionDetails.admin_email}
                             </p>
+                            <a
+                                className="button-outline w-fit"
+                                href={`/api/users/${completionDetails.user_id}/program-completions/${completionDetails.id}/certificate`}
+                                download
+                            >
+                                Download Certificate
+                            </a>
                         </div>
                     </div>
                 ) : (
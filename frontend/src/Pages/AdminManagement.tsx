@@ -92,7 +92,7 @@ export default function AdminManagement() {
     const deleteUser = async () => {
         if (targetUser?.target.role === UserRole.SystemAdmin) {
             toaster(
-                'This is the primary administrator and cannot be deleted',
+                'This is the primary administrator and cannot be deactivated',
                 ToastState.error
             );
             return;
@@ -100,8 +100,8 @@ export default function AdminManagement() {
         const response = await API.delete('users/' + targetUser?.target.id);
         checkResponseForDelete(
             response.success,
-            'Failed to delete administrator',
-            'Administrator deleted successfully'
+            'Failed to deactivate administrator',
+            'Administrator deactivated successfully'
         );
         handleCancelModal(deleteUserModal);
     };
@@ -325,7 +325,7 @@ export default function AdminManagement() {
                                                     ) ? (
                                                         <ULIComponent
                                                             dataTip={
-                                                                'Delete Admin'
+                                                                'Deactivate Admin'
                                                             }
                                                             tooltipClassName="tooltip-left cursor-pointer"
                                                             onClick={() => {
@@ -341,7 +341,7 @@ export default function AdminManagement() {
                                                       UserRole.SystemAdmin ? (
                                                         <ULIComponent
                                                             dataTip={
-                                                                'Cannot Delete'
+                                                                'Cannot Deactivate'
                                                             }
                                                             tooltipClassName="tooltip-left cursor-pointer"
                                                             icon={
@@ -394,9 +394,9 @@ export default function AdminManagement() {
             <TextOnlyModal
                 ref={deleteUserModal}
                 type={TextModalType.Delete}
-                title={'Delete Admin'}
+                title={'Deactivate Admin'}
                 text={
-                    'Are you sure you would like to delete this admin? This action cannot be undone.'
+                    'Are you sure you would like to deactivate this admin? They will no longer be able to log in.'
                 }
                 onSubmit={() => void deleteUser()}
                 onClose={() => handleCancelModal(deleteUserModal)}
@@ -130,7 +130,7 @@ const ResidentProfile = () => {
         const user = targetUser?.target;
         if (user?.role === UserRole.SystemAdmin) {
             toaster(
-                'This is the primary administrator and cannot be deleted',
+                'This is the primary administrator and cannot be deactivated',
                 ToastState.error
             );
             return;
@@ -140,8 +140,8 @@ const ResidentProfile = () => {
 
         checkResponseForDelete(
             response.success,
-            'Failed to delete user',
-            'User deleted successfully'
+            'Failed to deactivate user',
+            'User deactivated successfully'
         );
         if (response.success) {
             navigate('/residents');
@@ -246,7 +246,7 @@ const ResidentProfile = () => {
                                         showModal(deleteUserModal);
                                     }}
                                 >
-                                    Delete Resident
+                                    Deactivate Resident
                                 </button>
                                 {user && canSwitchFacility(user) && (
                                     <button
@@ -423,9 +423,9 @@ const ResidentProfile = () => {
             <TextOnlyModal
                 ref={deleteUserModal}
                 type={TextModalType.Delete}
-                title={'Delete Resident'}
+                title={'Deactivate Resident'}
                 text={
-                    'Are you sure you would like to delete this resident? This action cannot be undone.'
+                    'Are you sure you would like to deactivate this resident? They will no longer be able to log in, and their history will be kept.'
                 }
                 onSubmit={() => void deleteUser()}
                 onClose={() => void closeModal(deleteUserModal)}
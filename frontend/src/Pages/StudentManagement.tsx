@@ -100,7 +100,7 @@ export default function StudentManagement() {
     const deleteUser = async () => {
         if (targetUser?.target.role === UserRole.SystemAdmin) {
             toaster(
-                'This is the primary administrator and cannot be deleted',
+                'This is the primary administrator and cannot be deactivated',
                 ToastState.error
             );
             return;
@@ -108,8 +108,8 @@ export default function StudentManagement() {
         const response = await API.delete('users/' + targetUser?.target.id);
         checkResponseForDelete(
             response.success,
-            'Failed to delete user',
-            'User deleted successfully'
+            'Failed to deactivate user',
+            'User deactivated successfully'
         );
         handleCancelModal(deleteUserModal);
     };
@@ -295,7 +295,7 @@ export default function StudentManagement() {
 
                                                     <ULIComponent
                                                         dataTip={
-                                                            'Delete Resident'
+                                                            'Deactivate Resident'
                                                         }
                                                         tooltipClassName="tooltip-left cursor-pointer"
                                                         icon={TrashIcon}
@@ -355,9 +355,9 @@ export default function StudentManagement() {
             <TextOnlyModal
                 ref={deleteUserModal}
                 type={TextModalType.Delete}
-                title={'Delete Resident'}
+                title={'Deactivate Resident'}
                 text={
-                    'Are you sure you would like to delete this resident? This action cannot be undone.'
+                    'Are you sure you would like to deactivate this resident? They will no longer be able to log in, and their history will be kept.'
                 }
                 onSubmit={() => void deleteUser()}
                 onClose={() => void handleCancelModal(deleteUserModal)}
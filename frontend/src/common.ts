@@ -4,6 +4,7 @@ export enum UserRole {
     SystemAdmin = 'system_admin',
     DepartmentAdmin = 'department_admin',
     FacilityAdmin = 'facility_admin',
+    Instructor = 'instructor',
     Student = 'student'
 }
 
@@ -36,6 +37,9 @@ export interface User {
     feature_access: FeatureAccess[];
     timezone: string;
     facilities?: Facility[];
+    release_date?: string;
+    released_at?: string;
+    deactivated_at?: string;
     [key: string]:
         | number
         | string
@@ -492,7 +496,9 @@ export enum ProviderPlatformType {
     CANVAS_CLOUD = 'canvas_cloud',
     CANVAS_OSS = 'canvas_oss',
     KOLIBRI = 'kolibri',
-    BRIGHTSPACE = 'brightspace'
+    BRIGHTSPACE = 'brightspace',
+    MOODLE = 'moodle',
+    OPEN_EDX = 'open_edx'
 }
 
 export enum FundingType {
@@ -775,6 +781,7 @@ export interface Class {
     capacity: number;
     credit_hours: number;
     archived_at: string | null;
+    flagged_reason: string | null;
     enrollments?: ClassEnrollment[];
     events: ProgramClassEvent[];
     created_at: Date;
@@ -815,6 +822,19 @@ export interface FacilityProgramClassEvent extends ProgramClassEvent {
     start: Date;
     end: Date;
     frequency: string;
+    closure_reason?: string;
+}
+
+export interface FacilityClosure {
+    id: number;
+    facility_id: number;
+    start_dt: string;
+    end_dt: string;
+    reason: string;
+    rooms: string[] | null;
+    create_user_id: number;
+    cancelled_sessions: number;
+    created_at: Date;
 }
 
 export interface CalendarEvent {
@@ -910,7 +930,8 @@ export enum EnrollmentStatus {
     Withdrawn = 'Incomplete: Withdrawn',
     Dropped = 'Incomplete: Dropped',
     'Failed To Complete' = 'Incomplete: Failed to Complete',
-    Transfered = 'Incomplete: Transfered'
+    Transfered = 'Incomplete: Transfered',
+    Released = 'Incomplete: Released'
 }
 
 export enum CancelEventReason {
@@ -948,6 +969,7 @@ export enum AttendanceFlagType {
 
 export interface ProgramCompletion {
     id: number;
+    user_id: number;
     program_class_id: number;
     facility_name: string;
     credit_type: string;
@@ -1141,8 +1163,10 @@ export interface ActivityHistoryResponse {
     user_username: string;
     admin_username?: string;
     facility_name?: string;
+    class_name?: string;
     program_classes_history_id?: number;
     program_classes_history?: ProgramClassesHistory;
+    transfer_packet_id?: number;
     field_name: string;
     new_value: string;
     old_value: string;
@@ -1162,7 +1186,11 @@ export type ActivityHistoryAction =
     | 'facility_transfer'
     | 'set_password'
     | 'reset_password'
-    | 'progclass_history';
+    | 'progclass_history'
+    | 'waitlist_promoted'
+    | 'resident_release'
+    | 'account_deactivation'
+    | 'account_reactivation';
 
 export type ErrorType = 'unauthorized' | 'not-found' | 'server-error';
 
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -37,6 +38,8 @@ type BrightspaceService struct {
 	JobParams          map[string]any
 	IsDownloaded       bool //flag to let process know that bulk data has been downloaded
 	CsvFileMap         map[string]string
+	ExportIDs          map[string]string //latest export of each data set, used as the sync cursor
+	GrantedScopes      string
 }
 
 func newBrightspaceService(provider *models.ProviderPlatform, db *gorm.DB, params map[string]any) (*BrightspaceService, error) {
@@ -85,8 +88,12 @@ func newBrightspaceService(provider *models.ProviderPlatform, db *gorm.DB, param
 		return nil, errors.New(msg)
 	}
 	brightspaceService.AccessToken = tokenMap["access_token"].(string)
+	brightspaceService.GrantedScopes, _ = tokenMap["scope"].(string)
 	brightspaceService.RefreshToken = tokenMap["refresh_token"].(string)
 	provider.AccessKey = brightspaceService.ClientSecret + ";" + brightspaceService.RefreshToken
+	if key, err := models.EncryptAccessKey(provider.AccessKey); err == nil {
+		provider.AccessKey = key
+	}
 	if err := db.Save(&provider).Error; err != nil {
 		log.Errorf("error trying to update provider access_key with new refresh token, error is %v", err)
 		return nil, err
@@ -97,9 +104,25 @@ func newBrightspaceService(provider *models.ProviderPlatform, db *gorm.DB, param
 	headers["Accept"] = "application/json"
 	brightspaceService.BaseHeaders = headers
 	brightspaceService.CsvFileMap = make(map[string]string)
+	brightspaceService.ExportIDs = make(map[string]string)
 	return &brightspaceService, nil
 }
 
+// newUnauthenticatedBrightspaceService can only call the public APIs of a brightspace instance,
+// it is used to test the connection of a provider that hasn't gone through the OAuth flow yet
+func newUnauthenticatedBrightspaceService(provider *models.ProviderPlatform) *BrightspaceService {
+	return &BrightspaceService{
+		ProviderPlatformID: provider.ID,
+		Client:             &http.Client{},
+		BaseURL:            strings.TrimSuffix(provider.BaseUrl, "/"),
+		ClientID:           provider.AccountID,
+		Scope:              models.BrightspaceScopes,
+		BaseHeaders:        map[string]string{"Accept": "application/json"},
+		CsvFileMap:         make(map[string]string),
+		ExportIDs:          make(map[string]string),
+	}
+}
+
 func (srv *BrightspaceService) SendPostRequest(url string, data url.Values) (*http.Response, error) {
 	encodedUrl := data.Encode()
 	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(encodedUrl))
@@ -176,6 +199,15 @@ func (srv *BrightspaceService) GetUsers(db *gorm.DB) ([]models.ImportUser, error
 }
 
 func (srv *BrightspaceService) ImportCourses(db *gorm.DB) error {
+	exportID, err := srv.exportID("Content Objects", "Organizational Units")
+	if err != nil {
+		log.Errorf("error attempting to get the data exports for Brightspace courses, error is: %v", err)
+		return err
+	}
+	if exportID == getSyncCursor(db, srv.ProviderPlatformID, models.CoursesSyncEntity) {
+		log.Infof("brightspace course data sets have not been regenerated since the last sync, skipping")
+		return nil
+	}
 	contentObjCsvFile, err := srv.getBrightspaceBulkData("Content Objects", "courses/ContentObjects.zip")
 	if err != nil {
 		log.Errorf("error attempting to get bulk data for Brightspace content objects for importing activities, error is: %v", err)
@@ -201,6 +233,7 @@ func (srv *BrightspaceService) ImportCourses(db *gorm.DB) error {
 	cleanUpFiles(organizationalCsvFile, contentObjCsvFile)
 	fields := log.Fields{"provider": srv.ProviderPlatformID, "Function": "ImportCourses", "organizationalCsvFile": organizationalCsvFile, "contentObjCsvFile": contentObjCsvFile}
 	log.WithFields(fields).Info("importing courses from provider using csv file")
+	failed := false
 	for _, bsCourse := range bsCourses {
 		if strings.ToUpper(bsCourse.IsActive) == "TRUE" && strings.ToUpper(bsCourse.IsDeleted) == "FALSE" && bsCourse.Type == "Course Offering" && totalProgressMilestonesMap[bsCourse.OrgUnitId] > 0 {
 			bsCourse.TotalContentCount = totalProgressMilestonesMap[bsCourse.OrgUnitId]
@@ -212,10 +245,14 @@ func (srv *BrightspaceService) ImportCourses(db *gorm.DB) error {
 			course := srv.IntoCourse(bsCourse)
 			if err := db.Create(&course).Error; err != nil {
 				log.Errorf("error creating course in db, error is: %v", err)
+				failed = true
 				continue
 			}
 		}
 	}
+	if !failed {
+		setSyncCursor(db, srv.ProviderPlatformID, models.CoursesSyncEntity, exportID)
+	}
 	return nil
 }
 
@@ -228,6 +265,15 @@ func (srv *BrightspaceService) updateTotalProgress(db *gorm.DB, totalProgressMil
 }
 
 func (srv *BrightspaceService) ImportMilestones(course map[string]interface{}, users []map[string]interface{}, db *gorm.DB, lastRun time.Time) error {
+	entity := models.CourseSyncEntity(models.MilestonesSyncEntity, uint(course["course_id"].(int64)))
+	exportID, err := srv.exportID("User Enrollments", "Assignment Submissions", "Quiz Attempts")
+	if err != nil {
+		log.Errorf("error attempting to get the data exports for Brightspace milestones, error is: %v", err)
+		return err
+	}
+	if exportID == getSyncCursor(db, srv.ProviderPlatformID, entity) {
+		return nil
+	}
 	usersMap := make(map[string]uint)
 	for _, user := range users {
 		usersMap[user["external_user_id"].(string)] = uint(user["user_id"].(int64))
@@ -239,19 +285,26 @@ func (srv *BrightspaceService) ImportMilestones(course map[string]interface{}, u
 	if !srv.IsDownloaded {
 		cleanUpCsvFiles("milestones")
 	}
-	err := importBSEnrollmentMilestones(srv, paramObj, db)
+	failed := false
+	err = importBSEnrollmentMilestones(srv, paramObj, db)
 	if err != nil {
 		log.Errorln("error importing enrollment milestones, error is ", err)
+		failed = true
 	}
 	err = importBSAssignmentSubmissionMilestones(srv, paramObj, db)
 	if err != nil {
 		log.Errorln("error importing assignment submission milestones, error is ", err)
+		failed = true
 	}
 	err = importBSQuizSubmissionMilestones(srv, paramObj, db)
 	if err != nil {
 		log.Errorln("error importing quiz submission milestones, error is ", err)
+		failed = true
 	}
 	srv.IsDownloaded = true
+	if !failed {
+		setSyncCursor(db, srv.ProviderPlatformID, entity, exportID)
+	}
 	return nil
 }
 
@@ -470,13 +523,21 @@ func addQuizMilestoneIfNotExists(db *gorm.DB, userId, courseId uint, bsQuiz Brig
 }
 
 func (srv *BrightspaceService) ImportActivityForCourse(course map[string]any, db *gorm.DB) error {
+	entity := models.CourseSyncEntity(models.ActivitySyncEntity, uint(course["course_id"].(int64)))
+	exportID, err := srv.exportID("Content Objects", "Content User Progress")
+	if err != nil {
+		log.Errorf("error attempting to get the data exports for Brightspace activity, error is: %v", err)
+		return err
+	}
+	if exportID == getSyncCursor(db, srv.ProviderPlatformID, entity) {
+		return nil
+	}
 	if !srv.IsDownloaded {
 		cleanUpCsvFiles("activities")
 	}
 	var (
 		contentObjCsvFile   string
 		userProgressCsvFile string
-		err                 error
 	)
 	switch srv.IsDownloaded {
 	case true:
@@ -502,6 +563,7 @@ func (srv *BrightspaceService) ImportActivityForCourse(course map[string]any, db
 	bsContentDtoMap := srv.getUsersActivity(db, externalCourseId, contentObjCsvFile, userProgressCsvFile)
 	fields := log.Fields{"provider": srv.ProviderPlatformID, "Function": "ImportActivityForCourse", "contentObjCsvFile": contentObjCsvFile, "userProgressCsvFile": userProgressCsvFile}
 	log.WithFields(fields).Info("importing activities from provider using csv file")
+	failed := false
 	for userId, bsUserContentDtos := range bsContentDtoMap {
 		for _, bsDto := range bsUserContentDtos {
 			var acts []map[string]any
@@ -512,10 +574,14 @@ func (srv *BrightspaceService) ImportActivityForCourse(course map[string]any, db
 			if err = db.Exec("CALL insert_daily_activity_brightspace(?, ?, ?, ?, ?, ?)",
 				userId, courseId, models.ContentInteraction, bsDto.TotalTime, bsDto.ExternalId, time.Now()).Error; err != nil {
 				log.WithFields(log.Fields{"user_id": userId, "course_id": courseId, "error": err}).Error("Failed to create activity using brightspace data")
+				failed = true
 				continue
 			}
 		}
 	}
+	if !failed {
+		setSyncCursor(db, srv.ProviderPlatformID, entity, exportID)
+	}
 	return nil
 }
 
@@ -562,3 +628,77 @@ func (srv *BrightspaceService) getUsersActivity(db *gorm.DB, externalCourseId, c
 func (srv *BrightspaceService) GetJobParams() map[string]interface{} {
 	return srv.JobParams
 }
+
+/**
+* A refresh token can only be used once, so authentication has already been checked
+* by the time the service is created. An unsaved provider has no refresh token yet,
+* so only the public versions API can be checked for it.
+* @info - GET /d2l/api/versions/, GET /d2l/api/lp/{version}/users/whoami
+**/
+func (srv *BrightspaceService) TestConnection(report *models.ProviderConnectionReport, db *gorm.DB) {
+	resp, err := srv.SendRequest(srv.BaseURL + "/d2l/api/versions/")
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", srv.BaseURL, err)
+		return
+	}
+	products := []BrightspaceProductVersions{}
+	err = json.NewDecoder(resp.Body).Decode(&products)
+	if resp.Body.Close() != nil {
+		logger().Error("Failed to close response body")
+	}
+	if err != nil || resp.StatusCode != http.StatusOK {
+		report.Fail(models.CheckConnectivity, "%s does not appear to be a brightspace instance (%s)", srv.BaseURL, resp.Status)
+		return
+	}
+	report.Pass(models.CheckConnectivity, "%s is reachable", srv.BaseURL)
+	for _, product := range products {
+		if product.ProductCode != "lp" {
+			continue
+		}
+		report.APIVersion = product.LatestVersion
+		if !slices.Contains(product.SupportedVersions, models.BrightspaceApiVersion) {
+			report.Fail(models.CheckAPIVersion, "learning platform API %s is not supported (latest is %s)", models.BrightspaceApiVersion, product.LatestVersion)
+			return
+		}
+		report.Pass(models.CheckAPIVersion, "learning platform API %s supported", models.BrightspaceApiVersion)
+	}
+	if srv.AccessToken == "" {
+		report.Pass(models.CheckAuthentication, "credentials will be verified when the provider is authorized after it is saved")
+		return
+	}
+	resp, err = srv.SendRequest(fmt.Sprintf("%s/d2l/api/lp/%s/users/whoami", srv.BaseURL, models.BrightspaceApiVersion))
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", srv.BaseURL, err)
+		return
+	}
+	whoami := struct {
+		UniqueName string `json:"UniqueName"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&whoami)
+	if resp.Body.Close() != nil {
+		logger().Error("Failed to close response body")
+	}
+	if err != nil || resp.StatusCode != http.StatusOK {
+		report.Fail(models.CheckAuthentication, "token was refreshed but brightspace rejected it (%s)", resp.Status)
+		return
+	}
+	report.Pass(models.CheckAuthentication, "token valid for %s", whoami.UniqueName)
+	required := strings.Fields(models.BrightspaceScopes)
+	reportScopes(report, required, missingScopes(required, strings.Fields(srv.GrantedScopes)))
+
+	if _, err := srv.getPluginId(brightspaceDataSets[0]); err != nil {
+		report.Fail(models.CheckDataSets, "unable to list the data sets: %v", err)
+		return
+	}
+	missing := make([]string, 0)
+	for _, name := range brightspaceDataSets {
+		if _, ok := srv.ExportIDs[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		report.Fail(models.CheckDataSets, "data sets not available: %s", strings.Join(missing, ", "))
+		return
+	}
+	report.Pass(models.CheckDataSets, "all %d data sets available", len(brightspaceDataSets))
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gocarina/gocsv"
 	"github.com/microcosm-cc/bluemonday"
@@ -307,6 +308,9 @@ func (srv *BrightspaceService) getPluginId(pluginName string) (string, error) {
 		log.Errorf("error decoding to response from url %v, error is: %v", DataSetsEndpoint, err)
 		return pluginId, err
 	}
+	for _, plugin := range pluginData {
+		srv.ExportIDs[plugin.Name] = plugin.PluginId + "@" + plugin.CreatedDate
+	}
 	for _, plugin := range pluginData {
 		if plugin.Name == pluginName {
 			log.Infof("found plugin named %v with id %v", plugin.Name, plugin.PluginId)
@@ -317,6 +321,30 @@ func (srv *BrightspaceService) getPluginId(pluginName string) (string, error) {
 	return pluginId, nil
 }
 
+// exportID identifies the latest export of the given data sets. Brightspace only regenerates
+// them periodically, so if it matches the sync cursor there is nothing new to import.
+func (srv *BrightspaceService) exportID(pluginNames ...string) (string, error) {
+	ids := make([]string, 0, len(pluginNames))
+	for _, name := range pluginNames {
+		if _, ok := srv.ExportIDs[name]; !ok {
+			if _, err := srv.getPluginId(name); err != nil {
+				return "", err
+			}
+		}
+		ids = append(ids, srv.ExportIDs[name])
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// the data sets downloaded by the sync jobs
+var brightspaceDataSets = []string{"Users", "Organizational Units", "Content Objects", "User Enrollments", "Assignment Submissions", "Quiz Attempts", "Content User Progress"}
+
+type BrightspaceProductVersions struct {
+	ProductCode       string   `json:"ProductCode"`
+	LatestVersion     string   `json:"LatestVersion"`
+	SupportedVersions []string `json:"SupportedVersions"`
+}
+
 func readCSV[T any](values *T, csvFilePath string) {
 	coursesFile, err := os.OpenFile(csvFilePath, os.O_RDWR|os.O_CREATE, os.ModePerm)
 	if err != nil {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -128,34 +130,69 @@ func (srv *CanvasService) GetUsers(db *gorm.DB) ([]models.ImportUser, error) {
 	return unlockedUsers, nil
 }
 
-func (srv *CanvasService) ImportCourses(db *gorm.DB) error {
-	url := srv.BaseURL + "/api/v1/accounts/" + srv.AccountID + "/courses?include[]=course_image&include[]=public_description"
-	resp, err := srv.SendRequest(url)
-	if err != nil {
-		log.Printf("Failed to send request: %v", err)
-		return err
+// nextPageURL returns the rel="next" url from canvas' pagination Link header, if there is one
+func nextPageURL(resp *http.Response) string {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 || strings.TrimSpace(parts[1]) != `rel="next"` {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(parts[0]), "<>")
 	}
-	fields := log.Fields{"provider": srv.ProviderPlatformID, "Function": "ImportCourses"}
-	log.WithFields(fields).Info("importing courses from provider")
-	defer func() {
+	return ""
+}
+
+func (srv *CanvasService) getAccountCourses() ([]map[string]any, error) {
+	url := srv.BaseURL + "/api/v1/accounts/" + srv.AccountID + "/courses?include[]=course_image&include[]=public_description&per_page=100"
+	courses := make([]map[string]any, 0)
+	for url != "" {
+		resp, err := srv.SendRequest(url)
+		if err != nil {
+			log.Printf("Failed to send request: %v", err)
+			return nil, err
+		}
+		page := make([]map[string]any, 0)
+		err = json.NewDecoder(resp.Body).Decode(&page)
 		if resp.Body.Close() != nil {
 			logger().Error("Failed to close response body")
 		}
-	}()
-	courses := make([]map[string]any, 0)
-	err = json.NewDecoder(resp.Body).Decode(&courses)
+		if err != nil {
+			log.Printf("Failed to decode response: %v", err)
+			return nil, err
+		}
+		courses = append(courses, page...)
+		url = nextPageURL(resp)
+	}
+	return courses, nil
+}
+
+/**
+* The courses API has no way to filter on when a course was created or updated, so the cursor
+* is the highest canvas course id that has been imported. Course ids only ever increase, so
+* anything at or below it has already been handled and we skip straight past it.
+**/
+func (srv *CanvasService) ImportCourses(db *gorm.DB) error {
+	fields := log.Fields{"provider": srv.ProviderPlatformID, "Function": "ImportCourses"}
+	log.WithFields(fields).Info("importing courses from provider")
+	courses, err := srv.getAccountCourses()
 	if err != nil {
-		log.Printf("Failed to decode response: %v", err)
 		return err
 	}
+	cursor, _ := strconv.Atoi(getSyncCursor(db, srv.ProviderPlatformID, models.CoursesSyncEntity))
+	highestID, failed := cursor, false
 	for _, course := range courses {
-		id := int(course["id"].(int64))
+		id := int(course["id"].(float64))
+		if id <= cursor {
+			continue
+		}
+		highestID = max(highestID, id)
 		var count int64 = 0
 		log.Infof("importing course %d", id)
 		if db.Table("courses").Where("provider_platform_id = ?", srv.ProviderPlatformID).
 			Where("external_id = ?", fmt.Sprintf("%d", id)).
 			Count(&count).Error != nil {
 			log.Error("error getting count of provider courses")
+			failed = true
 			continue
 		}
 		if count > 0 {
@@ -204,9 +241,14 @@ func (srv *CanvasService) ImportCourses(db *gorm.DB) error {
 		}
 		if err = db.Create(&unlockedCourse).Error; err != nil {
 			log.Printf("Failed to create course: %v", err)
+			failed = true
 			continue
 		}
 	}
+	// leave the cursor where it was so the courses that failed are retried next run
+	if !failed && highestID > cursor {
+		setSyncCursor(db, srv.ProviderPlatformID, models.CoursesSyncEntity, strconv.Itoa(highestID))
+	}
 	return nil
 }
 
@@ -329,9 +371,9 @@ func (srv *CanvasService) getCountAssignmentsForCourse(courseId int) (int, error
 // 	return submissions, nil
 // }
 
-func (srv *CanvasService) getUsersSubmissionsForCourse(courseId string, queryString url.Values, lastRun time.Time) ([]map[string]interface{}, error) {
+func (srv *CanvasService) getUsersSubmissionsForCourse(courseId string, queryString url.Values, since time.Time) ([]map[string]interface{}, error) {
 	fields := log.Fields{"handler": "getUserSubmissionForCourse"}
-	queryString.Add("submitted_since", lastRun.Format(time.RFC3339))
+	queryString.Add("submitted_since", since.Format(time.RFC3339))
 	queryString.Add("per_page", "100")
 	url := srv.BaseURL + "/api/v1/courses/" + courseId + "/students/submissions"
 	url += "?" + queryString.Encode()
@@ -369,10 +411,21 @@ func (srv *CanvasService) getUsersSubmissionsForCourse(courseId string, queryStr
 *  /api/v1/courses/:course_id/assignments/:assignment_id/submissions/:user_id
 *
 * Quizzes are included in assignments so we don't need to get them separately
+*
+* Submissions are requested from the course's milestones cursor rather than the
+* last run of the task, so a course that failed to sync is caught up on the next run
 * */
 func (srv *CanvasService) ImportMilestones(courseIdPair map[string]any, mapping []map[string]any, db *gorm.DB, lastRun time.Time) error {
 	courseId := int(courseIdPair["course_id"].(int64))
 	externalCourseId := courseIdPair["external_course_id"].(string)
+	entity := models.CourseSyncEntity(models.MilestonesSyncEntity, uint(courseId))
+	syncStarted := time.Now()
+	var since time.Time
+	if cursor := getSyncCursor(db, srv.ProviderPlatformID, entity); cursor != "" {
+		if parsed, err := time.Parse(time.RFC3339, cursor); err == nil {
+			since = parsed
+		}
+	}
 	values := url.Values{}
 	reversed := make(map[string]int)
 	for _, userMap := range mapping {
@@ -380,12 +433,13 @@ func (srv *CanvasService) ImportMilestones(courseIdPair map[string]any, mapping
 		values.Add("user_ids[]", userMap["external_user_id"].(string))
 	}
 	fields := log.Fields{"task": "ImportMilestones", "course_id": courseId, "external_id": externalCourseId}
-	submissions, err := srv.getUsersSubmissionsForCourse(externalCourseId, values, lastRun)
+	submissions, err := srv.getUsersSubmissionsForCourse(externalCourseId, values, since)
 	if err != nil {
 		fields["error"] = err.Error()
 		log.Printf("Failed to get submission for assignment: %v", err)
 		return err
 	}
+	failed := false
 	for _, submission := range submissions {
 		externalUserID := fmt.Sprintf("%d", int(submission["user_id"].(float64)))
 		milestone := models.Milestone{
@@ -395,15 +449,19 @@ func (srv *CanvasService) ImportMilestones(courseIdPair map[string]any, mapping
 			Type:        "assignment_submission",
 			IsCompleted: submission["workflow_state"] == "complete" || submission["workflow_state"] == "graded",
 		}
-		if db.Create(&milestone).Error != nil {
-			log.Errorln("failed to create milestone in GetMilestonesForCourseUser: ", err)
+		// submissions graded since the cursor were already recorded when they were submitted
+		if db.Where("external_id = ?", milestone.ExternalID).First(&models.Milestone{}).Error != nil {
+			if err := db.Create(&milestone).Error; err != nil {
+				log.WithFields(fields).Errorln("failed to create milestone in GetMilestonesForCourseUser: ", err)
+				failed = true
+			}
 		}
 		_, ok := submission["grade"].(string)
 		if !ok {
 			log.WithFields(fields).Traceln("no grade found for quiz submission")
 			continue
 		}
-		if checkTimespanOfSubmission(lastRun, submission) {
+		if checkTimespanOfSubmission(since, submission) {
 			anonId := submission["anonymous_id"].(string)
 			gradeReceived := models.Milestone{
 				CourseID:    uint(courseId),
@@ -412,12 +470,21 @@ func (srv *CanvasService) ImportMilestones(courseIdPair map[string]any, mapping
 				Type:        "grade_received",
 				IsCompleted: true,
 			}
-			if db.Create(&gradeReceived).Error != nil {
+			if db.Where("external_id = ?", gradeReceived.ExternalID).First(&models.Milestone{}).Error == nil {
+				continue
+			}
+			if err := db.Create(&gradeReceived).Error; err != nil {
 				log.WithFields(fields).Errorln("failed to create grade_received milestone: ", err)
+				failed = true
 				continue
 			}
 		}
 	}
+	// leave the cursor where it was so the submissions that failed are fetched again next run
+	if failed {
+		return fmt.Errorf("failed to record the milestones of course %d", courseId)
+	}
+	setSyncCursor(db, srv.ProviderPlatformID, entity, syncStarted.Format(time.RFC3339))
 	return nil
 }
 
@@ -478,8 +545,16 @@ func (srv *CanvasService) ImportActivityForCourse(coursePair map[string]any, db
 		log.Printf("Failed to get enrollments for course: %v", err)
 		return err
 	}
+	// enrollments without any activity since the cursor have nothing new to record
+	entity := models.CourseSyncEntity(models.ActivitySyncEntity, uint(courseId))
+	cursor := getSyncCursor(db, srv.ProviderPlatformID, entity)
+	latest, failed := cursor, 0
 	for _, enrollment := range enrollments {
-		userId := fmt.Sprintf("%d", int(enrollment["user_id"].(int64)))
+		lastActivity, _ := enrollment["last_activity_at"].(string)
+		if lastActivity == "" || (cursor != "" && !isAfterRFC3339(lastActivity, cursor)) {
+			continue
+		}
+		userId := fmt.Sprintf("%d", int(enrollment["user_id"].(float64)))
 		var userID uint
 		err := db.Model(models.ProviderUserMapping{}).Select("user_id").First(&userID, "provider_platform_id = ? AND external_user_id = ?", srv.ProviderPlatformID, userId).Error
 		if err != nil {
@@ -496,12 +571,35 @@ func (srv *CanvasService) ImportActivityForCourse(coursePair map[string]any, db
 		// NOTE: this is calling a stored procedure to calculate the time delta
 		if err := db.Exec("CALL insert_daily_activity_canvas(?, ?, ?, ?, ?, ?)", userID, courseId, "course_interaction", totalTime, externalId, time.Now()).Error; err != nil {
 			log.WithFields(log.Fields{"userId": userID, "course_id": courseId, "error": err}).Error("Failed to create activity")
+			failed++
 			continue
 		}
+		if latest == "" || isAfterRFC3339(lastActivity, latest) {
+			latest = lastActivity
+		}
+	}
+	// the cursor stays put when activity wasn't recorded, so the next run picks it up again
+	if failed > 0 {
+		return fmt.Errorf("failed to record %d course activities for course %d", failed, courseId)
+	}
+	if latest != cursor {
+		setSyncCursor(db, srv.ProviderPlatformID, entity, latest)
 	}
 	return nil
 }
 
+func isAfterRFC3339(timestamp, cursor string) bool {
+	ts, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return true
+	}
+	cur, err := time.Parse(time.RFC3339, cursor)
+	if err != nil {
+		return true
+	}
+	return ts.After(cur)
+}
+
 // func (srv *CanvasService) ImportOutcomesForCourse(coursePair map[string]interface{}, userMappings []map[string]interface{}) error {
 // 	courseId := int(coursePair["course_id"].(float64))
 // 	externalId := coursePair["external_course_id"].(string)
@@ -513,3 +611,173 @@ func (srv *CanvasService) ImportActivityForCourse(coursePair map[string]any, db
 //
 // 	}
 // }
+
+// the endpoints the sync jobs call, as they are named in the scopes of a canvas developer key
+const (
+	canvasAccountUsersScope      = "url:GET|/api/v1/accounts/:account_id/users"
+	canvasAccountCoursesScope    = "url:GET|/api/v1/accounts/:account_id/courses"
+	canvasCourseEnrollmentsScope = "url:GET|/api/v1/courses/:course_id/enrollments"
+	canvasCourseSubmissionsScope = "url:GET|/api/v1/courses/:course_id/students/submissions"
+)
+
+// counting users stops after this many pages when testing the connection
+const canvasMaxUserPagesWhenTesting = 20
+
+func (srv *CanvasService) getStatus(url string) (int, error) {
+	resp, err := srv.SendRequest(url)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Body.Close() != nil {
+		logger().Error("Failed to close response body")
+	}
+	return resp.StatusCode, nil
+}
+
+// countAccountUsers pages through the users of the account, stopping after a reasonable number of pages
+func (srv *CanvasService) countAccountUsers() (int, bool, error) {
+	url := srv.BaseURL + "/api/v1/accounts/" + srv.AccountID + "/users?per_page=100"
+	total := 0
+	for page := 0; url != ""; page++ {
+		if page == canvasMaxUserPagesWhenTesting {
+			return total, true, nil
+		}
+		resp, err := srv.SendRequest(url)
+		if err != nil {
+			return total, false, err
+		}
+		users := make([]map[string]any, 0)
+		err = json.NewDecoder(resp.Body).Decode(&users)
+		if resp.Body.Close() != nil {
+			logger().Error("Failed to close response body")
+		}
+		if err != nil {
+			return total, false, err
+		}
+		total += len(users)
+		url = nextPageURL(resp)
+	}
+	return total, false, nil
+}
+
+/**
+* Canvas developer keys can enforce scopes, in which case every endpoint that wasn't
+* granted responds with a 401. So after the token itself is checked, each endpoint the
+* sync jobs depend on is called once to find the scopes that are missing.
+**/
+func (srv *CanvasService) TestConnection(report *models.ProviderConnectionReport, db *gorm.DB) {
+	resp, err := srv.SendRequest(srv.BaseURL + "/api/v1/users/self")
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", srv.BaseURL, err)
+		return
+	}
+	defer func() {
+		if resp.Body.Close() != nil {
+			logger().Error("Failed to close response body")
+		}
+	}()
+	report.Pass(models.CheckConnectivity, "%s is reachable", srv.BaseURL)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		self := map[string]any{}
+		if err := json.NewDecoder(resp.Body).Decode(&self); err != nil {
+			report.Fail(models.CheckAuthentication, "unexpected response from canvas: %v", err)
+			return
+		}
+		name, _ := self["name"].(string)
+		report.Pass(models.CheckAuthentication, "token valid for %s", name)
+	case http.StatusUnauthorized:
+		report.Fail(models.CheckAuthentication, "token is invalid or has expired")
+		return
+	default:
+		report.Fail(models.CheckAuthentication, "canvas responded with %s", resp.Status)
+		return
+	}
+	report.APIVersion = "v1"
+
+	required := []string{canvasAccountUsersScope, canvasAccountCoursesScope, canvasCourseEnrollmentsScope, canvasCourseSubmissionsScope}
+	missing := make([]string, 0)
+	accountURL := srv.BaseURL + "/api/v1/accounts/" + srv.AccountID
+	status, err := srv.getStatus(accountURL + "/users?per_page=1")
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", srv.BaseURL, err)
+		return
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		report.Fail(models.CheckConfiguration, "account %s was not found", srv.AccountID)
+		return
+	default:
+		missing = append(missing, canvasAccountUsersScope)
+	}
+	resp, err = srv.SendRequest(accountURL + "/courses?per_page=1")
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", srv.BaseURL, err)
+		return
+	}
+	courses := make([]map[string]any, 0)
+	if resp.StatusCode != http.StatusOK {
+		missing = append(missing, canvasAccountCoursesScope)
+	} else if err := json.NewDecoder(resp.Body).Decode(&courses); err != nil {
+		log.Errorf("failed to decode courses: %v", err)
+	}
+	if resp.Body.Close() != nil {
+		logger().Error("Failed to close response body")
+	}
+	// the course endpoints can only be checked if the account has a course to check them with
+	if len(courses) > 0 {
+		courseURL := fmt.Sprintf("%s/api/v1/courses/%d", srv.BaseURL, int(courses[0]["id"].(float64)))
+		for scope, url := range map[string]string{
+			canvasCourseEnrollmentsScope: courseURL + "/enrollments?per_page=1",
+			canvasCourseSubmissionsScope: courseURL + "/students/submissions?student_ids[]=all&per_page=1",
+		} {
+			if status, err := srv.getStatus(url); err == nil && status == http.StatusUnauthorized {
+				missing = append(missing, scope)
+			}
+		}
+	}
+	reportScopes(report, required, missing)
+
+	if slices.Contains(missing, canvasAccountUsersScope) {
+		return
+	}
+	count, truncated, err := srv.countAccountUsers()
+	if err != nil {
+		report.Fail(models.CheckUsers, "unable to list users: %v", err)
+		return
+	}
+	report.SetUsersVisible(count)
+	if truncated {
+		report.Pass(models.CheckUsers, "more than %d users visible", count)
+		return
+	}
+	report.Pass(models.CheckUsers, "%d users visible", count)
+}
+
+// DeactivateUser suspends the canvas account of a released resident, which keeps their submissions and grades
+func (srv *CanvasService) DeactivateUser(mapping *models.ProviderUserMapping) error {
+	form := url.Values{}
+	form.Set("user[event]", "suspend")
+	req, err := http.NewRequest(http.MethodPut, srv.BaseURL+"/api/v1/users/"+mapping.ExternalUserID, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	for key, value := range srv.BaseHeaders {
+		req.Header.Add(key, value)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := srv.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if resp.Body.Close() != nil {
+			log.Error("Failed to close response body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("canvas responded with code %s suspending user %s", resp.Status, mapping.ExternalUserID)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newCanvasTestServer answers each canvas API path with a fixed response
+func newCanvasTestServer(t *testing.T, responses map[string]string) *CanvasService {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok || r.Header.Get("Authorization") != "Bearer canvas-token" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	provider := &models.ProviderPlatform{Type: models.CanvasCloud, BaseUrl: srv.URL, AccessKey: "canvas-token", AccountID: "1"}
+	provider.ID = 1
+	return newCanvasService(provider, nil)
+}
+
+func TestCanvasImportActivityKeepsCursorWhenActivityFails(t *testing.T) {
+	service := newCanvasTestServer(t, map[string]string{
+		"/api/v1/courses/12/enrollments": `[{"user_id": 3, "last_activity_at": "2024-02-08T00:00:00Z", "total_activity_time": 600}]`,
+	})
+	db := newProviderTestDB(t)
+	require.NoError(t, db.Create(&models.ProviderUserMapping{UserID: 2, ProviderPlatformID: 1, ExternalUserID: "3", ExternalUsername: "jdoe"}).Error)
+
+	// the activity is recorded by a postgres stored procedure, which sqlite doesn't have
+	err := service.ImportActivityForCourse(map[string]any{"course_id": int64(7), "external_course_id": "12"}, db)
+	require.ErrorContains(t, err, "failed to record 1 course activities for course 7")
+	require.Empty(t, getSyncCursor(db, 1, models.CourseSyncEntity(models.ActivitySyncEntity, 7)))
+}
+
+func TestCanvasImportMilestonesOnlyAdvancesCursorWhenEverythingSaved(t *testing.T) {
+	service := newCanvasTestServer(t, map[string]string{
+		"/api/v1/courses/12/students/submissions": `[
+			{"id": 41, "user_id": 3, "workflow_state": "graded", "grade": "A", "anonymous_id": "anon41", "submitted_at": "2024-02-08T00:00:00Z"},
+			{"id": 42, "user_id": 3, "workflow_state": "submitted"}
+		]`,
+	})
+	db := newProviderTestDB(t)
+	course := map[string]any{"course_id": int64(7), "external_course_id": "12"}
+	mappings := []map[string]any{{"user_id": int64(2), "external_user_id": "3"}}
+	entity := models.CourseSyncEntity(models.MilestonesSyncEntity, 7)
+
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:fail_milestones", func(tx *gorm.DB) {
+		if tx.Statement.Table == "milestones" {
+			_ = tx.AddError(errors.New("milestones are unavailable"))
+		}
+	}))
+	require.Error(t, service.ImportMilestones(course, mappings, db, time.Time{}))
+	require.Empty(t, getSyncCursor(db, 1, entity), "the cursor isn't advanced past submissions that weren't saved")
+	require.NoError(t, db.Callback().Create().Remove("test:fail_milestones"))
+
+	require.NoError(t, service.ImportMilestones(course, mappings, db, time.Time{}))
+	require.NotEmpty(t, getSyncCursor(db, 1, entity))
+	var count int64
+	require.NoError(t, db.Model(&models.Milestone{}).Count(&count).Error)
+	require.Equal(t, int64(3), count)
+
+	// submissions fetched again, e.g. when they are graded after the cursor, are already saved
+	require.NoError(t, service.ImportMilestones(course, mappings, db, time.Time{}))
+	require.NoError(t, db.Model(&models.Milestone{}).Count(&count).Error)
+	require.Equal(t, int64(3), count)
+}
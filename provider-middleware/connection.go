@@ -0,0 +1,71 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const CONNECTION_TEST_TIMEOUT = time.Minute
+
+/**
+* GET: /api/test-connection?id=
+* POST: /api/test-connection (body is an unsaved provider platform)
+* Checks connectivity, authentication, API version and scopes against the provider
+* and responds with a diagnostic report. Failed checks are part of the report, so this
+* only responds with an error status when the request itself is invalid.
+**/
+func (sh *ServiceHandler) handleTestConnection(w http.ResponseWriter, r *http.Request) {
+	fields := logrus.Fields{"handler": "handleTestConnection"}
+	service, provider, err := sh.initServiceFromRequest(r.Context(), r)
+	if provider == nil {
+		fields["error"] = err.Error()
+		logger().WithFields(fields).Error("Failed to find provider")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report := models.NewConnectionReport(provider)
+	if err != nil {
+		report.Fail(models.CheckConfiguration, "unable to initialize %s service: %v", provider.Type, err)
+	} else {
+		service.TestConnection(report, sh.db)
+	}
+	report.Summarize()
+	fields["provider_platform_id"] = provider.ID
+	fields["success"] = report.Success
+	logger().WithFields(fields).Info(report.Summary)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger().WithFields(fields).Errorln("Failed to write response")
+	}
+}
+
+// missingScopes returns the required scopes that were not granted
+func missingScopes(required, granted []string) []string {
+	have := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		have[scope] = true
+	}
+	missing := make([]string, 0)
+	for _, scope := range required {
+		if !have[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// reportScopes records the scopes check, listing any that are missing
+func reportScopes(report *models.ProviderConnectionReport, required, missing []string) {
+	if len(missing) == 0 {
+		report.Pass(models.CheckScopes, "all %d required scopes granted", len(required))
+		return
+	}
+	report.MissingScopes = missing
+	for _, scope := range missing {
+		report.Fail(models.CheckScopes, "missing scope %s", scope)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// returned by handlers when the message can never be processed, so it is dead lettered without redelivery
+var errMalformedJob = errors.New("malformed job message")
+
+// taskAcker settles a delivery with the work queue, it is the delivered *nats.Msg
+type taskAcker interface {
+	Ack(opts ...nats.AckOpt) error
+	NakWithDelay(delay time.Duration, opts ...nats.AckOpt) error
+	Term(opts ...nats.AckOpt) error
+}
+
+// taskDelivery is one delivery of a message from the task work queue
+type taskDelivery struct {
+	msg     *nats.Msg
+	acker   taskAcker
+	jobType models.JobType
+	// zero for one off jobs (e.g. add_videos) that are published outside of the scheduler
+	taskID uint
+	// the job run the scheduler created when it published the message
+	runID   uint
+	attempt uint64
+}
+
+var openJobStatuses = []models.JobStatus{models.StatusQueued, models.StatusRunning}
+
+// startDelivery marks the task and its run as running. It returns nil, after terminating the message,
+// when the run was closed before the message was delivered so the job is not run twice
+func (sh *ServiceHandler) startDelivery(msg *nats.Msg) *taskDelivery {
+	delivery := &taskDelivery{
+		msg:     msg,
+		acker:   msg,
+		jobType: models.JobType(strings.TrimPrefix(msg.Subject, "tasks.")),
+		attempt: 1,
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		delivery.attempt = meta.NumDelivered
+	}
+	var body struct {
+		TaskID   uint `json:"task_id"`
+		JobRunID uint `json:"job_run_id"`
+	}
+	if err := json.Unmarshal(msg.Data, &body); err == nil {
+		delivery.taskID = body.TaskID
+		delivery.runID = body.JobRunID
+	}
+	logger().Infof("received %s job, delivery attempt %d of %d", delivery.jobType, delivery.attempt, models.MaxTaskDeliveries)
+	if delivery.taskID == 0 {
+		return delivery
+	}
+	if !sh.claimJobRun(delivery) {
+		logger().Warnf("job run %d of task %d was closed before it was delivered, dropping the %s job", delivery.runID, delivery.taskID, delivery.jobType)
+		if err := delivery.acker.Term(); err != nil {
+			logger().Errorf("failed to terminate %s job: %v", delivery.jobType, err)
+		}
+		return nil
+	}
+	if err := sh.db.WithContext(sh.ctx).Model(&models.RunnableTask{}).
+		Where("id = ?", delivery.taskID).
+		Update("status", models.StatusRunning).Error; err != nil {
+		logger().Errorf("failed to mark task %d running: %v", delivery.taskID, err)
+	}
+	return delivery
+}
+
+// claimJobRun marks the run of the delivery running, returning false if it was already closed
+// (e.g. failed by the watchdog of the scheduler)
+func (sh *ServiceHandler) claimJobRun(delivery *taskDelivery) bool {
+	if delivery.runID == 0 {
+		// messages published before they carried their run use the latest open run of the task
+		var run models.JobRun
+		if err := sh.db.WithContext(sh.ctx).
+			Where("task_id = ? AND status IN ?", delivery.taskID, openJobStatuses).
+			Order("started_at DESC").
+			First(&run).Error; err != nil {
+			logger().Warnf("no open job run found for task %d: %v", delivery.taskID, err)
+			return true
+		}
+		delivery.runID = run.ID
+	}
+	result := sh.db.WithContext(sh.ctx).Model(&models.JobRun{}).
+		Where("id = ? AND status IN ?", delivery.runID, openJobStatuses).
+		Updates(map[string]any{"status": models.StatusRunning, "attempts": delivery.attempt, "started_at": time.Now()})
+	if result.Error != nil {
+		// the job still runs, the run is only its record
+		logger().Errorf("failed to mark job run %d running: %v", delivery.runID, result.Error)
+		return true
+	}
+	return result.RowsAffected > 0
+}
+
+// the delay before the next delivery of a failed job, doubling with each attempt
+func redeliveryBackoff(attempt uint64) time.Duration {
+	return models.TaskRedeliveryBackoff * time.Duration(1<<(attempt-1))
+}
+
+// settle acks the message if the job succeeded. Failed jobs are nacked with a backoff
+// until they run out of deliveries, then they are moved to the dead letter stream.
+func (sh *ServiceHandler) settle(delivery *taskDelivery, jobErr error) {
+	if jobErr == nil {
+		if err := delivery.acker.Ack(); err != nil {
+			logger().Errorf("failed to ack %s job: %v", delivery.jobType, err)
+		}
+		sh.finishJobRun(delivery, nil)
+		return
+	}
+	if delivery.attempt < models.MaxTaskDeliveries && !errors.Is(jobErr, errMalformedJob) {
+		backoff := redeliveryBackoff(delivery.attempt)
+		logger().Warnf("%s job failed on attempt %d, redelivering in %v: %v", delivery.jobType, delivery.attempt, backoff, jobErr)
+		if err := delivery.acker.NakWithDelay(backoff); err != nil {
+			logger().Errorf("failed to nak %s job: %v", delivery.jobType, err)
+		}
+		sh.requeueJobRun(delivery, jobErr)
+		return
+	}
+	sh.deadLetter(delivery, jobErr)
+}
+
+func (sh *ServiceHandler) requeueJobRun(delivery *taskDelivery, jobErr error) {
+	if delivery.taskID == 0 || delivery.runID == 0 {
+		return
+	}
+	var run models.JobRun
+	run.SetError(jobErr)
+	if err := sh.db.WithContext(sh.ctx).Model(&models.JobRun{}).
+		Where("id = ? AND status = ?", delivery.runID, models.StatusRunning).
+		Updates(map[string]any{"status": models.StatusQueued, "error_message": run.ErrorMessage}).Error; err != nil {
+		logger().Errorf("failed to requeue job run %d: %v", delivery.runID, err)
+	}
+	if err := sh.db.WithContext(sh.ctx).Model(&models.RunnableTask{}).
+		Where("id = ?", delivery.taskID).
+		Update("status", models.StatusQueued).Error; err != nil {
+		logger().Errorf("failed to requeue task %d: %v", delivery.taskID, err)
+	}
+}
+
+// deadLetter copies the message to the dead letter stream with the reason it failed,
+// terminates redelivery and closes out the job run as failed
+func (sh *ServiceHandler) deadLetter(delivery *taskDelivery, jobErr error) {
+	logger().Errorf("%s job failed after %d attempts, moving to dead letter: %v", delivery.jobType, delivery.attempt, jobErr)
+	dead := nats.NewMsg(delivery.jobType.DeadLetterName())
+	dead.Data = delivery.msg.Data
+	dead.Header.Set("Task-Subject", delivery.msg.Subject)
+	dead.Header.Set("Task-Attempts", strconv.FormatUint(delivery.attempt, 10))
+	dead.Header.Set("Task-Error", jobErr.Error())
+	if _, err := sh.js.PublishMsg(dead); err != nil {
+		// leave the message to be redelivered after the ack wait rather than losing it
+		logger().Errorf("failed to publish %s job to dead letter: %v", delivery.jobType, err)
+		return
+	}
+	if err := delivery.acker.Term(); err != nil {
+		logger().Errorf("failed to terminate %s job: %v", delivery.jobType, err)
+	}
+	if delivery.taskID == 0 {
+		return
+	}
+	sh.finishJobRun(delivery, jobErr)
+	if err := sh.db.WithContext(sh.ctx).Model(&models.RunnableTask{}).
+		Where("id = ?", delivery.taskID).
+		Update("status", models.StatusPending).Error; err != nil {
+		logger().Errorf("failed to update task %d: %v", delivery.taskID, err)
+	}
+}
+
+// finishJobRun closes out the run of the delivery, recording the outcome
+// and the number of records the job touched while it was running
+func (sh *ServiceHandler) finishJobRun(delivery *taskDelivery, jobErr error) {
+	if delivery.runID == 0 {
+		return
+	}
+	var run models.JobRun
+	if err := sh.db.WithContext(sh.ctx).
+		Where("id = ? AND status IN ?", delivery.runID, openJobStatuses).
+		First(&run).Error; err != nil {
+		logger().Warnf("job run %d is no longer open: %v", delivery.runID, err)
+		return
+	}
+	run.Finish(jobErr)
+	if jobErr == nil {
+		sh.countJobRunResults(sh.ctx, string(delivery.jobType), &run)
+	}
+	if err := sh.db.WithContext(sh.ctx).Omit("Task", "Job").Save(&run).Error; err != nil {
+		logger().Errorf("failed to update job run: %v", err)
+	}
+}
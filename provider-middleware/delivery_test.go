@@ -0,0 +1,139 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+// testAcker records how a delivery was settled with the work queue
+type testAcker struct {
+	acked      bool
+	nakDelay   time.Duration
+	terminated bool
+}
+
+func (ta *testAcker) Ack(...nats.AckOpt) error { ta.acked = true; return nil }
+
+func (ta *testAcker) NakWithDelay(delay time.Duration, _ ...nats.AckOpt) error {
+	ta.nakDelay = delay
+	return nil
+}
+
+func (ta *testAcker) Term(...nats.AckOpt) error { ta.terminated = true; return nil }
+
+// testJetStream keeps the messages published to the dead letter stream
+type testJetStream struct {
+	nats.JetStreamContext
+	published []*nats.Msg
+}
+
+func (js *testJetStream) PublishMsg(msg *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	js.published = append(js.published, msg)
+	return &nats.PubAck{}, nil
+}
+
+func newDeliveryTestHandler(t *testing.T) (*ServiceHandler, *testJetStream) {
+	db := newProviderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.CronJob{}, &models.RunnableTask{}, &models.JobRun{}))
+	js := &testJetStream{}
+	return &ServiceHandler{db: db, js: js, ctx: context.Background()}, js
+}
+
+// newTestDelivery publishes a task the way the scheduler does: the task is queued with an open run
+func newTestDelivery(t *testing.T, sh *ServiceHandler, attempt uint64) (*taskDelivery, *testAcker) {
+	task := models.RunnableTask{JobID: "daily-history", Status: models.StatusQueued}
+	require.NoError(t, sh.db.Create(&task).Error)
+	run := models.NewJobRun(&task)
+	require.NoError(t, sh.db.Create(run).Error)
+	acker := &testAcker{}
+	msg := nats.NewMsg(models.DailyProgHistoryJob.PubName())
+	msg.Data = []byte(`{"job_id":"daily-history"}`)
+	delivery := &taskDelivery{msg: msg, acker: acker, jobType: models.DailyProgHistoryJob, taskID: task.ID, runID: run.ID, attempt: attempt}
+	require.True(t, sh.claimJobRun(delivery))
+	return delivery, acker
+}
+
+func TestSettleAcksSucceededJobs(t *testing.T) {
+	sh, _ := newDeliveryTestHandler(t)
+	delivery, acker := newTestDelivery(t, sh, 1)
+
+	sh.settle(delivery, nil)
+	require.True(t, acker.acked)
+	var run models.JobRun
+	require.NoError(t, sh.db.First(&run, delivery.runID).Error)
+	require.Equal(t, models.StatusSucceeded, run.Status)
+	require.NotNil(t, run.EndedAt)
+}
+
+func TestSettleRedeliversFailedJobsWithBackoff(t *testing.T) {
+	sh, js := newDeliveryTestHandler(t)
+	delivery, acker := newTestDelivery(t, sh, 1)
+
+	sh.settle(delivery, errors.New("provider timed out"))
+	require.Equal(t, models.TaskRedeliveryBackoff, acker.nakDelay)
+	require.False(t, acker.terminated)
+	require.Empty(t, js.published)
+	var run models.JobRun
+	require.NoError(t, sh.db.First(&run, delivery.runID).Error)
+	require.Equal(t, models.StatusQueued, run.Status, "the run waits for its redelivery")
+	require.Equal(t, "provider timed out", run.ErrorMessage)
+	var task models.RunnableTask
+	require.NoError(t, sh.db.First(&task, delivery.taskID).Error)
+	require.Equal(t, models.StatusQueued, task.Status)
+
+	require.Equal(t, 2*models.TaskRedeliveryBackoff, redeliveryBackoff(2))
+}
+
+func TestSettleDeadLettersJobsOutOfDeliveries(t *testing.T) {
+	sh, js := newDeliveryTestHandler(t)
+	delivery, acker := newTestDelivery(t, sh, models.MaxTaskDeliveries)
+
+	sh.settle(delivery, errors.New("provider timed out"))
+	require.True(t, acker.terminated)
+	require.Zero(t, acker.nakDelay)
+	require.Len(t, js.published, 1)
+	require.Equal(t, models.DailyProgHistoryJob.DeadLetterName(), js.published[0].Subject)
+	require.Equal(t, "3", js.published[0].Header.Get("Task-Attempts"))
+	require.Equal(t, "provider timed out", js.published[0].Header.Get("Task-Error"))
+	var run models.JobRun
+	require.NoError(t, sh.db.First(&run, delivery.runID).Error)
+	require.Equal(t, models.StatusFailed, run.Status)
+	var task models.RunnableTask
+	require.NoError(t, sh.db.First(&task, delivery.taskID).Error)
+	require.Equal(t, models.StatusPending, task.Status)
+
+	// malformed jobs can never succeed, so they aren't redelivered
+	malformed, acker := newTestDelivery(t, sh, 1)
+	sh.settle(malformed, errMalformedJob)
+	require.True(t, acker.terminated)
+	require.Len(t, js.published, 2)
+}
+
+func TestClaimJobRunOnlyClaimsItsOwnOpenRun(t *testing.T) {
+	sh, _ := newDeliveryTestHandler(t)
+	delivery, _ := newTestDelivery(t, sh, 1)
+	// a second message for the same task, published before the first was picked up
+	second := models.NewJobRun(&models.RunnableTask{ID: delivery.taskID, JobID: "daily-history"})
+	require.NoError(t, sh.db.Create(second).Error)
+
+	sh.settle(delivery, errors.New("provider timed out"))
+	var run models.JobRun
+	require.NoError(t, sh.db.First(&run, second.ID).Error)
+	require.Equal(t, models.StatusQueued, run.Status)
+	require.Empty(t, run.ErrorMessage, "the other delivery's run is left alone")
+
+	// the watchdog closed the run before its message was delivered
+	closed := models.NewJobRun(&models.RunnableTask{ID: delivery.taskID, JobID: "daily-history"})
+	closed.Finish(errors.New("task was not picked up by the middleware"))
+	require.NoError(t, sh.db.Create(closed).Error)
+	require.False(t, sh.claimJobRun(&taskDelivery{taskID: delivery.taskID, runID: closed.ID, attempt: 1}))
+	var closedRun models.JobRun
+	require.NoError(t, sh.db.First(&closedRun, closed.ID).Error)
+	require.Equal(t, models.StatusFailed, closedRun.Status)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"UnlockEdv2/src/database"
 	"UnlockEdv2/src/models"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -16,6 +19,9 @@ func (sh *ServiceHandler) registerRoutes() {
 		w.WriteHeader(http.StatusOK)
 	})
 	sh.Mux.HandleFunc("GET /api/users", sh.handleUsers)
+	testConnection := http.TimeoutHandler(http.HandlerFunc(sh.handleTestConnection), CONNECTION_TEST_TIMEOUT, "connection test timed out")
+	sh.Mux.Handle("GET /api/test-connection", testConnection)
+	sh.Mux.Handle("POST /api/test-connection", testConnection)
 }
 
 const (
@@ -23,33 +29,50 @@ const (
 	VIDEO_CANCEL_TIMEOUT = 4 * time.Hour
 )
 
+// the consumer waits this long past the job's own timeout before redelivering an unacked message
+const ACK_WAIT_GRACE = 5 * time.Minute
+
 func (sh *ServiceHandler) initSubscription() error {
 	subscriptions := []struct {
-		topic string
-		fn    func(ctx context.Context, msg *nats.Msg)
+		job models.JobType
+		fn  func(ctx context.Context, msg *nats.Msg) error
 	}{
-		{models.GetCoursesJob.PubName(), sh.handleCourses},
-		{models.GetMilestonesJob.PubName(), sh.handleMilestonesForCourseUser},
-		{models.GetActivityJob.PubName(), sh.handleAcitivityForCourse},
-		{models.ScrapeKiwixJob.PubName(), sh.handleScrapeLibraries},
-		{models.AddVideosJob.PubName(), sh.handleAddVideos},
-		{models.RetryVideoDownloadsJob.PubName(), sh.handleRetryFailedVideos},
-		{models.RetryManualDownloadJob.PubName(), sh.handleManualRetryDownload},
-		{models.SyncVideoMetadataJob.PubName(), sh.handleSyncVideoMetadata},
-		{models.DailyProgHistoryJob.PubName(), sh.handleInsertDailyProgHistory},
+		{models.GetCoursesJob, sh.handleCourses},
+		{models.GetMilestonesJob, sh.handleMilestonesForCourseUser},
+		{models.GetActivityJob, sh.handleAcitivityForCourse},
+		{models.ScrapeKiwixJob, sh.handleScrapeLibraries},
+		{models.AddVideosJob, sh.handleAddVideos},
+		{models.RetryVideoDownloadsJob, sh.handleRetryFailedVideos},
+		{models.RetryManualDownloadJob, sh.handleManualRetryDownload},
+		{models.SyncVideoMetadataJob, sh.handleSyncVideoMetadata},
+		{models.DailyProgHistoryJob, sh.handleInsertDailyProgHistory},
+		{models.ClassStatusJob, sh.handleClassStatusTransitions},
+		{models.ResidentReleaseJob, sh.handleResidentReleases},
 	}
 	for _, sub := range subscriptions {
 		timeout := CANCEL_TIMEOUT
-		if sub.topic == models.RetryVideoDownloadsJob.PubName() {
+		if sub.job == models.RetryVideoDownloadsJob {
 			timeout = VIDEO_CANCEL_TIMEOUT
 		}
-		_, err := sh.nats.QueueSubscribe(sub.topic, "middleware", func(msg *nats.Msg) {
+		_, err := sh.js.QueueSubscribe(sub.job.PubName(), "middleware", func(msg *nats.Msg) {
 			ctx, cancel := context.WithTimeout(sh.ctx, timeout)
 			defer cancel()
-			sub.fn(ctx, msg)
-		})
+			delivery := sh.startDelivery(msg)
+			if delivery == nil {
+				return
+			}
+			sh.settle(delivery, sub.fn(ctx, msg))
+		},
+			nats.Durable(sub.job.ConsumerName()),
+			nats.BindStream(models.TaskStream),
+			nats.ManualAck(),
+			nats.AckExplicit(),
+			nats.AckWait(timeout+ACK_WAIT_GRACE),
+			nats.MaxDeliver(models.MaxTaskDeliveries),
+			nats.DeliverAll(),
+		)
 		if err != nil {
-			logger().Fatalf("Error subscribing to NATS topic %s: %v", sub.topic, err)
+			logger().Fatalf("Error subscribing to NATS topic %s: %v", sub.job.PubName(), err)
 			return err
 		}
 	}
@@ -57,19 +80,33 @@ func (sh *ServiceHandler) initSubscription() error {
 	return nil
 }
 
-func (sh *ServiceHandler) handleInsertDailyProgHistory(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleInsertDailyProgHistory(ctx context.Context, msg *nats.Msg) error {
 	var body map[string]any
 	if err := json.Unmarshal(msg.Data, &body); err != nil {
 		logger().Errorf("failed to unmarshal message: %v", err)
-		return
+		return fmt.Errorf("%w: %v", errMalformedJob, err)
 	}
 	jobId, ok := body["job_id"].(string)
 	if !ok {
 		logger().Errorf("failed to parse job_id: %v", body["job_id"])
-		return
+		return fmt.Errorf("%w: invalid job_id %v", errMalformedJob, body["job_id"])
+	}
+	return sh.cleanupJob(ctx, nil, jobId, InsertDailyProgHistory(ctx, sh.db))
+}
+
+// moves program classes to their next status by their dates and sessions, see database.TransitionClassStatuses
+func (sh *ServiceHandler) handleClassStatusTransitions(ctx context.Context, msg *nats.Msg) error {
+	var body map[string]any
+	if err := json.Unmarshal(msg.Data, &body); err != nil {
+		logger().Errorf("failed to unmarshal message: %v", err)
+		return fmt.Errorf("%w: %v", errMalformedJob, err)
+	}
+	jobId, ok := body["job_id"].(string)
+	if !ok {
+		logger().Errorf("failed to parse job_id: %v", body["job_id"])
+		return fmt.Errorf("%w: invalid job_id %v", errMalformedJob, body["job_id"])
 	}
-	success := InsertDailyProgHistory(ctx, sh.db) != nil
-	sh.cleanupJob(ctx, nil, jobId, success)
+	return sh.cleanupJob(ctx, nil, jobId, database.NewDB(sh.db).TransitionClassStatuses(ctx, time.Now()))
 }
 
 /**
@@ -77,30 +114,29 @@ func (sh *ServiceHandler) handleInsertDailyProgHistory(ctx context.Context, msg
 * This handler will be responsible for importing courses from Providers
 * to the UnlockEd platform, mapping their Content objects to our Course object
  */
-func (sh *ServiceHandler) handleCourses(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleCourses(ctx context.Context, msg *nats.Msg) error {
 	service, err := sh.initProviderPlatformService(ctx, msg)
 	if err != nil {
 		logger().WithFields(logrus.Fields{"error": err.Error()}).Error("Failed to initialize service")
-		return
+		return err
 	}
 	params := service.GetJobParams()
 	jobId := params["job_id"].(string)
 	providerPlatformId := int(params["provider_platform_id"].(float64))
-	success := service.ImportCourses(sh.db) != nil
-	sh.cleanupJob(ctx, &providerPlatformId, jobId, success)
+	return sh.cleanupJob(ctx, &providerPlatformId, jobId, service.ImportCourses(sh.db))
 }
 
-func (sh *ServiceHandler) handleScrapeLibraries(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleScrapeLibraries(ctx context.Context, msg *nats.Msg) error {
 	provider, body, err := sh.getContentProvider(msg)
 	if err != nil {
 		logger().Errorf("error fetching provider service from msg parameters %v", err)
-		return
+		return err
 	}
 	jobId := body["job_id"].(string)
 	kiwixService := NewKiwixService(provider, body)
-	success := kiwixService.ImportLibraries(ctx, sh.db) != nil
+	err = kiwixService.ImportLibraries(ctx, sh.db)
 	providerIdPtr := int(provider.ID)
-	sh.cleanupJob(ctx, &providerIdPtr, jobId, success)
+	return sh.cleanupJob(ctx, &providerIdPtr, jobId, err)
 }
 
 /**
@@ -111,7 +147,7 @@ func (sh *ServiceHandler) handleScrapeLibraries(ctx context.Context, msg *nats.M
 **/
 func (sh *ServiceHandler) handleUsers(w http.ResponseWriter, r *http.Request) {
 	fields := logrus.Fields{"handler": "handleUsers"}
-	service, err := sh.initServiceFromRequest(sh.ctx, r)
+	service, _, err := sh.initServiceFromRequest(sh.ctx, r)
 	if err != nil {
 		fields["error"] = err.Error()
 		logger().WithFields(fields).Error("Failed to initialize service")
@@ -136,11 +172,11 @@ func (sh *ServiceHandler) handleUsers(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (sh *ServiceHandler) handleMilestonesForCourseUser(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleMilestonesForCourseUser(ctx context.Context, msg *nats.Msg) error {
 	service, err := sh.initProviderPlatformService(ctx, msg)
 	if err != nil {
 		logger().WithFields(logrus.Fields{"error": err.Error()}).Error("Failed to initialize service")
-		return
+		return err
 	}
 	logger().Println("initiating GetMilestonesForCourseUser milestones")
 	params := service.GetJobParams()
@@ -148,90 +184,89 @@ func (sh *ServiceHandler) handleMilestonesForCourseUser(ctx context.Context, msg
 	providerPlatformId := int(params["provider_platform_id"].(float64))
 	usersMap, err := sh.lookupUserMapping(params)
 	if err != nil {
-		sh.cleanupJob(ctx, &providerPlatformId, jobId, false)
+		return sh.cleanupJob(ctx, &providerPlatformId, jobId, err)
 	}
 	coursesMap, err := sh.lookupCoursesMapping(providerPlatformId)
 	if err != nil {
-		sh.cleanupJob(ctx, &providerPlatformId, jobId, false)
+		return sh.cleanupJob(ctx, &providerPlatformId, jobId, err)
 	}
-	success := true
+	var jobErr error
 	lastRunStr := params["last_run"].(string)
 	lastRun, err := time.Parse(time.RFC3339, lastRunStr)
 	if err != nil {
-		sh.cleanupJob(ctx, &providerPlatformId, jobId, false)
-		return
+		return sh.cleanupJob(ctx, &providerPlatformId, jobId, err)
 	}
 	for _, course := range coursesMap {
 		select {
 		case <-ctx.Done():
 			logger().Println("context cancelled for getMilestones")
-			return
+			return ctx.Err()
 		default:
 			err = service.ImportMilestones(course, usersMap, sh.db, lastRun)
 			time.Sleep(TIMEOUT_WAIT * time.Second) // to avoid rate limiting with the provider
 			if err != nil {
-				success = false
+				jobErr = errors.Join(jobErr, err)
 				logger().Errorf("Failed to retrieve milestones: %v", err)
 				continue
 			}
 		}
 	}
-	sh.cleanupJob(ctx, &providerPlatformId, jobId, success)
+	return sh.cleanupJob(ctx, &providerPlatformId, jobId, jobErr)
 }
 
-func (sh *ServiceHandler) handleAcitivityForCourse(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleAcitivityForCourse(ctx context.Context, msg *nats.Msg) error {
 	service, err := sh.initProviderPlatformService(ctx, msg)
 	if err != nil {
 		logger().WithFields(logrus.Fields{"error": err.Error()}).Error("Failed to initialize service")
-		return
+		return err
 	}
-	success := true
+	var jobErr error
 	params := service.GetJobParams()
 	jobId := params["job_id"].(string)
 	providerPlatformId := int(params["provider_platform_id"].(float64))
 	courses, err := sh.lookupCoursesMapping(providerPlatformId)
 	if err != nil {
-		sh.cleanupJob(ctx, &providerPlatformId, jobId, false)
-		return
+		return sh.cleanupJob(ctx, &providerPlatformId, jobId, err)
 	}
 	for _, course := range courses {
 		select {
 		case <-ctx.Done():
 			logger().Println("context cancelled for getActivity")
-			return
+			return ctx.Err()
 		default:
 			err = service.ImportActivityForCourse(course, sh.db)
 			if err != nil {
-				success = false
+				jobErr = errors.Join(jobErr, err)
 				logger().Errorf("failed to get course activity: %v", err)
 				continue
 			}
 		}
 	}
-	sh.cleanupJob(ctx, &providerPlatformId, jobId, success)
+	return sh.cleanupJob(ctx, &providerPlatformId, jobId, jobErr)
 }
 
-func (sh *ServiceHandler) handleAddVideos(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleAddVideos(ctx context.Context, msg *nats.Msg) error {
 	provider, body, err := sh.getContentProvider(msg)
 	if err != nil {
 		logger().Errorf("error fetching provider from msg parameters %v", err)
-		return
+		return err
 	}
 	ytService := NewVideoService(provider, sh.db, body)
 	err = ytService.addVideos(ctx)
 	if err != nil {
 		logger().Errorf("error adding videos: %v", err)
-		return
+		return err
 	}
 	// this is a one time job, so it doesn't need cleanup
+	return nil
 }
 
-func (sh *ServiceHandler) handleManualRetryDownload(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleManualRetryDownload(ctx context.Context, msg *nats.Msg) error {
 	logger().Infof("Retrying failed video")
 	provider, body, err := sh.getContentProvider(msg)
 	if err != nil {
 		logger().Errorf("error fetching provider from msg parameters %v", err)
-		return
+		return err
 	}
 	ytService := NewVideoService(provider, sh.db, body)
 	videoId, ok := body["video_id"].(float64)
@@ -240,43 +275,40 @@ func (sh *ServiceHandler) handleManualRetryDownload(ctx context.Context, msg *na
 		if err != nil {
 			logger().Errorf("error retrying single video: %v", err)
 		}
-		return
+		return err
 	}
 	logger().Errorf("video_id not found in body")
+	return fmt.Errorf("%w: video_id not found in body", errMalformedJob)
 }
 
-func (sh *ServiceHandler) handleRetryFailedVideos(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleRetryFailedVideos(ctx context.Context, msg *nats.Msg) error {
 	logger().Infof("Retrying failed videos")
-	success := true
 	provider, body, err := sh.getContentProvider(msg)
 	if err != nil {
 		logger().Errorf("error fetching provider from msg parameters %v", err)
-		return
+		return err
 	}
 	ytService := NewVideoService(provider, sh.db, body)
 	err = ytService.retryFailedVideos(ctx)
 	if err != nil {
 		logger().Errorf("error retrying failed videos: %v", err)
-		success = false
 	}
 	providerIdPtr := int(provider.ID)
-	sh.cleanupJob(ctx, &providerIdPtr, body["job_id"].(string), success)
+	return sh.cleanupJob(ctx, &providerIdPtr, body["job_id"].(string), err)
 }
 
-func (sh *ServiceHandler) handleSyncVideoMetadata(ctx context.Context, msg *nats.Msg) {
+func (sh *ServiceHandler) handleSyncVideoMetadata(ctx context.Context, msg *nats.Msg) error {
 	logger().Infof("Syncing video metadata")
-	success := true
 	provider, body, err := sh.getContentProvider(msg)
 	if err != nil {
 		logger().Errorf("error fetching provider from msg parameters %v", err)
-		return
+		return err
 	}
 	ytService := NewVideoService(provider, sh.db, body)
 	err = ytService.syncVideoMetadata(ctx)
 	if err != nil {
 		logger().Errorf("error syncing video metadata: %v", err)
-		success = false
 	}
 	providerIdPtr := int(provider.ID)
-	sh.cleanupJob(ctx, &providerIdPtr, body["job_id"].(string), success)
+	return sh.cleanupJob(ctx, &providerIdPtr, body["job_id"].(string), err)
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"UnlockEdv2/src/models"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -23,6 +25,7 @@ type KolibriService struct {
 	BaseURL            string
 	Client             *http.Client
 	AccountID          string
+	AccessKey          string
 	db                 *gorm.DB
 	JobParams          map[string]any
 }
@@ -46,6 +49,8 @@ func NewKolibriService(provider *models.ProviderPlatform, params map[string]any)
 	return &KolibriService{
 		ProviderPlatformID: provider.ID,
 		AccountID:          provider.AccountID,
+		AccessKey:          provider.AccessKey,
+		Client:             &http.Client{},
 		db:                 conn,
 		BaseURL:            provider.BaseUrl,
 		JobParams:          params,
@@ -99,10 +104,18 @@ func (ks *KolibriService) GetUsers(db *gorm.DB) ([]models.ImportUser, error) {
 **/
 func (ks *KolibriService) ImportCourses(db *gorm.DB) error { //add more to this:::
 	log.Println("Importing channel and classroom courses from Kolibri")
+	// channels only need to be looked at again once they are re-imported or updated in kolibri,
+	// classrooms are always checked as their lessons and quizzes change the total progress
+	cursor := getSyncCursor(db, ks.ProviderPlatformID, models.CoursesSyncEntity)
+	channelFilter, args := "", []any{}
+	if cursor != "" {
+		channelFilter, args = "WHERE last_updated > ?", append(args, cursor)
+	}
 	var courses []map[string]interface{} //modified to left join as quizzes only activate when teachers click 'START QUIZ'
-	sql := `SELECT id, name, description, thumbnail, total_resource_count, root_id, 'channel' as course_type FROM content_channelmetadata
+	sql := `SELECT id, name, description, thumbnail, total_resource_count, root_id, 'channel' as course_type, last_updated FROM content_channelmetadata
+			` + channelFilter + `
 			UNION 
-			SELECT col.id, name, '' as description, '' as thumbnail, lesson.resource_count+COALESCE(exam.resource_count,0) as total_resource_count, col.id as root_id, 'class' as course_type 
+			SELECT col.id, name, '' as description, '' as thumbnail, lesson.resource_count+COALESCE(exam.resource_count,0) as total_resource_count, col.id as root_id, 'class' as course_type, NULL as last_updated 
 			FROM kolibriauth_collection col
 			inner join (SELECT col.id, count(col.id) as resource_count 
         		FROM kolibriauth_collection col
@@ -117,12 +130,17 @@ func (ks *KolibriService) ImportCourses(db *gorm.DB) error { //add more to this:
         			group by exam.collection_id
 			) exam on col.id = exam.collection_id
 			where kind = 'classroom'`
-	if err := ks.db.Raw(sql).Scan(&courses).Error; err != nil {
+	if err := ks.db.Raw(sql, args...).Scan(&courses).Error; err != nil {
 		log.Errorln("error querying kolibri database for courses")
 		return err
 	}
+	var latest time.Time
+	failed := false
 	for _, course := range courses {
 		id := course["id"].(string)
+		if updated, ok := course["last_updated"].(time.Time); ok && updated.After(latest) {
+			latest = updated
+		}
 		if db.Where("provider_platform_id = ? AND external_id = ?", ks.ProviderPlatformID, id).First(&models.Course{}).Error == nil {
 			if course["course_type"].(string) == "class" {
 				updateTotalProgress(db, ks.ProviderPlatformID, course)
@@ -132,9 +150,13 @@ func (ks *KolibriService) ImportCourses(db *gorm.DB) error { //add more to this:
 		prog := ks.IntoCourse(course)
 		if err := db.Create(&prog).Error; err != nil {
 			log.Errorln("error creating course in db")
+			failed = true
 			continue
 		}
 	}
+	if !failed && !latest.IsZero() {
+		setSyncCursor(db, ks.ProviderPlatformID, models.CoursesSyncEntity, latest.Format(time.RFC3339Nano))
+	}
 	return nil
 }
 
@@ -232,7 +254,7 @@ func importEnrollmentMilestones(ks *KolibriService, paramObj milestonePO, db *go
 func importAssignmentQuizGradeMilestones(ks *KolibriService, paramObj milestonePO, db *gorm.DB) error {
 	var aqgMilestones []map[string]any
 	sql := `select col.name, prog.id as milestone_ex_id, prog.user_id, col.id as course_id, prog.notification_object as submission_type, 
-				prog.notification_event as is_complete, quiz_num_correct, quiz_num_answered
+				prog.notification_event as is_complete, quiz_num_correct, quiz_num_answered, prog.timestamp
 		from notifications_learnerprogressnotification prog  
 		inner join kolibriauth_collection col on prog.classroom_id = col.id
         	and col.kind = 'classroom'
@@ -240,17 +262,35 @@ func importAssignmentQuizGradeMilestones(ks *KolibriService, paramObj milestoneP
         	and prog.notification_event = 'Completed'
         	and prog.notification_object IN ('Quiz','Lesson')
         	and prog.user_id IN (?)
+        	and prog.timestamp > ?
 		`
 	course := paramObj.course
 	externalUserIds := paramObj.externalUserIds
 	usersMap := paramObj.usersMap
 	externalCourseId := course["external_course_id"].(string)
-	if err := ks.db.Raw(sql, externalCourseId, externalUserIds).Scan(&aqgMilestones).Error; err != nil {
+	courseId := uint(course["course_id"].(int64))
+	// only notifications raised since the last sync of the course are fetched
+	entity := models.CourseSyncEntity(models.MilestonesSyncEntity, courseId)
+	since := time.Time{}
+	if cursor := getSyncCursor(db, ks.ProviderPlatformID, entity); cursor != "" {
+		if parsed, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
+			since = parsed
+		}
+	}
+	if err := ks.db.Raw(sql, externalCourseId, externalUserIds, since).Scan(&aqgMilestones).Error; err != nil {
 		log.Errorln("error querying kolibri database for assignment submissions, quiz submissions, and graded milestones")
 		return err
 	}
-	courseId := uint(course["course_id"].(int64))
+	latest := since
+	defer func() {
+		if latest.After(since) {
+			setSyncCursor(db, ks.ProviderPlatformID, entity, latest.Format(time.RFC3339Nano))
+		}
+	}()
 	for _, aqgMilestone := range aqgMilestones {
+		if timestamp, ok := aqgMilestone["timestamp"].(time.Time); ok && timestamp.After(latest) {
+			latest = timestamp
+		}
 		userId := usersMap[aqgMilestone["user_id"].(string)]
 		id := strconv.Itoa(int(aqgMilestone["milestone_ex_id"].(int32)))
 		//concatenated the user id to external id for graded milestones because these are actual quiz submissions and need a way to distinguish graded milestones
@@ -287,25 +327,39 @@ func importAssignmentQuizGradeMilestones(ks *KolibriService, paramObj milestoneP
 }
 
 type KolibriActivity struct {
-	ID                  string `json:"id"`
-	UserId              string `json:"user_id"`
-	TimeSpent           string `json:"time_spent"`
-	CompletionTimestamp string `json:"completion_timestamp"`
-	ContentId           string `json:"content_id"`
-	Progress            string `json:"progress"`
-	Kind                string `json:"kind"`
+	ID                  string    `json:"id"`
+	UserId              string    `json:"user_id"`
+	TimeSpent           string    `json:"time_spent"`
+	CompletionTimestamp string    `json:"completion_timestamp"`
+	ContentId           string    `json:"content_id"`
+	Progress            string    `json:"progress"`
+	Kind                string    `json:"kind"`
+	EndTimestamp        time.Time `json:"end_timestamp"`
 }
 
 func (ks *KolibriService) ImportActivityForCourse(courseIdPair map[string]any, db *gorm.DB) error {
 	courseId := int(courseIdPair["course_id"].(int64))
 	externalId := courseIdPair["external_course_id"].(string)
-	sql := `SELECT id, user_id, time_spent, completion_timestamp, content_id, progress, kind FROM logger_contentsummarylog WHERE channel_id = ?`
+	// summary logs are only read back as far as the last one that was processed for the course
+	entity := models.CourseSyncEntity(models.ActivitySyncEntity, uint(courseId))
+	since := time.Time{}
+	if cursor := getSyncCursor(db, ks.ProviderPlatformID, entity); cursor != "" {
+		if parsed, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
+			since = parsed
+		}
+	}
+	sql := `SELECT id, user_id, time_spent, completion_timestamp, content_id, progress, kind, end_timestamp FROM logger_contentsummarylog WHERE channel_id = ? AND end_timestamp > ? ORDER BY end_timestamp`
 	var activities []KolibriActivity
-	if err := ks.db.Raw(sql, externalId).Find(&activities).Error; err != nil {
+	if err := ks.db.Raw(sql, externalId, since).Find(&activities).Error; err != nil {
 		log.Errorln("error querying kolibri database for course activities")
 		return err
 	}
-	for _, activity := range activities {
+	// the cursor stops short of the first activity that could not be recorded, so it is retried next run
+	latest, failed := since, false
+	for idx, activity := range activities {
+		if !failed {
+			latest = activity.EndTimestamp
+		}
 		var user_id uint
 		if err := db.Model(&models.ProviderUserMapping{}).Select("user_id").First(&user_id, "external_user_id = ?", activity.UserId).Error; err != nil {
 			log.Errorln("error finding user by external id in ImportActivityForCourse")
@@ -334,9 +388,18 @@ func (ks *KolibriService) ImportActivityForCourse(courseIdPair map[string]any, d
 		}
 		if err := db.Exec("CALL insert_daily_activity_kolibri(?, ?, ?, ?, ?, ?)", user_id, courseId, kind, timeSpent, activity.ID, time.Now()).Error; err != nil {
 			log.WithFields(log.Fields{"userId": user_id, "course_id": courseId, "error": err}).Error("Failed to create activity")
+			if !failed {
+				failed, latest = true, since
+				if idx > 0 {
+					latest = activities[idx-1].EndTimestamp
+				}
+			}
 			continue
 		}
 	}
+	if latest.After(since) {
+		setSyncCursor(db, ks.ProviderPlatformID, entity, latest.Format(time.RFC3339Nano))
+	}
 	return nil
 }
 
@@ -344,3 +407,73 @@ func IsNumber(u string) bool {
 	_, err := strconv.Atoi(u)
 	return err == nil
 }
+
+/**
+* The sync jobs read kolibri's database directly, so besides the API being reachable with the
+* admin credentials (username:password) we check that the facility exists in the database
+* @info - GET /api/public/info/, POST /api/auth/session/
+**/
+func (ks *KolibriService) TestConnection(report *models.ProviderConnectionReport, db *gorm.DB) {
+	baseURL := strings.TrimSuffix(ks.BaseURL, "/")
+	resp, err := ks.Client.Get(baseURL + "/api/public/info/")
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", baseURL, err)
+		return
+	}
+	info := struct {
+		Application    string `json:"application"`
+		KolibriVersion string `json:"kolibri_version"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if resp.Body.Close() != nil {
+		logger().Error("Failed to close response body")
+	}
+	if err != nil || resp.StatusCode != http.StatusOK || info.Application != "kolibri" {
+		report.Fail(models.CheckConnectivity, "%s does not appear to be a kolibri server (%s)", baseURL, resp.Status)
+		return
+	}
+	report.Pass(models.CheckConnectivity, "%s is reachable", baseURL)
+	report.APIVersion = info.KolibriVersion
+	report.Pass(models.CheckAPIVersion, "kolibri %s", info.KolibriVersion)
+
+	username, password, found := strings.Cut(ks.AccessKey, ":")
+	if !found {
+		report.Fail(models.CheckConfiguration, "access key must be in the format username:password")
+		return
+	}
+	credentials, err := json.Marshal(map[string]string{"username": username, "password": password, "facility": ks.AccountID})
+	if err != nil {
+		report.Fail(models.CheckAuthentication, "unable to encode credentials: %v", err)
+		return
+	}
+	resp, err = ks.Client.Post(baseURL+"/api/auth/session/", "application/json", bytes.NewReader(credentials))
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", baseURL, err)
+		return
+	}
+	if resp.Body.Close() != nil {
+		logger().Error("Failed to close response body")
+	}
+	if resp.StatusCode != http.StatusOK {
+		report.Fail(models.CheckAuthentication, "kolibri rejected the credentials for %s (%s)", username, resp.Status)
+		return
+	}
+	report.Pass(models.CheckAuthentication, "credentials valid for %s", username)
+
+	var facilities int64
+	if err := ks.db.Table("kolibriauth_collection").Where("id = ? AND kind = 'facility'", ks.AccountID).Count(&facilities).Error; err != nil {
+		report.Fail(models.CheckUsers, "unable to query the kolibri database: %v", err)
+		return
+	}
+	if facilities == 0 {
+		report.Fail(models.CheckConfiguration, "facility %s was not found in the kolibri database", ks.AccountID)
+		return
+	}
+	var users int64
+	if err := ks.db.Table("kolibriauth_facilityuser").Where("facility_id = ?", ks.AccountID).Count(&users).Error; err != nil {
+		report.Fail(models.CheckUsers, "unable to query the kolibri database: %v", err)
+		return
+	}
+	report.SetUsersVisible(int(users))
+	report.Pass(models.CheckUsers, "%d users visible", users)
+}
@@ -27,6 +27,9 @@ type ProviderServiceInterface interface {
 	ImportActivityForCourse(coursePair map[string]any, db *gorm.DB) error
 
 	GetJobParams() map[string]interface{}
+
+	// TestConnection records the result of each check it runs against the provider in the report
+	TestConnection(report *models.ProviderConnectionReport, db *gorm.DB)
 }
 
 /**
@@ -36,6 +39,7 @@ type ProviderServiceInterface interface {
 **/
 type ServiceHandler struct {
 	nats   *nats.Conn
+	js     nats.JetStreamContext
 	Mux    *http.ServeMux
 	token  string
 	db     *gorm.DB
@@ -58,11 +62,19 @@ func newServiceHandler(token string, db *gorm.DB) *ServiceHandler {
 		log.Fatalf("Failed to connect to NATS: %v", err)
 	}
 	log.Println("Connected to NATS at ", options.Url)
+	js, err := conn.JetStream()
+	if err != nil {
+		log.Fatalf("Failed to initialize JetStream: %v", err)
+	}
+	if err := models.EnsureTaskStreams(js); err != nil {
+		log.Fatalf("Failed to setup JetStream task streams: %v", err)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &ServiceHandler{
 		token:  token,
 		db:     db,
 		nats:   conn,
+		js:     js,
 		Mux:    http.NewServeMux(),
 		cancel: cancel,
 		ctx:    ctx,
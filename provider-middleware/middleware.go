@@ -4,6 +4,7 @@ import (
 	"UnlockEdv2/src/models"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -13,59 +14,78 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func (sh *ServiceHandler) initServiceFromRequest(ctx context.Context, r *http.Request) (ProviderServiceInterface, error) {
-	id, err := strconv.Atoi(r.URL.Query().Get("id"))
-	if err != nil {
-		log.Printf("Error: %v", err)
-		return nil, fmt.Errorf("failed to find provider: %v", err)
-	}
+/**
+* Builds the service for the provider in the request: either a saved provider, given by
+* its ?id=, or the unsaved provider platform in a POST body (used to test the settings
+* of a provider before it is created)
+**/
+func (sh *ServiceHandler) initServiceFromRequest(ctx context.Context, r *http.Request) (ProviderServiceInterface, *models.ProviderPlatform, error) {
 	var provider models.ProviderPlatform
-	err = sh.db.WithContext(ctx).First(&provider, "id = ?", id).Error
-	if err != nil {
-		log.Println("Failed to find provider")
-		return nil, err
-	}
-	switch provider.Type {
-	case models.Kolibri:
-		return NewKolibriService(&provider, nil), nil
-	case models.CanvasCloud, models.CanvasOSS:
-		return newCanvasService(&provider, nil), nil
-	case models.Brightspace:
-		return newBrightspaceService(&provider, sh.db, nil)
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&provider); err != nil {
+			log.Printf("Error: %v", err)
+			return nil, nil, fmt.Errorf("failed to decode provider: %v", err)
+		}
+		provider.ID = 0
+		// brightspace refresh tokens can only be used once, so an unsaved provider is only checked
+		// for connectivity, its credentials are verified by the OAuth flow when it is created
+		if provider.Type == models.Brightspace {
+			return newUnauthenticatedBrightspaceService(&provider), &provider, nil
+		}
+	} else {
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return nil, nil, fmt.Errorf("failed to find provider: %v", err)
+		}
+		err = sh.db.WithContext(ctx).First(&provider, "id = ?", id).Error
+		if err != nil {
+			log.Println("Failed to find provider")
+			return nil, nil, err
+		}
 	}
-	return nil, fmt.Errorf("unsupported provider type: %s", provider.Type)
+	service, err := sh.newProviderService(&provider, nil)
+	return service, &provider, err
 }
 
 func (sh *ServiceHandler) initProviderPlatformService(ctx context.Context, msg *nats.Msg) (ProviderServiceInterface, error) {
 	var body map[string]interface{}
 	if err := json.Unmarshal(msg.Data, &body); err != nil {
 		log.Errorf("failed to unmarshal message: %v", err)
-		return nil, fmt.Errorf("failed to unmarshal message: %v", err)
+		return nil, fmt.Errorf("%w: failed to unmarshal message: %v", errMalformedJob, err)
 	}
 	providerId, ok := body["provider_platform_id"].(float64)
 	if !ok {
-		return nil, fmt.Errorf("failed to parse provider_platform_id: %v", body["provider_platform_id"])
+		return nil, fmt.Errorf("%w: failed to parse provider_platform_id: %v", errMalformedJob, body["provider_platform_id"])
 	}
-	jobId, ok := body["job_id"].(string)
-	if !ok {
-		return nil, fmt.Errorf("failed to parse job_id: %v", body["job_id"])
+	if _, ok := body["job_id"].(string); !ok {
+		return nil, fmt.Errorf("%w: failed to parse job_id: %v", errMalformedJob, body["job_id"])
 	}
-	// prior to here, we are unable to cleanup the job
-	providerIdPtr := int(providerId)
 	var provider models.ProviderPlatform
-	err := sh.db.WithContext(ctx).First(&provider, "id = ?", providerIdPtr).Error
+	err := sh.db.WithContext(ctx).First(&provider, "id = ?", int(providerId)).Error
 	if err != nil {
 		log.Errorf("error looking up provider platform: %v", err)
-		sh.cleanupJob(ctx, &providerIdPtr, jobId, false)
 		return nil, fmt.Errorf("failed to find provider: %v", err)
 	}
+	return sh.newProviderService(&provider, body)
+}
+
+func (sh *ServiceHandler) newProviderService(provider *models.ProviderPlatform, params map[string]any) (ProviderServiceInterface, error) {
 	switch provider.Type {
 	case models.Kolibri:
-		return NewKolibriService(&provider, body), nil
+		service := NewKolibriService(provider, params)
+		if service == nil {
+			return nil, errors.New("unable to connect to the kolibri database")
+		}
+		return service, nil
 	case models.CanvasCloud, models.CanvasOSS:
-		return newCanvasService(&provider, body), nil
+		return newCanvasService(provider, params), nil
 	case models.Brightspace:
-		return newBrightspaceService(&provider, sh.db, body)
+		return newBrightspaceService(provider, sh.db, params)
+	case models.Moodle:
+		return newMoodleService(provider, params), nil
+	case models.OpenEdx:
+		return newOpenEdxService(provider, params)
 	}
 	return nil, fmt.Errorf("unsupported provider type: %s", provider.Type)
 }
@@ -88,10 +108,16 @@ func (sh *ServiceHandler) getContentProvider(msg *nats.Msg) (*models.OpenContent
 	return openContentProvider, body, nil
 }
 
-func (sh *ServiceHandler) cleanupJob(ctx context.Context, provId *int, jobId string, success bool) {
-	log.Infof("job %s succeeded?: %v \n cleaning up task", jobId, success)
+// cleanupJob records a successful run against the task. Failed jobs are returned to be
+// settled with the work queue, which decides whether they are redelivered or dead lettered.
+func (sh *ServiceHandler) cleanupJob(ctx context.Context, provId *int, jobId string, jobErr error) error {
+	if jobErr != nil {
+		log.Errorf("job %s failed: %v", jobId, jobErr)
+		return jobErr
+	}
+	log.Infof("job %s succeeded, cleaning up task", jobId)
 	var task models.RunnableTask
-	tx := sh.db.WithContext(ctx).Model(models.RunnableTask{})
+	tx := sh.db.WithContext(ctx).Model(models.RunnableTask{}).Preload("Job")
 	if provId != nil {
 		tx = tx.Where("(provider_platform_id = ? AND job_id = ?) OR (open_content_provider_id = ? AND job_id = ?)", *provId, jobId, *provId, jobId)
 	} else {
@@ -101,14 +127,35 @@ func (sh *ServiceHandler) cleanupJob(ctx context.Context, provId *int, jobId str
 		First(&task).
 		Error; err != nil {
 		log.Errorf("failed to fetch task: %v", err)
-		return
+		return nil
 	}
 	task.Status = models.StatusPending
-	if success {
-		task.LastRun = time.Now()
-	}
-	if err := sh.db.WithContext(ctx).Save(&task).Error; err != nil {
+	task.LastRun = time.Now()
+	if err := sh.db.WithContext(ctx).Omit("Job").Save(&task).Error; err != nil {
 		log.Errorf("failed to update task: %v", err)
-		return
+	}
+	return nil
+}
+
+func (sh *ServiceHandler) countJobRunResults(ctx context.Context, jobName string, run *models.JobRun) {
+	tx := sh.db.WithContext(ctx)
+	var err error
+	switch models.JobType(jobName) {
+	case models.GetCoursesJob:
+		err = tx.Model(&models.Course{}).
+			Where("provider_platform_id = ? AND updated_at >= ?", run.ProviderPlatformID, run.StartedAt).
+			Count(&run.CoursesImported).Error
+	case models.GetMilestonesJob:
+		err = tx.Model(&models.Milestone{}).
+			Joins("JOIN courses c ON c.id = milestones.course_id").
+			Where("c.provider_platform_id = ? AND milestones.updated_at >= ?", run.ProviderPlatformID, run.StartedAt).
+			Count(&run.MilestonesUpserted).Error
+	case models.RetryVideoDownloadsJob, models.AddVideosJob:
+		err = tx.Model(&models.Video{}).
+			Where("open_content_provider_id = ? AND availability = ? AND updated_at >= ?", run.OpenContentProviderID, models.VideoAvailable, run.StartedAt).
+			Count(&run.VideosDownloaded).Error
+	}
+	if err != nil {
+		log.Errorf("failed to count results for job run %d: %v", run.ID, err)
 	}
 }
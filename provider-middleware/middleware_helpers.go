@@ -5,6 +5,8 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const TIMEOUT_WAIT = 5
@@ -40,6 +42,28 @@ func (sh *ServiceHandler) lookupCoursesMapping(provId int) ([]map[string]any, er
 	return courses, nil
 }
 
+// getSyncCursor returns where the last sync of the entity left off, or an empty string
+// if it has never been synced (or an admin requested a full resync)
+func getSyncCursor(db *gorm.DB, providerID uint, entity string) string {
+	var cursor models.ProviderSyncCursor
+	if err := db.Where("provider_platform_id = ? AND entity = ?", providerID, entity).Limit(1).Find(&cursor).Error; err != nil {
+		log.Errorf("failed to fetch sync cursor %s for provider %d: %v", entity, providerID, err)
+	}
+	return cursor.Cursor
+}
+
+// setSyncCursor should only be called once the entity has been fully processed up to the cursor,
+// otherwise a failed run would skip over the records it did not get to
+func setSyncCursor(db *gorm.DB, providerID uint, entity, value string) {
+	cursor := models.ProviderSyncCursor{ProviderPlatformID: providerID, Entity: entity, Cursor: value, UpdatedAt: time.Now()}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider_platform_id"}, {Name: "entity"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cursor", "updated_at"}),
+	}).Create(&cursor).Error; err != nil {
+		log.Errorf("failed to save sync cursor %s for provider %d: %v", entity, providerID, err)
+	}
+}
+
 func parseDate(dateToParse, pattern string) *time.Time {
 	var (
 		returnDt time.Time
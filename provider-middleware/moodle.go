@@ -0,0 +1,500 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microcosm-cc/bluemonday"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+/**
+* MoodleService talks to the Moodle web services REST API (/webservice/rest/server.php).
+* The provider's AccessKey is a web service token for a user with the functions below enabled,
+* and the AccountID is the course category to import courses from (0 imports every course)
+**/
+type MoodleService struct {
+	ProviderPlatformID uint
+	Client             *http.Client
+	BaseURL            string
+	Token              string
+	CategoryID         string
+	JobParams          map[string]any
+}
+
+func newMoodleService(provider *models.ProviderPlatform, params map[string]any) *MoodleService {
+	return &MoodleService{
+		ProviderPlatformID: provider.ID,
+		Client:             &http.Client{},
+		BaseURL:            strings.TrimSuffix(provider.BaseUrl, "/"),
+		Token:              provider.AccessKey,
+		CategoryID:         provider.AccountID,
+		JobParams:          params,
+	}
+}
+
+func (ms *MoodleService) GetJobParams() map[string]any {
+	return ms.JobParams
+}
+
+// callFunction invokes a web service function and decodes the JSON response into result
+func (ms *MoodleService) callFunction(function string, params url.Values, result any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("wstoken", ms.Token)
+	params.Set("wsfunction", function)
+	params.Set("moodlewsrestformat", "json")
+	resp, err := ms.Client.PostForm(ms.BaseURL+"/webservice/rest/server.php", params)
+	if err != nil {
+		log.Errorf("failed to send request to moodle function %s: %v", function, err)
+		return err
+	}
+	defer func() {
+		if resp.Body.Close() != nil {
+			logger().Error("Failed to close response body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("moodle function %s responded with code: %s", function, resp.Status)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return err
+	}
+	var exception MoodleException
+	if json.Unmarshal(raw, &exception) == nil && exception.Exception != "" {
+		return &exception
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// getActiveUsers returns every user on the site, other than the guest and suspended users
+func (ms *MoodleService) getActiveUsers() ([]MoodleUser, error) {
+	params := url.Values{}
+	// the criteria is required, a wildcard on email returns every user
+	params.Set("criteria[0][key]", "email")
+	params.Set("criteria[0][value]", "%")
+	var resp MoodleUsersResponse
+	if err := ms.callFunction("core_user_get_users", params, &resp); err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(resp.Users, func(user MoodleUser) bool {
+		return user.Suspended || user.Username == "guest"
+	}), nil
+}
+
+func (ms *MoodleService) GetUsers(db *gorm.DB) ([]models.ImportUser, error) {
+	users, err := ms.getActiveUsers()
+	if err != nil {
+		return nil, err
+	}
+	importUsers := make([]models.ImportUser, 0, len(users))
+	for _, user := range users {
+		externalID := strconv.Itoa(user.ID)
+		var count int64
+		if err := db.Model(&models.ProviderUserMapping{}).Where("provider_platform_id = ? AND external_user_id = ?", ms.ProviderPlatformID, externalID).Count(&count).Error; err != nil {
+			log.Errorf("Error counting provider_user_mappings: %v", err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		importUsers = append(importUsers, user.IntoImportUser())
+	}
+	log.Printf("returning %d Unlocked Users", len(importUsers))
+	return importUsers, nil
+}
+
+func (ms *MoodleService) getCourses() ([]MoodleCourse, error) {
+	params := url.Values{}
+	if ms.CategoryID != "" && ms.CategoryID != "0" {
+		params.Set("field", "category")
+		params.Set("value", ms.CategoryID)
+	}
+	var resp MoodleCoursesResponse
+	if err := ms.callFunction("core_course_get_courses_by_field", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Courses, nil
+}
+
+// counts the activities in the course that have completion tracking enabled
+func (ms *MoodleService) getCountTrackedActivities(courseID int) (int, error) {
+	params := url.Values{}
+	params.Set("courseid", strconv.Itoa(courseID))
+	sections := make([]MoodleSection, 0)
+	if err := ms.callFunction("core_course_get_contents", params, &sections); err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, section := range sections {
+		for _, module := range section.Modules {
+			if module.Completion > 0 {
+				total++
+			}
+		}
+	}
+	return total, nil
+}
+
+func (ms *MoodleService) ImportCourses(db *gorm.DB) error {
+	fields := log.Fields{"provider": ms.ProviderPlatformID, "Function": "ImportCourses"}
+	log.WithFields(fields).Info("importing courses from provider")
+	courses, err := ms.getCourses()
+	if err != nil {
+		return err
+	}
+	// the cursor holds the revision of each course as of its last sync
+	revisions := make(map[string]string)
+	if cursor := getSyncCursor(db, ms.ProviderPlatformID, models.CoursesSyncEntity); cursor != "" {
+		if err := json.Unmarshal([]byte(cursor), &revisions); err != nil {
+			log.WithFields(fields).Warnf("ignoring invalid courses sync cursor: %v", err)
+		}
+	}
+	defer func() {
+		cursor, err := json.Marshal(revisions)
+		if err != nil {
+			log.WithFields(fields).Errorf("failed to encode courses sync cursor: %v", err)
+			return
+		}
+		setSyncCursor(db, ms.ProviderPlatformID, models.CoursesSyncEntity, string(cursor))
+	}()
+	policy := bluemonday.StrictPolicy()
+	for _, course := range courses {
+		// the front page is returned as a course with the 'site' format
+		if course.Format == "site" || course.Visible == 0 {
+			continue
+		}
+		externalID := strconv.Itoa(course.ID)
+		var existing models.Course
+		exists := db.Where("provider_platform_id = ? AND external_id = ?", ms.ProviderPlatformID, externalID).First(&existing).Error == nil
+		if exists && revisions[externalID] == course.revision() {
+			continue
+		}
+		totalMilestones, err := ms.getCountTrackedActivities(course.ID)
+		if err != nil {
+			log.WithFields(fields).Errorf("failed to count activities for course %d: %v", course.ID, err)
+		}
+		if exists {
+			if err != nil {
+				continue
+			}
+			if existing.TotalProgressMilestones != uint(totalMilestones) {
+				if err := db.Model(&existing).Update("total_progress_milestones", totalMilestones).Error; err != nil {
+					log.WithFields(fields).Errorf("failed to update total_progress_milestones: %v", err)
+					continue
+				}
+			}
+			revisions[externalID] = course.revision()
+			continue
+		}
+		description := strings.TrimSpace(policy.Sanitize(course.Summary))
+		if len(description) > 510 {
+			description = description[:510]
+		}
+		unlockedCourse := models.Course{
+			ProviderPlatformID:      ms.ProviderPlatformID,
+			Name:                    course.FullName,
+			AltName:                 course.ShortName,
+			ExternalID:              externalID,
+			ExternalURL:             ms.BaseURL + "/course/view.php?id=" + externalID,
+			Type:                    models.FixedEnrollment,
+			OutcomeTypes:            "grade, completion",
+			Description:             description,
+			ThumbnailURL:            course.thumbnailURL(),
+			TotalProgressMilestones: uint(totalMilestones),
+		}
+		if course.StartDate > 0 {
+			startDt := time.Unix(course.StartDate, 0)
+			unlockedCourse.StartDt = &startDt
+		}
+		if course.EndDate > 0 {
+			endDt := time.Unix(course.EndDate, 0)
+			unlockedCourse.EndDt = &endDt
+		}
+		if err := db.Create(&unlockedCourse).Error; err != nil {
+			log.WithFields(fields).Errorf("Failed to create course: %v", err)
+			continue
+		}
+		// if the activities could not be counted the course is revisited on the next run
+		if err == nil {
+			revisions[externalID] = course.revision()
+		}
+	}
+	return nil
+}
+
+func (ms *MoodleService) getCompletionStatuses(courseID, userID string) ([]MoodleCompletionStatus, error) {
+	params := url.Values{}
+	params.Set("courseid", courseID)
+	params.Set("userid", userID)
+	var resp MoodleCompletionStatusResponse
+	if err := ms.callFunction("core_completion_get_activities_completion_status", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Statuses, nil
+}
+
+func (ms *MoodleService) getGradeItems(courseID, userID string) ([]MoodleGradeItem, error) {
+	params := url.Values{}
+	params.Set("courseid", courseID)
+	params.Set("userid", userID)
+	var resp MoodleGradeItemsResponse
+	if err := ms.callFunction("gradereport_user_get_grade_items", params, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.UserGrades) == 0 {
+		return nil, nil
+	}
+	return resp.UserGrades[0].GradeItems, nil
+}
+
+func (ms *MoodleService) isCourseCompleted(courseID, userID string) (bool, error) {
+	params := url.Values{}
+	params.Set("courseid", courseID)
+	params.Set("userid", userID)
+	var resp MoodleCourseCompletionResponse
+	if err := ms.callFunction("core_completion_get_course_completion_status", params, &resp); err != nil {
+		var exception *MoodleException
+		// moodle raises an exception when completion tracking is disabled for the course
+		if errors.As(err, &exception) && exception.ErrorCode == "nocriteriaset" {
+			return false, nil
+		}
+		return false, err
+	}
+	return resp.CompletionStatus.Completed, nil
+}
+
+/**
+* Activity completions become submission milestones and graded items become grade_received milestones.
+* When the course itself is completed, an outcome is recorded with the course total grade
+**/
+func (ms *MoodleService) ImportMilestones(coursePair map[string]any, mappings []map[string]any, db *gorm.DB, lastRun time.Time) error {
+	courseID := uint(coursePair["course_id"].(int64))
+	externalCourseID := coursePair["external_course_id"].(string)
+	fields := log.Fields{"task": "ImportMilestones", "course_id": courseID, "external_id": externalCourseID}
+	// completion and grade lookups are per user, so once a course has been synced only the users
+	// who have accessed it since are checked again. A full resync clears the cursor.
+	entity := models.CourseSyncEntity(models.MilestonesSyncEntity, courseID)
+	syncStarted := time.Now().Unix()
+	var accessedSince map[string]bool
+	if since, err := strconv.ParseInt(getSyncCursor(db, ms.ProviderPlatformID, entity), 10, 64); err == nil {
+		enrolled, err := ms.getEnrolledUsers(externalCourseID)
+		if err != nil {
+			log.WithFields(fields).Errorf("failed to get enrolled users: %v", err)
+			return err
+		}
+		accessedSince = make(map[string]bool)
+		for _, user := range enrolled {
+			if user.LastCourseAccess >= since {
+				accessedSince[strconv.Itoa(user.ID)] = true
+			}
+		}
+	}
+	var jobErr error
+	for _, mapping := range mappings {
+		externalUserID := mapping["external_user_id"].(string)
+		userID := uint(mapping["user_id"].(int64))
+		if accessedSince != nil && !accessedSince[externalUserID] {
+			continue
+		}
+		statuses, err := ms.getCompletionStatuses(externalCourseID, externalUserID)
+		if err != nil {
+			log.WithFields(fields).Errorf("failed to get completion statuses for user %s: %v", externalUserID, err)
+			jobErr = errors.Join(jobErr, err)
+			continue
+		}
+		for _, status := range statuses {
+			if status.State != moodleComplete && status.State != moodleCompletePass && status.State != moodleCompleteFail {
+				continue
+			}
+			milestone := models.Milestone{
+				UserID:      userID,
+				CourseID:    courseID,
+				ExternalID:  fmt.Sprintf("moodle-%s-%d-%s", externalCourseID, status.CmID, externalUserID),
+				Type:        moodleMilestoneType(status.ModName),
+				IsCompleted: true,
+			}
+			createMilestoneIfNotExists(db, &milestone, fields)
+		}
+		grades, err := ms.getGradeItems(externalCourseID, externalUserID)
+		if err != nil {
+			log.WithFields(fields).Errorf("failed to get grades for user %s: %v", externalUserID, err)
+			jobErr = errors.Join(jobErr, err)
+			continue
+		}
+		courseGrade := ""
+		for _, item := range grades {
+			if item.GradeRaw == nil {
+				continue
+			}
+			if item.ItemType == "course" {
+				courseGrade = item.GradeFormatted
+				continue
+			}
+			milestone := models.Milestone{
+				UserID:      userID,
+				CourseID:    courseID,
+				ExternalID:  fmt.Sprintf("moodle-%s-grade-%d-%s", externalCourseID, item.ID, externalUserID),
+				Type:        models.GradeReceived,
+				IsCompleted: true,
+			}
+			createMilestoneIfNotExists(db, &milestone, fields)
+		}
+		completed, err := ms.isCourseCompleted(externalCourseID, externalUserID)
+		if err != nil {
+			log.WithFields(fields).Errorf("failed to get course completion for user %s: %v", externalUserID, err)
+			jobErr = errors.Join(jobErr, err)
+			continue
+		}
+		if !completed {
+			continue
+		}
+		if db.Where("user_id = ? AND course_id = ?", userID, courseID).First(&models.Outcome{}).Error == nil {
+			continue
+		}
+		outcome := models.Outcome{
+			Type:     models.CourseCompletion,
+			CourseID: courseID,
+			UserID:   userID,
+			Value:    courseGrade,
+		}
+		if err := db.Create(&outcome).Error; err != nil {
+			log.WithFields(fields).Errorf("failed to create outcome: %v", err)
+		}
+	}
+	if jobErr == nil {
+		setSyncCursor(db, ms.ProviderPlatformID, entity, strconv.FormatInt(syncStarted, 10))
+	}
+	return jobErr
+}
+
+func createMilestoneIfNotExists(db *gorm.DB, milestone *models.Milestone, fields log.Fields) {
+	if db.Where("external_id = ?", milestone.ExternalID).First(&models.Milestone{}).Error == nil {
+		return
+	}
+	if err := db.Create(milestone).Error; err != nil {
+		log.WithFields(fields).Errorf("failed to create milestone: %v", err)
+	}
+}
+
+func (ms *MoodleService) getEnrolledUsers(courseID string) ([]MoodleEnrolledUser, error) {
+	params := url.Values{}
+	params.Set("courseid", courseID)
+	users := make([]MoodleEnrolledUser, 0)
+	if err := ms.callFunction("core_enrol_get_enrolled_users", params, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+/**
+* The web services don't expose time spent in a course, so each new course access is recorded
+* as a course_interaction with no time, which keeps the resident's engagement days accurate
+**/
+func (ms *MoodleService) ImportActivityForCourse(coursePair map[string]any, db *gorm.DB) error {
+	courseID := int(coursePair["course_id"].(int64))
+	externalID := coursePair["external_course_id"].(string)
+	enrolled, err := ms.getEnrolledUsers(externalID)
+	if err != nil {
+		log.Printf("Failed to get enrollments for course: %v", err)
+		return err
+	}
+	// course accesses at or before the cursor have already been recorded
+	entity := models.CourseSyncEntity(models.ActivitySyncEntity, uint(courseID))
+	cursor, _ := strconv.ParseInt(getSyncCursor(db, ms.ProviderPlatformID, entity), 10, 64)
+	latest, failed := cursor, 0
+	for _, enrollment := range enrolled {
+		if !enrollment.IsStudent() {
+			continue
+		}
+		var userID uint
+		if err := db.Model(models.ProviderUserMapping{}).Select("user_id").First(&userID, "provider_platform_id = ? AND external_user_id = ?", ms.ProviderPlatformID, strconv.Itoa(enrollment.ID)).Error; err != nil {
+			continue
+		}
+		if db.Model(&models.UserEnrollment{}).First(&models.UserEnrollment{}, "user_id = ? AND course_id = ?", userID, courseID).RowsAffected == 0 {
+			if err := db.Create(&models.UserEnrollment{UserID: userID, CourseID: uint(courseID)}).Error; err != nil {
+				log.WithFields(log.Fields{"userId": userID, "course_id": courseID, "error": err}).Error("Failed to create enrollment")
+				continue
+			}
+		}
+		if enrollment.LastCourseAccess == 0 || enrollment.LastCourseAccess <= cursor {
+			continue
+		}
+		activityID := fmt.Sprintf("%s-%d-%d", externalID, enrollment.ID, enrollment.LastCourseAccess)
+		if db.Where("course_id = ? AND external_id = ?", courseID, activityID).First(&models.Activity{}).Error == nil {
+			continue
+		}
+		if err := db.Exec("CALL insert_daily_activity_kolibri(?, ?, ?, ?, ?, ?)", userID, courseID, models.CourseInteraction, 0, activityID, time.Unix(enrollment.LastCourseAccess, 0)).Error; err != nil {
+			log.WithFields(log.Fields{"userId": userID, "course_id": courseID, "error": err}).Error("Failed to create activity")
+			failed++
+			continue
+		}
+		latest = max(latest, enrollment.LastCourseAccess)
+	}
+	// the cursor stays put when an access wasn't recorded, so the next run picks it up again
+	if failed > 0 {
+		return fmt.Errorf("failed to record %d course accesses for course %d", failed, courseID)
+	}
+	if latest > cursor {
+		setSyncCursor(db, ms.ProviderPlatformID, entity, strconv.FormatInt(latest, 10))
+	}
+	return nil
+}
+
+/**
+* The site info tells us who the token belongs to, the moodle release and which functions
+* the token's web service allows, which are the closest thing moodle has to scopes
+**/
+func (ms *MoodleService) TestConnection(report *models.ProviderConnectionReport, db *gorm.DB) {
+	var info MoodleSiteInfo
+	if err := ms.callFunction("core_webservice_get_site_info", nil, &info); err != nil {
+		var exception *MoodleException
+		if !errors.As(err, &exception) {
+			report.Fail(models.CheckConnectivity, "unable to reach the web services at %s: %v", ms.BaseURL, err)
+			return
+		}
+		report.Pass(models.CheckConnectivity, "%s is reachable", ms.BaseURL)
+		report.Fail(models.CheckAuthentication, "token was rejected: %s", exception.Message)
+		return
+	}
+	report.Pass(models.CheckConnectivity, "%s is reachable", ms.BaseURL)
+	report.Pass(models.CheckAuthentication, "token valid for %s", info.Username)
+	report.APIVersion = info.Release
+	report.Pass(models.CheckAPIVersion, "moodle %s", info.Release)
+	granted := make([]string, 0, len(info.Functions))
+	for _, function := range info.Functions {
+		granted = append(granted, function.Name)
+	}
+	missing := missingScopes(moodleRequiredFunctions, granted)
+	reportScopes(report, moodleRequiredFunctions, missing)
+	if slices.Contains(missing, "core_user_get_users") {
+		return
+	}
+	users, err := ms.getActiveUsers()
+	if err != nil {
+		report.Fail(models.CheckUsers, "unable to list users: %v", err)
+		return
+	}
+	report.SetUsersVisible(len(users))
+	report.Pass(models.CheckUsers, "%d users visible", len(users))
+}
+
+// DeactivateUser suspends the moodle account of a released resident, which keeps their course completions
+func (ms *MoodleService) DeactivateUser(mapping *models.ProviderUserMapping) error {
+	params := url.Values{}
+	params.Set("users[0][id]", mapping.ExternalUserID)
+	params.Set("users[0][suspended]", "1")
+	var ignored any
+	return ms.callFunction("core_user_update_users", params, &ignored)
+}
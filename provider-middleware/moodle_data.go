@@ -0,0 +1,185 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"fmt"
+	"strings"
+)
+
+// returned by the web services with a 200 status when a call fails
+type MoodleException struct {
+	Exception string `json:"exception"`
+	ErrorCode string `json:"errorcode"`
+	Message   string `json:"message"`
+}
+
+func (e *MoodleException) Error() string {
+	return fmt.Sprintf("moodle %s: %s", e.ErrorCode, e.Message)
+}
+
+type MoodleUser struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstname"`
+	LastName  string `json:"lastname"`
+	Email     string `json:"email"`
+	Suspended bool   `json:"suspended"`
+}
+
+func (mu *MoodleUser) IntoImportUser() models.ImportUser {
+	email := mu.Email
+	if email == "" {
+		email = mu.Username + "@unlocked.v2"
+	}
+	return models.ImportUser{
+		ExternalUserID:   fmt.Sprintf("%d", mu.ID),
+		ExternalUsername: mu.Username,
+		Username:         mu.Username,
+		NameFirst:        mu.FirstName,
+		NameLast:         mu.LastName,
+		Email:            email,
+	}
+}
+
+type MoodleUsersResponse struct {
+	Users []MoodleUser `json:"users"`
+}
+
+type MoodleCourse struct {
+	ID            int    `json:"id"`
+	FullName      string `json:"fullname"`
+	ShortName     string `json:"shortname"`
+	CategoryID    int    `json:"categoryid"`
+	Summary       string `json:"summary"`
+	Format        string `json:"format"`
+	StartDate     int64  `json:"startdate"`
+	EndDate       int64  `json:"enddate"`
+	Visible       int    `json:"visible"`
+	TimeModified  int64  `json:"timemodified"`
+	CacheRev      int64  `json:"cacherev"`
+	CourseImage   string `json:"courseimage"`
+	OverviewFiles []struct {
+		FileURL string `json:"fileurl"`
+	} `json:"overviewfiles"`
+}
+
+type MoodleCoursesResponse struct {
+	Courses []MoodleCourse `json:"courses"`
+}
+
+type MoodleSection struct {
+	ID      int `json:"id"`
+	Modules []struct {
+		ID         int    `json:"id"`
+		ModName    string `json:"modname"`
+		Completion int    `json:"completion"`
+	} `json:"modules"`
+}
+
+type MoodleCompletionStatus struct {
+	CmID          int    `json:"cmid"`
+	ModName       string `json:"modname"`
+	State         int    `json:"state"`
+	TimeCompleted int64  `json:"timecompleted"`
+}
+
+type MoodleCompletionStatusResponse struct {
+	Statuses []MoodleCompletionStatus `json:"statuses"`
+}
+
+type MoodleCourseCompletionResponse struct {
+	CompletionStatus struct {
+		Completed bool `json:"completed"`
+	} `json:"completionstatus"`
+}
+
+type MoodleGradeItem struct {
+	ID              int      `json:"id"`
+	ItemName        string   `json:"itemname"`
+	ItemType        string   `json:"itemtype"`
+	ItemModule      string   `json:"itemmodule"`
+	CmID            int      `json:"cmid"`
+	GradeRaw        *float64 `json:"graderaw"`
+	GradeFormatted  string   `json:"gradeformatted"`
+	GradeDateGraded *int64   `json:"gradedategraded"`
+}
+
+type MoodleGradeItemsResponse struct {
+	UserGrades []struct {
+		CourseID   int               `json:"courseid"`
+		UserID     int               `json:"userid"`
+		GradeItems []MoodleGradeItem `json:"gradeitems"`
+	} `json:"usergrades"`
+}
+
+type MoodleEnrolledUser struct {
+	ID               int   `json:"id"`
+	LastCourseAccess int64 `json:"lastcourseaccess"`
+	Roles            []struct {
+		ShortName string `json:"shortname"`
+	} `json:"roles"`
+}
+
+func (eu *MoodleEnrolledUser) IsStudent() bool {
+	for _, role := range eu.Roles {
+		if role.ShortName == "student" {
+			return true
+		}
+	}
+	return false
+}
+
+// moodle activity completion states, 0 is incomplete
+const (
+	moodleComplete     = 1
+	moodleCompletePass = 2
+	moodleCompleteFail = 3
+)
+
+func moodleMilestoneType(modName string) models.MilestoneType {
+	switch modName {
+	case "quiz":
+		return models.QuizSubmission
+	case "forum":
+		return models.DiscussionPost
+	default:
+		return models.AssignmentSubmission
+	}
+}
+
+func (mc *MoodleCourse) thumbnailURL() string {
+	if mc.CourseImage != "" && !strings.HasPrefix(mc.CourseImage, "data:") {
+		return mc.CourseImage
+	}
+	if len(mc.OverviewFiles) > 0 {
+		return mc.OverviewFiles[0].FileURL
+	}
+	return ""
+}
+
+// moodle bumps the cache revision whenever the contents of a course change and timemodified
+// whenever its settings are saved, so together they tell us if the course needs to be synced again
+func (mc *MoodleCourse) revision() string {
+	return fmt.Sprintf("%d-%d", mc.TimeModified, mc.CacheRev)
+}
+
+type MoodleSiteInfo struct {
+	SiteName  string `json:"sitename"`
+	Username  string `json:"username"`
+	Release   string `json:"release"`
+	Version   string `json:"version"`
+	Functions []struct {
+		Name string `json:"name"`
+	} `json:"functions"`
+}
+
+// the web service functions the sync jobs call, they all need to be added to the token's service
+var moodleRequiredFunctions = []string{
+	"core_user_get_users",
+	"core_course_get_courses_by_field",
+	"core_course_get_contents",
+	"core_completion_get_activities_completion_status",
+	"core_completion_get_course_completion_status",
+	"gradereport_user_get_grade_items",
+	"core_enrol_get_enrolled_users",
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const moodleTestToken = "test-ws-token"
+
+// newMoodleTestServer stands in for a moodle site, answering each web service function
+// with the recorded response in testdata/moodle/<wsfunction>.json
+func newMoodleTestServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/webservice/rest/server.php" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("wstoken") != moodleTestToken {
+			_, _ = w.Write([]byte(`{"exception":"moodle_exception","errorcode":"invalidtoken","message":"Invalid token - token not found"}`))
+			return
+		}
+		fixture, err := os.ReadFile(filepath.Join("testdata", "moodle", r.Form.Get("wsfunction")+".json"))
+		if err != nil {
+			_, _ = w.Write([]byte(`{"exception":"dml_missing_record_exception","errorcode":"invalidrecord","message":"Can't find data record in database table external_functions."}`))
+			return
+		}
+		_, _ = w.Write(fixture)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newProviderTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.ProviderUserMapping{}, &models.Course{}, &models.Milestone{}, &models.Outcome{}, &models.UserEnrollment{}, &models.Activity{}, &models.ProviderSyncCursor{}))
+	return db
+}
+
+func newTestMoodleService(srv *httptest.Server, token string) *MoodleService {
+	provider := &models.ProviderPlatform{Type: models.Moodle, BaseUrl: srv.URL, AccessKey: token, AccountID: "2"}
+	provider.ID = 1
+	return newMoodleService(provider, nil)
+}
+
+func TestMoodleGetUsers(t *testing.T) {
+	srv := newMoodleTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestMoodleService(srv, moodleTestToken)
+	require.NoError(t, db.Create(&models.ProviderUserMapping{UserID: 1, ProviderPlatformID: 1, ExternalUserID: "4", ExternalUsername: "bsmith"}).Error)
+
+	users, err := service.GetUsers(db)
+	require.NoError(t, err)
+	require.Len(t, users, 1, "guest, suspended and already mapped users should be skipped")
+	require.Equal(t, "3", users[0].ExternalUserID)
+	require.Equal(t, "Jane", users[0].NameFirst)
+	require.Equal(t, "Doe", users[0].NameLast)
+	require.Equal(t, "jdoe@example.org", users[0].Email)
+
+	_, err = newTestMoodleService(srv, "wrong").GetUsers(db)
+	require.ErrorContains(t, err, "invalidtoken")
+}
+
+func TestMoodleImportCourses(t *testing.T) {
+	srv := newMoodleTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestMoodleService(srv, moodleTestToken)
+
+	require.NoError(t, service.ImportCourses(db))
+	var courses []models.Course
+	require.NoError(t, db.Find(&courses).Error)
+	require.Len(t, courses, 1, "the site course and hidden courses should be skipped")
+	course := courses[0]
+	require.Equal(t, "12", course.ExternalID)
+	require.Equal(t, "Intro to Algebra", course.Name)
+	require.Equal(t, "Linear equations and graphing.", course.Description)
+	require.Equal(t, srv.URL+"/course/view.php?id=12", course.ExternalURL)
+	require.Equal(t, uint(3), course.TotalProgressMilestones, "only activities with completion tracking count towards progress")
+	require.NotNil(t, course.StartDt)
+
+	// a second import should not duplicate the course
+	require.NoError(t, service.ImportCourses(db))
+	var count int64
+	require.NoError(t, db.Model(&models.Course{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
+
+func TestMoodleImportMilestones(t *testing.T) {
+	srv := newMoodleTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestMoodleService(srv, moodleTestToken)
+	course := map[string]any{"course_id": int64(7), "external_course_id": "12"}
+	mappings := []map[string]any{{"user_id": int64(2), "external_user_id": "3"}}
+
+	require.NoError(t, service.ImportMilestones(course, mappings, db, time.Time{}))
+	var milestones []models.Milestone
+	require.NoError(t, db.Order("external_id").Find(&milestones).Error)
+	require.Len(t, milestones, 3, "two completed activities and one graded item")
+	types := map[models.MilestoneType]int{}
+	for _, milestone := range milestones {
+		require.Equal(t, uint(2), milestone.UserID)
+		require.Equal(t, uint(7), milestone.CourseID)
+		types[milestone.Type]++
+	}
+	require.Equal(t, 1, types[models.AssignmentSubmission])
+	require.Equal(t, 1, types[models.QuizSubmission])
+	require.Equal(t, 1, types[models.GradeReceived])
+
+	var outcome models.Outcome
+	require.NoError(t, db.First(&outcome, "user_id = ? AND course_id = ?", 2, 7).Error)
+	require.Equal(t, models.CourseCompletion, outcome.Type)
+	require.Equal(t, "87.50", outcome.Value)
+
+	// re-importing is idempotent
+	require.NoError(t, service.ImportMilestones(course, mappings, db, time.Time{}))
+	var count int64
+	require.NoError(t, db.Model(&models.Milestone{}).Count(&count).Error)
+	require.Equal(t, int64(3), count)
+	require.NoError(t, db.Model(&models.Outcome{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
+
+func TestMoodleImportActivityEnrollsStudents(t *testing.T) {
+	srv := newMoodleTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestMoodleService(srv, moodleTestToken)
+	require.NoError(t, db.Create(&[]models.ProviderUserMapping{
+		{UserID: 2, ProviderPlatformID: 1, ExternalUserID: "3", ExternalUsername: "jdoe"},
+		{UserID: 5, ProviderPlatformID: 1, ExternalUserID: "9", ExternalUsername: "teacher"},
+	}).Error)
+
+	// the activity itself is recorded by a postgres stored procedure, which sqlite doesn't have
+	err := service.ImportActivityForCourse(map[string]any{"course_id": int64(7), "external_course_id": "12"}, db)
+	require.ErrorContains(t, err, "failed to record 1 course accesses for course 7")
+	var enrollments []models.UserEnrollment
+	require.NoError(t, db.Find(&enrollments).Error)
+	require.Len(t, enrollments, 1, "only students should be enrolled")
+	require.Equal(t, uint(2), enrollments[0].UserID)
+	var cursors int64
+	require.NoError(t, db.Model(&models.ProviderSyncCursor{}).Count(&cursors).Error)
+	require.Zero(t, cursors, "the cursor isn't advanced past accesses that weren't recorded")
+}
+
+func TestMoodleTestConnection(t *testing.T) {
+	srv := newMoodleTestServer(t)
+	db := newProviderTestDB(t)
+	provider := &models.ProviderPlatform{Type: models.Moodle, BaseUrl: srv.URL}
+
+	report := models.NewConnectionReport(provider)
+	newTestMoodleService(srv, moodleTestToken).TestConnection(report, db)
+	require.False(t, report.Success, "the token's service is missing a required function")
+	require.Equal(t, []string{"gradereport_user_get_grade_items"}, report.MissingScopes)
+	require.Equal(t, "4.3.2 (Build: 20231222)", report.APIVersion)
+	require.NotNil(t, report.UsersVisible)
+	require.Equal(t, 2, *report.UsersVisible, "guest and suspended users are not counted")
+
+	report = models.NewConnectionReport(provider)
+	newTestMoodleService(srv, "wrong").TestConnection(report, db)
+	require.False(t, report.Success)
+	require.Len(t, report.Checks, 2)
+	require.True(t, report.Checks[0].Passed, "the site is reachable")
+	require.Equal(t, models.CheckAuthentication, report.Checks[1].Name)
+	require.False(t, report.Checks[1].Passed)
+}
+
+func TestMoodleDeactivateUser(t *testing.T) {
+	srv := newMoodleTestServer(t)
+	mapping := &models.ProviderUserMapping{UserID: 1, ProviderPlatformID: 1, ExternalUserID: "3", ExternalUsername: "jdoe"}
+	var service AccountDeactivator = newTestMoodleService(srv, moodleTestToken)
+	require.NoError(t, service.DeactivateUser(mapping))
+	require.ErrorContains(t, newTestMoodleService(srv, "wrong").DeactivateUser(mapping), "invalidtoken")
+}
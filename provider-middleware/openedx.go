@@ -0,0 +1,429 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"gorm.io/gorm"
+)
+
+const (
+	OpenEdxTokenEndpoint = "/oauth2/access_token"
+	// the accounts API accepts a comma separated list of usernames
+	openEdxAccountsBatchSize = 50
+	openEdxRequestTimeout    = 30 * time.Second
+)
+
+/**
+* OpenEdxService uses the LMS REST APIs with a JWT from an OAuth application using the
+* client credentials grant. The AccessKey holds the application's "client_id;client_secret",
+* and the AccountID optionally limits imported course runs to a single organization.
+* Unlike the other providers, Open edX keeps no sync cursors: the course runs, course blocks,
+* enrollments and grades APIs can't filter on when a record last changed, and completion is only
+* reported per learner, so there is no watermark to resume from. Every run processes everything,
+* which is safe because the imports skip records they already saved, and a full resync of an
+* Open edX provider only runs the tasks (see models.FullSyncOnlyProviders).
+**/
+type OpenEdxService struct {
+	ProviderPlatformID uint
+	Client             *http.Client
+	BaseURL            string
+	Org                string
+	JobParams          map[string]any
+}
+
+func newOpenEdxService(provider *models.ProviderPlatform, params map[string]any) (*OpenEdxService, error) {
+	keysSplit := strings.SplitN(provider.AccessKey, ";", 2)
+	if len(keysSplit) < 2 || keysSplit[0] == "" || keysSplit[1] == "" {
+		return nil, errors.New("access key must be in the format client_id;client_secret, unable to initialize OpenEdxService")
+	}
+	baseURL := strings.TrimSuffix(provider.BaseUrl, "/")
+	config := clientcredentials.Config{
+		ClientID:       keysSplit[0],
+		ClientSecret:   keysSplit[1],
+		TokenURL:       baseURL + OpenEdxTokenEndpoint,
+		EndpointParams: url.Values{"token_type": {"jwt"}},
+	}
+	org := provider.AccountID
+	if org == "0" {
+		org = ""
+	}
+	// the token requests go through the context's client, so it needs the timeout as well
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: openEdxRequestTimeout})
+	client := config.Client(ctx)
+	client.Timeout = openEdxRequestTimeout
+	return &OpenEdxService{
+		ProviderPlatformID: provider.ID,
+		Client:             client,
+		BaseURL:            baseURL,
+		Org:                org,
+		JobParams:          params,
+	}, nil
+}
+
+func (srv *OpenEdxService) GetJobParams() map[string]any {
+	return srv.JobParams
+}
+
+func (srv *OpenEdxService) getJSON(endpoint string, result any) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := srv.Client.Do(req)
+	if err != nil {
+		log.Errorf("failed to send request to open edx: %v", err)
+		return err
+	}
+	defer func() {
+		if resp.Body.Close() != nil {
+			logger().Error("Failed to close response body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("open edx responded with code: %s for %s", resp.Status, req.URL.Path)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// getAllPages follows the 'next' links of a paginated list endpoint
+func getAllPages[T any](srv *OpenEdxService, endpoint string) ([]T, error) {
+	results := make([]T, 0)
+	for endpoint != "" {
+		var page OpenEdxPage[T]
+		if err := srv.getJSON(endpoint, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page.Results...)
+		endpoint = page.NextURL()
+	}
+	return results, nil
+}
+
+func (srv *OpenEdxService) getEnrollments(courseID string) ([]OpenEdxEnrollment, error) {
+	query := url.Values{}
+	if courseID != "" {
+		query.Set("course_id", courseID)
+	}
+	return getAllPages[OpenEdxEnrollment](srv, srv.BaseURL+"/api/enrollment/v1/enrollments/?"+query.Encode())
+}
+
+/**
+* There is no endpoint to list every account, so the learners enrolled in any course
+* are collected from the enrollments API and then looked up through the accounts API
+**/
+func (srv *OpenEdxService) GetUsers(db *gorm.DB) ([]models.ImportUser, error) {
+	enrollments, err := srv.getEnrollments("")
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, enrollment := range enrollments {
+		if seen[enrollment.User] {
+			continue
+		}
+		seen[enrollment.User] = true
+		var count int64
+		if err := db.Model(&models.ProviderUserMapping{}).Where("provider_platform_id = ? AND external_user_id = ?", srv.ProviderPlatformID, enrollment.User).Count(&count).Error; err != nil {
+			log.Errorf("Error counting provider_user_mappings: %v", err)
+			continue
+		}
+		if count == 0 {
+			usernames = append(usernames, enrollment.User)
+		}
+	}
+	importUsers := make([]models.ImportUser, 0, len(usernames))
+	for start := 0; start < len(usernames); start += openEdxAccountsBatchSize {
+		end := min(start+openEdxAccountsBatchSize, len(usernames))
+		query := url.Values{}
+		query.Set("username", strings.Join(usernames[start:end], ","))
+		accounts := make([]OpenEdxAccount, 0)
+		if err := srv.getJSON(srv.BaseURL+"/api/user/v1/accounts?"+query.Encode(), &accounts); err != nil {
+			return nil, err
+		}
+		for _, account := range accounts {
+			if !account.IsActive {
+				continue
+			}
+			importUsers = append(importUsers, account.IntoImportUser())
+		}
+	}
+	log.Printf("returning %d Unlocked Users", len(importUsers))
+	return importUsers, nil
+}
+
+func (srv *OpenEdxService) getCourseBlocks(courseID, username, blockTypes string) (*OpenEdxBlocksResponse, error) {
+	query := url.Values{}
+	query.Set("course_id", courseID)
+	query.Set("depth", "all")
+	query.Set("requested_fields", "graded,format,completion")
+	if blockTypes != "" {
+		query.Set("block_types_filter", blockTypes)
+	}
+	if username != "" {
+		query.Set("username", username)
+	} else {
+		query.Set("all_blocks", "true")
+	}
+	var blocks OpenEdxBlocksResponse
+	if err := srv.getJSON(srv.BaseURL+"/api/courses/v1/blocks/?"+query.Encode(), &blocks); err != nil {
+		return nil, err
+	}
+	return &blocks, nil
+}
+
+// every course run is listed each time, existing courses only have their milestone totals updated
+func (srv *OpenEdxService) ImportCourses(db *gorm.DB) error {
+	fields := log.Fields{"provider": srv.ProviderPlatformID, "Function": "ImportCourses"}
+	log.WithFields(fields).Info("importing courses from provider")
+	query := url.Values{}
+	query.Set("page_size", "100")
+	if srv.Org != "" {
+		query.Set("org", srv.Org)
+	}
+	runs, err := getAllPages[OpenEdxCourseRun](srv, srv.BaseURL+"/api/courses/v1/courses/?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		// progress is tracked by subsection, which is how open edx reports completion to learners
+		totalMilestones := 0
+		blocks, err := srv.getCourseBlocks(run.ID, "", "sequential")
+		if err != nil {
+			log.WithFields(fields).Errorf("failed to get blocks for course %s: %v", run.ID, err)
+		} else {
+			totalMilestones = len(blocks.Blocks)
+		}
+		var existing models.Course
+		if db.Where("provider_platform_id = ? AND external_id = ?", srv.ProviderPlatformID, run.ID).First(&existing).Error == nil {
+			if err == nil && existing.TotalProgressMilestones != uint(totalMilestones) {
+				if err := db.Model(&existing).Update("total_progress_milestones", totalMilestones).Error; err != nil {
+					log.WithFields(fields).Errorf("failed to update total_progress_milestones: %v", err)
+				}
+			}
+			continue
+		}
+		description := run.ShortDescription
+		if len(description) > 510 {
+			description = description[:510]
+		}
+		thumbnailURL := run.Media.Image.Raw
+		if thumbnailURL != "" && !strings.HasPrefix(thumbnailURL, "http") {
+			thumbnailURL = srv.BaseURL + thumbnailURL
+		}
+		course := models.Course{
+			ProviderPlatformID:      srv.ProviderPlatformID,
+			Name:                    run.Name,
+			AltName:                 run.Number,
+			ExternalID:              run.ID,
+			ExternalURL:             srv.BaseURL + "/courses/" + run.ID + "/course/",
+			Type:                    models.FixedEnrollment,
+			OutcomeTypes:            "grade, completion",
+			Description:             description,
+			ThumbnailURL:            thumbnailURL,
+			TotalProgressMilestones: uint(totalMilestones),
+		}
+		if run.Start != nil {
+			course.StartDt = parseDate(*run.Start, time.RFC3339)
+		}
+		if run.End != nil {
+			course.EndDt = parseDate(*run.End, time.RFC3339)
+		}
+		if err := db.Create(&course).Error; err != nil {
+			log.WithFields(fields).Errorf("Failed to create course: %v", err)
+			continue
+		}
+	}
+	return nil
+}
+
+func (srv *OpenEdxService) getCourseGrades(courseID string) ([]OpenEdxCourseGrade, error) {
+	return getAllPages[OpenEdxCourseGrade](srv, srv.BaseURL+"/api/grades/v1/courses/"+url.PathEscape(courseID)+"/")
+}
+
+/**
+* Completed subsections become submission milestones. The course grade is recorded as a
+* grade_received milestone once the learner has a score, and as an outcome once they pass
+**/
+func (srv *OpenEdxService) ImportMilestones(coursePair map[string]any, mappings []map[string]any, db *gorm.DB, lastRun time.Time) error {
+	courseID := uint(coursePair["course_id"].(int64))
+	externalCourseID := coursePair["external_course_id"].(string)
+	fields := log.Fields{"task": "ImportMilestones", "course_id": courseID, "external_id": externalCourseID}
+	users := make(map[string]uint)
+	for _, mapping := range mappings {
+		users[mapping["external_user_id"].(string)] = uint(mapping["user_id"].(int64))
+	}
+	for username, userID := range users {
+		blocks, err := srv.getCourseBlocks(externalCourseID, username, "sequential")
+		if err != nil {
+			// a 403/404 here means the user isn't enrolled in the course
+			log.WithFields(fields).Debugf("failed to get blocks for user %s: %v", username, err)
+			continue
+		}
+		for _, block := range blocks.Blocks {
+			if block.Type != "sequential" || !block.IsComplete() {
+				continue
+			}
+			milestone := models.Milestone{
+				UserID:      userID,
+				CourseID:    courseID,
+				ExternalID:  fmt.Sprintf("%s-%s", block.ID, username),
+				Type:        block.milestoneType(),
+				IsCompleted: true,
+			}
+			createMilestoneIfNotExists(db, &milestone, fields)
+		}
+	}
+	grades, err := srv.getCourseGrades(externalCourseID)
+	if err != nil {
+		log.WithFields(fields).Errorf("failed to get grades for course: %v", err)
+		return err
+	}
+	for _, grade := range grades {
+		userID, ok := users[grade.Username]
+		if !ok || grade.Percent <= 0 {
+			continue
+		}
+		milestone := models.Milestone{
+			UserID:      userID,
+			CourseID:    courseID,
+			ExternalID:  fmt.Sprintf("%s-grade-%s", externalCourseID, grade.Username),
+			Type:        models.GradeReceived,
+			IsCompleted: true,
+		}
+		createMilestoneIfNotExists(db, &milestone, fields)
+		if !grade.Passed {
+			continue
+		}
+		if db.Where("user_id = ? AND course_id = ?", userID, courseID).First(&models.Outcome{}).Error == nil {
+			continue
+		}
+		value := fmt.Sprintf("%.0f%%", grade.Percent*100)
+		if grade.LetterGrade != nil && *grade.LetterGrade != "" {
+			value = *grade.LetterGrade
+		}
+		outcome := models.Outcome{
+			Type:     models.CourseCompletion,
+			CourseID: courseID,
+			UserID:   userID,
+			Value:    value,
+		}
+		if err := db.Create(&outcome).Error; err != nil {
+			log.WithFields(fields).Errorf("failed to create outcome: %v", err)
+		}
+	}
+	return nil
+}
+
+/**
+* Open edX has no API that reports unit completion for every learner in a course, so the course
+* grades are fetched once per course and activity is recorded as a course_interaction whenever a
+* learner's grade changes since the last import. Open edX doesn't report time spent either, so
+* the interactions are recorded with no time
+**/
+func (srv *OpenEdxService) ImportActivityForCourse(coursePair map[string]any, db *gorm.DB) error {
+	courseID := int(coursePair["course_id"].(int64))
+	externalID := coursePair["external_course_id"].(string)
+	enrollments, err := srv.getEnrollments(externalID)
+	if err != nil {
+		log.Printf("Failed to get enrollments for course: %v", err)
+		return err
+	}
+	grades, err := srv.getCourseGrades(externalID)
+	if err != nil {
+		log.Printf("Failed to get grades for course: %v", err)
+		return err
+	}
+	percents := make(map[string]float64, len(grades))
+	for _, grade := range grades {
+		percents[grade.Username] = grade.Percent
+	}
+	failed := 0
+	for _, enrollment := range enrollments {
+		if !enrollment.IsActive {
+			continue
+		}
+		var userID uint
+		if err := db.Model(models.ProviderUserMapping{}).Select("user_id").First(&userID, "provider_platform_id = ? AND external_user_id = ?", srv.ProviderPlatformID, enrollment.User).Error; err != nil {
+			continue
+		}
+		if db.Model(&models.UserEnrollment{}).First(&models.UserEnrollment{}, "user_id = ? AND course_id = ?", userID, courseID).RowsAffected == 0 {
+			if err := db.Create(&models.UserEnrollment{UserID: userID, CourseID: uint(courseID)}).Error; err != nil {
+				log.WithFields(log.Fields{"userId": userID, "course_id": courseID, "error": err}).Error("Failed to create enrollment")
+				continue
+			}
+		}
+		percent := percents[enrollment.User]
+		if percent <= 0 {
+			continue
+		}
+		activityID := fmt.Sprintf("%s-%s-%.0f", externalID, enrollment.User, percent*10000)
+		if db.Where("course_id = ? AND external_id = ?", courseID, activityID).First(&models.Activity{}).Error == nil {
+			continue
+		}
+		if err := db.Exec("CALL insert_daily_activity_kolibri(?, ?, ?, ?, ?, ?)", userID, courseID, models.CourseInteraction, 0, activityID, time.Now()).Error; err != nil {
+			log.WithFields(log.Fields{"userId": userID, "course_id": courseID, "error": err}).Error("Failed to create activity")
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to record %d course interactions for course %d", failed, courseID)
+	}
+	return nil
+}
+
+/**
+* The application's token is requested on the first API call, so listing a single course run
+* checks the client credentials. The enrollments and grades APIs also need the application's
+* user to be global staff, which is the only permission open edx has in place of scopes.
+**/
+func (srv *OpenEdxService) TestConnection(report *models.ProviderConnectionReport, db *gorm.DB) {
+	// the heartbeat is checked without a token so rejected credentials are reported as an authentication failure
+	heartbeat := &http.Client{Timeout: srv.Client.Timeout}
+	resp, err := heartbeat.Get(srv.BaseURL + "/heartbeat")
+	if err != nil {
+		report.Fail(models.CheckConnectivity, "unable to reach %s: %v", srv.BaseURL, err)
+		return
+	}
+	if resp.Body.Close() != nil {
+		logger().Error("Failed to close response body")
+	}
+	report.Pass(models.CheckConnectivity, "%s is reachable", srv.BaseURL)
+
+	query := url.Values{}
+	query.Set("page_size", "1")
+	if srv.Org != "" {
+		query.Set("org", srv.Org)
+	}
+	var courses OpenEdxPage[OpenEdxCourseRun]
+	if err := srv.getJSON(srv.BaseURL+"/api/courses/v1/courses/?"+query.Encode(), &courses); err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			report.Fail(models.CheckAuthentication, "client credentials were rejected: %v", retrieveErr)
+			return
+		}
+		report.Fail(models.CheckAuthentication, "unable to list course runs: %v", err)
+		return
+	}
+	report.Pass(models.CheckAuthentication, "client credentials valid, %d course runs visible", courses.Pagination.Count)
+
+	var enrollments OpenEdxPage[OpenEdxEnrollment]
+	if err := srv.getJSON(srv.BaseURL+"/api/enrollment/v1/enrollments/?page_size=1", &enrollments); err != nil {
+		report.MissingScopes = []string{"global staff"}
+		report.Fail(models.CheckScopes, "the application's user needs global staff access to read enrollments: %v", err)
+		return
+	}
+	report.Pass(models.CheckScopes, "enrollments are readable")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"strings"
+)
+
+// list endpoints in open edx paginate either at the top level or in a 'pagination' object
+type OpenEdxPage[T any] struct {
+	Next       *string `json:"next"`
+	Pagination struct {
+		Next  *string `json:"next"`
+		Count int     `json:"count"`
+	} `json:"pagination"`
+	Results []T `json:"results"`
+}
+
+func (p *OpenEdxPage[T]) NextURL() string {
+	if p.Next != nil {
+		return *p.Next
+	}
+	if p.Pagination.Next != nil {
+		return *p.Pagination.Next
+	}
+	return ""
+}
+
+type OpenEdxAccount struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	IsActive bool   `json:"is_active"`
+}
+
+func (oa *OpenEdxAccount) IntoImportUser() models.ImportUser {
+	first, last := oa.Name, ""
+	if split := strings.SplitN(strings.TrimSpace(oa.Name), " ", 2); len(split) > 1 {
+		first, last = split[0], split[1]
+	}
+	if first == "" {
+		first = oa.Username
+	}
+	email := oa.Email
+	if email == "" {
+		email = oa.Username + "@unlocked.v2"
+	}
+	// the open edx APIs are keyed by username rather than the numeric user id
+	return models.ImportUser{
+		ExternalUserID:   oa.Username,
+		ExternalUsername: oa.Username,
+		Username:         oa.Username,
+		NameFirst:        first,
+		NameLast:         last,
+		Email:            email,
+	}
+}
+
+type OpenEdxCourseRun struct {
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Number           string  `json:"number"`
+	Org              string  `json:"org"`
+	ShortDescription string  `json:"short_description"`
+	Start            *string `json:"start"`
+	End              *string `json:"end"`
+	Media            struct {
+		Image struct {
+			Raw string `json:"raw"`
+		} `json:"image"`
+	} `json:"media"`
+}
+
+type OpenEdxEnrollment struct {
+	User     string `json:"user"`
+	CourseID string `json:"course_id"`
+	IsActive bool   `json:"is_active"`
+	Mode     string `json:"mode"`
+}
+
+type OpenEdxCourseGrade struct {
+	Username    string  `json:"username"`
+	CourseID    string  `json:"course_id"`
+	Passed      bool    `json:"passed"`
+	Percent     float64 `json:"percent"`
+	LetterGrade *string `json:"letter_grade"`
+}
+
+type OpenEdxBlock struct {
+	ID          string  `json:"id"`
+	Type        string  `json:"type"`
+	DisplayName string  `json:"display_name"`
+	Graded      bool    `json:"graded"`
+	Format      *string `json:"format"`
+	Completion  float64 `json:"completion"`
+}
+
+type OpenEdxBlocksResponse struct {
+	Root   string                  `json:"root"`
+	Blocks map[string]OpenEdxBlock `json:"blocks"`
+}
+
+func (b *OpenEdxBlock) IsComplete() bool {
+	return b.Completion >= 1
+}
+
+// graded subsections are given an assignment type (Homework, Midterm Exam, etc.) in studio
+func (b *OpenEdxBlock) milestoneType() models.MilestoneType {
+	if b.Format != nil {
+		format := strings.ToLower(*b.Format)
+		if strings.Contains(format, "exam") || strings.Contains(format, "quiz") {
+			return models.QuizSubmission
+		}
+	}
+	return models.AssignmentSubmission
+}
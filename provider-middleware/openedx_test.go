@@ -0,0 +1,178 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newOpenEdxTestServer stands in for the LMS, serving the recorded responses in testdata/openedx
+// and rejecting API calls that don't carry the JWT issued by the token endpoint
+func newOpenEdxTestServer(t *testing.T) *httptest.Server {
+	srv, _ := newCountingOpenEdxTestServer(t)
+	return srv
+}
+
+// newCountingOpenEdxTestServer also counts the requests made to each path
+func newCountingOpenEdxTestServer(t *testing.T) (*httptest.Server, map[string]int) {
+	var srv *httptest.Server
+	var mu sync.Mutex
+	requests := map[string]int{}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		fixture := ""
+		switch r.URL.Path {
+		case "/oauth2/access_token":
+			if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("token_type") != "jwt" {
+				http.Error(w, `{"error": "unsupported_grant_type"}`, http.StatusBadRequest)
+				return
+			}
+			if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+				http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+				return
+			}
+			fixture = "access_token"
+		case "/api/enrollment/v1/enrollments/":
+			fixture = "enrollments"
+			if r.URL.Query().Get("cursor") != "" {
+				fixture = "enrollments_page2"
+			}
+		case "/api/user/v1/accounts":
+			fixture = "accounts"
+		case "/api/courses/v1/courses/":
+			fixture = "courses"
+		case "/api/courses/v1/blocks/":
+			fixture = "blocks_course"
+			if r.URL.Query().Get("username") != "" {
+				fixture = "blocks_user"
+			}
+		case "/api/grades/v1/courses/course-v1:UnlockEd+GED101+2024/":
+			fixture = "grades"
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if fixture != "access_token" && r.Header.Get("Authorization") != "JWT test-jwt" {
+			http.Error(w, `{"detail": "Authentication credentials were not provided."}`, http.StatusUnauthorized)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", "openedx", fixture+".json"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(strings.ReplaceAll(string(body), "{{base_url}}", srv.URL)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func newTestOpenEdxService(t *testing.T, srv *httptest.Server, accessKey string) *OpenEdxService {
+	provider := &models.ProviderPlatform{Type: models.OpenEdx, BaseUrl: srv.URL, AccessKey: accessKey, AccountID: "UnlockEd"}
+	provider.ID = 1
+	service, err := newOpenEdxService(provider, nil)
+	require.NoError(t, err)
+	return service
+}
+
+func TestNewOpenEdxServiceRequiresClientCredentials(t *testing.T) {
+	_, err := newOpenEdxService(&models.ProviderPlatform{Type: models.OpenEdx, AccessKey: "only-a-secret"}, nil)
+	require.Error(t, err)
+}
+
+func TestOpenEdxGetUsers(t *testing.T) {
+	srv := newOpenEdxTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestOpenEdxService(t, srv, "client;secret")
+
+	users, err := service.GetUsers(db)
+	require.NoError(t, err)
+	require.Len(t, users, 2, "inactive accounts should be skipped")
+	require.Equal(t, "jdoe", users[0].ExternalUserID)
+	require.Equal(t, "Jane", users[0].NameFirst)
+	require.Equal(t, "Doe", users[0].NameLast)
+	require.Equal(t, "bsmith@unlocked.v2", users[1].Email)
+
+	_, err = newTestOpenEdxService(t, srv, "client;wrong").GetUsers(db)
+	require.Error(t, err, "a rejected client secret should fail the request")
+}
+
+func TestOpenEdxImportCourses(t *testing.T) {
+	srv := newOpenEdxTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestOpenEdxService(t, srv, "client;secret")
+
+	require.NoError(t, service.ImportCourses(db))
+	var course models.Course
+	require.NoError(t, db.First(&course, "external_id = ?", "course-v1:UnlockEd+GED101+2024").Error)
+	require.Equal(t, "GED Math Prep", course.Name)
+	require.Equal(t, uint(3), course.TotalProgressMilestones)
+	require.Equal(t, srv.URL+"/asset-v1:UnlockEd+GED101+2024+type@asset+block@course_image.png", course.ThumbnailURL)
+	require.Nil(t, course.EndDt)
+
+	require.NoError(t, service.ImportCourses(db))
+	var count int64
+	require.NoError(t, db.Model(&models.Course{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
+
+func TestOpenEdxImportMilestones(t *testing.T) {
+	srv := newOpenEdxTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestOpenEdxService(t, srv, "client;secret")
+	course := map[string]any{"course_id": int64(4), "external_course_id": "course-v1:UnlockEd+GED101+2024"}
+	mappings := []map[string]any{{"user_id": int64(2), "external_user_id": "jdoe"}}
+
+	require.NoError(t, service.ImportMilestones(course, mappings, db, time.Time{}))
+	types := map[models.MilestoneType]int{}
+	var milestones []models.Milestone
+	require.NoError(t, db.Find(&milestones).Error)
+	for _, milestone := range milestones {
+		types[milestone.Type]++
+	}
+	require.Equal(t, map[models.MilestoneType]int{
+		models.AssignmentSubmission: 1,
+		models.QuizSubmission:       1,
+		models.GradeReceived:        1,
+	}, types, "only completed subsections and non-zero grades become milestones")
+
+	var outcome models.Outcome
+	require.NoError(t, db.First(&outcome, "user_id = ? AND course_id = ?", 2, 4).Error)
+	require.Equal(t, "Pass", outcome.Value)
+
+	require.NoError(t, service.ImportMilestones(course, mappings, db, time.Time{}))
+	var count int64
+	require.NoError(t, db.Model(&models.Milestone{}).Count(&count).Error)
+	require.Equal(t, int64(3), count)
+}
+
+func TestOpenEdxImportActivityFetchesGradesOncePerCourse(t *testing.T) {
+	srv, requests := newCountingOpenEdxTestServer(t)
+	db := newProviderTestDB(t)
+	service := newTestOpenEdxService(t, srv, "client;secret")
+	require.NoError(t, db.Create(&[]models.ProviderUserMapping{
+		{UserID: 2, ProviderPlatformID: 1, ExternalUserID: "jdoe", ExternalUsername: "jdoe"},
+		{UserID: 3, ProviderPlatformID: 1, ExternalUserID: "bsmith", ExternalUsername: "bsmith"},
+	}).Error)
+
+	// the activity itself is recorded by a postgres stored procedure, which sqlite doesn't have,
+	// so only jdoe, the learner with a grade, is expected to fail
+	err := service.ImportActivityForCourse(map[string]any{"course_id": int64(4), "external_course_id": "course-v1:UnlockEd+GED101+2024"}, db)
+	require.ErrorContains(t, err, "failed to record 1 course interactions for course 4")
+	var enrollments []models.UserEnrollment
+	require.NoError(t, db.Order("user_id").Find(&enrollments).Error)
+	require.Len(t, enrollments, 2)
+	require.Equal(t, 1, requests["/api/grades/v1/courses/course-v1:UnlockEd+GED101+2024/"])
+	require.Zero(t, requests["/api/courses/v1/blocks/"], "blocks are not fetched per learner")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"UnlockEdv2/src/database"
+	"UnlockEdv2/src/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+// AccountDeactivator is implemented by the provider services that can suspend the account of a released resident
+type AccountDeactivator interface {
+	DeactivateUser(mapping *models.ProviderUserMapping) error
+}
+
+/**
+* The resident release job releases every resident whose release date has come: their login is deactivated in kratos,
+* their enrollments are closed and their transcript is saved, then their provider accounts are suspended.
+* Accounts that fail to be suspended are retried on the next run
+**/
+func (sh *ServiceHandler) handleResidentReleases(ctx context.Context, msg *nats.Msg) error {
+	var body map[string]any
+	if err := json.Unmarshal(msg.Data, &body); err != nil {
+		logger().Errorf("failed to unmarshal message: %v", err)
+		return fmt.Errorf("%w: %v", errMalformedJob, err)
+	}
+	jobId, ok := body["job_id"].(string)
+	if !ok {
+		logger().Errorf("failed to parse job_id: %v", body["job_id"])
+		return fmt.Errorf("%w: invalid job_id %v", errMalformedJob, body["job_id"])
+	}
+	return sh.cleanupJob(ctx, nil, jobId, sh.releaseResidents(ctx, time.Now()))
+}
+
+func (sh *ServiceHandler) releaseResidents(ctx context.Context, now time.Time) error {
+	db := database.NewDB(sh.db)
+	residents, err := db.GetDueReleases(ctx, now)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, resident := range residents {
+		fields := logrus.Fields{"user_id": resident.ID}
+		// the resident is only released once they can no longer log in, so a failure is retried on the next run
+		if err := deactivateKratosIdentity(ctx, http.DefaultClient, resident.KratosID); err != nil {
+			logger().WithFields(fields).Errorf("failed to deactivate kratos identity: %v", err)
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := db.ReleaseResident(ctx, resident.ID, now); err != nil {
+			logger().WithFields(fields).Errorf("failed to release resident: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	mappings, err := db.GetReleasedProviderMappings(ctx)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for idx := range mappings {
+		mapping := &mappings[idx]
+		fields := logrus.Fields{"user_id": mapping.UserID, "provider_platform_id": mapping.ProviderPlatformID}
+		if mapping.ProviderPlatform == nil {
+			continue
+		}
+		service, err := sh.newProviderService(mapping.ProviderPlatform, nil)
+		if err != nil {
+			logger().WithFields(fields).Errorf("failed to initialize provider service: %v", err)
+			errs = append(errs, err)
+			continue
+		}
+		deactivator, ok := service.(AccountDeactivator)
+		if !ok {
+			logger().WithFields(fields).Warnf("provider type %s does not support deactivating accounts, skipping", mapping.ProviderPlatform.Type)
+			continue
+		}
+		if err := deactivator.DeactivateUser(mapping); err != nil {
+			logger().WithFields(fields).Errorf("failed to deactivate provider account: %v", err)
+			errs = append(errs, err)
+			continue
+		}
+		if err := db.SetProviderMappingDeactivated(ctx, mapping.ID, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deactivateKratosIdentity sets the identity of the resident as inactive and revokes the sessions they have open
+func deactivateKratosIdentity(ctx context.Context, client *http.Client, kratosID string) error {
+	if kratosID == "" {
+		return nil
+	}
+	identityURL := os.Getenv("KRATOS_ADMIN_URL") + "/admin/identities/" + kratosID
+	patch, err := json.Marshal([]map[string]any{{"op": "replace", "path": "/state", "value": "inactive"}})
+	if err != nil {
+		return err
+	}
+	if err := sendKratosRequest(ctx, client, http.MethodPatch, identityURL, patch); err != nil {
+		return err
+	}
+	return sendKratosRequest(ctx, client, http.MethodDelete, identityURL+"/sessions", nil)
+}
+
+func sendKratosRequest(ctx context.Context, client *http.Client, method, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("KRATOS_TOKEN"))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if resp.Body.Close() != nil {
+			logger().Error("Failed to close response body")
+		}
+	}()
+	// kratos answers 404 when deleting the sessions of an identity that has none
+	if resp.StatusCode >= 300 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("kratos %s %s responded with code: %s", method, url, resp.Status)
+	}
+	return nil
+}
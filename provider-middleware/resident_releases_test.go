@@ -0,0 +1,18 @@
+package main
+
+import (
+	"UnlockEdv2/src/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// released residents are only queued for deactivation on these providers, so each one must be able to deactivate them
+func TestAccountDeactivatingProvidersImplementDeactivateUser(t *testing.T) {
+	sh := &ServiceHandler{db: newProviderTestDB(t)}
+	for _, providerType := range models.AccountDeactivatingProviders {
+		service, err := sh.newProviderService(&models.ProviderPlatform{Type: providerType, BaseUrl: "http://localhost", AccessKey: "key", AccountID: "1"}, nil)
+		require.NoError(t, err)
+		require.Implements(t, (*AccountDeactivator)(nil), service, "%s services must deactivate released residents", providerType)
+	}
+}